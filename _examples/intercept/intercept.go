@@ -2,10 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 
 	"gopkg.in/h2non/gentleman.v2"
 	"gopkg.in/h2non/gentleman.v2/context"
-	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
 func main() {
@@ -17,12 +19,14 @@ func main() {
 
 	// Attach a request midddleware function to intercept the request.
 	req.UseRequest(func(ctx *context.Context, h context.Handler) {
-		// If host matches, intercept the request
+		// If host matches, intercept the request with a synthetic response
 		if ctx.Request.URL.Host == "httpbin.org" {
-			ctx.Response.StatusCode = 200
-			utils.WriteBodyString(ctx.Response, "intercepted\n")
+			ctx.Intercept(&http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("intercepted\n")),
+			})
 		}
-		h.Stop(ctx)
+		h.Next(ctx)
 	})
 
 	// Perform the request
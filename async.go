@@ -0,0 +1,189 @@
+package gentleman
+
+import (
+	gocontext "context"
+	"errors"
+	"sync"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// ErrNoRequests is returned by Any when no requests are given.
+var ErrNoRequests = errors.New("gentleman: no requests to dispatch")
+
+// futureKey is the context store key used to store the Future of asynchronous requests.
+const futureKey = "$future"
+
+// Future represents the eventual result of an asynchronously dispatched Request.
+// Future is safe for concurrent use by multiple goroutines.
+type Future struct {
+	// mtx protects the cancelation state
+	mtx sync.Mutex
+
+	// canceled stores if the request was canceled by the caller
+	canceled bool
+
+	// cancel stores the request context cancel function, once available.
+	cancel gocontext.CancelFunc
+
+	// done is closed once the request has been resolved.
+	done chan struct{}
+
+	// Resolved response and error
+	res *Response
+	err error
+}
+
+// SendAsync dispatches the current request in a new goroutine and returns
+// a Future to wait for the Response or cancel the request later.
+func (r *Request) SendAsync() *Future {
+	f := &Future{done: make(chan struct{})}
+
+	// The dispatcher attaches a cancelable context right before dialing,
+	// so contexts defined by the request middleware are honored too.
+	r.Context.Set(futureKey, f)
+
+	go func() {
+		defer close(f.done)
+		f.res, f.err = r.Send()
+	}()
+
+	return f
+}
+
+// getFuture returns the Future of the asynchronous request stored in the given context, if any.
+func getFuture(ctx *context.Context) *Future {
+	f, _ := ctx.Get(futureKey).(*Future)
+	return f
+}
+
+// attach attaches a cancelable context to the given request context.
+// Returns false if the future was already canceled.
+func (f *Future) attach(ctx *context.Context) bool {
+	reqCtx, cancel := gocontext.WithCancel(ctx.Request.Context())
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	return f.setCancel(cancel)
+}
+
+// setCancel stores the request cancel function.
+// Returns false if the future was already canceled.
+func (f *Future) setCancel(cancel gocontext.CancelFunc) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.cancel = cancel
+	if f.canceled {
+		cancel()
+		return false
+	}
+	return true
+}
+
+// Done returns a channel that is closed once the request has been resolved.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the request is resolved and returns the Response and error.
+func (f *Future) Wait() (*Response, error) {
+	<-f.done
+	return f.res, f.err
+}
+
+// Cancel cancels the request. If the request was already resolved,
+// the response body stream will be aborted.
+func (f *Future) Cancel() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.canceled = true
+	if f.cancel != nil {
+		f.cancel()
+	}
+}
+
+// All dispatches the given requests concurrently, honoring the middleware of each one,
+// and waits until all of them are resolved.
+// Responses are returned in the same order as the given requests.
+// The returned error is the first request error, by request order, if any.
+func All(reqs ...*Request) ([]*Response, error) {
+	futures := make([]*Future, len(reqs))
+	for i, req := range reqs {
+		futures[i] = req.SendAsync()
+	}
+
+	var first error
+	responses := make([]*Response, len(reqs))
+	for i, future := range futures {
+		res, err := future.Wait()
+		responses[i] = res
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return responses, first
+}
+
+// Any dispatches the given requests concurrently, honoring the middleware of each one,
+// and returns the first successful response, which is the one without error and
+// with a 2xx or 3xx status code. Pending requests are then canceled, and the
+// responses of the other requests are closed.
+// If no request succeeds, the response and error of the last resolved request are returned.
+func Any(reqs ...*Request) (*Response, error) {
+	if len(reqs) == 0 {
+		return nil, ErrNoRequests
+	}
+
+	type result struct {
+		index int
+		res   *Response
+		err   error
+	}
+
+	results := make(chan result, len(reqs))
+	futures := make([]*Future, len(reqs))
+	for i, req := range reqs {
+		futures[i] = req.SendAsync()
+		go func(i int, f *Future) {
+			res, err := f.Wait()
+			results <- result{i, res, err}
+		}(i, futures[i])
+	}
+
+	var last result
+	var losers []*Response
+	pending := len(reqs)
+	for pending > 0 {
+		last = <-results
+		pending--
+		if last.err == nil && last.res.Ok {
+			break
+		}
+		if pending > 0 {
+			losers = append(losers, last.res)
+		}
+	}
+
+	// Cancel the remaining requests, closing the other responses
+	for i, future := range futures {
+		if i != last.index {
+			future.Cancel()
+		}
+	}
+	for _, res := range losers {
+		discard(res)
+	}
+	go func() {
+		for ; pending > 0; pending-- {
+			discard((<-results).res)
+		}
+	}()
+
+	return last.res, last.err
+}
+
+// discard closes the body of the given response, if any, without reading it.
+func discard(res *Response) {
+	if res != nil && res.RawResponse != nil && res.RawResponse.Body != nil {
+		res.RawResponse.Body.Close()
+	}
+}
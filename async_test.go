@@ -0,0 +1,187 @@
+package gentleman
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestRequestSendAsync(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL)
+	foo := cli.Request().Path("/foo").SendAsync()
+	bar := cli.Request().Path("/bar").SendAsync()
+
+	res, err := foo.Wait()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "/foo")
+
+	<-bar.Done()
+	res, err = bar.Wait()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "/bar")
+}
+
+func TestRequestSendAsyncCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	future := NewRequest().URL(ts.URL).SendAsync()
+	time.Sleep(50 * time.Millisecond)
+	future.Cancel()
+
+	res, err := future.Wait()
+	st.Expect(t, errors.Is(err, gocontext.Canceled), true)
+	st.Expect(t, res.Ok, false)
+}
+
+func TestRequestSendAsyncCancelBeforeDial(t *testing.T) {
+	req := NewRequest().URL("http://127.0.0.1:9123")
+	req.UseRequest(func(ctx *context.Context, h context.Handler) {
+		time.Sleep(50 * time.Millisecond)
+		h.Next(ctx)
+	})
+
+	future := req.SendAsync()
+	future.Cancel()
+
+	_, err := future.Wait()
+	st.Expect(t, err, gocontext.Canceled)
+}
+
+func TestAll(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path+r.Header.Get("Suffix"))
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL)
+	cli.UseRequest(func(ctx *context.Context, h context.Handler) {
+		ctx.Request.Header.Set("Suffix", "/bar")
+		h.Next(ctx)
+	})
+
+	responses, err := All(cli.Request().Path("/foo"), cli.Request().Path("/baz"))
+	st.Expect(t, err, nil)
+	st.Expect(t, len(responses), 2)
+	st.Expect(t, responses[0].String(), "/foo/bar")
+	st.Expect(t, responses[1].String(), "/baz/bar")
+}
+
+func TestAllError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "foo")
+	}))
+	defer ts.Close()
+
+	responses, err := All(NewRequest().URL(ts.URL), NewRequest().URL("http://127.0.0.1:9123"))
+	st.Reject(t, err, nil)
+	st.Expect(t, responses[0].String(), "foo")
+	st.Expect(t, responses[1].Ok, false)
+}
+
+func TestAny(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		fmt.Fprint(w, "slow")
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fast")
+	}))
+	defer fast.Close()
+
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer failed.Close()
+
+	res, err := Any(NewRequest().URL(slow.URL), NewRequest().URL(failed.URL), NewRequest().URL(fast.URL))
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "fast")
+}
+
+func TestAnyCloseLosers(t *testing.T) {
+	var closed int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(503)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	failed := func() *Request {
+		req := NewRequest().URL(ts.URL + "/fail")
+		req.UseResponse(func(ctx *context.Context, h context.Handler) {
+			ctx.Response.Body = &closeNotifier{ReadCloser: ctx.Response.Body, closed: &closed}
+			h.Next(ctx)
+		})
+		return req
+	}
+
+	res, err := Any(failed(), NewRequest().URL(ts.URL+"/ok"), failed())
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "/ok")
+
+	// The responses of the other requests are closed
+	st.Expect(t, atomic.LoadInt32(&closed), int32(2))
+}
+
+// closeNotifier counts the closed response bodies.
+type closeNotifier struct {
+	io.ReadCloser
+	closed *int32
+}
+
+func (b *closeNotifier) Close() error {
+	atomic.AddInt32(b.closed, 1)
+	return b.ReadCloser.Close()
+}
+
+func TestAnyFailure(t *testing.T) {
+	res, err := Any(NewRequest().URL("http://127.0.0.1:9123"))
+	st.Reject(t, err, nil)
+	st.Expect(t, res.Ok, false)
+
+	res, err = Any()
+	st.Expect(t, err, ErrNoRequests)
+	st.Expect(t, res == nil, true)
+}
+
+func TestRequestSendAsyncMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	req := NewRequest().URL(ts.URL)
+	plugins := len(req.Middleware.GetStack())
+	_, err := req.SendAsync().Wait()
+	st.Expect(t, err, nil)
+
+	// The request middleware is not modified
+	st.Expect(t, len(req.Middleware.GetStack()), plugins)
+}
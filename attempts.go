@@ -0,0 +1,79 @@
+package gentleman
+
+import (
+	"net/http"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// AttemptsKey is the context store key used to store the dial attempts
+// performed by the request. See GetAttempts().
+const AttemptsKey = "$attempts"
+
+// Attempt represents a network dial attempt performed by a request.
+type Attempt struct {
+	// Number stores the attempt number, starting from 1.
+	Number int
+
+	// Status stores the response status code, if any.
+	Status int
+
+	// Error stores the attempt error, if any.
+	Error error
+
+	// Start stores when the attempt started.
+	Start time.Time
+
+	// Duration stores the time spent until the response headers were received or the attempt failed.
+	Duration time.Duration
+}
+
+// Attempts represents the dial attempts performed by a request, in order.
+type Attempts []Attempt
+
+// Retried returns true if the request was attempted more than once.
+func (a Attempts) Retried() bool {
+	return len(a) > 1
+}
+
+// Duration returns the time elapsed from the start of the first attempt
+// until the end of the last one, including the delays between attempts.
+func (a Attempts) Duration() time.Duration {
+	if len(a) == 0 {
+		return 0
+	}
+	last := a[len(a)-1]
+	return last.Start.Add(last.Duration).Sub(a[0].Start)
+}
+
+// GetAttempts returns the dial attempts recorded in the given context.
+func GetAttempts(ctx *context.Context) Attempts {
+	attempts, _ := ctx.Get(AttemptsKey).(Attempts)
+	return attempts
+}
+
+// RecordAttempt records the given dial attempt in the given context,
+// such as from plugins performing retries at the transport level.
+// The attempt number is defined, if missing, based on AttemptKey.
+func RecordAttempt(ctx *context.Context, attempt Attempt) {
+	if attempt.Number == 0 {
+		attempt.Number, _ = ctx.GetInt(AttemptKey)
+	}
+	attempts := GetAttempts(ctx)
+	ctx.Set(AttemptsKey, append(attempts[:len(attempts):len(attempts)], attempt))
+}
+
+// NewAttempt creates a new attempt started at the given time, based on the given response or error.
+func NewAttempt(start time.Time, res *http.Response, err error) Attempt {
+	attempt := Attempt{Start: start, Duration: time.Since(start), Error: err}
+	if res != nil {
+		attempt.Status = res.StatusCode
+	}
+	return attempt
+}
+
+// Attempts returns the dial attempts performed by the request.
+func (r *Response) Attempts() Attempts {
+	return GetAttempts(r.Context)
+}
@@ -0,0 +1,53 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestResponseAttempts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Get().Send()
+	st.Expect(t, err, nil)
+	attempts := res.Attempts()
+	st.Expect(t, len(attempts), 1)
+	st.Expect(t, attempts.Retried(), false)
+	st.Expect(t, attempts[0].Number, 1)
+	st.Expect(t, attempts[0].Status, 201)
+	st.Expect(t, attempts[0].Error, nil)
+	st.Expect(t, attempts.Duration(), attempts[0].Duration)
+
+	// Failed attempts
+	ts.Close()
+	res, err = New().URL(ts.URL).Get().Send()
+	st.Reject(t, err, nil)
+	attempts = GetAttempts(res.Context)
+	st.Expect(t, len(attempts), 1)
+	st.Reject(t, attempts[0].Error, nil)
+	st.Expect(t, attempts[0].Status, 0)
+}
+
+func TestRecordAttempt(t *testing.T) {
+	ctx := context.New()
+	ctx.Set(AttemptKey, 1)
+	start := time.Now()
+	RecordAttempt(ctx, Attempt{Start: start, Duration: time.Second, Status: 503})
+	ctx.Set(AttemptKey, 2)
+	RecordAttempt(ctx, Attempt{Start: start.Add(3 * time.Second), Duration: time.Second, Status: 200})
+
+	attempts := GetAttempts(ctx)
+	st.Expect(t, len(attempts), 2)
+	st.Expect(t, attempts.Retried(), true)
+	st.Expect(t, attempts[0].Number, 1)
+	st.Expect(t, attempts[1].Number, 2)
+	st.Expect(t, attempts.Duration(), 4*time.Second)
+}
@@ -3,12 +3,18 @@ package gentleman
 import (
 	gocontext "context"
 	"net/http"
+	"strings"
+	"sync/atomic"
 
+	"golang.org/x/text/language"
 	"gopkg.in/h2non/gentleman.v2/context"
 	"gopkg.in/h2non/gentleman.v2/middleware"
+	"gopkg.in/h2non/gentleman.v2/mux"
 	"gopkg.in/h2non/gentleman.v2/plugin"
 	"gopkg.in/h2non/gentleman.v2/plugins/cookies"
 	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+	"gopkg.in/h2non/gentleman.v2/plugins/negotiate"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
@@ -37,12 +43,34 @@ type Client struct {
 // New creates a new high level client entity
 // able to perform HTTP requests.
 func New() *Client {
+	ctx := context.New()
+	ctx.Set(closerKey, newCloser())
 	return &Client{
-		Context:    context.New(),
+		Context:    ctx,
 		Middleware: middleware.New(),
 	}
 }
 
+// Freeze returns an immutable snapshot of the current Client, flattening the
+// middleware and context inherited from parent clients. Requests can be created
+// concurrently from frozen clients, which panic on any middleware or context mutation.
+// Changes in the current Client do not affect the frozen snapshot.
+func (c *Client) Freeze() *Client {
+	if c.Frozen() {
+		return c
+	}
+	return &Client{
+		Context:    c.Context.Snapshot(),
+		Middleware: middleware.Freeze(c.Middleware),
+	}
+}
+
+// Frozen returns true if the current Client is an immutable snapshot.
+func (c *Client) Frozen() bool {
+	_, ok := c.Middleware.(*middleware.Frozen)
+	return ok
+}
+
 // Request creates a new Request based on the current Client
 func (c *Client) Request() *Request {
 	req := NewRequest()
@@ -99,6 +127,15 @@ func (c *Client) Head() *Request {
 	return req
 }
 
+// Query creates a new QUERY request, a safe and idempotent request
+// carrying the query in its body, such as a JSON or SQL document.
+// See the redirect.PreserveMethod option to keep the body on 301 and 302 redirects.
+func (c *Client) Query() *Request {
+	req := c.Request()
+	req.Method(MethodQuery)
+	return req
+}
+
 // Method defines a the default HTTP method used by outgoing client requests.
 //
 // ⚠️ Method employs a new plugin within the middleware stack.
@@ -106,10 +143,7 @@ func (c *Client) Head() *Request {
 // Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
 // use `Request.Method()` instead.
 func (c *Client) Method(name string) *Client {
-	c.Middleware.UseRequest(func(ctx *context.Context, h context.Handler) {
-		ctx.Request.Method = name
-		h.Next(ctx)
-	})
+	c.Middleware.UseRequest(methodHandler(name))
 	return c
 }
 
@@ -205,6 +239,29 @@ func (c *Client) AddHeader(name, value string) *Client {
 	return c
 }
 
+// SetHeaderValues sets a multi-valued header field by name, sent as multiple field lines.
+// If another header exists with the same key, it will be overwritten.
+//
+// ⚠️ SetHeaderValues employs a new plugin within the middleware stack.
+// Exercise caution when utilising this method. Considering its applicability to all requests, it may yield unforeseen consequences.
+// Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
+// use `Request.SetHeaderValues()` instead.
+func (c *Client) SetHeaderValues(name string, values ...string) *Client {
+	c.Use(headers.SetValues(name, values...))
+	return c
+}
+
+// DelHeader deletes a header field by its name.
+//
+// ⚠️ DelHeader employs a new plugin within the middleware stack.
+// Exercise caution when utilising this method. Considering its applicability to all requests, it may yield unforeseen consequences.
+// Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
+// use `Request.DelHeader()` instead.
+func (c *Client) DelHeader(name string) *Client {
+	c.Use(headers.Del(name))
+	return c
+}
+
 // SetHeaders adds new header fields based on the given map.
 //
 // ⚠️ SetHeaders employs a new plugin within the middleware stack.
@@ -216,6 +273,41 @@ func (c *Client) SetHeaders(fields map[string]string) *Client {
 	return c
 }
 
+// PreserveHeaderCase sends the headers defined via SetHeader(), AddHeader() and SetHeaders()
+// with the exact given name casing, instead of the canonical one, for legacy servers
+// rejecting canonical header names. The header fields keep their canonical name, so
+// http.Header.Get() still finds them, and the exact names are written on the wire.
+// Only HTTP/1.x requests preserve the casing.
+func (c *Client) PreserveHeaderCase() *Client {
+	headers.EnablePreserveCase(c.Context)
+	c.Use(transport.HeaderCase())
+	return c
+}
+
+// Accepts defines the Accept header field of the client requests based on the given
+// media ranges and q-values, such as Accepts("application/json", "application/xml;q=0.8").
+//
+// ⚠️ Accepts employs a new plugin within the middleware stack.
+// Exercise caution when utilising this method. Considering its applicability to all requests, it may yield unforeseen consequences.
+// Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
+// use `Request.Accepts()` instead.
+func (c *Client) Accepts(types ...string) *Client {
+	c.Use(negotiate.Accept(types...))
+	return c
+}
+
+// AcceptLanguage defines the Accept-Language header field of the client requests
+// based on the given language preferences, sorted by preference.
+//
+// ⚠️ AcceptLanguage employs a new plugin within the middleware stack.
+// Exercise caution when utilising this method. Considering its applicability to all requests, it may yield unforeseen consequences.
+// Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
+// use `Request.AcceptLanguage()` instead.
+func (c *Client) AcceptLanguage(tags ...language.Tag) *Client {
+	c.Use(negotiate.AcceptLanguage(tags...))
+	return c
+}
+
 // AddCookie sets a new cookie field based on the given http.Cookie struct
 // without overwriting any existent cookie.
 //
@@ -241,13 +333,32 @@ func (c *Client) AddCookies(data []*http.Cookie) *Client {
 }
 
 // CookieJar creates a cookie jar to store HTTP cookies when they are sent down.
+// The jar is owned by the client, shared by its requests and child clients,
+// and rejects cookies set for public suffixes. See Jar().
 //
 // ⚠️ CookieJar employs a new plugin within the middleware stack.
 // Exercise caution when utilising this method. Considering its applicability to all requests, it may yield unforeseen consequences.
 // Behaviours, such as mutex locks, may lead to complications if misused. Should you require middleware for a single request only?
 // use `Request.CookieJar()` instead.
 func (c *Client) CookieJar() *Client {
-	c.Use(cookies.Jar())
+	jar, _ := cookies.NewStore(cookies.StoreOptions{})
+	c.Context.Set(cookies.JarKey, jar)
+	c.Use(cookies.UseJar(jar))
+	return c
+}
+
+// Jar returns the cookie jar created via CookieJar(), if any, which can be used
+// to list, get, set and delete cookies. The jar is inherited from parent clients.
+func (c *Client) Jar() *cookies.Store {
+	return cookies.GetJar(c.Context)
+}
+
+// Dialer defines the function used to dial the network connections of the client
+// requests, such as (&net.Dialer{}).DialContext or a proxy, Tor or in-memory test dialer.
+// The client requests use an isolated copy of their transport, therefore
+// its other options, like the TLS config or the proxy servers, are preserved.
+func (c *Client) Dialer(dial transport.DialContextFunc) *Client {
+	c.Use(transport.Dialer(dial))
 	return c
 }
 
@@ -262,6 +373,104 @@ func (c *Client) UseContext(cancelContext gocontext.Context) *Client {
 	return c
 }
 
+// Recover enables the recovery of panics raised by plugins, which are
+// reported as *middleware.PanicError via the error phase instead of crashing the program.
+func (c *Client) Recover() *Client {
+	middleware.EnableRecover(c.Context)
+	return c
+}
+
+// Replayable enables the buffering of the final request body, so the sent request
+// can be snapshotted via Response.Snapshot().
+func (c *Client) Replayable() *Client {
+	c.Context.Set(ReplayableKey, true)
+	return c
+}
+
+// OnBeforeSend registers a function called with every outgoing request before it is sent,
+// once the request phase defined it. This is a simple alternative to before dial plugins.
+func (c *Client) OnBeforeSend(fn BeforeSendHook) *Client {
+	c.Use(beforeSend(fn))
+	return c
+}
+
+// OnAfterResponse registers a function called with every received response.
+// This is a simple alternative to response plugins.
+func (c *Client) OnAfterResponse(fn AfterResponseHook) *Client {
+	c.Use(afterResponse(fn))
+	return c
+}
+
+// Validate registers the given validators, inspecting the final request before it is sent,
+// such as to enforce the required headers. Failed validators veto the request
+// with a *ValidationError reported via the error phase.
+func (c *Client) Validate(validators ...Validator) *Client {
+	c.Use(validate(validators...))
+	return c
+}
+
+// FailOnError enables the conversion of non-2xx responses into *HTTPError,
+// which is reported via the error phase and returned by Request.Send().
+func (c *Client) FailOnError() *Client {
+	c.Context.Set(FailOnErrorKey, true)
+	return c
+}
+
+// ErrorType registers the type used to decode non-2xx JSON response bodies,
+// such as &APIError{}. Non-2xx responses are reported as *HTTPError storing
+// the decoded value in Model, which can be retrieved via errors.As if it implements error.
+// Bodies larger than MaxErrorBodySize are not decoded.
+func (c *Client) ErrorType(model interface{}) *Client {
+	c.Context.Set(ErrorTypeKey, errorType(model))
+	return c
+}
+
+// UseSession applies the given session state, including the cookies, headers
+// and authorization credentials, to every client request. Replaces any previous session.
+// The client cookie jar, if any, is replaced by the session one. See Session.Export().
+func (c *Client) UseSession(session *Session) *Client {
+	c.Context.Set(cookies.JarKey, session.Jar())
+	c.Replace(SessionPluginName, session.plugin())
+	return c
+}
+
+// CancelOnClose enables the cancelation of the in-flight client requests on Close().
+func (c *Client) CancelOnClose() *Client {
+	if cl := getCloser(c.Context); cl != nil {
+		atomic.StoreInt32(&cl.cancel, 1)
+	}
+	return c
+}
+
+// Close closes the client: new requests fail with ErrClientClosed,
+// the in-flight requests are canceled if CancelOnClose() is enabled
+// and the resources of the client plugins implementing plugin.Closer are released,
+// such as the idle connections of the transports set or derived by the client plugins.
+// Shared transports, such as DefaultTransport, are never closed.
+// Returns the first error reported by the plugins.
+func (c *Client) Close() error {
+	if cl := getCloser(c.Context); cl != nil {
+		cl.close()
+	}
+	return closePlugins(c.Middleware.GetStack())
+}
+
+// OnInformational registers a function called on every interim 1xx response
+// received by the client requests, such as 103 Early Hints preload links.
+func (c *Client) OnInformational(fn InformationalHandler) *Client {
+	c.Use(informational(fn))
+	return c
+}
+
+// UserAgent adds the given product name, version and comments to the User-Agent header
+// of the client requests. The tokens are composed in decreasing order of significance:
+// the parent client tokens, followed by the child client ones and the library token,
+// such as "app/1.0 module/2.1 (linux) gentleman/2.x".
+func (c *Client) UserAgent(product, version string, comments ...string) *Client {
+	c.Use(userAgent(product, version, comments...))
+	return c
+}
+
 // Use uses a new plugin to the middleware stack.
 //
 // ⚠️ Use employs a new plugin within the middleware stack.
@@ -278,6 +487,54 @@ func (c *Client) Use(p plugin.Plugin) *Client {
 	return c
 }
 
+// UseWhen uses a new plugin which is only executed if the given predicate matches.
+func (c *Client) UseWhen(predicate plugin.Predicate, p plugin.Plugin) *Client {
+	c.Middleware.Use(plugin.When(predicate, p))
+	return c
+}
+
+// Group creates a new middleware group whose plugins are only
+// executed if the given predicate matches the outgoing request.
+func (c *Client) Group(predicate plugin.Predicate) *mux.Mux {
+	mx := mux.New()
+	c.UseWhen(predicate, mx)
+	return mx
+}
+
+// HostGroup creates a new middleware group scoped to the given request hosts.
+func (c *Client) HostGroup(hosts ...string) *mux.Mux {
+	return c.Group(plugin.Host(hosts...))
+}
+
+// PathGroup creates a new middleware group scoped to the given request path prefix.
+// A trailing wildcard is optional, such as "/admin/" or "/admin/*".
+func (c *Client) PathGroup(prefix string) *mux.Mux {
+	return c.Group(plugin.PathPrefix(strings.TrimSuffix(prefix, "*")))
+}
+
+// Unuse removes the plugins registered with the given name,
+// if supported by the client middleware. See middleware.Replacer.
+// Plugins inherited from a parent Client are only removed for the current Client.
+func (c *Client) Unuse(name string) *Client {
+	if m, ok := c.Middleware.(middleware.Replacer); ok {
+		m.Unuse(name)
+	}
+	return c
+}
+
+// Replace replaces the plugins registered with the given name by the given plugin,
+// or registers it if there is no plugin with the given name or the client middleware
+// does not support replacing plugins. See middleware.Replacer.
+// Plugins inherited from a parent Client are only replaced for the current Client.
+func (c *Client) Replace(name string, p plugin.Plugin) *Client {
+	if m, ok := c.Middleware.(middleware.Replacer); ok {
+		m.Replace(name, p)
+		return c
+	}
+	c.Middleware.Use(plugin.WithName(name, p))
+	return c
+}
+
 // UseRequest uses a new middleware function for request phase.
 //
 // ⚠️ UseRequest employs a new plugin within the middleware stack.
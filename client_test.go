@@ -1,16 +1,27 @@
 package gentleman
 
 import (
+	"bufio"
 	gocontext "context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/middleware"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+	"gopkg.in/h2non/gentleman.v2/plugins/cookies"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 )
 
 func TestClientMiddlewareContext(t *testing.T) {
@@ -51,6 +62,134 @@ func TestClientInheritance(t *testing.T) {
 	st.Expect(t, ctx.Request.Header.Get("Client"), "gogo")
 }
 
+func TestClientUnuseReplace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	parent := New().URL(ts.URL)
+	parent.Use(auth.Bearer("foo"))
+
+	child := New().UseParent(parent)
+	child.Replace("auth", auth.Bearer("bar"))
+
+	res, err := child.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer bar")
+
+	res, err = parent.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer foo")
+
+	res, err = child.Request().Unuse("auth").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "")
+}
+
+func TestClientReplaceUnsupported(t *testing.T) {
+	// Middleware implementations without middleware.Replacer support
+	cli := New()
+	cli.Middleware = struct{ middleware.Middleware }{middleware.New()}
+
+	cli.Replace("auth", auth.Bearer("foo"))
+	cli.Unuse("auth")
+	st.Expect(t, len(cli.Middleware.GetStack()), 1)
+	st.Expect(t, plugin.NameOf(cli.Middleware.GetStack()[0]), "auth")
+}
+
+func TestClientUseWhen(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL)
+	cli.UseWhen(plugin.PathPrefix("/admin/"), auth.Bearer("foo"))
+
+	res, err := cli.Request().Path("/admin/users").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer foo")
+
+	res, err = cli.Request().Path("/public").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "")
+
+	cli.UseWhen(plugin.Method("POST"), auth.Bearer("bar"))
+	res, err = cli.Post().Path("/admin/users").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer bar")
+}
+
+func TestClientGroups(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization")+r.Header.Get("Scope"))
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL)
+	cli.PathGroup("/admin/*").Use(auth.Bearer("foo")).UseResponse(func(ctx *context.Context, h context.Handler) {
+		ctx.Response.Header.Set("Group", "admin")
+		h.Next(ctx)
+	})
+	cli.HostGroup("example.com").UseRequest(func(ctx *context.Context, h context.Handler) {
+		ctx.Request.Header.Set("Scope", "example")
+		h.Next(ctx)
+	})
+
+	res, err := cli.Request().Path("/admin/users").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer foo")
+	st.Expect(t, res.Header.Get("Group"), "admin")
+
+	res, err = cli.Request().Path("/users").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "")
+	st.Expect(t, res.Header.Get("Group"), "")
+
+	cli.HostGroup("127.0.0.1").Use(headers.Set("Scope", "local"))
+	res, err = cli.Request().Path("/users").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "local")
+}
+
+func TestClientFreeze(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization")+r.URL.Path)
+	}))
+	defer ts.Close()
+
+	parent := New().URL(ts.URL)
+	parent.Context.Set("foo", "bar")
+	cli := New().UseParent(parent).Use(auth.Bearer("foo"))
+
+	frozen := cli.Freeze()
+	st.Expect(t, frozen.Frozen(), true)
+	st.Expect(t, cli.Frozen(), false)
+	st.Expect(t, frozen.Freeze(), frozen)
+	st.Expect(t, frozen.Context.Get("foo"), "bar")
+
+	// Changes do not affect the frozen client
+	cli.Use(auth.Bearer("bar"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := frozen.Request().Path("/foo").Send()
+			st.Expect(t, err, nil)
+			st.Expect(t, res.String(), "Bearer foo/foo")
+		}()
+	}
+	wg.Wait()
+
+	defer func() {
+		st.Expect(t, recover(), middleware.ErrFrozen)
+	}()
+	frozen.SetHeader("foo", "bar")
+}
+
 func TestClientRequestMiddleware(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Server", r.Header.Get("Client"))
@@ -251,6 +390,26 @@ func TestClientCookieJar(t *testing.T) {
 	st.Reject(t, cli.Context.Client.Jar, nil)
 }
 
+func TestClientJar(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL)
+	st.Expect(t, cli.Jar(), (*cookies.Store)(nil))
+	cli.CookieJar()
+
+	req := cli.Request()
+	_, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, req.Jar(), cli.Jar())
+
+	entries := cli.Jar().All()
+	st.Expect(t, len(entries), 1)
+	st.Expect(t, entries[0].Value, "secret")
+}
+
 func TestClientVerbMethods(t *testing.T) {
 	cli := New()
 	req := cli.Get()
@@ -300,6 +459,13 @@ func TestClientVerbMethods(t *testing.T) {
 	if req.Context.Request.Method != "OPTIONS" {
 		t.Errorf("Invalid request method: %s", req.Context.Request.Method)
 	}
+
+	cli = New()
+	req = cli.Query()
+	req.Middleware.Run("request", req.Context)
+	if req.Context.Request.Method != "QUERY" {
+		t.Errorf("Invalid request method: %s", req.Context.Request.Method)
+	}
 }
 
 func TestClientWithCanceledContext(t *testing.T) {
@@ -314,3 +480,177 @@ func TestClientWithCanceledContext(t *testing.T) {
 
 	st.Expect(t, strings.Contains(err.Error(), "context canceled"), true)
 }
+
+func TestClientRecover(t *testing.T) {
+	var recovered error
+	cli := New().URL("http://127.0.0.1:9123").Recover()
+	cli.UseRequest(func(ctx *context.Context, h context.Handler) {
+		panic("foo")
+	})
+	cli.UseError(func(ctx *context.Context, h context.Handler) {
+		recovered = ctx.Error
+		h.Next(ctx)
+	})
+
+	_, err := cli.Request().Send()
+	st.Reject(t, err, nil)
+	_, ok := err.(*middleware.PanicError)
+	st.Expect(t, ok, true)
+	st.Expect(t, recovered, err)
+}
+
+func TestClientClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	closed := 0
+	cli := New().URL(ts.URL)
+	cli.Use(plugin.WithCloser(plugin.NewRequestPlugin(nil), func() error {
+		closed++
+		return errors.New("foo")
+	}))
+
+	_, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+
+	st.Expect(t, cli.Close().Error(), "foo")
+	st.Expect(t, closed, 1)
+
+	_, err = cli.Request().Send()
+	st.Expect(t, err, ErrClientClosed)
+}
+
+func TestClientCloseTransports(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	reused := func(cli *Client) bool {
+		var reused bool
+		unsubscribe := cli.Events().Subscribe(func(Event) { reused = true }, ConnectionReused)
+		defer unsubscribe()
+		res, err := cli.Request().Send()
+		st.Expect(t, err, nil)
+		res.Close()
+		return reused
+	}
+
+	// Closing a client never closes the shared default transport connections
+	closed, shared := New().URL(ts.URL), New().URL(ts.URL)
+	reused(closed)
+	reused(shared)
+	st.Expect(t, closed.Close(), nil)
+	st.Expect(t, reused(shared), true)
+
+	// The transports derived by the client plugins are closed
+	derive := transport.Derive(func(*http.Transport) {})
+	closed, other := New().URL(ts.URL).Use(derive), New().URL(ts.URL).Use(derive)
+	reused(closed)
+	st.Expect(t, reused(other), true)
+	st.Expect(t, closed.Close(), nil)
+	st.Expect(t, reused(other), false)
+
+	// The transports set by the client plugins are closed
+	owned := &http.Transport{}
+	closed, other = New().URL(ts.URL).Use(transport.Set(owned)), New().URL(ts.URL).Use(transport.Set(owned))
+	reused(closed)
+	st.Expect(t, reused(other), true)
+	st.Expect(t, closed.Close(), nil)
+	st.Expect(t, reused(other), false)
+}
+
+func TestClientCancelOnClose(t *testing.T) {
+	started := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).CancelOnClose()
+	done := make(chan error)
+	go func() {
+		_, err := cli.Request().Send()
+		done <- err
+	}()
+
+	<-started
+	st.Expect(t, cli.Close(), nil)
+	err := <-done
+	st.Expect(t, errors.Is(err, gocontext.Canceled), true)
+}
+
+func TestClientPreserveHeaderCase(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	st.Expect(t, err, nil)
+	defer ln.Close()
+
+	// Captures the header lines as written on the wire
+	heads := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		reader.ReadLine()
+		var lines []string
+		for {
+			line, err := reader.ReadLine()
+			if err != nil || line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n"))
+		heads <- lines
+	}()
+
+	var contentType string
+	cli := New().URL("http://" + ln.Addr().String()).PreserveHeaderCase()
+	cli.SetHeader("user-agent", "legacy/1.0").SetHeader("x-api-KEY", "foo")
+	cli.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		contentType = ctx.Request.Header.Get("Content-Type")
+		h.Next(ctx)
+	})
+
+	res, err := cli.Post().SetHeader("content-TYPE", "text/plain").BodyString("hello").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 204)
+	st.Expect(t, contentType, "text/plain")
+
+	lines := <-heads
+	var names []string
+	for _, line := range lines {
+		names = append(names, strings.SplitN(line, ":", 2)[0])
+	}
+	sort.Strings(names)
+	st.Expect(t, names, []string{"Accept-Encoding", "Content-Length", "Host", "content-TYPE", "user-agent", "x-api-KEY"})
+	for _, line := range []string{"user-agent: legacy/1.0", "x-api-KEY: foo", "content-TYPE: text/plain"} {
+		found := false
+		for _, l := range lines {
+			found = found || l == line
+		}
+		st.Expect(t, found, true)
+	}
+}
+
+func TestClientDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer ts.Close()
+
+	var dialed []string
+	dialer := &net.Dialer{}
+	cli := New().URL("http://foo.test").Dialer(func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return dialer.DialContext(ctx, network, ts.Listener.Addr().String())
+	})
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "foo.test")
+	st.Expect(t, dialed, []string{"foo.test:80"})
+	st.Expect(t, DefaultTransport.DialContext == nil, true)
+}
@@ -0,0 +1,184 @@
+package gentleman
+
+import (
+	gocontext "context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// closerKey is the context store key used to store the client closing state.
+const closerKey = "$closer"
+
+// closer stores the client closing state, tracking the in-flight requests
+// of the client. closer is safe for concurrent use.
+type closer struct {
+	// closed stores if the client was closed.
+	closed int32
+
+	// cancel stores if the in-flight requests are canceled on close.
+	cancel int32
+
+	// mtx protects the closed state and the in-flight requests.
+	mtx sync.Mutex
+
+	// inflight stores the in-flight requests.
+	inflight map[*inflight]struct{}
+
+	// draining stores the number of in-flight requests awaited by the drained function.
+	draining int
+
+	// drained stores the function called once the awaited in-flight requests are complete, if any.
+	drained func()
+}
+
+// inflight represents an in-flight request.
+type inflight struct {
+	// cancel stores the request cancel function, if cancelable.
+	cancel gocontext.CancelFunc
+
+	// awaited stores if the request is awaited by the drained function.
+	awaited bool
+}
+
+func newCloser() *closer {
+	return &closer{inflight: make(map[*inflight]struct{})}
+}
+
+// getCloser returns the client closing state stored in the given context, if any.
+func getCloser(ctx *c.Context) *closer {
+	cl, _ := ctx.Get(closerKey).(*closer)
+	return cl
+}
+
+// acquire registers the given outgoing request, returning the function
+// used to release it once the request is complete.
+func (cl *closer) acquire(ctx *c.Context) (release func(), err error) {
+	if atomic.LoadInt32(&cl.closed) == 1 {
+		return nil, ErrClientClosed
+	}
+
+	req := &inflight{}
+	if atomic.LoadInt32(&cl.cancel) == 1 {
+		var reqCtx gocontext.Context
+		reqCtx, req.cancel = gocontext.WithCancel(ctx.Request.Context())
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+	}
+
+	// Register the request under the same lock as close(), so it is either
+	// rejected or canceled by a concurrent close
+	cl.mtx.Lock()
+	if atomic.LoadInt32(&cl.closed) == 1 {
+		cl.mtx.Unlock()
+		if req.cancel != nil {
+			req.cancel()
+		}
+		return nil, ErrClientClosed
+	}
+	cl.inflight[req] = struct{}{}
+	cl.mtx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			var drained func()
+			cl.mtx.Lock()
+			delete(cl.inflight, req)
+			if req.awaited {
+				cl.draining--
+				if cl.draining == 0 {
+					drained, cl.drained = cl.drained, nil
+				}
+			}
+			cl.mtx.Unlock()
+			if req.cancel != nil {
+				req.cancel()
+			}
+			if drained != nil {
+				drained()
+			}
+		})
+	}, nil
+}
+
+// drain calls the given function once the current in-flight requests are complete,
+// or right away if there are none. The requests started afterwards are not awaited.
+func (cl *closer) drain(fn func()) {
+	cl.mtx.Lock()
+	for req := range cl.inflight {
+		if !req.awaited {
+			req.awaited = true
+			cl.draining++
+		}
+	}
+	if cl.draining > 0 {
+		cl.drained = fn
+		cl.mtx.Unlock()
+		return
+	}
+	cl.mtx.Unlock()
+	fn()
+}
+
+// close marks the client as closed, canceling the in-flight requests if enabled.
+// The request transports are not closed here, since they are usually shared,
+// such as DefaultTransport: the transports derived by the client plugins
+// are closed by the plugins themselves. See transport.Derive().
+func (cl *closer) close() {
+	cl.mtx.Lock()
+	atomic.StoreInt32(&cl.closed, 1)
+	for req := range cl.inflight {
+		if req.cancel != nil {
+			req.cancel()
+		}
+	}
+	cl.mtx.Unlock()
+}
+
+// closeBody wraps the response body in order to release the in-flight
+// request once the body is fully read or closed.
+type closeBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Read reads from the response body, releasing the request on EOF.
+func (b *closeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+// Close closes the response body, releasing the request.
+func (b *closeBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// trackBody wraps the given response body releasing the request once consumed.
+func trackBody(res *http.Response, release func()) {
+	if res == nil || res.Body == nil {
+		release()
+		return
+	}
+	res.Body = &closeBody{ReadCloser: res.Body, release: release}
+}
+
+// closePlugins releases the resources of the given plugins implementing
+// plugin.Closer, returning the first error.
+func closePlugins(plugins []plugin.Plugin) error {
+	var err error
+	for _, p := range plugins {
+		if closeErr := plugin.Close(p); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,483 @@
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/proxy"
+	"gopkg.in/h2non/gentleman.v2/plugins/retry"
+	"gopkg.in/h2non/gentleman.v2/plugins/timeout"
+	gtls "gopkg.in/h2non/gentleman.v2/plugins/tls"
+)
+
+// DefaultEnvPrefix stores the default prefix of the environment variables read by Load().
+const DefaultEnvPrefix = "GENTLEMAN"
+
+// ErrInvalidCertificate is returned when the configured CA file contains no valid certificates.
+var ErrInvalidCertificate = errors.New("config: no valid certificates found in CA file")
+
+// Duration represents a time duration, defined as a string such as "1.5s" or "300ms",
+// or as a number of seconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("config: invalid duration: %s", data)
+	}
+	return d.UnmarshalText([]byte(value))
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("config: invalid duration: %s", text)
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// TLS represents the TLS connection configuration.
+type TLS struct {
+	// CAFile stores the path of the PEM encoded certificate authorities used to verify the servers.
+	CAFile string `json:"caFile,omitempty"`
+
+	// CertFile stores the path of the PEM encoded client certificate.
+	CertFile string `json:"certFile,omitempty"`
+
+	// KeyFile stores the path of the PEM encoded client certificate private key.
+	KeyFile string `json:"keyFile,omitempty"`
+
+	// ServerName stores the server name used to verify the server certificates.
+	ServerName string `json:"serverName,omitempty"`
+
+	// MinVersion stores the minimum TLS version, such as "1.2".
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// Insecure disables the verification of the server certificates, if true.
+	// A nil value keeps the inherited setting when merging configurations.
+	Insecure *bool `json:"insecure,omitempty"`
+}
+
+// Timeouts represents the timeouts configuration. See timeout.Timeouts.
+type Timeouts struct {
+	Request   Duration `json:"request,omitempty"`
+	Dial      Duration `json:"dial,omitempty"`
+	TLS       Duration `json:"tls,omitempty"`
+	KeepAlive Duration `json:"keepAlive,omitempty"`
+}
+
+// Retry represents the retry policy configuration. See retry.Options.
+type Retry struct {
+	Attempts int      `json:"attempts,omitempty"`
+	MinDelay Duration `json:"minDelay,omitempty"`
+	MaxDelay Duration `json:"maxDelay,omitempty"`
+}
+
+// Config represents the declarative configuration of a client.
+type Config struct {
+	// BaseURL stores the base URL used by the client requests.
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// Headers stores the headers sent by the client requests.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Proxy stores the URL of the proxy server used for both HTTP and HTTPS requests.
+	Proxy string `json:"proxy,omitempty"`
+
+	// TLS stores the TLS connection configuration.
+	TLS *TLS `json:"tls,omitempty"`
+
+	// Timeouts stores the timeouts configuration.
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// Retry stores the retry policy configuration.
+	Retry *Retry `json:"retry,omitempty"`
+}
+
+// Document represents a configuration document, defining the default
+// configuration and the named profiles overriding it, such as "dev" or "prod".
+type Document struct {
+	Config
+
+	// Profiles stores the configuration profiles by name.
+	Profiles map[string]*Config `json:"profiles,omitempty"`
+}
+
+// Parse parses the given JSON or YAML configuration document.
+// YAML documents are converted to JSON, hence both formats use the same field names.
+// See parseYAML() for the supported YAML subset.
+func Parse(data []byte) (*Document, error) {
+	doc := &Document{}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, doc); err != nil {
+			return nil, fmt.Errorf("config: %s", err)
+		}
+		return doc, nil
+	}
+
+	value, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, errors.New("config: yaml: document must be a mapping")
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s", err)
+	}
+	if err := json.Unmarshal(buf, doc); err != nil {
+		return nil, fmt.Errorf("config: %s", err)
+	}
+	return doc, nil
+}
+
+// LoadFile reads and parses the JSON or YAML configuration document at the given path.
+func LoadFile(path string) (*Document, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Profile returns the default configuration overridden by the given profile.
+// An empty name returns the default configuration.
+func (d *Document) Profile(name string) (*Config, error) {
+	if name == "" {
+		return d.Config.Merge(nil), nil
+	}
+	profile, ok := d.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("config: unknown profile: %s", name)
+	}
+	return d.Config.Merge(profile), nil
+}
+
+// Load loads the configuration document at the given path, selecting the given profile,
+// which defaults to the GENTLEMAN_PROFILE environment variable, and overrides it via the
+// environment variables prefixed by DefaultEnvPrefix. See FromEnv().
+func Load(path, profile string) (*Config, error) {
+	doc, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = os.Getenv(DefaultEnvPrefix + "_PROFILE")
+	}
+	config, err := doc.Profile(profile)
+	if err != nil {
+		return nil, err
+	}
+	env, err := FromEnv(DefaultEnvPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return config.Merge(env), nil
+}
+
+// FromEnv creates a new configuration from the environment variables with the given prefix,
+// such as GENTLEMAN_BASE_URL. The supported variables, without the prefix, are:
+//
+//	BASE_URL, PROXY, HEADER_<NAME> (such as HEADER_X_API_KEY for X-Api-Key),
+//	TIMEOUT, DIAL_TIMEOUT, TLS_TIMEOUT, KEEP_ALIVE,
+//	RETRY_ATTEMPTS, RETRY_MIN_DELAY, RETRY_MAX_DELAY,
+//	TLS_CA_FILE, TLS_CERT_FILE, TLS_KEY_FILE, TLS_SERVER_NAME, TLS_MIN_VERSION and TLS_INSECURE.
+func FromEnv(prefix string) (*Config, error) {
+	env := map[string]string{}
+	for _, pair := range os.Environ() {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], prefix+"_") {
+			env[strings.TrimPrefix(parts[0], prefix+"_")] = parts[1]
+		}
+	}
+
+	config := &Config{BaseURL: env["BASE_URL"], Proxy: env["PROXY"]}
+	for name, value := range env {
+		if strings.HasPrefix(name, "HEADER_") && len(name) > len("HEADER_") {
+			if config.Headers == nil {
+				config.Headers = map[string]string{}
+			}
+			config.Headers[strings.Replace(strings.TrimPrefix(name, "HEADER_"), "_", "-", -1)] = value
+		}
+	}
+
+	var err error
+	duration := func(name string) Duration {
+		var d Duration
+		if value, ok := env[name]; ok && err == nil {
+			if seconds, convErr := strconv.ParseFloat(value, 64); convErr == nil {
+				return Duration(seconds * float64(time.Second))
+			}
+			if textErr := d.UnmarshalText([]byte(value)); textErr != nil {
+				err = fmt.Errorf("config: invalid %s_%s: %s", prefix, name, value)
+			}
+		}
+		return d
+	}
+
+	timeouts := Timeouts{
+		Request:   duration("TIMEOUT"),
+		Dial:      duration("DIAL_TIMEOUT"),
+		TLS:       duration("TLS_TIMEOUT"),
+		KeepAlive: duration("KEEP_ALIVE"),
+	}
+	if timeouts != (Timeouts{}) {
+		config.Timeouts = &timeouts
+	}
+
+	policy := Retry{MinDelay: duration("RETRY_MIN_DELAY"), MaxDelay: duration("RETRY_MAX_DELAY")}
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := env["RETRY_ATTEMPTS"]; ok {
+		if policy.Attempts, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("config: invalid %s_RETRY_ATTEMPTS: %s", prefix, value)
+		}
+	}
+	if policy != (Retry{}) {
+		config.Retry = &policy
+	}
+
+	tlsConfig := TLS{
+		CAFile:     env["TLS_CA_FILE"],
+		CertFile:   env["TLS_CERT_FILE"],
+		KeyFile:    env["TLS_KEY_FILE"],
+		ServerName: env["TLS_SERVER_NAME"],
+		MinVersion: env["TLS_MIN_VERSION"],
+	}
+	if value, ok := env["TLS_INSECURE"]; ok {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("config: invalid %s_TLS_INSECURE: %s", prefix, value)
+		}
+		tlsConfig.Insecure = &insecure
+	}
+	if tlsConfig != (TLS{}) {
+		config.TLS = &tlsConfig
+	}
+
+	return config, nil
+}
+
+// Merge returns a copy of the current configuration overridden by the non-empty
+// fields of the given one, which can be nil. Headers are merged by canonical name.
+func (c *Config) Merge(override *Config) *Config {
+	merged := *c
+	merged.Headers = map[string]string{}
+	for name, value := range c.Headers {
+		merged.Headers[http.CanonicalHeaderKey(name)] = value
+	}
+	merged.TLS = mergeTLS(c.TLS, nil)
+	merged.Timeouts = mergeTimeouts(c.Timeouts, nil)
+	merged.Retry = mergeRetry(c.Retry, nil)
+	if override == nil {
+		return &merged
+	}
+
+	if override.BaseURL != "" {
+		merged.BaseURL = override.BaseURL
+	}
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+	for name, value := range override.Headers {
+		merged.Headers[http.CanonicalHeaderKey(name)] = value
+	}
+	merged.TLS = mergeTLS(merged.TLS, override.TLS)
+	merged.Timeouts = mergeTimeouts(merged.Timeouts, override.Timeouts)
+	merged.Retry = mergeRetry(merged.Retry, override.Retry)
+	return &merged
+}
+
+func mergeTLS(base, override *TLS) *TLS {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := TLS{}
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		if override.Insecure != nil {
+			insecure := *override.Insecure
+			merged.Insecure = &insecure
+		}
+		for _, field := range []struct{ dst, src *string }{
+			{&merged.CAFile, &override.CAFile},
+			{&merged.CertFile, &override.CertFile},
+			{&merged.KeyFile, &override.KeyFile},
+			{&merged.ServerName, &override.ServerName},
+			{&merged.MinVersion, &override.MinVersion},
+		} {
+			if *field.src != "" {
+				*field.dst = *field.src
+			}
+		}
+	}
+	return &merged
+}
+
+func mergeTimeouts(base, override *Timeouts) *Timeouts {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := Timeouts{}
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		for _, field := range []struct{ dst, src *Duration }{
+			{&merged.Request, &override.Request},
+			{&merged.Dial, &override.Dial},
+			{&merged.TLS, &override.TLS},
+			{&merged.KeepAlive, &override.KeepAlive},
+		} {
+			if *field.src != 0 {
+				*field.dst = *field.src
+			}
+		}
+	}
+	return &merged
+}
+
+func mergeRetry(base, override *Retry) *Retry {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := Retry{}
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		if override.Attempts != 0 {
+			merged.Attempts = override.Attempts
+		}
+		if override.MinDelay != 0 {
+			merged.MinDelay = override.MinDelay
+		}
+		if override.MaxDelay != 0 {
+			merged.MaxDelay = override.MaxDelay
+		}
+	}
+	return &merged
+}
+
+// Client creates a new client based on the current configuration.
+func (c *Config) Client() (*gentleman.Client, error) {
+	cli := gentleman.New()
+	if err := c.Apply(cli); err != nil {
+		return nil, err
+	}
+	return cli, nil
+}
+
+// Apply configures the given client based on the current configuration.
+func (c *Config) Apply(cli *gentleman.Client) error {
+	if c.BaseURL != "" {
+		cli.BaseURL(c.BaseURL)
+	}
+	for name, value := range c.Headers {
+		cli.SetHeader(name, value)
+	}
+	if c.Proxy != "" {
+		cli.Use(proxy.Set(map[string]string{"http": c.Proxy, "https": c.Proxy}))
+	}
+	if c.TLS != nil {
+		config, err := c.TLS.config()
+		if err != nil {
+			return err
+		}
+		cli.Use(gtls.Config(config))
+	}
+	if c.Timeouts != nil {
+		cli.Use(timeout.All(timeout.Timeouts{
+			Request:   time.Duration(c.Timeouts.Request),
+			Dial:      time.Duration(c.Timeouts.Dial),
+			TLS:       time.Duration(c.Timeouts.TLS),
+			KeepAlive: time.Duration(c.Timeouts.KeepAlive),
+		}))
+	}
+	if c.Retry != nil {
+		opts := retry.Options{Attempts: c.Retry.Attempts}
+		if c.Retry.MinDelay != 0 || c.Retry.MaxDelay != 0 {
+			min, max := time.Duration(c.Retry.MinDelay), time.Duration(c.Retry.MaxDelay)
+			if min == 0 {
+				min = retry.DefaultMinDelay
+			}
+			if max == 0 {
+				max = retry.DefaultMaxDelay
+			}
+			opts.Backoff, opts.MaxDelay = retry.Exponential(min, max), max
+		}
+		cli.Use(retry.New(opts))
+	}
+	return nil
+}
+
+// tlsVersions stores the supported TLS versions by name.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// config creates the TLS connection config.
+func (t *TLS) config() (*tls.Config, error) {
+	config := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.Insecure != nil && *t.Insecure}
+
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("config: unsupported TLS version: %s", t.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if t.CAFile != "" {
+		data, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, ErrInvalidCertificate
+		}
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
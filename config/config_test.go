@@ -0,0 +1,168 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+const document = `
+baseURL: http://localhost
+headers:
+  X-Client: gentleman
+timeouts:
+  request: 10s
+  dial: 2
+retry:
+  attempts: 2
+  minDelay: 1ms
+profiles:
+  prod:
+    baseURL: https://api.example.com
+    headers:
+      X-Env: prod
+    timeouts:
+      request: 30s
+    tls:
+      minVersion: "1.2"
+`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(document))
+	st.Expect(t, err, nil)
+
+	config, err := doc.Profile("")
+	st.Expect(t, err, nil)
+	st.Expect(t, config.BaseURL, "http://localhost")
+	st.Expect(t, config.Headers, map[string]string{"X-Client": "gentleman"})
+	st.Expect(t, config.Timeouts, &Timeouts{Request: Duration(10 * time.Second), Dial: Duration(2 * time.Second)})
+	st.Expect(t, config.Retry, &Retry{Attempts: 2, MinDelay: Duration(time.Millisecond)})
+	st.Expect(t, config.TLS, (*TLS)(nil))
+
+	config, err = doc.Profile("prod")
+	st.Expect(t, err, nil)
+	st.Expect(t, config.BaseURL, "https://api.example.com")
+	st.Expect(t, config.Headers, map[string]string{"X-Client": "gentleman", "X-Env": "prod"})
+	st.Expect(t, config.Timeouts, &Timeouts{Request: Duration(30 * time.Second), Dial: Duration(2 * time.Second)})
+	st.Expect(t, config.TLS, &TLS{MinVersion: "1.2"})
+
+	// The default configuration is not mutated by profiles
+	st.Expect(t, doc.Config.Headers, map[string]string{"X-Client": "gentleman"})
+
+	_, err = doc.Profile("staging")
+	st.Reject(t, err, nil)
+
+	// JSON documents
+	doc, err = Parse([]byte(`{"baseURL": "http://localhost", "timeouts": {"request": "5s"}, "profiles": {"dev": {"proxy": "http://proxy"}}}`))
+	st.Expect(t, err, nil)
+	config, err = doc.Profile("dev")
+	st.Expect(t, err, nil)
+	st.Expect(t, config.BaseURL, "http://localhost")
+	st.Expect(t, config.Proxy, "http://proxy")
+	st.Expect(t, config.Timeouts.Request, Duration(5*time.Second))
+
+	doc, err = Parse([]byte("tls:\n  insecure: true\nprofiles:\n  prod:\n    tls:\n      insecure: false\n"))
+	st.Expect(t, err, nil)
+	config, err = doc.Profile("prod")
+	st.Expect(t, err, nil)
+	st.Expect(t, *config.TLS.Insecure, false)
+
+	_, err = Parse([]byte(`{"baseURL": http://localhost}`))
+	st.Reject(t, err, nil)
+	_, err = Parse([]byte(`timeouts: {request: 5s}`))
+	st.Reject(t, err, nil)
+	_, err = Parse([]byte("timeouts:\n  request: forever"))
+	st.Reject(t, err, nil)
+}
+
+func TestMergeTLS(t *testing.T) {
+	yes, no := true, false
+	base := &Config{TLS: &TLS{Insecure: &yes, ServerName: "localhost"}}
+
+	config := base.Merge(&Config{TLS: &TLS{MinVersion: "1.2"}})
+	st.Expect(t, config.TLS, &TLS{Insecure: &yes, ServerName: "localhost", MinVersion: "1.2"})
+
+	config = base.Merge(&Config{TLS: &TLS{Insecure: &no}})
+	st.Expect(t, config.TLS, &TLS{Insecure: &no, ServerName: "localhost"})
+	st.Expect(t, *base.TLS.Insecure, true)
+
+	tlsConfig, err := config.TLS.config()
+	st.Expect(t, err, nil)
+	st.Expect(t, tlsConfig.InsecureSkipVerify, false)
+}
+
+func TestFromEnv(t *testing.T) {
+	os.Setenv("TEST_BASE_URL", "http://env")
+	os.Setenv("TEST_HEADER_X_API_KEY", "secret")
+	os.Setenv("TEST_TIMEOUT", "1.5")
+	os.Setenv("TEST_RETRY_ATTEMPTS", "4")
+	os.Setenv("TEST_TLS_INSECURE", "true")
+	defer func() {
+		for _, name := range []string{"BASE_URL", "HEADER_X_API_KEY", "TIMEOUT", "RETRY_ATTEMPTS", "TLS_INSECURE"} {
+			os.Unsetenv("TEST_" + name)
+		}
+	}()
+
+	config, err := FromEnv("TEST")
+	st.Expect(t, err, nil)
+	st.Expect(t, config.BaseURL, "http://env")
+	st.Expect(t, config.Headers, map[string]string{"X-API-KEY": "secret"})
+	st.Expect(t, config.Merge(nil).Headers, map[string]string{"X-Api-Key": "secret"})
+	st.Expect(t, config.Timeouts, &Timeouts{Request: Duration(1500 * time.Millisecond)})
+	st.Expect(t, config.Retry, &Retry{Attempts: 4})
+	insecure := true
+	st.Expect(t, config.TLS, &TLS{Insecure: &insecure})
+
+	os.Setenv("TEST_TIMEOUT", "soon")
+	_, err = FromEnv("TEST")
+	st.Reject(t, err, nil)
+}
+
+func TestLoad(t *testing.T) {
+	var calls int32
+	var client, env string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, env = r.Header.Get("X-Client"), r.Header.Get("X-Env")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "config")
+	st.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "client.yaml")
+	err = ioutil.WriteFile(path, []byte(document+"  test:\n    baseURL: "+ts.URL+"\n"), 0600)
+	st.Expect(t, err, nil)
+
+	os.Setenv(DefaultEnvPrefix+"_PROFILE", "test")
+	os.Setenv(DefaultEnvPrefix+"_HEADER_X_ENV", "ci")
+	defer os.Unsetenv(DefaultEnvPrefix + "_PROFILE")
+	defer os.Unsetenv(DefaultEnvPrefix + "_HEADER_X_ENV")
+
+	config, err := Load(path, "")
+	st.Expect(t, err, nil)
+	st.Expect(t, config.BaseURL, ts.URL)
+
+	cli, err := config.Client()
+	st.Expect(t, err, nil)
+	res, err := cli.Get().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, atomic.LoadInt32(&calls), int32(2))
+	st.Expect(t, client, "gentleman")
+	st.Expect(t, env, "ci")
+
+	_, err = (&Config{TLS: &TLS{MinVersion: "2.0"}}).Client()
+	st.Reject(t, err, nil)
+	_, err = Load(path, "staging")
+	st.Reject(t, err, nil)
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// line represents a significant line of a YAML document.
+type line struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML parses the YAML subset used by configuration documents, such as
+// block mappings, block sequences, empty or single line flow collections and scalars,
+// into the generic values produced by encoding/json.
+// Anchors, tags, multiple documents and multi-line scalars are not supported.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []line
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || (len(lines) == 0 && trimmed == "---") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("config: yaml: line %d: tabs are not allowed as indentation", i+1)
+		}
+		lines = append(lines, line{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return value, nil
+}
+
+// yamlParser parses a sequence of YAML lines.
+type yamlParser struct {
+	lines []line
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("config: yaml: line %d: %s", p.lines[p.pos].number, fmt.Sprintf(format, args...))
+}
+
+// parseBlock parses the block collection starting at the current line and the given indentation.
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+		current := p.lines[p.pos]
+		item := strings.TrimLeft(strings.TrimPrefix(current.text, "-"), " ")
+
+		switch {
+		case item == "":
+			p.pos++
+			value, err := p.parseNested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		case isMappingEntry(item):
+			// Mapping starting in the sequence item line, such as "- name: foo"
+			p.lines[p.pos] = line{number: current.number, indent: indent + len(current.text) - len(item), text: item}
+			value, err := p.parseMapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		default:
+			value, err := parseScalar(item)
+			if err != nil {
+				return nil, p.errorf("%s", err)
+			}
+			items = append(items, value)
+			p.pos++
+		}
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	entries := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		text := p.lines[p.pos].text
+		if isSequenceItem(text) || !isMappingEntry(text) {
+			return nil, p.errorf("expected a mapping entry")
+		}
+
+		key, value := splitEntry(text)
+		key, err := parseKey(key)
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		if _, ok := entries[key]; ok {
+			return nil, p.errorf("duplicated key %q", key)
+		}
+
+		if value != "" {
+			if entries[key], err = parseScalar(value); err != nil {
+				return nil, p.errorf("%s", err)
+			}
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if entries[key], err = p.parseNested(indent, true); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// parseNested parses the collection nested in the previous line, if any.
+// Sequences nested in mappings can use the same indentation as the parent key.
+func (p *yamlParser) parseNested(indent int, mapping bool) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (mapping && next.indent == indent && isSequenceItem(next.text)) {
+		return p.parseBlock(next.indent)
+	}
+	return nil, nil
+}
+
+// isSequenceItem returns true if the given line text is a block sequence item.
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isMappingEntry returns true if the given line text is a block mapping entry.
+func isMappingEntry(text string) bool {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return false
+	}
+	key, _ := splitEntry(text)
+	return key != text
+}
+
+// splitEntry splits the given mapping entry into its key and value.
+func splitEntry(text string) (string, string) {
+	quote := byte(0)
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		}
+	}
+	return text, ""
+}
+
+// stripComment removes the trailing comment of the given line, if any.
+func stripComment(text string) string {
+	quote := byte(0)
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == ':' || text[i-1] == '-' {
+				quote = ch
+			}
+		case ch == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func parseKey(key string) (string, error) {
+	if strings.HasPrefix(key, `"`) || strings.HasPrefix(key, "'") {
+		value, err := parseScalar(key)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(value), nil
+	}
+	return key, nil
+}
+
+// parseScalar parses the given scalar or single line flow collection.
+func parseScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double quoted string %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("invalid single quoted string %s", text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	case strings.HasPrefix(text, "["):
+		return parseFlowSequence(text)
+	case text == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(text, "{"), strings.HasPrefix(text, "|"), strings.HasPrefix(text, ">"),
+		strings.HasPrefix(text, "&"), strings.HasPrefix(text, "*"), strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("unsupported value %s", text)
+	}
+
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return float64(n), nil
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return n, nil
+	}
+	return text, nil
+}
+
+// parseFlowSequence parses a single line flow sequence of scalars, such as "[a, b]".
+func parseFlowSequence(text string) (interface{}, error) {
+	if !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("invalid flow sequence %s", text)
+	}
+	items := []interface{}{}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return items, nil
+	}
+	for _, item := range strings.Split(inner, ",") {
+		item = strings.TrimSpace(item)
+		if strings.HasPrefix(item, "[") || strings.HasPrefix(item, "{") {
+			return nil, fmt.Errorf("unsupported nested flow collection %s", text)
+		}
+		value, err := parseScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestParseYAML(t *testing.T) {
+	value, err := parseYAML([]byte(`
+# Comment
+name: "foo # bar" # trailing comment
+count: 3
+enabled: true
+empty:
+ratio: 1.5
+tags: [a, 'b', 2]
+nested:
+  key: value
+  list:
+  - one
+  - two
+items:
+  - name: first
+    value: 1
+  -
+    name: second
+`))
+	st.Expect(t, err, nil)
+	st.Expect(t, value, map[string]interface{}{
+		"name":    "foo # bar",
+		"count":   float64(3),
+		"enabled": true,
+		"empty":   nil,
+		"ratio":   1.5,
+		"tags":    []interface{}{"a", "b", float64(2)},
+		"nested": map[string]interface{}{
+			"key":  "value",
+			"list": []interface{}{"one", "two"},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"name": "first", "value": float64(1)},
+			map[string]interface{}{"name": "second"},
+		},
+	})
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	cases := []string{
+		"foo: bar\n  baz: qux",
+		"foo: bar\nfoo: baz",
+		"foo: |\n  multi-line",
+		"foo: bar\n\tbaz: qux",
+		"foo: \"unterminated",
+		"foo\nbar: baz",
+	}
+	for _, test := range cases {
+		_, err := parseYAML([]byte(test))
+		st.Reject(t, err, nil)
+	}
+}
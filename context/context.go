@@ -11,19 +11,153 @@ package context
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
-// Key stores the key identifier for the built-in context
+// Key stores the key identifier for the built-in context.
+// The request context value stored under Key is a Store snapshot of the context
+// store values, which must not be modified: use Context.Set() instead.
 var Key interface{} = "$gentleman"
 
+// storeKey is the request context key used to store the context store.
+type storeKey struct{}
+
+// ErrFrozen is used when trying to mutate a frozen Context.
+var ErrFrozen = errors.New("gentleman: cannot mutate a frozen context")
+
 // Store represents the map store for context store.
 type Store map[interface{}]interface{}
 
+// store implements a key-value store safe for concurrent access,
+// since parent contexts are shared by concurrent requests.
+// Stores are copy-on-write: cloned stores share the data until any of them is modified.
+type store struct {
+	// mtx protects the store data
+	mtx sync.RWMutex
+
+	// data stores the context values
+	data Store
+
+	// shared stores if the data is shared with other stores,
+	// therefore it must be copied before being modified.
+	shared bool
+}
+
+// newStore creates a new store with the given data.
+func newStore(data Store) *store {
+	return &store{data: data}
+}
+
+// storeContext carries the context store in the request context,
+// exposing its values under Key.
+type storeContext struct {
+	context.Context
+	store *store
+}
+
+// withStore returns a copy of the given parent context carrying the given store.
+func withStore(parent context.Context, s *store) context.Context {
+	return &storeContext{Context: parent, store: s}
+}
+
+// Value returns the store under storeKey, a snapshot of its values under Key,
+// or the parent context value.
+func (c *storeContext) Value(key interface{}) interface{} {
+	switch key {
+	case storeKey{}:
+		return c.store
+	case Key:
+		return c.store.view()
+	}
+	return c.Context.Value(key)
+}
+
+// get returns the stored value for the given key.
+func (s *store) get(key interface{}) (interface{}, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// set stores a value by key.
+func (s *store) set(key, value interface{}) {
+	s.mtx.Lock()
+	s.own()
+	s.data[key] = value
+	s.mtx.Unlock()
+}
+
+// delete deletes the stored value for the given key.
+func (s *store) delete(key interface{}) {
+	s.mtx.Lock()
+	s.own()
+	delete(s.data, key)
+	s.mtx.Unlock()
+}
+
+// clear deletes all the stored values.
+func (s *store) clear() {
+	s.mtx.Lock()
+	s.data = Store{}
+	s.shared = false
+	s.mtx.Unlock()
+}
+
+// own copies the shared data before being modified.
+// The caller must hold the lock.
+func (s *store) own() {
+	if !s.shared {
+		return
+	}
+	data := make(Store, len(s.data))
+	for key, value := range s.data {
+		data[key] = value
+	}
+	s.data = data
+	s.shared = false
+}
+
+// view returns the stored values, which must not be modified.
+func (s *store) view() Store {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.shared = true
+	return s.data
+}
+
+// clone returns a new store sharing the stored values until modified.
+func (s *store) clone() *store {
+	return &store{data: s.view(), shared: true}
+}
+
+// size returns the number of stored values.
+func (s *store) size() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return len(s.data)
+}
+
+// copy returns a copy of the stored values.
+func (s *store) copy() Store {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	buf := make(Store, len(s.data))
+	for key, value := range s.data {
+		buf[key] = value
+	}
+	return buf
+}
+
 // Context encapsulates required domain-specific HTTP entities
 // to share data and entities for HTTP transactions in a middleware chain
 type Context struct {
@@ -44,38 +178,91 @@ type Context struct {
 
 	// Reference to the http.Response used in the current HTTP transaction
 	Response *http.Response
+
+	// frozen stores if the context store can no longer be mutated
+	frozen bool
+
+	// released stores if the context was returned to the pool
+	released bool
+
+	// intercepted stores if the HTTP transaction was intercepted with a synthetic response
+	intercepted bool
+
+	// headers stores the request and response header maps owned by the context,
+	// which are reused once the context is released.
+	headers [2]http.Header
+}
+
+// pool stores released contexts for reuse.
+var pool = sync.Pool{
+	New: func() interface{} {
+		return new(Context)
+	},
 }
 
 // New creates an empty default Context
 func New() *Context {
-	req := createRequest()
-	res := createResponse(req)
-	cli := &http.Client{Transport: http.DefaultTransport}
-	return &Context{Request: req, Response: res, Client: cli}
+	ctx := pool.Get().(*Context)
+	if ctx.headers[0] == nil {
+		ctx.headers = [2]http.Header{make(http.Header), make(http.Header)}
+	}
+	ctx.released = false
+	ctx.Request = createRequest(ctx.headers[0])
+	ctx.Response = createResponse(ctx.Request, ctx.headers[1])
+	ctx.Client = &http.Client{Transport: http.DefaultTransport}
+	return ctx
+}
+
+// Release drains and closes the response body, resets the context and returns it
+// to the internal pool, reducing allocations in high throughput scenarios.
+// The context, its request and its response must not be used after calling Release.
+// Frozen contexts are never released, since they are shared.
+func (c *Context) Release() {
+	if c.frozen || c.released {
+		return
+	}
+
+	if c.Response != nil && c.Response.Body != nil {
+		io.Copy(ioutil.Discard, c.Response.Body)
+		c.Response.Body.Close()
+	}
+
+	headers := c.headers
+	for _, header := range headers {
+		for key := range header {
+			delete(header, key)
+		}
+	}
+
+	*c = Context{headers: headers, released: true}
+	pool.Put(c)
 }
 
 // getStore retrieves the current request context data store.
-func (c *Context) getStore() Store {
-	store, ok := c.Request.Context().Value(Key).(Store)
+func (c *Context) getStore() *store {
+	store, ok := c.Request.Context().Value(storeKey{}).(*store)
 	if !ok {
 		panic("invalid request context")
 	}
 	return store
 }
 
+// mutate panics if the context is frozen.
+func (c *Context) mutate() {
+	if c.frozen {
+		panic(ErrFrozen)
+	}
+}
+
 // Set sets a value on the current store
 func (c *Context) Set(key interface{}, value interface{}) {
-	store := c.getStore()
-	store[key] = value
+	c.mutate()
+	c.getStore().set(key, value)
 }
 
 // Get gets a value by key in the current or parent context
 func (c *Context) Get(key interface{}) interface{} {
-	store := c.getStore()
-	if store == nil {
-		return store
-	}
-	if value, ok := store[key]; ok {
+	if value, ok := c.getStore().get(key); ok {
 		return value
 	}
 	if c.Parent != nil {
@@ -87,8 +274,7 @@ func (c *Context) Get(key interface{}) interface{} {
 // GetOk gets a context value from req.
 // Returns (nil, false) if key not found in the request context.
 func (c *Context) GetOk(key interface{}) (interface{}, bool) {
-	store := c.getStore()
-	val, ok := store[key]
+	val, ok := c.getStore().get(key)
 	if !ok {
 		if c.Parent != nil {
 			return c.Parent.GetOk(key)
@@ -117,8 +303,7 @@ func (c *Context) GetInt(key interface{}) (int, bool) {
 // Returns an empty string if key not found in the request context,
 // or the value does not evaluate to a string
 func (c *Context) GetString(key interface{}) string {
-	store := c.getStore()
-	if value, ok := store[key]; ok {
+	if value, ok := c.getStore().get(key); ok {
 		if typed, ok := value.(string); ok {
 			return typed
 		}
@@ -133,10 +318,7 @@ func (c *Context) GetString(key interface{}) string {
 // Will always return a valid map. Returns an empty map for
 // requests context data previously set
 func (c *Context) GetAll() Store {
-	buf := Store{}
-	for key, value := range c.getStore() {
-		buf[key] = value
-	}
+	buf := c.getStore().copy()
 	if c.Parent != nil {
 		for key, value := range c.Parent.GetAll() {
 			buf[key] = value
@@ -147,20 +329,20 @@ func (c *Context) GetAll() Store {
 
 // Delete deletes a stored value from a request’s context
 func (c *Context) Delete(key interface{}) {
-	delete(c.getStore(), key)
+	c.mutate()
+	c.getStore().delete(key)
 }
 
 // Clear clears all stored values in the current request’s context.
 // Parent context store will not be cleaned.
 func (c *Context) Clear() {
-	store := c.getStore()
-	for key := range store {
-		delete(store, key)
-	}
+	c.mutate()
+	c.getStore().clear()
 }
 
 // UseParent uses a new parent Context
 func (c *Context) UseParent(ctx *Context) {
+	c.mutate()
 	c.Parent = ctx
 }
 
@@ -175,35 +357,99 @@ func (c *Context) Root() *Context {
 
 // SetRequest replaces the context http.Request
 func (c *Context) SetRequest(req *http.Request) {
+	c.mutate()
 	c.Request = req.WithContext(c.Request.Context())
 }
 
+// Intercept short-circuits the HTTP transaction with the given synthetic response,
+// such as a cached, mocked or fallback response, skipping the network dialing.
+// The response still flows through the "intercept" and "response" middleware phases.
+// Undefined response status, headers and body are defaulted.
+func (c *Context) Intercept(res *http.Response) {
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	if res.Status == "" {
+		res.Status = strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode)
+	}
+	if res.ProtoMajor == 0 {
+		res.Proto, res.ProtoMajor, res.ProtoMinor = "HTTP/1.1", 1, 1
+	}
+	if res.Header == nil {
+		res.Header = make(http.Header)
+	}
+	if res.Body == nil {
+		res.Body = utils.NopCloser()
+	}
+	if res.Request == nil {
+		res.Request = c.Request
+	}
+	c.Response = res
+	c.intercepted = true
+}
+
+// Intercepted returns true if the HTTP transaction was intercepted,
+// either via Intercept() or by defining the response status code before dialing.
+func (c *Context) Intercepted() bool {
+	return c.intercepted || c.Response.StatusCode != 0
+}
+
 // Clone returns a clone of the current context.
+// The clone of a frozen context is not frozen.
 func (c *Context) Clone() *Context {
 	ctx := new(Context)
 	*ctx = *c
+	ctx.frozen = false
+	// header maps are copied, since the original ones are reused once released
+	ctx.headers = [2]http.Header{}
 
 	req := new(http.Request)
 	*req = *c.Request
+	req.Header = c.Request.Header.Clone()
 	ctx.Request = req
 	c.CopyTo(ctx)
 
 	res := new(http.Response)
 	*res = *c.Response
+	res.Header = c.Response.Header.Clone()
 	ctx.Response = res
 
 	return ctx
 }
 
-// CopyTo copies the current context store into a new Context.
-func (c *Context) CopyTo(newCtx *Context) {
-	store := Store{}
+// Snapshot returns a frozen copy of the current context, flattening
+// the current and parent context stores. Frozen contexts panic on mutation,
+// but can be safely used as parent by concurrent contexts.
+func (c *Context) Snapshot() *Context {
+	ctx := c.Clone()
+	ctx.Parent = nil
 
-	for key, value := range c.getStore() {
-		store[key] = value
+	store := Store{}
+	var contexts []*Context
+	for parent := c; parent != nil; parent = parent.Parent {
+		contexts = append(contexts, parent)
+	}
+	for i := len(contexts) - 1; i >= 0; i-- {
+		for key, value := range contexts[i].getStore().view() {
+			store[key] = value
+		}
 	}
 
-	ctx := context.WithValue(context.Background(), Key, store)
+	ctx.Request = ctx.Request.WithContext(withStore(ctx.Request.Context(), newStore(store)))
+	ctx.frozen = true
+	return ctx
+}
+
+// Frozen returns true if the context can no longer be mutated.
+func (c *Context) Frozen() bool {
+	return c.frozen
+}
+
+// CopyTo copies the current context store into a new Context.
+// The store values are copied lazily, once any of the contexts is modified,
+// therefore copying is cheap regardless of the amount of stored values.
+func (c *Context) CopyTo(newCtx *Context) {
+	ctx := withStore(context.Background(), c.getStore().clone())
 	newCtx.Request = newCtx.Request.WithContext(ctx)
 }
 
@@ -307,18 +553,19 @@ func (c *Context) Value(key interface{}) interface{} {
 // SetCancelContext This will set an external context.Context as a parent to this context so cancellations can be
 // propagated quickly and reduce resource usage.
 func (c *Context) SetCancelContext(ctx context.Context) *Context {
-	golRequestContext := context.WithValue(ctx, Key, c.Value(Key))
+	c.mutate()
+	golRequestContext := withStore(ctx, c.getStore())
 	c.Request = c.Request.WithContext(golRequestContext)
 	return c
 }
 
 // emptyContext creates a new empty context.Context
 func emptyContext() context.Context {
-	return context.WithValue(context.Background(), Key, Store{})
+	return withStore(context.Background(), newStore(Store{}))
 }
 
 // createRequest creates a default http.Request instance.
-func createRequest() *http.Request {
+func createRequest(header http.Header) *http.Request {
 	// Create HTTP request
 	req := &http.Request{
 		Method:     "GET",
@@ -327,7 +574,7 @@ func createRequest() *http.Request {
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 		Proto:      "HTTP/1.1",
-		Header:     make(http.Header),
+		Header:     header,
 		Body:       utils.NopCloser(),
 	}
 	// Return shallow copy of Request with the new context
@@ -335,13 +582,13 @@ func createRequest() *http.Request {
 }
 
 // createResponse creates a default http.Response instance.
-func createResponse(req *http.Request) *http.Response {
+func createResponse(req *http.Request, header http.Header) *http.Response {
 	return &http.Response{
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 		Proto:      "HTTP/1.1",
 		Request:    req,
-		Header:     make(http.Header),
+		Header:     header,
 		Body:       utils.NopCloser(),
 	}
 }
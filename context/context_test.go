@@ -4,6 +4,7 @@
 package context
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/nbio/st"
@@ -18,7 +19,9 @@ const (
 
 func TestContext(t *testing.T) {
 	ctx := New()
-	store := ctx.getStore()
+	store := func() Store {
+		return ctx.Request.Context().Value(Key).(Store)
+	}
 
 	// Get()
 	st.Expect(t, ctx.Get(key1), nil)
@@ -26,12 +29,18 @@ func TestContext(t *testing.T) {
 	// Set()
 	ctx.Set(key1, "1")
 	st.Expect(t, ctx.Get(key1), "1")
-	st.Expect(t, len(store), 1)
-	st.Expect(t, store[key1], "1")
+	st.Expect(t, len(store()), 1)
+	st.Expect(t, store()[key1], "1")
 
 	ctx.Set(key2, "2")
 	st.Expect(t, ctx.Get(key2), "2")
-	st.Expect(t, len(store), 2)
+	st.Expect(t, len(store()), 2)
+
+	// The store values are a snapshot
+	snapshot := store()
+	ctx.Set(key2, "3")
+	st.Expect(t, snapshot[key2], "2")
+	ctx.Set(key2, "2")
 
 	// GetOk()
 	value, ok := ctx.GetOk(key1)
@@ -62,21 +71,47 @@ func TestContext(t *testing.T) {
 	// Delete()
 	ctx.Delete(key1)
 	st.Expect(t, ctx.Get(key1), nil)
-	st.Expect(t, len(store), 4)
+	st.Expect(t, len(store()), 4)
 
 	ctx.Delete(key2)
 	st.Expect(t, ctx.Get(key2), nil)
-	st.Expect(t, len(store), 3)
+	st.Expect(t, len(store()), 3)
 
 	// Clear()
 	ctx.Set(key1, true)
 	values = ctx.GetAll()
 	ctx.Clear()
-	st.Expect(t, len(store), 0)
+	st.Expect(t, len(store()), 0)
 	val, _ := values["int value"].(int)
 	st.Expect(t, val, 13) // Clear shouldn't delete values grabbed before
 }
 
+func TestContextConcurrency(t *testing.T) {
+	parent := New()
+	parent.Set(key1, "1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := New()
+			ctx.UseParent(parent)
+			for n := 0; n < 100; n++ {
+				parent.Set(key2, i)
+				ctx.Set(key1, n)
+				ctx.Get(key2)
+				ctx.GetAll()
+				parent.Delete(key2)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	st.Expect(t, parent.Get(key1), "1")
+	st.Expect(t, parent.Get(key2), nil)
+}
+
 func TestContextInheritance(t *testing.T) {
 	parent := New()
 	ctx := New()
@@ -169,6 +204,106 @@ func TestContextCopy(t *testing.T) {
 	st.Expect(t, newCtx.Get("bar"), "bar")
 }
 
+func TestContextRelease(t *testing.T) {
+	ctx := New()
+	ctx.Set("foo", "bar")
+	ctx.Request.Header.Set("foo", "bar")
+	ctx.Response.Header.Set("bar", "foo")
+	header := ctx.Request.Header
+
+	ctx.Release()
+	st.Expect(t, ctx.Request == nil, true)
+	st.Expect(t, len(header), 0)
+
+	// Released contexts are reset
+	ctx = New()
+	st.Expect(t, ctx.Get("foo"), nil)
+	st.Expect(t, len(ctx.Request.Header), 0)
+	st.Expect(t, len(ctx.Response.Header), 0)
+
+	// Clones do not own the header maps
+	clone := ctx.Clone()
+	clone.Release()
+	st.Expect(t, clone.headers[0] == nil, true)
+	ctx.Release()
+
+	// Clones do not share the header maps of the released context
+	ctx = New()
+	ctx.Request.Header.Set("Authorization", "Bearer token")
+	clone = ctx.Clone()
+	ctx.Release()
+	other := New()
+	other.Request.Header.Set("X-Other", "foo")
+	st.Expect(t, clone.Request.Header.Get("Authorization"), "Bearer token")
+	st.Expect(t, clone.Request.Header.Get("X-Other"), "")
+	other.Release()
+
+	// Frozen contexts are never released
+	snapshot := New().Snapshot()
+	snapshot.Release()
+	st.Reject(t, snapshot.Request, nil)
+}
+
+func TestContextCopyOnWrite(t *testing.T) {
+	ctx := New()
+	ctx.Set("foo", "bar")
+	ctx.Set("bar", "foo")
+
+	clone := ctx.Clone()
+	st.Expect(t, clone.getStore().shared, true)
+	st.Expect(t, clone.Get("foo"), "bar")
+
+	// Writes copy the shared values
+	clone.Set("foo", "baz")
+	st.Expect(t, clone.getStore().shared, false)
+	st.Expect(t, clone.Get("foo"), "baz")
+	st.Expect(t, ctx.Get("foo"), "bar")
+
+	ctx.Delete("bar")
+	st.Expect(t, ctx.Get("bar"), nil)
+	st.Expect(t, clone.Get("bar"), "foo")
+
+	other := ctx.Clone()
+	ctx.Clear()
+	st.Expect(t, ctx.Get("foo"), nil)
+	st.Expect(t, other.Get("foo"), "bar")
+}
+
+func BenchmarkContextClone(b *testing.B) {
+	ctx := New()
+	for i := 0; i < 1000; i++ {
+		ctx.Set(i, i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clone := ctx.Clone()
+		clone.Set("foo", "bar")
+	}
+}
+
+func BenchmarkContextCloneRead(b *testing.B) {
+	ctx := New()
+	for i := 0; i < 1000; i++ {
+		ctx.Set(i, i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clone := ctx.Clone()
+		clone.Get(1)
+	}
+}
+
+func BenchmarkContextNew(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		ctx := New()
+		ctx.Set("foo", "bar")
+		ctx.Request.Header.Set("foo", "bar")
+		ctx.Release()
+	}
+}
+
 func TestContextSetRequest(t *testing.T) {
 	ctx := New()
 	ctx.Set("bar", "foo")
@@ -183,3 +318,36 @@ func TestContextSetRequest(t *testing.T) {
 	st.Expect(t, ctx.Get("bar"), "foo")
 	st.Expect(t, newCtx.Get("bar"), "bar")
 }
+
+func TestContextSnapshot(t *testing.T) {
+	parent := New()
+	parent.Set("foo", "bar")
+	parent.Set("bar", "foo")
+	ctx := New()
+	ctx.UseParent(parent)
+	ctx.Set("foo", "baz")
+
+	snapshot := ctx.Snapshot()
+	st.Expect(t, snapshot.Frozen(), true)
+	st.Expect(t, snapshot.Parent == nil, true)
+	st.Expect(t, snapshot.Get("foo"), "baz")
+	st.Expect(t, snapshot.Get("bar"), "foo")
+
+	// Changes do not affect the snapshot
+	ctx.Set("foo", "qux")
+	st.Expect(t, snapshot.Get("foo"), "baz")
+
+	defer func() {
+		st.Expect(t, recover(), ErrFrozen)
+	}()
+	snapshot.Set("foo", "bar")
+}
+
+func TestContextSnapshotClone(t *testing.T) {
+	snapshot := New().Snapshot()
+	ctx := snapshot.Clone()
+	st.Expect(t, ctx.Frozen(), false)
+	ctx.Set("foo", "bar")
+	st.Expect(t, ctx.Get("foo"), "bar")
+	st.Expect(t, snapshot.Get("foo"), nil)
+}
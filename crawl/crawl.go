@@ -0,0 +1,368 @@
+// Package crawl implements a simple, concurrent web crawler on top of gentleman,
+// providing a URL frontier, a bounded pool of workers, deduplication of visited
+// URLs and hooks to extract follow-up links from the crawled pages.
+//
+// Crawled requests are created from the given gentleman Client, therefore
+// all the client middleware and plugins are honored for every page.
+package crawl
+
+import (
+	gocontext "context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/politeness"
+	"gopkg.in/h2non/gentleman.v2/plugins/referer"
+)
+
+// DefaultWorkers defines the default number of concurrent crawling workers.
+var DefaultWorkers = 4
+
+// ErrNoSeeds is returned when the crawler is started without seed URLs.
+var ErrNoSeeds = errors.New("gentleman: crawler requires at least one seed URL")
+
+// Extractor represents the function used to extract follow-up URLs
+// from a crawled page. Relative URLs are resolved against the page URL.
+type Extractor func(page *Page) ([]string, error)
+
+// Filter represents the function used to decide if a discovered URL must be crawled.
+type Filter func(u *url.URL, depth int) bool
+
+// Page represents a crawled page.
+type Page struct {
+	// URL stores the requested page URL.
+	URL *url.URL
+
+	// Parent stores the URL of the page where this page was discovered.
+	// Parent is nil for seed URLs.
+	Parent *url.URL
+
+	// Depth stores the link distance from the seed URLs.
+	Depth int
+
+	// Response stores the page response, if any.
+	Response *gentleman.Response
+
+	// Error stores the page request error, if any.
+	Error error
+}
+
+// Crawler crawls pages concurrently using a gentleman Client.
+type Crawler struct {
+	// Client is used to create the requests of every crawled page.
+	Client *gentleman.Client
+
+	// Workers defines the maximum number of concurrent requests.
+	Workers int
+
+	// MaxDepth defines the maximum link distance from the seeds to crawl.
+	// Zero means no limit.
+	MaxDepth int
+
+	// MaxPages defines the maximum number of pages to crawl.
+	// Zero means no limit.
+	MaxPages int
+
+	// Extract is used to extract the follow-up URLs of successfully crawled pages.
+	// Defaults to Links.
+	Extract Extractor
+
+	// Filter is an optional function to decide if a discovered URL must be crawled.
+	Filter Filter
+
+	// Scheduler is an optional politeness scheduler used to enforce
+	// a minimum delay between consecutive requests to the same host.
+	Scheduler *politeness.Scheduler
+
+	// OnPage is an optional function called for every crawled page, even if failed.
+	// Returning an error aborts the crawling.
+	OnPage func(page *Page) error
+
+	// mtx protects the visited URLs store.
+	mtx sync.Mutex
+
+	// visited stores the normalized URLs already queued.
+	visited map[string]bool
+}
+
+// task represents a frontier item.
+type task struct {
+	url    *url.URL
+	parent *url.URL
+	depth  int
+}
+
+// New creates a new Crawler based on the given Client.
+func New(cli *gentleman.Client) *Crawler {
+	return &Crawler{Client: cli, Workers: DefaultWorkers, Extract: Links}
+}
+
+// Visited returns true if the given URL was already visited or queued.
+func (c *Crawler) Visited(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.visited[Normalize(u)]
+}
+
+// Run crawls the given seed URLs and the discovered follow-up URLs until
+// the frontier is exhausted, the limits are reached or the context is canceled.
+func (c *Crawler) Run(ctx gocontext.Context, seeds ...string) error {
+	if len(seeds) == 0 {
+		return ErrNoSeeds
+	}
+
+	c.mtx.Lock()
+	if c.visited == nil {
+		c.visited = make(map[string]bool)
+	}
+	c.mtx.Unlock()
+
+	var queue []task
+	for _, seed := range seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			return err
+		}
+		if c.visit(u) {
+			queue = append(queue, task{url: u})
+		}
+	}
+
+	ctx, cancel := gocontext.WithCancel(ctx)
+	defer cancel()
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	var wg sync.WaitGroup
+	var failure error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() { failure = err })
+		cancel()
+	}
+
+	jobs := make(chan task)
+	results := make(chan []task)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				found, err := c.crawl(ctx, t)
+				if err != nil {
+					fail(err)
+				}
+				select {
+				case results <- found:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	pages, inflight := 0, 0
+	for (len(queue) > 0 || inflight > 0) && ctx.Err() == nil {
+		var out chan task
+		var next task
+		if len(queue) > 0 && (c.MaxPages == 0 || pages < c.MaxPages) {
+			out, next = jobs, queue[0]
+		} else if inflight == 0 {
+			break
+		}
+
+		select {
+		case out <- next:
+			queue = queue[1:]
+			inflight++
+			pages++
+		case found := <-results:
+			inflight--
+			queue = append(queue, found...)
+		case <-ctx.Done():
+		}
+	}
+
+	close(jobs)
+	cancel()
+	wg.Wait()
+
+	return failure
+}
+
+// crawl fetches the given frontier task and returns the follow-up tasks.
+func (c *Crawler) crawl(ctx gocontext.Context, t task) ([]task, error) {
+	req := c.Client.Request().URL(t.url.String())
+	req.Context.SetCancelContext(ctx)
+	if c.Scheduler != nil {
+		req.Use(politeness.New(c.Scheduler))
+	}
+	if t.parent != nil {
+		req.Use(referer.From(t.parent.String()))
+	}
+
+	page := &Page{URL: t.url, Parent: t.parent, Depth: t.depth}
+	page.Response, page.Error = req.Send()
+
+	var links []string
+	if page.Error == nil && page.Response.Ok && c.follow(t.depth+1) && c.Extract != nil {
+		links, page.Error = c.Extract(page)
+	}
+
+	if c.OnPage != nil {
+		if err := c.OnPage(page); err != nil {
+			return nil, err
+		}
+	}
+	if page.Response != nil && page.Response.RawResponse != nil {
+		page.Response.Close()
+	}
+
+	base := t.url
+	if page.Response != nil && page.Response.RawResponse != nil && page.Response.RawResponse.Request != nil {
+		base = page.Response.RawResponse.Request.URL
+	}
+
+	var found []task
+	for _, link := range links {
+		u, err := base.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		if c.Filter != nil && !c.Filter(u, t.depth+1) {
+			continue
+		}
+		if c.visit(u) {
+			found = append(found, task{url: u, parent: t.url, depth: t.depth + 1})
+		}
+	}
+
+	return found, nil
+}
+
+// follow returns true if pages at the given depth can be crawled.
+func (c *Crawler) follow(depth int) bool {
+	return c.MaxDepth == 0 || depth <= c.MaxDepth
+}
+
+// visit marks the given URL as visited.
+// Returns false if the URL was already visited.
+func (c *Crawler) visit(u *url.URL) bool {
+	key := Normalize(u)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.visited[key] {
+		return false
+	}
+	c.visited[key] = true
+	return true
+}
+
+// Normalize returns the normalized representation of the given URL
+// used for deduplication: lower case scheme and host, without default ports,
+// fragments or empty paths.
+func Normalize(u *url.URL) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	n.Fragment = ""
+	if (n.Scheme == "http" && strings.HasSuffix(n.Host, ":80")) ||
+		(n.Scheme == "https" && strings.HasSuffix(n.Host, ":443")) {
+		n.Host = n.Host[:strings.LastIndex(n.Host, ":")]
+	}
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return n.String()
+}
+
+// SameHost returns a Filter that only allows URLs matching the given hosts.
+func SameHost(hosts ...string) Filter {
+	return func(u *url.URL, _ int) bool {
+		for _, host := range hosts {
+			if strings.EqualFold(u.Hostname(), host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Links is the default Extractor, which extracts the anchor links of HTML pages.
+// Comments and scripts are skipped, and the links are resolved against the
+// document <base> URL, if any.
+func Links(page *Page) ([]string, error) {
+	if !strings.Contains(page.Response.Header.Get("Content-Type"), "html") {
+		return nil, nil
+	}
+
+	var base string
+	var links []string
+	z := html.NewTokenizer(strings.NewReader(page.Response.String()))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		tag := string(name)
+		if (tag != "a" && tag != "base") || !hasAttr {
+			continue
+		}
+		for more := true; more; {
+			var key, val []byte
+			key, val, more = z.TagAttr()
+			if string(key) != "href" {
+				continue
+			}
+			link := strings.TrimSpace(string(val))
+			if tag == "a" && link != "" {
+				links = append(links, link)
+			} else if tag == "base" && base == "" {
+				base = link
+			}
+			break
+		}
+	}
+
+	if base != "" {
+		links = resolve(page, base, links)
+	}
+	return links, page.Response.Error
+}
+
+// resolve resolves the given links against the given document base URL,
+// which is relative to the page URL.
+func resolve(page *Page, base string, links []string) []string {
+	ref := page.URL
+	if res := page.Response.RawResponse; res != nil && res.Request != nil {
+		ref = res.Request.URL
+	}
+	if ref == nil {
+		return links
+	}
+	u, err := ref.Parse(base)
+	if err != nil {
+		return links
+	}
+	for i, link := range links {
+		if l, err := u.Parse(link); err == nil {
+			links[i] = l.String()
+		}
+	}
+	return links
+}
@@ -0,0 +1,186 @@
+package crawl
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/politeness"
+)
+
+func newSite() *httptest.Server {
+	pages := map[string]string{
+		"/":    `<a href="/foo">foo</a> <a href='bar'>bar</a> <a href="http://example.com/">out</a>`,
+		"/foo": `<a href="/">home</a> <a href="/foo/baz#top">baz</a>`,
+		"/bar": `<a href="/">home</a>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, body)
+	}))
+}
+
+type recorder struct {
+	mtx   sync.Mutex
+	paths []string
+}
+
+func (r *recorder) OnPage(page *Page) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.paths = append(r.paths, fmt.Sprintf("%s:%d:%d", page.URL.Path, page.Depth, page.Response.StatusCode))
+	return nil
+}
+
+func (r *recorder) sorted() []string {
+	sort.Strings(r.paths)
+	return r.paths
+}
+
+func TestCrawler(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	rec := &recorder{}
+	crawler := New(gentleman.New())
+	crawler.Filter = SameHost(u.Hostname())
+	crawler.OnPage = rec.OnPage
+
+	err := crawler.Run(gocontext.Background(), ts.URL+"/")
+	st.Expect(t, err, nil)
+	st.Expect(t, rec.sorted(), []string{"/:0:200", "/bar:1:200", "/foo/baz:2:404", "/foo:1:200"})
+	st.Expect(t, crawler.Visited(ts.URL+"/foo"), true)
+	st.Expect(t, crawler.Visited("http://example.com"), false)
+}
+
+func TestCrawlerMaxDepth(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	rec := &recorder{}
+	crawler := New(gentleman.New())
+	crawler.MaxDepth = 1
+	crawler.Workers = 1
+	crawler.Filter = SameHost(u.Hostname())
+	crawler.OnPage = rec.OnPage
+
+	err := crawler.Run(gocontext.Background(), ts.URL+"/")
+	st.Expect(t, err, nil)
+	st.Expect(t, rec.sorted(), []string{"/:0:200", "/bar:1:200", "/foo:1:200"})
+}
+
+func TestCrawlerMaxPages(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+
+	rec := &recorder{}
+	crawler := New(gentleman.New())
+	crawler.MaxPages = 2
+	crawler.Workers = 1
+	crawler.OnPage = rec.OnPage
+
+	err := crawler.Run(gocontext.Background(), ts.URL)
+	st.Expect(t, err, nil)
+	st.Expect(t, len(rec.paths), 2)
+}
+
+func TestCrawlerAbort(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+
+	crawler := New(gentleman.New())
+	crawler.OnPage = func(page *Page) error {
+		return errors.New("abort")
+	}
+
+	err := crawler.Run(gocontext.Background(), ts.URL)
+	st.Expect(t, err.Error(), "abort")
+	st.Expect(t, New(nil).Run(gocontext.Background()), ErrNoSeeds)
+}
+
+func TestNormalize(t *testing.T) {
+	u, _ := url.Parse("HTTP://Example.COM:80#foo")
+	st.Expect(t, Normalize(u), "http://example.com/")
+	u, _ = url.Parse("https://example.com:443/foo?bar=baz")
+	st.Expect(t, Normalize(u), "https://example.com/foo?bar=baz")
+}
+
+func TestCrawlerScheduler(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	rec := &recorder{}
+	crawler := New(gentleman.New())
+	crawler.Filter = SameHost(u.Hostname())
+	crawler.Scheduler = politeness.NewScheduler(20 * time.Millisecond)
+	crawler.OnPage = rec.OnPage
+
+	start := time.Now()
+	err := crawler.Run(gocontext.Background(), ts.URL+"/")
+	st.Expect(t, err, nil)
+	st.Expect(t, len(rec.paths), 4)
+	st.Expect(t, time.Since(start) >= 60*time.Millisecond, true)
+}
+
+func TestCrawlerReferer(t *testing.T) {
+	ts := newSite()
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	var mtx sync.Mutex
+	referers := map[string]string{}
+	cli := gentleman.New()
+	cli.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		mtx.Lock()
+		referers[ctx.Request.URL.Path] = ctx.Request.Header.Get("Referer")
+		mtx.Unlock()
+		h.Next(ctx)
+	})
+
+	crawler := New(cli)
+	crawler.Filter = SameHost(u.Hostname())
+	crawler.MaxDepth = 1
+
+	err := crawler.Run(gocontext.Background(), ts.URL+"/")
+	st.Expect(t, err, nil)
+	st.Expect(t, referers, map[string]string{"/": "", "/foo": ts.URL + "/", "/bar": ts.URL + "/"})
+}
+
+func TestLinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><base href="/docs/"></head><body>
+<!-- <a href="/commented">hidden</a> -->
+<script>var s = '<a href="/scripted">';</script>
+<A HREF="guide?a=1&amp;b=2">guide</A>
+<a class="x" href = 'https://example.com/'>out</a>
+<a name="anchor">no link</a>
+</body></html>`)
+	}))
+	defer ts.Close()
+
+	res, err := gentleman.New().URL(ts.URL + "/index.html").Request().Send()
+	st.Expect(t, err, nil)
+	u, _ := url.Parse(ts.URL + "/index.html")
+	links, err := Links(&Page{URL: u, Response: res})
+	st.Expect(t, err, nil)
+	st.Expect(t, links, []string{ts.URL + "/docs/guide?a=1&b=2", "https://example.com/"})
+}
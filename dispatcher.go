@@ -1,7 +1,15 @@
 package gentleman
 
 import (
+	gocontext "context"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
 	c "gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/query"
+	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
 // Dispatcher dispatches a given request triggering the middleware
@@ -29,6 +37,9 @@ func (d *Dispatcher) Dispatch() *c.Context {
 		func(ctx *c.Context) (*c.Context, bool) {
 			return d.runBefore("before dial", ctx)
 		},
+		func(ctx *c.Context) (*c.Context, bool) {
+			return d.runValidate(ctx)
+		},
 		func(ctx *c.Context) (*c.Context, bool) {
 			return d.doDial(ctx)
 		},
@@ -38,10 +49,17 @@ func (d *Dispatcher) Dispatch() *c.Context {
 		func(ctx *c.Context) (*c.Context, bool) {
 			return d.runAfter("response", ctx)
 		},
+		func(ctx *c.Context) (*c.Context, bool) {
+			return d.checkStatus(ctx)
+		},
+		func(ctx *c.Context) (*c.Context, bool) {
+			return d.runAfter("rewrite", ctx)
+		},
 	}
 
 	// Reference to initial context
 	ctx := d.req.Context
+	ctx.Set(timingKey, &timing{start: time.Now()})
 
 	// Execute tasks in order, stopping in case of error or explicit stop.
 	for _, task := range pipeline {
@@ -51,15 +69,121 @@ func (d *Dispatcher) Dispatch() *c.Context {
 		}
 	}
 
+	// Trigger the after body middleware once the response body is consumed
+	if ctx.Error == nil && ctx.Response.Body != nil {
+		ctx.Response.Body = &afterBody{ReadCloser: ctx.Response.Body, ctx: ctx, dispatcher: d}
+	}
+
 	return ctx
 }
 
+// afterBody wraps the response body in order to trigger the
+// after body middleware phase once the body is fully read or closed.
+type afterBody struct {
+	io.ReadCloser
+	ctx        *c.Context
+	dispatcher *Dispatcher
+	once       sync.Once
+}
+
+// Read reads from the response body, triggering the after body phase on EOF.
+func (b *afterBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+// Close closes the response body, triggering the after body phase if pending.
+// Returns the error reported by the after body middleware, if any.
+func (b *afterBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	if err == nil {
+		err = b.ctx.Error
+	}
+	return err
+}
+
+func (b *afterBody) done() {
+	b.once.Do(func() {
+		if t := getTiming(b.ctx); t != nil {
+			t.bodyDone()
+		}
+		b.ctx, _ = b.dispatcher.run("after body", b.ctx)
+	})
+}
+
 func (d *Dispatcher) doDial(ctx *c.Context) (*c.Context, bool) {
-	// Perform the request via ctx.Client
+	// Attach the cancelable context of the asynchronous requests, if any
+	if f := getFuture(ctx); f != nil && !f.attach(ctx) {
+		ctx.Error = gocontext.Canceled
+		ctx = d.fail(ctx)
+		return ctx, ctx.Error != nil
+	}
+
+	// Count the dial attempts performed by the request
+	attempt, _ := ctx.GetInt(AttemptKey)
+	attempt++
+	ctx.Set(AttemptKey, attempt)
+
+	// Buffer the final request body, so the request can be snapshotted
+	if replayable, _ := ctx.Get(ReplayableKey).(bool); replayable {
+		if _, err := utils.ReplayRequestBody(ctx.Request); err != nil {
+			ctx.Error = err
+			ctx = d.fail(ctx)
+			return ctx, ctx.Error != nil
+		}
+	}
+
+	// Track the request in the client closing state, if any
+	release := func() {}
+	if cl := getCloser(ctx); cl != nil {
+		var err error
+		if release, err = cl.acquire(ctx); err != nil {
+			ctx.Error = err
+			ctx = d.fail(ctx)
+			return ctx, ctx.Error != nil
+		}
+	}
+
+	// Emit the lifecycle events, if observed
+	if events := getEvents(ctx); events != nil {
+		ctx.Request = ctx.Request.WithContext(httptrace.WithClientTrace(ctx.Request.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if info.Reused {
+					EmitEvent(ctx, Event{Type: ConnectionReused})
+				}
+			},
+		}))
+		EmitEvent(ctx, Event{Type: RequestStarted})
+	}
+
+	// Track the request timing
+	tracker := getTiming(ctx)
+	if tracker != nil {
+		ctx.Request = ctx.Request.WithContext(httptrace.WithClientTrace(ctx.Request.Context(), tracker.trace()))
+	}
+
+	// Perform the request via ctx.Client, recording the attempt unless recorded by the transport
+	recorded := len(GetAttempts(ctx))
+	start := time.Now()
 	res, err := ctx.Client.Do(ctx.Request)
-	ctx.Error = err
+	if len(GetAttempts(ctx)) == recorded {
+		RecordAttempt(ctx, NewAttempt(start, res, err))
+	}
+	if tracker != nil {
+		tracker.responseReceived()
+	}
+	if res != nil {
+		EmitEvent(ctx, Event{Type: ResponseReceived, Response: res})
+	}
+	trackBody(res, release)
+	attempt, _ = ctx.GetInt(AttemptKey)
+	ctx.Error = newRequestError(ctx, err, attempt)
 	if err != nil {
-		ctx = d.req.Middleware.Run("error", ctx)
+		ctx = d.fail(ctx)
 		if ctx.Error != nil {
 			return ctx, true
 		}
@@ -90,6 +214,26 @@ func (d *Dispatcher) runBefore(phase string, ctx *c.Context) (*c.Context, bool)
 	return d.stop(ctx)
 }
 
+func (d *Dispatcher) runValidate(ctx *c.Context) (*c.Context, bool) {
+	// Run the validate middleware
+	ctx, stop := d.run("validate", ctx)
+	if stop {
+		return ctx, true
+	}
+
+	// Veto the request if any validator failed
+	if err := validationError(ctx); err != nil {
+		ctx.Error = err
+		ctx = d.fail(ctx)
+		if ctx.Error != nil {
+			return ctx, true
+		}
+	}
+
+	// Verify if the should stop
+	return d.stop(ctx)
+}
+
 func (d *Dispatcher) runAfter(phase string, ctx *c.Context) (*c.Context, bool) {
 	// Trigger the after dial phase
 	ctx, stop := d.run(phase, ctx)
@@ -103,7 +247,7 @@ func (d *Dispatcher) runAfter(phase string, ctx *c.Context) (*c.Context, bool) {
 
 func (d *Dispatcher) intercepted(ctx *c.Context) (*c.Context, bool) {
 	// Verify if the request was intercepted
-	if ctx.Response.StatusCode == 0 {
+	if !ctx.Intercepted() {
 		return ctx, false
 	}
 
@@ -113,11 +257,29 @@ func (d *Dispatcher) intercepted(ctx *c.Context) (*c.Context, bool) {
 		return ctx, true
 	}
 
-	// Finally trigger the response middleware
-	ctx, _ = d.run("response", ctx)
+	// Finally trigger the response and rewrite middleware
+	ctx, stop = d.run("response", ctx)
+	if !stop {
+		ctx, stop = d.checkStatus(ctx)
+	}
+	if !stop {
+		ctx, _ = d.run("rewrite", ctx)
+	}
 	return ctx, true
 }
 
+func (d *Dispatcher) checkStatus(ctx *c.Context) (*c.Context, bool) {
+	// Verify if the response status must be reported as error
+	expected, failed := failedStatus(ctx)
+	if !failed {
+		return ctx, false
+	}
+
+	ctx.Error = newHTTPError(ctx, expected)
+	ctx = d.fail(ctx)
+	return ctx, ctx.Error != nil
+}
+
 func (d *Dispatcher) stop(ctx *c.Context) (*c.Context, bool) {
 	if !ctx.Stopped {
 		return ctx, false
@@ -126,7 +288,7 @@ func (d *Dispatcher) stop(ctx *c.Context) (*c.Context, bool) {
 	mw := d.req.Middleware
 	ctx = mw.Run("stop", ctx)
 	if ctx.Error != nil {
-		ctx = mw.Run("error", ctx)
+		ctx = d.fail(ctx)
 		if ctx.Error != nil {
 			return ctx, true
 		}
@@ -135,17 +297,33 @@ func (d *Dispatcher) stop(ctx *c.Context) (*c.Context, bool) {
 	return ctx, ctx.Stopped
 }
 
+// fail emits the error event and runs the error middleware.
+func (d *Dispatcher) fail(ctx *c.Context) *c.Context {
+	event := Event{Type: ErrorOccurred, Error: ctx.Error}
+	if ctx.Response != nil && ctx.Response.StatusCode != 0 {
+		event.Response = ctx.Response
+	}
+	EmitEvent(ctx, event)
+	return d.req.Middleware.Run("error", ctx)
+}
+
 func (d *Dispatcher) run(phase string, ctx *c.Context) (*c.Context, bool) {
 	mw := d.req.Middleware
 
 	// Run the middleware by phase
 	ctx = mw.Run(phase, ctx)
+
+	// Apply the query changes deferred by the request phase plugins
+	if phase == "request" {
+		query.Flush(ctx)
+	}
+
 	if ctx.Error == nil {
 		return ctx, false
 	}
 
 	// Run error middleware
-	ctx = mw.Run("error", ctx)
+	ctx = d.fail(ctx)
 	if ctx.Error != nil {
 		return ctx, true
 	}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
 func TestDispatcher(t *testing.T) {
@@ -65,6 +67,104 @@ func TestDispatcherInterceptor(t *testing.T) {
 	st.Expect(t, ctx.Response.StatusCode, 204)
 }
 
+func TestDispatcherIntercept(t *testing.T) {
+	var phases []string
+	req := NewRequest().URL("http://127.0.0.1:9123")
+	req.UseRequest(func(ctx *context.Context, h context.Handler) {
+		ctx.Intercept(&http.Response{Header: http.Header{"Foo": []string{"bar"}}})
+		h.Next(ctx)
+	})
+	req.UseHandler("intercept", func(ctx *context.Context, h context.Handler) {
+		phases = append(phases, "intercept")
+		h.Next(ctx)
+	})
+	req.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		phases = append(phases, "before dial")
+		h.Next(ctx)
+	})
+	req.UseResponse(func(ctx *context.Context, h context.Handler) {
+		phases = append(phases, "response")
+		h.Next(ctx)
+	})
+
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, phases, []string{"intercept", "response"})
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.Header.Get("Foo"), "bar")
+	st.Expect(t, res.String(), "")
+	st.Expect(t, res.RawResponse.Request, res.RawRequest)
+}
+
+func TestDispatcherRewrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer ts.Close()
+
+	var phases []string
+	rewrite := func(suffix string) plugin.Plugin {
+		return plugin.NewRewritePlugin(func(ctx *context.Context, body []byte) ([]byte, error) {
+			phases = append(phases, "rewrite")
+			return append(body, suffix...), nil
+		})
+	}
+
+	req := NewRequest().URL(ts.URL)
+	req.Use(rewrite(" world"))
+	req.Use(rewrite("!"))
+	req.UseResponse(func(ctx *context.Context, h context.Handler) {
+		phases = append(phases, "response")
+		h.Next(ctx)
+	})
+
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "hello world!")
+	st.Expect(t, phases, []string{"response", "rewrite", "rewrite"})
+
+	// Intercepted responses are rewritten too
+	req = NewRequest().Use(rewrite("!"))
+	req.UseRequest(func(ctx *context.Context, h context.Handler) {
+		ctx.Intercept(&http.Response{Body: utils.StringReader("intercepted")})
+		h.Next(ctx)
+	})
+	res, err = req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "intercepted!")
+}
+
+func TestDispatcherAfterBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer ts.Close()
+
+	calls := 0
+	req := NewRequest().URL(ts.URL)
+	req.UseHandler("after body", func(ctx *context.Context, h context.Handler) {
+		calls++
+		h.Next(ctx)
+	})
+
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, calls, 0)
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, calls, 1)
+	st.Expect(t, res.Close(), nil)
+	st.Expect(t, calls, 1)
+
+	// Errors are reported when closing the body
+	req = NewRequest().URL(ts.URL)
+	req.UseHandler("after body", func(ctx *context.Context, h context.Handler) {
+		h.Error(ctx, errors.New("foo error"))
+	})
+	res, err = req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.RawResponse.Body.Close().Error(), "foo error")
+}
+
 func TestDispatcherResponseError(t *testing.T) {
 	req := NewRequest().URL("http://127.0.0.1:9123")
 	req.UseError(func(ctx *context.Context, h context.Handler) {
@@ -0,0 +1,105 @@
+package gentleman
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// PhaseTiming represents the time spent running the middleware of a phase.
+type PhaseTiming struct {
+	// Phase stores the middleware phase name.
+	Phase string
+
+	// Duration stores the time spent running the phase middleware.
+	Duration time.Duration
+}
+
+// DryRunResult represents the request which would have been sent by a dry run.
+type DryRunResult struct {
+	// Request stores the final request. Its body can be read again via GetBody.
+	Request *http.Request
+
+	// Dump stores the request in HTTP/1.x wire format, as dumped by httputil.DumpRequestOut.
+	Dump []byte
+
+	// Curl stores the equivalent curl command.
+	Curl string
+
+	// Timings stores the time spent running the middleware of every phase, in order.
+	Timings []PhaseTiming
+}
+
+// DryRun runs the request, before dial and validate middleware over a copy of the request,
+// recording the request which would have been sent, without performing any network I/O.
+// This is useful to test or review the effects of a middleware stack.
+// The request itself is not dispatched, so it can still be sent later.
+func (r *Request) DryRun() (*DryRunResult, error) {
+	result := &DryRunResult{}
+	req, err := r.build(nil, func(phase string, elapsed time.Duration) {
+		result.Timings = append(result.Timings, PhaseTiming{Phase: phase, Duration: elapsed})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.ReplayRequestBody(req); err != nil {
+		return nil, err
+	}
+
+	result.Request = req
+	if result.Dump, err = httputil.DumpRequestOut(req, true); err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		req.Body, _ = req.GetBody()
+	}
+	if result.Curl, err = curlCommand(req); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// curlCommand returns the curl command equivalent to the given request.
+func curlCommand(req *http.Request) (string, error) {
+	cmd := []string{"curl", "-X", quoteShell(req.Method), quoteShell(req.URL.String())}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if req.Host != "" && req.Host != req.URL.Host {
+		cmd = append(cmd, "-H", quoteShell("Host: "+req.Host))
+	}
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			cmd = append(cmd, "-H", quoteShell(name+": "+value))
+		}
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+		if len(data) > 0 {
+			cmd = append(cmd, "--data-binary", quoteShell(string(data)))
+		}
+	}
+	return strings.Join(cmd, " "), nil
+}
+
+// quoteShell quotes the given value as a single POSIX shell word.
+func quoteShell(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,55 @@
+package gentleman
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestRequestDryRun(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).SetHeader("Authorization", "Bearer token")
+	cli.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		ctx.Request.Header.Set("X-Signature", "signed")
+		h.Next(ctx)
+	})
+
+	req := cli.Post().Path("/orders").BodyString(`{"name":"O'Brien"}`)
+	result, err := req.DryRun()
+	st.Expect(t, err, nil)
+	st.Expect(t, calls, 0)
+
+	st.Expect(t, result.Request.Method, "POST")
+	st.Expect(t, result.Request.URL.String(), ts.URL+"/orders")
+	body, _ := ioutil.ReadAll(result.Request.Body)
+	st.Expect(t, string(body), `{"name":"O'Brien"}`)
+
+	dump := string(result.Dump)
+	st.Expect(t, strings.HasPrefix(dump, "POST /orders HTTP/1.1\r\n"), true)
+	st.Expect(t, strings.Contains(dump, "X-Signature: signed\r\n"), true)
+	st.Expect(t, strings.HasSuffix(dump, `{"name":"O'Brien"}`), true)
+
+	st.Expect(t, strings.HasPrefix(result.Curl, "curl -X 'POST' '"+ts.URL+"/orders' -H 'Authorization: Bearer token' "), true)
+	st.Expect(t, strings.HasSuffix(result.Curl, ` -H 'X-Signature: signed' --data-binary '{"name":"O'\''Brien"}'`), true)
+
+	st.Expect(t, len(result.Timings), 3)
+	st.Expect(t, result.Timings[0].Phase, "request")
+	st.Expect(t, result.Timings[1].Phase, "before dial")
+	st.Expect(t, result.Timings[2].Phase, "validate")
+
+	// The request can still be sent
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, calls, 1)
+}
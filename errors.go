@@ -0,0 +1,478 @@
+package gentleman
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+)
+
+var (
+	// ErrAlreadyDispatched is returned when trying to dispatch a request twice.
+	ErrAlreadyDispatched = errors.New("gentleman: Request was already dispatched")
+
+	// ErrInvalidExpression is wrapped by the errors returned on invalid
+	// JSONPath or XPath expressions. See ExpressionError.
+	ErrInvalidExpression = errors.New("gentleman: invalid expression")
+
+	// ErrInvalidMethod is returned when the request method is not a valid HTTP token.
+	ErrInvalidMethod = errors.New("gentleman: invalid HTTP method")
+
+	// ErrInvalidUserAgent is returned when a User-Agent product name or version is not a valid HTTP token.
+	ErrInvalidUserAgent = errors.New("gentleman: invalid User-Agent product")
+
+	// ErrClientClosed is returned when dispatching a request from a closed client.
+	ErrClientClosed = errors.New("gentleman: client closed")
+
+	// ErrNotReplayable is returned when snapshotting a request whose body was already consumed.
+	// See Client.Replayable().
+	ErrNotReplayable = errors.New("gentleman: request body cannot be replayed")
+)
+
+// MaxErrorBodySize defines the maximum amount of response body bytes
+// stored in the HTTPError body snapshot.
+var MaxErrorBodySize int64 = 4 << 10
+
+// FailOnErrorKey is the context store key used to enable the conversion of
+// non-2xx responses into HTTPError. See Client.FailOnError().
+const FailOnErrorKey = "$failOnError"
+
+// ExpectStatusKey is the context store key used to store the response
+// status codes allowed by the request. See Request.ExpectStatus().
+const ExpectStatusKey = "$expectStatus"
+
+// ErrorTypeKey is the context store key used to store the type used to decode
+// JSON error response bodies. See Client.ErrorType().
+const ErrorTypeKey = "$errorType"
+
+// AttemptKey is the context store key used to store the number of
+// network dial attempts performed by the request.
+const AttemptKey = "$attempt"
+
+// ErrorKind represents a class of request failure, such as DNS resolution
+// errors or timeouts, which can be used by retry policies and metrics
+// without matching the error text.
+type ErrorKind int
+
+const (
+	// KindUnknown is used for unclassified failures.
+	KindUnknown ErrorKind = iota
+
+	// KindDNS is used when the server host cannot be resolved.
+	KindDNS
+
+	// KindConnect is used when the connection to the server is refused or fails.
+	KindConnect
+
+	// KindTLS is used on TLS handshake or certificate verification failures.
+	KindTLS
+
+	// KindTimeout is used when the request or any network operation timed out.
+	KindTimeout
+
+	// KindCanceled is used when the request was canceled via its context.
+	KindCanceled
+
+	// KindReset is used when the connection was reset or closed by the peer.
+	KindReset
+
+	// KindClientStatus is used for 4xx response status codes.
+	KindClientStatus
+
+	// KindServerStatus is used for 5xx response status codes.
+	KindServerStatus
+)
+
+// kindNames stores the error kinds human friendly names.
+var kindNames = map[ErrorKind]string{
+	KindUnknown:      "unknown",
+	KindDNS:          "dns",
+	KindConnect:      "connect",
+	KindTLS:          "tls",
+	KindTimeout:      "timeout",
+	KindCanceled:     "canceled",
+	KindReset:        "reset",
+	KindClientStatus: "client status",
+	KindServerStatus: "server status",
+}
+
+// String returns the error kind name.
+func (k ErrorKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return kindNames[KindUnknown]
+}
+
+// TransportError represents a classified network failure returned
+// by the HTTP transport while dialing the request.
+// It can be retrieved from request errors via errors.As.
+type TransportError struct {
+	// Kind stores the failure class.
+	Kind ErrorKind
+
+	// Err stores the original transport error.
+	Err error
+}
+
+// Error returns the original transport error message.
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original transport error.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the failure is a timeout.
+// Implements net.Error.
+func (e *TransportError) Timeout() bool {
+	return e.Kind == KindTimeout
+}
+
+// Temporary returns true if the original error is temporary.
+// Implements net.Error.
+func (e *TransportError) Temporary() bool {
+	err, ok := e.Err.(interface{ Temporary() bool })
+	return ok && err.Temporary()
+}
+
+// ErrorKind returns the failure class.
+func (e *TransportError) ErrorKind() ErrorKind {
+	return e.Kind
+}
+
+// RequestError represents a request failure which occurred while dialing
+// the server, storing the request metadata and wrapping the TransportError cause.
+// It can be retrieved from request errors via errors.As.
+type RequestError struct {
+	// Method stores the request HTTP method.
+	Method string
+
+	// URL stores the request URL.
+	URL string
+
+	// Attempt stores the dial attempt number, starting from 1.
+	Attempt int
+
+	// Err stores the wrapped error cause.
+	Err error
+}
+
+// Error returns the wrapped error message, which already
+// includes the request method and URL.
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error cause.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the request timed out.
+// Implements net.Error.
+func (e *RequestError) Timeout() bool {
+	return Classify(e.Err) == KindTimeout
+}
+
+// Temporary returns true if the wrapped error is temporary.
+// Implements net.Error.
+func (e *RequestError) Temporary() bool {
+	err, ok := e.Err.(interface{ Temporary() bool })
+	return ok && err.Temporary()
+}
+
+// ErrorKind returns the failure class of the wrapped error cause.
+func (e *RequestError) ErrorKind() ErrorKind {
+	return Classify(e.Err)
+}
+
+// ExpressionError represents an invalid JSONPath or XPath expression error.
+// It wraps ErrInvalidExpression, so it can be matched via errors.Is.
+type ExpressionError struct {
+	// Syntax stores the expression syntax, such as JSONPath or XPath.
+	Syntax string
+
+	// Expr stores the invalid expression.
+	Expr string
+}
+
+// Error returns the error message.
+func (e *ExpressionError) Error() string {
+	return "gentleman: invalid " + e.Syntax + " expression: " + e.Expr
+}
+
+// Unwrap returns ErrInvalidExpression.
+func (e *ExpressionError) Unwrap() error {
+	return ErrInvalidExpression
+}
+
+// HTTPError represents a non-2xx server response, reported via the error phase
+// when the request is configured to fail on error status via FailOnError().
+type HTTPError struct {
+	// Method stores the request HTTP method.
+	Method string
+
+	// URL stores the request URL.
+	URL string
+
+	// StatusCode stores the response status code.
+	StatusCode int
+
+	// Status stores the response status line text.
+	Status string
+
+	// Header stores the response headers.
+	Header http.Header
+
+	// Body stores the first MaxErrorBodySize bytes of the response body.
+	Body []byte
+
+	// Expected stores the status codes allowed by the request, if defined
+	// via Request.ExpectStatus().
+	Expected []int
+
+	// Model stores the response body decoded into the error type
+	// registered via Client.ErrorType(), or into *ProblemDetails
+	// for application/problem+json responses, if any.
+	Model interface{}
+}
+
+// Error returns the error message.
+func (e *HTTPError) Error() string {
+	msg := "gentleman: " + e.Method + " " + e.URL + ": "
+	if len(e.Expected) == 0 {
+		return msg + e.Status
+	}
+
+	expected := make([]string, len(e.Expected))
+	for i, code := range e.Expected {
+		expected[i] = strconv.Itoa(code)
+	}
+	return msg + "unexpected status " + e.Status + " (expected " + strings.Join(expected, ", ") + ")"
+}
+
+// Unwrap returns the decoded error model if it implements the error interface,
+// so it can be retrieved via errors.As.
+func (e *HTTPError) Unwrap() error {
+	err, _ := e.Model.(error)
+	return err
+}
+
+// ErrorKind returns the failure class of the response status code.
+func (e *HTTPError) ErrorKind() ErrorKind {
+	return ClassifyStatus(e.StatusCode)
+}
+
+// classifier is implemented by errors which know their own failure class.
+type classifier interface {
+	ErrorKind() ErrorKind
+}
+
+// Classify returns the failure class of the given error, inspecting
+// the whole chain of wrapped errors.
+func Classify(err error) ErrorKind {
+	for e := err; e != nil; e = unwrap(e) {
+		if c, ok := e.(classifier); ok {
+			return c.ErrorKind()
+		}
+		if kind := classify(e); kind != KindUnknown {
+			return kind
+		}
+	}
+
+	// Fallback to generic timeout and dial errors
+	for e := err; e != nil; e = unwrap(e) {
+		if t, ok := e.(interface{ Timeout() bool }); ok && t.Timeout() {
+			return KindTimeout
+		}
+	}
+	for e := err; e != nil; e = unwrap(e) {
+		if op, ok := e.(*net.OpError); ok && op.Op == "dial" {
+			return KindConnect
+		}
+	}
+
+	return KindUnknown
+}
+
+// ClassifyStatus returns the failure class of the given response status code,
+// or KindUnknown if the status code does not represent a failure.
+func ClassifyStatus(code int) ErrorKind {
+	switch code / 100 {
+	case 4:
+		return KindClientStatus
+	case 5:
+		return KindServerStatus
+	}
+	return KindUnknown
+}
+
+// classify returns the failure class of a single error, ignoring wrapped errors.
+func classify(err error) ErrorKind {
+	switch err {
+	case gocontext.Canceled:
+		return KindCanceled
+	case gocontext.DeadlineExceeded:
+		return KindTimeout
+	case syscall.ECONNREFUSED:
+		return KindConnect
+	case syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE:
+		return KindReset
+	}
+
+	switch err.(type) {
+	case *net.DNSError:
+		return KindDNS
+	case tls.RecordHeaderError, x509.UnknownAuthorityError, x509.HostnameError,
+		x509.CertificateInvalidError, x509.SystemRootsError:
+		return KindTLS
+	}
+
+	return KindUnknown
+}
+
+// unwrap returns the error wrapped by the given error, if any.
+func unwrap(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case *url.Error:
+		return e.Err
+	case *net.OpError:
+		return e.Err
+	case *os.SyscallError:
+		return e.Err
+	}
+	return nil
+}
+
+// newRequestError wraps and classifies the given transport error,
+// storing the request metadata of the given context.
+func newRequestError(ctx *c.Context, err error, attempt int) error {
+	if err == nil {
+		return nil
+	}
+	reqErr := &RequestError{
+		Attempt: attempt,
+		Err:     &TransportError{Kind: Classify(err), Err: err},
+	}
+	if req := ctx.Request; req != nil {
+		reqErr.Method = req.Method
+		if req.URL != nil {
+			reqErr.URL = req.URL.String()
+		}
+	}
+	return reqErr
+}
+
+// failedStatus returns true if the response status of the given context
+// must be reported as HTTPError, and the status codes expected by the request, if any.
+func failedStatus(ctx *c.Context) ([]int, bool) {
+	code := ctx.Response.StatusCode
+	if code == 0 {
+		return nil, false
+	}
+
+	if expected, ok := ctx.Get(ExpectStatusKey).([]int); ok {
+		for _, allowed := range expected {
+			if code == allowed {
+				return nil, false
+			}
+		}
+		return expected, true
+	}
+
+	enabled, _ := ctx.Get(FailOnErrorKey).(bool)
+	if !enabled {
+		_, enabled = ctx.Get(ErrorTypeKey).(reflect.Type)
+	}
+	return nil, enabled && (code < 200 || code > 299)
+}
+
+// newHTTPError creates a new HTTPError based on the response of the given context,
+// taking a snapshot of the response body which can still be fully read afterwards.
+func newHTTPError(ctx *c.Context, expected []int) *HTTPError {
+	res := ctx.Response
+	err := &HTTPError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+		Expected:   expected,
+	}
+	if req := ctx.Request; req != nil {
+		err.Method = req.Method
+		if req.URL != nil {
+			err.URL = req.URL.String()
+		}
+	}
+	if err.Status == "" {
+		err.Status = strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode)
+	}
+
+	if body := res.Body; body != nil {
+		err.Body, _ = ioutil.ReadAll(io.LimitReader(body, MaxErrorBodySize))
+		res.Body = &snapshotBody{io.MultiReader(bytes.NewReader(err.Body), body), body}
+	}
+
+	if model, ok := ctx.Get(ErrorTypeKey).(reflect.Type); ok {
+		err.Model = decodeError(model, res.Header, err.Body)
+	}
+	if err.Model == nil {
+		if problem := decodeProblem(res.Header, err.Body); problem != nil {
+			err.Model = problem
+		}
+	}
+	return err
+}
+
+// errorType returns the type used to decode error bodies based on the given value.
+func errorType(model interface{}) reflect.Type {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// decodeError decodes the given JSON error body into a new value of the given type,
+// returning nil if the body is not JSON or cannot be decoded.
+func decodeError(model reflect.Type, header http.Header, body []byte) interface{} {
+	if !isJSON(header.Get("Content-Type")) {
+		return nil
+	}
+	value := reflect.New(model).Interface()
+	if json.Unmarshal(body, value) != nil {
+		return nil
+	}
+	return value
+}
+
+// isJSON returns true if the given content type is a JSON media type.
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// snapshotBody replays the body snapshot before reading the remaining body.
+type snapshotBody struct {
+	io.Reader
+	io.Closer
+}
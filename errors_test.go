@@ -0,0 +1,254 @@
+package gentleman
+
+import (
+	gocontext "context"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestClassify(t *testing.T) {
+	dialErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://foo", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+
+	cases := []struct {
+		err  error
+		kind ErrorKind
+	}{
+		{nil, KindUnknown},
+		{errors.New("foo"), KindUnknown},
+		{gocontext.Canceled, KindCanceled},
+		{gocontext.DeadlineExceeded, KindTimeout},
+		{&url.Error{Op: "Get", URL: "http://foo", Err: gocontext.Canceled}, KindCanceled},
+		{dialErr(&net.DNSError{Err: "no such host", Name: "foo"}), KindDNS},
+		{dialErr(&net.DNSError{Err: "timeout", Name: "foo", IsTimeout: true}), KindDNS},
+		{dialErr(os.NewSyscallError("connect", syscall.ECONNREFUSED)), KindConnect},
+		{dialErr(errors.New("unreachable")), KindConnect},
+		{&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, KindReset},
+		{&url.Error{Op: "Get", URL: "https://foo", Err: x509.UnknownAuthorityError{}}, KindTLS},
+		{&TransportError{Kind: KindTimeout, Err: errors.New("foo")}, KindTimeout},
+	}
+
+	for _, test := range cases {
+		st.Expect(t, Classify(test.err), test.kind)
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	st.Expect(t, ClassifyStatus(200), KindUnknown)
+	st.Expect(t, ClassifyStatus(302), KindUnknown)
+	st.Expect(t, ClassifyStatus(404), KindClientStatus)
+	st.Expect(t, ClassifyStatus(503), KindServerStatus)
+	st.Expect(t, KindServerStatus.String(), "server status")
+	st.Expect(t, ErrorKind(100).String(), "unknown")
+}
+
+func TestTransportErrorKinds(t *testing.T) {
+	// Connection refused
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := ts.URL
+	ts.Close()
+
+	_, err := New().URL(addr).Request().Send()
+	var transportErr *TransportError
+	st.Expect(t, errors.As(err, &transportErr), true)
+	st.Expect(t, transportErr.Kind, KindConnect)
+
+	// TLS verification failure
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tls.Close()
+	_, err = New().URL(tls.URL).Request().Send()
+	st.Expect(t, Classify(err), KindTLS)
+
+	// Timeout
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer slow.Close()
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = New().URL(slow.URL).UseContext(ctx).Request().Send()
+	st.Expect(t, Classify(err), KindTimeout)
+	st.Expect(t, err.(net.Error).Timeout(), true)
+
+	// Canceled
+	ctx, cancel = gocontext.WithCancel(gocontext.Background())
+	cancel()
+	_, err = New().URL(slow.URL).UseContext(ctx).Request().Send()
+	st.Expect(t, Classify(err), KindCanceled)
+}
+
+func TestRequestError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := ts.URL
+	ts.Close()
+
+	req := New().URL(addr).Request().Method("POST").Path("/foo")
+	_, err := req.Send()
+
+	var reqErr *RequestError
+	st.Expect(t, errors.As(err, &reqErr), true)
+	st.Expect(t, reqErr.Method, "POST")
+	st.Expect(t, reqErr.URL, addr+"/foo")
+	st.Expect(t, reqErr.Attempt, 1)
+	st.Expect(t, reqErr.ErrorKind(), KindConnect)
+	st.Expect(t, errors.Is(err, syscall.ECONNREFUSED), true)
+
+	_, err = req.Send()
+	st.Expect(t, err, ErrAlreadyDispatched)
+}
+
+func TestExpressionError(t *testing.T) {
+	_, err := compileJSONPath("foo")
+	st.Expect(t, errors.Is(err, ErrInvalidExpression), true)
+	st.Expect(t, err.Error(), "gentleman: invalid JSONPath expression: foo")
+
+	_, err = compileXPath("")
+	var exprErr *ExpressionError
+	st.Expect(t, errors.As(err, &exprErr), true)
+	st.Expect(t, exprErr.Syntax, "XPath")
+}
+
+func TestFailOnError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "foo")
+		w.WriteHeader(503)
+		w.Write([]byte("unavailable"))
+	}))
+	defer ts.Close()
+
+	var phaseErr error
+	cli := New().URL(ts.URL).FailOnError()
+	cli.UseError(func(ctx *context.Context, h context.Handler) {
+		phaseErr = ctx.Error
+		h.Next(ctx)
+	})
+
+	res, err := cli.Request().Path("/foo").Send()
+	var httpErr *HTTPError
+	st.Expect(t, errors.As(err, &httpErr), true)
+	st.Expect(t, phaseErr, err)
+	st.Expect(t, httpErr.StatusCode, 503)
+	st.Expect(t, httpErr.Method, "GET")
+	st.Expect(t, httpErr.URL, ts.URL+"/foo")
+	st.Expect(t, httpErr.Header.Get("Server"), "foo")
+	st.Expect(t, string(httpErr.Body), "unavailable")
+	st.Expect(t, err.Error(), "gentleman: GET "+ts.URL+"/foo: 503 Service Unavailable")
+	st.Expect(t, Classify(err), KindServerStatus)
+
+	// The response body remains readable
+	body, _ := ioutil.ReadAll(res.RawResponse.Body)
+	st.Expect(t, string(body), "unavailable")
+
+	// Disabled by default
+	_, err = New().URL(ts.URL).Request().Send()
+	st.Expect(t, err, nil)
+}
+
+func TestFailOnErrorBodyLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte(strings.Repeat("x", int(MaxErrorBodySize)+10)))
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Request().FailOnError().Send()
+	httpErr, ok := err.(*HTTPError)
+	st.Expect(t, ok, true)
+	st.Expect(t, int64(len(httpErr.Body)), MaxErrorBodySize)
+	st.Expect(t, httpErr.ErrorKind(), KindClientStatus)
+
+	body, _ := ioutil.ReadAll(res.RawResponse.Body)
+	st.Expect(t, len(body), int(MaxErrorBodySize)+10)
+
+	// Successful responses are not reported
+	ok200 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok200.Close()
+	_, err = New().URL(ok200.URL).Request().FailOnError().Send()
+	st.Expect(t, err, nil)
+}
+
+func TestRequestExpectStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(202)
+	}))
+	defer ts.Close()
+
+	_, err := New().URL(ts.URL).Request().ExpectStatus(200, 201).Send()
+	httpErr, ok := err.(*HTTPError)
+	st.Expect(t, ok, true)
+	st.Expect(t, httpErr.StatusCode, 202)
+	st.Expect(t, httpErr.Expected, []int{200, 201})
+	st.Expect(t, err.Error(), "gentleman: GET "+ts.URL+": unexpected status 202 Accepted (expected 200, 201)")
+
+	_, err = New().URL(ts.URL).Request().ExpectStatus(202).Send()
+	st.Expect(t, err, nil)
+
+	// Expected status codes take precedence over FailOnError
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer notFound.Close()
+	res, err := New().URL(notFound.URL).FailOnError().Request().ExpectStatus(200, 404).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 404)
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func TestClientErrorType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/text" {
+			w.WriteHeader(500)
+			w.Write([]byte("oops"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(400)
+		w.Write([]byte(`{"code":"invalid","message":"invalid name"}`))
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).ErrorType(&apiError{})
+
+	_, err := cli.Request().Send()
+	var apiErr *apiError
+	st.Expect(t, errors.As(err, &apiErr), true)
+	st.Expect(t, apiErr.Code, "invalid")
+	st.Expect(t, apiErr.Message, "invalid name")
+
+	var httpErr *HTTPError
+	st.Expect(t, errors.As(err, &httpErr), true)
+	st.Expect(t, httpErr.StatusCode, 400)
+
+	// Non JSON bodies are not decoded
+	_, err = cli.Request().Path("/text").Send()
+	st.Expect(t, errors.As(err, &httpErr), true)
+	st.Expect(t, httpErr.Model, nil)
+	st.Expect(t, string(httpErr.Body), "oops")
+
+	// Non pointer types are supported too
+	_, err = New().URL(ts.URL).Request().ErrorType(apiError{}).Send()
+	st.Expect(t, errors.As(err, &apiErr), true)
+	st.Expect(t, apiErr.Code, "invalid")
+}
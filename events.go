@@ -0,0 +1,177 @@
+package gentleman
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// eventsKey is the context store key used to store the client event bus.
+const eventsKey = "$events"
+
+// EventType represents the type of a client lifecycle event.
+type EventType int
+
+const (
+	// RequestStarted is emitted before every network dial attempt.
+	RequestStarted EventType = iota
+
+	// RetryScheduled is emitted by retry plugins when a failed request is going to be retried.
+	RetryScheduled
+
+	// ResponseReceived is emitted once the response of a dial attempt is received.
+	ResponseReceived
+
+	// ErrorOccurred is emitted when the request fails, before the error phase.
+	ErrorOccurred
+
+	// ConnectionReused is emitted when a dial attempt reuses a previously used connection.
+	ConnectionReused
+)
+
+// eventNames stores the event type names.
+var eventNames = map[EventType]string{
+	RequestStarted:   "RequestStarted",
+	RetryScheduled:   "RetryScheduled",
+	ResponseReceived: "ResponseReceived",
+	ErrorOccurred:    "ErrorOccurred",
+	ConnectionReused: "ConnectionReused",
+}
+
+// String returns the event type name.
+func (t EventType) String() string {
+	if name, ok := eventNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Event represents a client lifecycle event.
+type Event struct {
+	// Type stores the event type.
+	Type EventType
+
+	// Time stores when the event was emitted.
+	Time time.Time
+
+	// Context stores the request context.
+	Context *context.Context
+
+	// Request stores the outgoing request.
+	Request *http.Request
+
+	// Response stores the received response, if any.
+	Response *http.Response
+
+	// Error stores the request error, if any.
+	Error error
+
+	// Attempt stores the current dial attempt, starting from 1.
+	Attempt int
+
+	// Delay stores the delay before the next attempt, used by RetryScheduled events.
+	Delay time.Duration
+}
+
+// Listener represents the function called with the observed events.
+type Listener func(Event)
+
+// Events represents a client event bus, which multiple listeners can subscribe to.
+// Listeners are called synchronously, in subscription order, so they should not block.
+// Events is safe for concurrent use.
+type Events struct {
+	mtx       sync.RWMutex
+	listeners []*subscription
+}
+
+// subscription represents a listener subscribed to the event bus.
+type subscription struct {
+	fn    Listener
+	types map[EventType]bool
+}
+
+// Subscribe subscribes the given listener to the given event types, or to every
+// event type if none is given. The returned function unsubscribes the listener.
+func (e *Events) Subscribe(fn Listener, types ...EventType) (unsubscribe func()) {
+	sub := &subscription{fn: fn}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	e.mtx.Lock()
+	e.listeners = append(e.listeners, sub)
+	e.mtx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			e.mtx.Lock()
+			defer e.mtx.Unlock()
+			for i, listener := range e.listeners {
+				if listener == sub {
+					e.listeners = append(e.listeners[:i:i], e.listeners[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// Emit emits the given event to the subscribed listeners.
+// The event time is defined, if missing.
+func (e *Events) Emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	e.mtx.RLock()
+	listeners := e.listeners
+	e.mtx.RUnlock()
+
+	for _, listener := range listeners {
+		if listener.types == nil || listener.types[event.Type] {
+			listener.fn(event)
+		}
+	}
+}
+
+// Events returns the client event bus, inherited from the parent client, if any.
+func (c *Client) Events() *Events {
+	if events := getEvents(c.Context); events != nil {
+		return events
+	}
+	events := &Events{}
+	c.Context.Set(eventsKey, events)
+	return events
+}
+
+// getEvents returns the event bus stored in the given context, if any.
+func getEvents(ctx *context.Context) *Events {
+	events, _ := ctx.Get(eventsKey).(*Events)
+	return events
+}
+
+// EmitEvent emits the given event via the event bus of the client used
+// by the given request context, if any, such as from plugins.
+// The event context, request and attempt are defined, if missing.
+func EmitEvent(ctx *context.Context, event Event) {
+	events := getEvents(ctx)
+	if events == nil {
+		return
+	}
+	if event.Context == nil {
+		event.Context = ctx
+	}
+	if event.Request == nil {
+		event.Request = ctx.Request
+	}
+	if event.Attempt == 0 {
+		event.Attempt, _ = ctx.GetInt(AttemptKey)
+	}
+	events.Emit(event)
+}
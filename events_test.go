@@ -0,0 +1,53 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestClientEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).FailOnError()
+	var mtx sync.Mutex
+	var all, errs []EventType
+	cli.Events().Subscribe(func(event Event) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		all = append(all, event.Type)
+		st.Expect(t, event.Attempt, 1)
+		st.Reject(t, event.Request, nil)
+		st.Expect(t, event.Time.IsZero(), false)
+	})
+	unsubscribe := cli.Events().Subscribe(func(event Event) {
+		errs = append(errs, event.Type)
+		_, ok := event.Error.(*HTTPError)
+		st.Expect(t, ok, true)
+		st.Expect(t, event.Response.StatusCode, 503)
+	}, ErrorOccurred)
+
+	_, err := cli.Get().Send()
+	st.Reject(t, err, nil)
+	st.Expect(t, all, []EventType{RequestStarted, ResponseReceived, ErrorOccurred})
+	st.Expect(t, errs, []EventType{ErrorOccurred})
+
+	// Child clients share the parent event bus
+	child := New().UseParent(cli)
+	st.Expect(t, child.Events(), cli.Events())
+
+	// Connections are reused
+	unsubscribe()
+	all = nil
+	_, err = child.Get().Send()
+	st.Reject(t, err, nil)
+	st.Expect(t, all, []EventType{RequestStarted, ConnectionReused, ResponseReceived, ErrorOccurred})
+	st.Expect(t, errs, []EventType{ErrorOccurred})
+	st.Expect(t, ConnectionReused.String(), "ConnectionReused")
+}
@@ -0,0 +1,38 @@
+package gentleman
+
+import (
+	"net/http"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// BeforeSendHook represents the function called with the outgoing request before it is sent.
+// Returning an error fails the request.
+type BeforeSendHook func(req *http.Request) error
+
+// AfterResponseHook represents the function called with the received response.
+// Returning an error fails the request.
+type AfterResponseHook func(res *http.Response) error
+
+// beforeSend creates the before dial phase plugin calling the given hook.
+func beforeSend(fn BeforeSendHook) plugin.Plugin {
+	return plugin.NewPhasePlugin("before dial", func(ctx *context.Context, h context.Handler) {
+		if err := fn(ctx.Request); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
+
+// afterResponse creates the response phase plugin calling the given hook.
+func afterResponse(fn AfterResponseHook) plugin.Plugin {
+	return plugin.NewResponsePlugin(func(ctx *context.Context, h context.Handler) {
+		if err := fn(ctx.Response); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
@@ -0,0 +1,54 @@
+package gentleman
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestHooks(t *testing.T) {
+	var trace string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = r.Header.Get("X-Trace")
+		w.Header().Set("X-Server", "test")
+	}))
+	defer ts.Close()
+
+	var calls []string
+	cli := New().URL(ts.URL)
+	cli.OnBeforeSend(func(req *http.Request) error {
+		calls = append(calls, "client before send")
+		req.Header.Set("X-Trace", "abc")
+		return nil
+	})
+	cli.OnAfterResponse(func(res *http.Response) error {
+		calls = append(calls, "client after response: "+res.Header.Get("X-Server"))
+		return nil
+	})
+
+	res, err := cli.Get().OnAfterResponse(func(res *http.Response) error {
+		calls = append(calls, "request after response")
+		return nil
+	}).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, trace, "abc")
+	st.Expect(t, calls, []string{"client before send", "client after response: test", "request after response"})
+
+	// Hook errors fail the request
+	fail := errors.New("vetoed")
+	calls = nil
+	_, err = cli.Get().OnBeforeSend(func(req *http.Request) error {
+		return fail
+	}).Send()
+	st.Expect(t, err, fail)
+	st.Expect(t, calls, []string{"client before send"})
+
+	_, err = cli.Get().OnAfterResponse(func(res *http.Response) error {
+		return fail
+	}).Send()
+	st.Expect(t, err, fail)
+}
@@ -0,0 +1,31 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// InformationalHandler represents the function called on every interim 1xx response
+// received before the final response, such as 102 Processing or 103 Early Hints.
+// The 100 Continue responses are handled by net/http and not reported.
+type InformationalHandler func(status int, header http.Header)
+
+// informational creates the plugin reporting the interim 1xx responses to the given handler.
+func informational(fn InformationalHandler) plugin.Plugin {
+	// Uses the "before dial" phase in order to trace the final request context,
+	// once the request phase had the chance to define a custom one.
+	return plugin.NewPhasePlugin("before dial", func(ctx *context.Context, h context.Handler) {
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(status int, header textproto.MIMEHeader) error {
+				fn(status, http.Header(header))
+				return nil
+			},
+		}
+		ctx.Request = ctx.Request.WithContext(httptrace.WithClientTrace(ctx.Request.Context(), trace))
+		h.Next(ctx)
+	})
+}
@@ -0,0 +1,38 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestOnInformational(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.WriteHeader(http.StatusProcessing)
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	var statuses []int
+	var links []string
+	cli := New().URL(ts.URL).OnInformational(func(status int, header http.Header) {
+		statuses = append(statuses, status)
+		links = append(links, header.Get("Link"))
+	})
+
+	var requestStatuses []int
+	res, err := cli.Request().OnInformational(func(status int, header http.Header) {
+		requestStatuses = append(requestStatuses, status)
+	}).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, statuses, []int{103, 102})
+	st.Expect(t, links, []string{"</style.css>; rel=preload; as=style", ""})
+	st.Expect(t, requestStatuses, []int{103, 102})
+}
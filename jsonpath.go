@@ -0,0 +1,323 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonStep represents a compiled JSONPath expression step.
+type jsonStep struct {
+	// recursive stores if the step selector applies to all the descendants.
+	recursive bool
+
+	// wildcard matches all the object members or array items.
+	wildcard bool
+
+	// keys stores the matched object member names.
+	keys []string
+
+	// indexes stores the matched array indexes.
+	indexes []int
+
+	// slice stores the array slice bounds, if any.
+	slice *jsonSlice
+}
+
+// jsonSlice represents an array slice selector, such as [start:end:step].
+type jsonSlice struct {
+	start, end, step int
+	hasStart, hasEnd bool
+}
+
+// JSONPath evaluates the given JSONPath expression against the JSON response body
+// and returns the matched values, such as `$.data.items[*].id`.
+// Supported syntax: root ($), members (.name or ['name']), wildcards (* or [*]),
+// recursive descent (..name), array indexes ([0], [-1]), unions ([0,1] or ['a','b'])
+// and array slices ([start:end:step]). Numbers are returned as json.Number, preserving
+// their precision. The body remains buffered, therefore
+// multiple expressions can be evaluated on the same response.
+func (r *Response) JSONPath(expr string) ([]interface{}, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	steps, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.populateResponseByteBuffer()
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(r.textReader())
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	nodes := []interface{}{doc}
+	for _, step := range steps {
+		nodes = step.eval(nodes)
+	}
+	return nodes, nil
+}
+
+// compileJSONPath parses the given JSONPath expression.
+func compileJSONPath(expr string) ([]jsonStep, error) {
+	invalid := &ExpressionError{Syntax: "JSONPath", Expr: expr}
+	if !strings.HasPrefix(expr, "$") {
+		return nil, invalid
+	}
+
+	var steps []jsonStep
+	for i := 1; i < len(expr); {
+		var step jsonStep
+		switch {
+		case strings.HasPrefix(expr[i:], ".."):
+			step.recursive = true
+			i += 2
+		case expr[i] == '.':
+			i++
+		case expr[i] != '[':
+			return nil, invalid
+		}
+
+		if i < len(expr) && expr[i] == '[' {
+			end := indexBracket(expr, i)
+			if end < 0 || !parseSelector(expr[i+1:end], &step) {
+				return nil, invalid
+			}
+			i = end + 1
+		} else {
+			end := i
+			for end < len(expr) && expr[end] != '.' && expr[end] != '[' {
+				end++
+			}
+			name := expr[i:end]
+			if name == "" {
+				return nil, invalid
+			}
+			if name == "*" {
+				step.wildcard = true
+			} else {
+				step.keys = []string{name}
+			}
+			i = end
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// indexBracket returns the index of the bracket closing the one
+// at the given position, skipping quoted strings.
+func indexBracket(expr string, start int) int {
+	var quote byte
+	for i := start + 1; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseSelector parses the given bracket selector content into the step.
+func parseSelector(sel string, step *jsonStep) bool {
+	sel = strings.TrimSpace(sel)
+	if sel == "*" {
+		step.wildcard = true
+		return true
+	}
+
+	if sel != "" && (sel[0] == '\'' || sel[0] == '"') {
+		for _, part := range splitUnion(sel) {
+			part = strings.TrimSpace(part)
+			if len(part) < 2 || (part[0] != '\'' && part[0] != '"') || part[len(part)-1] != part[0] {
+				return false
+			}
+			step.keys = append(step.keys, part[1:len(part)-1])
+		}
+		return true
+	}
+
+	if strings.Contains(sel, ":") {
+		return parseSlice(sel, step)
+	}
+
+	for _, part := range strings.Split(sel, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return false
+		}
+		step.indexes = append(step.indexes, index)
+	}
+	return true
+}
+
+// splitUnion splits the given union selector by commas outside quoted strings.
+func splitUnion(sel string) []string {
+	var parts []string
+	var quote byte
+	last := 0
+	for i := 0; i < len(sel); i++ {
+		switch c := sel[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			parts = append(parts, sel[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, sel[last:])
+}
+
+// parseSlice parses the given [start:end:step] slice selector.
+func parseSlice(sel string, step *jsonStep) bool {
+	parts := strings.Split(sel, ":")
+	if len(parts) > 3 {
+		return false
+	}
+
+	slice := &jsonSlice{step: 1}
+	values := []*int{&slice.start, &slice.end, &slice.step}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return false
+		}
+		*values[i] = n
+		slice.hasStart = slice.hasStart || i == 0
+		slice.hasEnd = slice.hasEnd || i == 1
+	}
+	if slice.step <= 0 {
+		return false
+	}
+
+	step.slice = slice
+	return true
+}
+
+// eval applies the step to the given nodes and returns the matched nodes.
+func (s jsonStep) eval(nodes []interface{}) []interface{} {
+	if s.recursive {
+		var all []interface{}
+		for _, node := range nodes {
+			all = descendants(node, all)
+		}
+		nodes = all
+	}
+
+	var matches []interface{}
+	for _, node := range nodes {
+		matches = s.match(node, matches)
+	}
+	return matches
+}
+
+// match appends the values of the given node matched by the step selector.
+func (s jsonStep) match(node interface{}, matches []interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			for _, key := range sortedKeys(v) {
+				matches = append(matches, v[key])
+			}
+		}
+		for _, key := range s.keys {
+			if value, ok := v[key]; ok {
+				matches = append(matches, value)
+			}
+		}
+	case []interface{}:
+		if s.wildcard {
+			matches = append(matches, v...)
+		}
+		for _, index := range s.indexes {
+			if index < 0 {
+				index += len(v)
+			}
+			if index >= 0 && index < len(v) {
+				matches = append(matches, v[index])
+			}
+		}
+		if s.slice != nil {
+			start, end := s.slice.bounds(len(v))
+			for i := start; i < end; i += s.slice.step {
+				matches = append(matches, v[i])
+			}
+		}
+	}
+	return matches
+}
+
+// bounds returns the normalized slice bounds for an array of the given length.
+func (s *jsonSlice) bounds(length int) (int, int) {
+	start, end := 0, length
+	if s.hasStart {
+		start = s.start
+	}
+	if s.hasEnd {
+		end = s.end
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+// descendants appends the given node and all its descendants, in document order.
+func descendants(node interface{}, nodes []interface{}) []interface{} {
+	nodes = append(nodes, node)
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			nodes = descendants(v[key], nodes)
+		}
+	case []interface{}:
+		for _, item := range v {
+			nodes = descendants(item, nodes)
+		}
+	}
+	return nodes
+}
+
+// sortedKeys returns the object member names sorted alphabetically.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
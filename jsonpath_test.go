@@ -0,0 +1,79 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+const jsonPathBody = `{
+  "data": {
+    "items": [
+      {"id": 1, "name": "foo", "tags": ["a", "b"]},
+      {"id": 2, "name": "bar", "tags": []},
+      {"id": 3, "name": "baz", "tags": ["c"]}
+    ],
+    "cursor": {"next": "abc"}
+  },
+  "meta.info": {"total": 3, "big": 9007199254740993}
+}`
+
+func jsonPathResponse() *Response {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "application/json")
+	utils.WriteBodyString(ctx.Response, jsonPathBody)
+	res, _ := buildResponse(ctx)
+	return res
+}
+
+func TestResponseJSONPath(t *testing.T) {
+	cases := []struct {
+		expr   string
+		values []interface{}
+	}{
+		{"$.data.items[*].id", []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}},
+		{"$.data.cursor.next", []interface{}{"abc"}},
+		{"$['data']['cursor'].next", []interface{}{"abc"}},
+		{"$['meta.info'].total", []interface{}{json.Number("3")}},
+		{"$['meta.info'].big", []interface{}{json.Number("9007199254740993")}},
+		{"$.data.items[0].name", []interface{}{"foo"}},
+		{"$.data.items[-1].name", []interface{}{"baz"}},
+		{"$.data.items[0,2].id", []interface{}{json.Number("1"), json.Number("3")}},
+		{"$.data.items[1:].id", []interface{}{json.Number("2"), json.Number("3")}},
+		{"$.data.items[:2].id", []interface{}{json.Number("1"), json.Number("2")}},
+		{"$.data.items[::2].id", []interface{}{json.Number("1"), json.Number("3")}},
+		{"$..tags[*]", []interface{}{"a", "b", "c"}},
+		{"$.data.cursor.*", []interface{}{"abc"}},
+		{"$.data.items[0]['id','name']", []interface{}{json.Number("1"), "foo"}},
+		{"$.data.missing", nil},
+		{"$.data.items[10]", nil},
+	}
+
+	res := jsonPathResponse()
+	for _, test := range cases {
+		values, err := res.JSONPath(test.expr)
+		st.Expect(t, err, nil)
+		st.Expect(t, values, test.values)
+	}
+
+	values, err := res.JSONPath("$")
+	st.Expect(t, err, nil)
+	st.Expect(t, len(values), 1)
+	st.Expect(t, res.String(), jsonPathBody)
+}
+
+func TestResponseJSONPathInvalid(t *testing.T) {
+	res := jsonPathResponse()
+	for _, expr := range []string{"data.items", "$.", "$.data[", "$.data[foo]", "$[1:2:0]", "$.data[?(@.id)]"} {
+		_, err := res.JSONPath(expr)
+		st.Reject(t, err, nil)
+	}
+
+	ctx := NewContext()
+	utils.WriteBodyString(ctx.Response, "<html></html>")
+	res, _ = buildResponse(ctx)
+	_, err := res.JSONPath("$.foo")
+	st.Reject(t, err, nil)
+}
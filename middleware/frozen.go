@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"errors"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// ErrFrozen is used when trying to mutate a frozen middleware.
+var ErrFrozen = errors.New("gentleman: cannot mutate a frozen middleware")
+
+// Frozen represents an immutable middleware layer which stores the flattened
+// plugins stack of a middleware and its parents.
+// Frozen middleware panics on mutation and runs without locking.
+type Frozen struct {
+	// stack stores the flattened plugins stack.
+	stack []plugin.Plugin
+}
+
+// Freeze creates a new immutable middleware based on the plugins
+// registered in the given middleware and its parents.
+func Freeze(mw Middleware) *Frozen {
+	if frozen, ok := mw.(*Frozen); ok {
+		return frozen
+	}
+	return &Frozen{stack: flatten(mw, nil)}
+}
+
+// flatten returns the plugins stack of the given middleware and
+// its parents, applying the given masks.
+func flatten(mw Middleware, masks map[string]plugin.Plugin) []plugin.Plugin {
+	switch m := mw.(type) {
+	case *Frozen:
+		return applyMasks(m.stack, masks)
+	case *Layer:
+		m.mtx.Lock()
+		m.stack = filter(m.stack)
+		parent := m.parent
+		parentMasks := mergeMasks(m.masks, masks)
+		stack := m.stack
+		m.mtx.Unlock()
+
+		var buf []plugin.Plugin
+		if parent != nil {
+			buf = flatten(parent, parentMasks)
+		}
+		return append(buf, applyMasks(stack, masks)...)
+	}
+	return applyMasks(filter(mw.GetStack()), masks)
+}
+
+// Use panics since frozen middleware cannot be mutated.
+func (f *Frozen) Use(plugin.Plugin) Middleware {
+	panic(ErrFrozen)
+}
+
+// Unuse panics since frozen middleware cannot be mutated.
+func (f *Frozen) Unuse(string) Middleware {
+	panic(ErrFrozen)
+}
+
+// Replace panics since frozen middleware cannot be mutated.
+func (f *Frozen) Replace(string, plugin.Plugin) Middleware {
+	panic(ErrFrozen)
+}
+
+// UseError panics since frozen middleware cannot be mutated.
+func (f *Frozen) UseError(c.HandlerFunc) Middleware {
+	panic(ErrFrozen)
+}
+
+// UseRequest panics since frozen middleware cannot be mutated.
+func (f *Frozen) UseRequest(c.HandlerFunc) Middleware {
+	panic(ErrFrozen)
+}
+
+// UseResponse panics since frozen middleware cannot be mutated.
+func (f *Frozen) UseResponse(c.HandlerFunc) Middleware {
+	panic(ErrFrozen)
+}
+
+// UseHandler panics since frozen middleware cannot be mutated.
+func (f *Frozen) UseHandler(string, c.HandlerFunc) Middleware {
+	panic(ErrFrozen)
+}
+
+// UseParent panics since frozen middleware cannot be mutated.
+func (f *Frozen) UseParent(Middleware) Middleware {
+	panic(ErrFrozen)
+}
+
+// Flush panics since frozen middleware cannot be mutated.
+func (f *Frozen) Flush() {
+	panic(ErrFrozen)
+}
+
+// SetStack panics since frozen middleware cannot be mutated.
+func (f *Frozen) SetStack([]plugin.Plugin) {
+	panic(ErrFrozen)
+}
+
+// GetStack returns a copy of the flattened plugins stack.
+func (f *Frozen) GetStack() []plugin.Plugin {
+	return append([]plugin.Plugin(nil), f.stack...)
+}
+
+// Clone creates a new mutable middleware based on the flattened plugins stack.
+func (f *Frozen) Clone() Middleware {
+	mw := New()
+	mw.stack = f.GetStack()
+	return mw
+}
+
+// Run triggers the middleware call chain for the given phase.
+func (f *Frozen) Run(phase string, ctx *c.Context) *c.Context {
+	return f.run(phase, ctx, nil)
+}
+
+// run triggers the middleware call chain for the given phase,
+// applying the given masks inherited from child layers.
+func (f *Frozen) run(phase string, ctx *c.Context, masks map[string]plugin.Plugin) *c.Context {
+	return trigger(phase, applyMasks(f.stack, masks), ctx)
+}
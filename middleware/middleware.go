@@ -4,6 +4,7 @@ package middleware
 
 import (
 	"sync"
+	"sync/atomic"
 
 	c "gopkg.in/h2non/gentleman.v2/context"
 	"gopkg.in/h2non/gentleman.v2/plugin"
@@ -46,6 +47,18 @@ type Middleware interface {
 	SetStack([]plugin.Plugin)
 }
 
+// Replacer especifies the optional interface implemented by middleware
+// capable of removing or replacing the plugins registered by name.
+type Replacer interface {
+	// Unuse is used to remove the plugins registered with the given name,
+	// including the ones inherited from parent middleware.
+	Unuse(string) Middleware
+
+	// Replace is used to replace the plugins registered with the given name,
+	// including the ones inherited from parent middleware.
+	Replace(string, plugin.Plugin) Middleware
+}
+
 // Layer type represent an HTTP domain
 // specific middleware layer with inheritance support.
 type Layer struct {
@@ -57,25 +70,145 @@ type Layer struct {
 
 	// parent points to a parent middleware for behavior inheritance.
 	parent Middleware
+
+	// masks stores the inherited named plugins removed (nil) or replaced in this layer.
+	masks map[string]plugin.Plugin
+
+	// modified stores the generation of the last layer mutation.
+	modified uint64
+
+	// chain stores the compiled plugins stack of the layer and its parents.
+	chain *chain
 }
 
+// chain represents a compiled plugins stack.
+type chain struct {
+	// stamp stores the last mutation generation of the compiled layers.
+	stamp uint64
+
+	// stack stores the flattened plugins stack.
+	stack []plugin.Plugin
+}
+
+// generation stores the global middleware mutation counter,
+// used to invalidate the compiled plugins stacks.
+var generation uint64
+
 // New creates a new middleware layer.
 func New() *Layer {
 	return &Layer{}
 }
 
+// touch records a layer mutation. The caller must hold the lock.
+func (s *Layer) touch() {
+	s.modified = atomic.AddUint64(&generation, 1)
+}
+
 // Use registers a new plugin to the middleware stack.
 func (s *Layer) Use(plugin plugin.Plugin) Middleware {
 	s.mtx.Lock()
 	s.stack = append(s.stack, plugin)
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
 
+// Unuse removes the plugins registered with the given name from the middleware stack.
+// Plugins inherited from parent middleware are removed only for the current layer.
+func (s *Layer) Unuse(name string) Middleware {
+	if name == "" {
+		return s
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	buf := []plugin.Plugin{}
+	for _, p := range s.stack {
+		if plugin.NameOf(p) != name {
+			buf = append(buf, p)
+		}
+	}
+	s.stack = buf
+	s.mask(name, nil)
+	s.touch()
+	return s
+}
+
+// Replace replaces the plugins registered with the given name by the given plugin.
+// Plugins inherited from parent middleware are replaced only for the current layer.
+// The plugin is registered at the end of the stack if no plugin matches the given name.
+func (s *Layer) Replace(name string, p plugin.Plugin) Middleware {
+	if name == "" {
+		return s
+	}
+	if plugin.NameOf(p) != name {
+		p = plugin.WithName(name, p)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	defer s.touch()
+
+	replaced := false
+	buf := []plugin.Plugin{}
+	for _, current := range s.stack {
+		if plugin.NameOf(current) != name {
+			buf = append(buf, current)
+		} else if !replaced {
+			buf = append(buf, p)
+			replaced = true
+		}
+	}
+	s.stack = buf
+
+	switch {
+	case replaced:
+		// Inherited plugins are superseded by the local one
+		s.mask(name, nil)
+	case s.parent != nil && hasNamed(s.parent, name):
+		// Keep the position of the inherited plugin
+		s.mask(name, p)
+	default:
+		s.stack = append(s.stack, p)
+	}
+
+	return s
+}
+
+// mask registers a mask for the inherited plugins with the given name.
+// The caller must hold the lock.
+func (s *Layer) mask(name string, p plugin.Plugin) {
+	if s.masks == nil {
+		s.masks = make(map[string]plugin.Plugin)
+	}
+	s.masks[name] = p
+}
+
+// hasNamed returns true if the given middleware, or any parent,
+// has a plugin registered with the given name.
+func hasNamed(mw Middleware, name string) bool {
+	for _, p := range mw.GetStack() {
+		if plugin.NameOf(p) == name && !p.Removed() {
+			return true
+		}
+	}
+	if layer, ok := mw.(*Layer); ok {
+		layer.mtx.RLock()
+		parent := layer.parent
+		layer.mtx.RUnlock()
+		if parent != nil {
+			return hasNamed(parent, name)
+		}
+	}
+	return false
+}
+
 // UseHandler registers a phase specific plugin handler in the middleware stack.
 func (s *Layer) UseHandler(phase string, fn c.HandlerFunc) Middleware {
 	s.mtx.Lock()
 	s.stack = append(s.stack, plugin.NewPhasePlugin(phase, fn))
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
@@ -84,6 +217,7 @@ func (s *Layer) UseHandler(phase string, fn c.HandlerFunc) Middleware {
 func (s *Layer) UseResponse(fn c.HandlerFunc) Middleware {
 	s.mtx.Lock()
 	s.stack = append(s.stack, plugin.NewResponsePlugin(fn))
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
@@ -92,6 +226,7 @@ func (s *Layer) UseResponse(fn c.HandlerFunc) Middleware {
 func (s *Layer) UseRequest(fn c.HandlerFunc) Middleware {
 	s.mtx.Lock()
 	s.stack = append(s.stack, plugin.NewRequestPlugin(fn))
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
@@ -100,6 +235,7 @@ func (s *Layer) UseRequest(fn c.HandlerFunc) Middleware {
 func (s *Layer) UseError(fn c.HandlerFunc) Middleware {
 	s.mtx.Lock()
 	s.stack = append(s.stack, plugin.NewErrorPlugin(fn))
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
@@ -108,6 +244,7 @@ func (s *Layer) UseError(fn c.HandlerFunc) Middleware {
 func (s *Layer) UseParent(parent Middleware) Middleware {
 	s.mtx.Lock()
 	s.parent = parent
+	s.touch()
 	s.mtx.Unlock()
 	return s
 }
@@ -116,6 +253,7 @@ func (s *Layer) UseParent(parent Middleware) Middleware {
 func (s *Layer) Flush() {
 	s.mtx.Lock()
 	s.stack = s.stack[:0]
+	s.touch()
 	s.mtx.Unlock()
 }
 
@@ -123,6 +261,7 @@ func (s *Layer) Flush() {
 func (s *Layer) SetStack(stack []plugin.Plugin) {
 	s.mtx.Lock()
 	s.stack = stack
+	s.touch()
 	s.mtx.Unlock()
 }
 
@@ -139,14 +278,100 @@ func (s *Layer) Clone() Middleware {
 	mw.parent = s.parent
 	s.mtx.Lock()
 	mw.stack = append([]plugin.Plugin(nil), s.stack...)
+	for name, p := range s.masks {
+		mw.mask(name, p)
+	}
 	s.mtx.Unlock()
 	return mw
 }
 
 // Run triggers the middleware call chain for the given phase.
 func (s *Layer) Run(phase string, ctx *c.Context) *c.Context {
-	if s.parent != nil {
-		ctx = s.parent.Run(phase, ctx)
+	return s.run(phase, ctx, nil)
+}
+
+// run triggers the middleware call chain for the given phase,
+// applying the given masks inherited from child layers.
+func (s *Layer) run(phase string, ctx *c.Context, masks map[string]plugin.Plugin) *c.Context {
+	if len(masks) == 0 {
+		if stack, ok := s.compile(); ok {
+			return trigger(phase, stack, ctx)
+		}
+	} else if _, ok := s.stamp(); ok {
+		return trigger(phase, flatten(s, masks), ctx)
+	}
+	return s.runChained(phase, ctx, masks)
+}
+
+// compile returns the flattened plugins stack of the layer and its parents,
+// which is only compiled again if any layer or plugin changed.
+// Returns false if any parent middleware does not support compilation.
+func (s *Layer) compile() ([]plugin.Plugin, bool) {
+	stamp, ok := s.stamp()
+	if !ok {
+		return nil, false
+	}
+
+	s.mtx.RLock()
+	compiled := s.chain
+	s.mtx.RUnlock()
+	if compiled != nil && compiled.stamp == stamp && !removed(compiled.stack) {
+		return compiled.stack, true
+	}
+
+	stack := flatten(s, nil)
+	s.mtx.Lock()
+	s.chain = &chain{stamp: stamp, stack: stack}
+	s.mtx.Unlock()
+	return stack, true
+}
+
+// stamp returns the last mutation generation of the layer and its parents.
+// Returns false if any parent middleware does not support compilation.
+func (s *Layer) stamp() (uint64, bool) {
+	var stamp uint64
+	for mw := Middleware(s); mw != nil; {
+		switch m := mw.(type) {
+		case *Layer:
+			m.mtx.RLock()
+			if m.modified > stamp {
+				stamp = m.modified
+			}
+			mw = m.parent
+			m.mtx.RUnlock()
+		case *Frozen:
+			return stamp, true
+		default:
+			return 0, false
+		}
+	}
+	return stamp, true
+}
+
+// removed returns true if any plugin of the given stack was removed.
+func removed(stack []plugin.Plugin) bool {
+	for _, p := range stack {
+		if p.Removed() {
+			return true
+		}
+	}
+	return false
+}
+
+// runChained triggers the middleware call chain of the parent middleware
+// and then the current layer, applying the given masks inherited from child layers.
+func (s *Layer) runChained(phase string, ctx *c.Context, masks map[string]plugin.Plugin) *c.Context {
+	s.mtx.RLock()
+	parent := s.parent
+	parentMasks := mergeMasks(s.masks, masks)
+	s.mtx.RUnlock()
+
+	if parent != nil {
+		if layer, ok := parent.(runner); ok {
+			ctx = layer.run(phase, ctx, parentMasks)
+		} else {
+			ctx = parent.Run(phase, ctx)
+		}
 		if phase != "error" && (ctx.Error != nil || ctx.Stopped) {
 			return ctx
 		}
@@ -158,7 +383,47 @@ func (s *Layer) Run(phase string, ctx *c.Context) *c.Context {
 
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
-	return trigger(phase, s.stack, ctx)
+	return trigger(phase, applyMasks(s.stack, masks), ctx)
+}
+
+// runner is implemented by the middleware layers supporting masks inherited from child layers.
+type runner interface {
+	run(string, *c.Context, map[string]plugin.Plugin) *c.Context
+}
+
+// mergeMasks merges the layer masks with the masks inherited from child layers,
+// which take precedence.
+func mergeMasks(own, inherited map[string]plugin.Plugin) map[string]plugin.Plugin {
+	if len(own) == 0 {
+		return inherited
+	}
+	masks := make(map[string]plugin.Plugin, len(own)+len(inherited))
+	for name, p := range own {
+		masks[name] = p
+	}
+	for name, p := range inherited {
+		masks[name] = p
+	}
+	return masks
+}
+
+// applyMasks removes or replaces the named plugins of the given stack.
+func applyMasks(stack []plugin.Plugin, masks map[string]plugin.Plugin) []plugin.Plugin {
+	if len(masks) == 0 {
+		return stack
+	}
+	buf := []plugin.Plugin{}
+	for _, p := range stack {
+		name := plugin.NameOf(p)
+		mask, ok := masks[name]
+		switch {
+		case !ok || name == "":
+			buf = append(buf, p)
+		case mask != nil:
+			buf = append(buf, mask)
+		}
+	}
+	return buf
 }
 
 func filter(stack []plugin.Plugin) []plugin.Plugin {
@@ -177,9 +442,12 @@ func trigger(phase string, stack []plugin.Plugin, ctx *c.Context) *c.Context {
 	wg.Add(1)
 
 	// Finisher function
+	var once sync.Once
 	done := func(_ctx *c.Context) {
-		ctx = _ctx
-		wg.Done()
+		once.Do(func() {
+			ctx = _ctx
+			wg.Done()
+		})
 	}
 
 	i := len(stack)
@@ -198,7 +466,11 @@ func trigger(phase string, stack []plugin.Plugin, ctx *c.Context) *c.Context {
 	ctx.Set("$phase", phase)
 
 	// Triggers the middleware call chain
-	next(ctx)
+	if recovering(ctx) {
+		recoverCall(phase, next, done, ctx)
+	} else {
+		next(ctx)
+	}
 
 	wg.Wait()
 	return ctx
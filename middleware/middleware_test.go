@@ -310,3 +310,237 @@ func TestMiddlewareErrorWithInheritance(t *testing.T) {
 func forward(ctx *context.Context, h context.Handler) {
 	h.Next(ctx)
 }
+
+func appender(value string) context.HandlerFunc {
+	return func(c *context.Context, h context.Handler) {
+		c.Set("foo", c.GetString("foo")+value)
+		h.Next(c)
+	}
+}
+
+func TestMiddlewareUnuse(t *testing.T) {
+	parent := New()
+	parent.Use(plugin.WithName("foo", plugin.NewRequestPlugin(appender("foo"))))
+	parent.UseRequest(appender("bar"))
+
+	child := New()
+	child.UseParent(parent)
+	child.Use(plugin.WithName("foo", plugin.NewRequestPlugin(appender("baz"))))
+	child.Unuse("foo")
+
+	ctx := child.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "bar" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+	if len(child.GetStack()) != 0 {
+		t.Error("Stack must be empty")
+	}
+
+	// Parent middleware must not be altered
+	ctx = parent.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "foobar" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+}
+
+func TestMiddlewareReplace(t *testing.T) {
+	parent := New()
+	parent.Use(plugin.WithName("foo", plugin.NewRequestPlugin(appender("foo"))))
+	parent.UseRequest(appender("bar"))
+
+	child := New()
+	child.UseParent(parent)
+	child.UseRequest(appender("qux"))
+	child.Replace("foo", plugin.NewRequestPlugin(appender("baz")))
+
+	// Inherited plugins are replaced in place
+	ctx := child.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "bazbarqux" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+
+	// Local plugins are replaced in place, superseding inherited ones
+	mw := New()
+	mw.UseParent(child)
+	mw.UseRequest(appender("1"))
+	mw.Use(plugin.WithName("foo", plugin.NewRequestPlugin(appender("2"))))
+	mw.Replace("foo", plugin.NewRequestPlugin(appender("3")))
+	mw.UseRequest(appender("4"))
+
+	ctx = mw.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "barqux134" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+
+	// Unknown plugins are registered
+	mw.Replace("bar", plugin.NewRequestPlugin(appender("5")))
+	ctx = mw.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "barqux1345" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+
+	// Clones preserve the masks
+	ctx = mw.Clone().Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "barqux1345" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+}
+
+func TestMiddlewareFreeze(t *testing.T) {
+	parent := New()
+	parent.Use(plugin.WithName("foo", plugin.NewRequestPlugin(appender("foo"))))
+	child := New()
+	child.UseParent(parent)
+	child.UseRequest(appender("bar"))
+
+	frozen := Freeze(child)
+	if len(frozen.GetStack()) != 2 {
+		t.Error("Invalid stack size")
+	}
+
+	// Changes do not affect the frozen middleware
+	child.UseRequest(appender("baz"))
+
+	ctx := frozen.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "foobar" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+
+	// Masks of child layers are honored
+	mw := New()
+	mw.UseParent(frozen)
+	mw.Unuse("foo")
+	ctx = mw.Run("request", context.New())
+	if val := ctx.GetString("foo"); val != "bar" {
+		t.Errorf("Invalid context value: %s", val)
+	}
+
+	// Clones are mutable
+	clone := frozen.Clone()
+	clone.UseRequest(appender("qux"))
+	if len(clone.GetStack()) != 3 || len(frozen.GetStack()) != 2 {
+		t.Error("Invalid stack size")
+	}
+
+	if Freeze(frozen) != frozen {
+		t.Error("Frozen middleware must not be frozen again")
+	}
+}
+
+func TestMiddlewareFreezeMutation(t *testing.T) {
+	frozen := Freeze(New())
+	mutations := []func(){
+		func() { frozen.Use(plugin.New()) },
+		func() { frozen.UseRequest(forward) },
+		func() { frozen.UseParent(New()) },
+		func() { frozen.Unuse("foo") },
+		func() { frozen.Flush() },
+	}
+	for _, mutate := range mutations {
+		func() {
+			defer func() {
+				if recover() != ErrFrozen {
+					t.Error("Mutation must panic")
+				}
+			}()
+			mutate()
+		}()
+	}
+}
+
+func TestMiddlewareCompile(t *testing.T) {
+	parent := New()
+	parent.UseRequest(forward)
+	mw := New()
+	mw.UseParent(parent)
+	mw.UseRequest(forward)
+
+	stack, ok := mw.compile()
+	if !ok || len(stack) != 2 {
+		t.Fatalf("Invalid compiled stack size: %d", len(stack))
+	}
+
+	// Compiled stacks are reused until a mutation
+	if cached, _ := mw.compile(); &cached[0] != &stack[0] {
+		t.Error("Compiled stack must be reused")
+	}
+
+	// Parent mutations invalidate the compiled stack
+	p := plugin.NewRequestPlugin(forward)
+	parent.Use(p)
+	if stack, _ = mw.compile(); len(stack) != 3 {
+		t.Errorf("Invalid compiled stack size: %d", len(stack))
+	}
+
+	// Removed plugins invalidate the compiled stack
+	p.Remove()
+	if stack, _ = mw.compile(); len(stack) != 2 {
+		t.Errorf("Invalid compiled stack size: %d", len(stack))
+	}
+
+	parent.UseRequest(appender("parent"))
+	mw.UseRequest(appender("child"))
+	ctx := mw.Run("request", context.New())
+	if ctx.GetString("foo") != "parentchild" {
+		t.Errorf("Invalid call chain: %s", ctx.GetString("foo"))
+	}
+}
+
+func BenchmarkMiddlewareInheritance(b *testing.B) {
+	mw := New()
+	for i := 0; i < 5; i++ {
+		parent := New()
+		for n := 0; n < 5; n++ {
+			parent.UseRequest(forward)
+		}
+		parent.UseParent(mw)
+		mw = parent
+	}
+
+	for n := 0; n < b.N; n++ {
+		mw.Run("request", context.New())
+	}
+}
+
+func TestMiddlewareRecover(t *testing.T) {
+	mw := New()
+	mw.UseRequest(forward)
+	mw.UseRequest(func(ctx *context.Context, h context.Handler) {
+		panic("foo")
+	})
+
+	ctx := context.New()
+	EnableRecover(ctx)
+	ctx = mw.Run("request", ctx)
+	err, ok := ctx.Error.(*PanicError)
+	if !ok {
+		t.Fatalf("Invalid error: %#v", ctx.Error)
+	}
+	if err.Phase != "request" || err.Value != "foo" || len(err.Stack) == 0 {
+		t.Errorf("Invalid panic error: %s", err)
+	}
+	if err.Error() != "gentleman: recovered panic in request phase: foo" {
+		t.Errorf("Invalid error message: %s", err)
+	}
+
+	// Panics after the call chain finished are recovered too
+	mw = New()
+	mw.UseRequest(func(ctx *context.Context, h context.Handler) {
+		h.Next(ctx)
+		panic("bar")
+	})
+	ctx = context.New()
+	EnableRecover(ctx)
+	ctx = mw.Run("request", ctx)
+	if err, ok := ctx.Error.(*PanicError); !ok || err.Value != "bar" {
+		t.Errorf("Invalid error: %#v", ctx.Error)
+	}
+
+	// Recovery is disabled by default
+	defer func() {
+		if recover() == nil {
+			t.Error("Panic must not be recovered")
+		}
+	}()
+	mw.Run("request", context.New())
+}
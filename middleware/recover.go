@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+)
+
+// RecoverKey is the context store key used to enable the panics recovery.
+// See EnableRecover().
+const RecoverKey = "$recover"
+
+// PanicError represents a panic recovered while running the middleware
+// call chain, which is reported via the error phase.
+type PanicError struct {
+	// Phase stores the middleware phase which panicked.
+	Phase string
+
+	// Value stores the recovered panic value.
+	Value interface{}
+
+	// Stack stores the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns the error message.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("gentleman: recovered panic in %s phase: %v", e.Phase, e.Value)
+}
+
+// EnableRecover enables the panics recovery for the middleware call chains
+// triggered with the given context or any context inheriting from it.
+// Recovered panics are reported as *PanicError via the error phase.
+// Panics in goroutines spawned by plugins cannot be recovered.
+func EnableRecover(ctx *c.Context) {
+	ctx.Set(RecoverKey, true)
+}
+
+// recovering returns true if the panics recovery is enabled for the given context.
+func recovering(ctx *c.Context) bool {
+	enabled, _ := ctx.Get(RecoverKey).(bool)
+	return enabled
+}
+
+// recoverCall triggers the middleware call chain, finishing it with
+// a *PanicError if any plugin panics.
+func recoverCall(phase string, next, done c.HandlerCtx, ctx *c.Context) {
+	defer func() {
+		if value := recover(); value != nil {
+			ctx.Error = &PanicError{Phase: phase, Value: value, Stack: debug.Stack()}
+			done(ctx)
+		}
+	}()
+	next(ctx)
+}
@@ -0,0 +1,118 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// PathRegexp returns a new multiplexer who matches an HTTP request
+// path based on the given compiled regular expression.
+func PathRegexp(re *regexp.Regexp) *Mux {
+	return matchPhase("request", func(ctx *c.Context) bool {
+		return re.MatchString(ctx.Request.URL.Path)
+	})
+}
+
+// Body returns a new multiplexer who matches an HTTP request
+// body based on the given regexp pattern.
+// The body is buffered, so it can still be sent. Body read errors
+// and invalid patterns are reported via the error phase.
+func Body(pattern string) *Mux {
+	re, err := regexp.Compile(pattern)
+	return matchPhaseError("request", func(ctx *c.Context) (bool, error) {
+		if err != nil {
+			return false, err
+		}
+		body, err := utils.ReplayRequestBody(ctx.Request)
+		if err != nil {
+			return false, err
+		}
+		return re.Match(body), nil
+	})
+}
+
+// JSONField returns a new multiplexer who matches an HTTP request JSON body
+// field value, selected by the given dot separated path such as "user.roles.0",
+// which must be equal to the given value once encoded as JSON.
+// The body is buffered, so it can still be sent. Empty bodies never match,
+// while body read errors and invalid JSON bodies are reported via the error phase.
+func JSONField(path string, value interface{}) *Mux {
+	expected, err := normalizeJSON(value)
+	return matchPhaseError("request", func(ctx *c.Context) (bool, error) {
+		if err != nil {
+			return false, err
+		}
+		body, err := utils.ReplayRequestBody(ctx.Request)
+		if err != nil || len(bytes.TrimSpace(body)) == 0 {
+			return false, err
+		}
+
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return false, &JSONError{Err: err}
+		}
+		field, ok := lookupField(data, path)
+		return ok && reflect.DeepEqual(field, expected), nil
+	})
+}
+
+// JSONError is reported when matching a request body which is not valid JSON.
+type JSONError struct {
+	// Err stores the JSON decoding error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *JSONError) Error() string {
+	return "mux: invalid JSON body: " + e.Err.Error()
+}
+
+// Unwrap returns the JSON decoding error.
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// normalizeJSON returns the given value as decoded from its JSON encoding,
+// so it can be compared with decoded JSON values.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// lookupField returns the decoded JSON value field selected by the given dot separated path.
+func lookupField(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+	for _, name := range strings.Split(path, ".") {
+		switch node := data.(type) {
+		case map[string]interface{}:
+			value, ok := node[name]
+			if !ok {
+				return nil, false
+			}
+			data = value
+		case []interface{}:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			data = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
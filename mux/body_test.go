@@ -0,0 +1,104 @@
+package mux
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestMatchPathRegexp(t *testing.T) {
+	cases := []struct {
+		path    string
+		matches bool
+	}{
+		{"/users/12", true},
+		{"/users/12/orders", false},
+		{"/users/foo", false},
+	}
+
+	re := regexp.MustCompile(`^/users/\d+$`)
+	for _, test := range cases {
+		mx := New()
+		mx.Use(PathRegexp(re).UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.URL.Path = test.path
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+}
+
+func TestMatchBody(t *testing.T) {
+	cases := []struct {
+		pattern string
+		body    string
+		matches bool
+	}{
+		{`"type":\s*"refund"`, `{"type": "refund"}`, true},
+		{`"type":\s*"refund"`, `{"type": "charge"}`, false},
+		{`^hello`, `hello world`, true},
+		{`[`, `hello world`, false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(Body(test.pattern).UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.Body = ioutil.NopCloser(strings.NewReader(test.body))
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+
+		// The body can still be sent
+		data, _ := ioutil.ReadAll(ctx.Request.Body)
+		st.Expect(t, string(data), test.body)
+	}
+}
+
+func TestMatchJSONField(t *testing.T) {
+	body := `{"type": "refund", "amount": 10, "user": {"roles": ["admin", "ops"]}, "live": true}`
+	cases := []struct {
+		path    string
+		value   interface{}
+		matches bool
+	}{
+		{"type", "refund", true},
+		{"type", "charge", false},
+		{"amount", 10, true},
+		{"amount", 10.0, true},
+		{"amount", "10", false},
+		{"user.roles.0", "admin", true},
+		{"user.roles.1", "admin", false},
+		{"user.roles.5", "admin", false},
+		{"user.roles", []string{"admin", "ops"}, true},
+		{"live", true, true},
+		{"missing", nil, false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(JSONField(test.path, test.value).UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.Body = ioutil.NopCloser(strings.NewReader(body))
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+
+	// Invalid JSON bodies are reported via the error phase
+	mx := New()
+	mx.Use(JSONField("type", "refund").UseRequest(pass))
+	ctx := context.New()
+	ctx.Request.Body = ioutil.NopCloser(strings.NewReader("type=refund"))
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+	_, ok := ctx.Error.(*JSONError)
+	st.Expect(t, ok, true)
+
+	// Empty bodies never match, without errors
+	ctx = context.New()
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+	st.Expect(t, ctx.Error, nil)
+}
@@ -9,13 +9,33 @@ func If(muxes ...*Mux) *Mux {
 	mx := New()
 	for _, mm := range muxes {
 		mx.AddMatcher(mm.Matchers...)
+		if mx.phase == "" {
+			mx.phase = mm.phase
+		}
 	}
 	return mx
 }
 
+// And creates a new multiplexer that will be executed if all the given muxes match.
+// Unlike If(), the given muxes are evaluated as a whole when matching, so they can be
+// composed with Or() and Not() into nested conditions, such as
+// And(Method("POST"), Or(Path("^/orders"), Not(Host("internal")))).
+func And(muxes ...*Mux) *Mux {
+	mx := Match(func(ctx *c.Context) bool {
+		for _, mm := range muxes {
+			if !mm.Match(ctx) {
+				return false
+			}
+		}
+		return true
+	})
+	mx.phase = commonPhase(muxes)
+	return mx
+}
+
 // Or creates a new multiplexer that will be executed if at least one mux matcher passes.
 func Or(muxes ...*Mux) *Mux {
-	return Match(func(ctx *c.Context) bool {
+	mx := Match(func(ctx *c.Context) bool {
 		for _, mm := range muxes {
 			if mm.Match(ctx) {
 				return true
@@ -23,4 +43,34 @@ func Or(muxes ...*Mux) *Mux {
 		}
 		return false
 	})
+	mx.phase = commonPhase(muxes)
+	return mx
+}
+
+// Not creates a new multiplexer that will be executed if the given mux matchers do not pass.
+// Multiplexers matching a specific middleware phase are only negated in that phase, so
+// Not(Method("GET")) matches the non-GET requests, but never matches in the response phase.
+func Not(mm *Mux) *Mux {
+	mx := Match(func(ctx *c.Context) bool {
+		if mm.phase != "" && ctx.GetString("$phase") != mm.phase {
+			return false
+		}
+		return !mm.Match(ctx)
+	})
+	mx.phase = mm.phase
+	return mx
+}
+
+// commonPhase returns the phase shared by all the given multiplexers, if any.
+func commonPhase(muxes []*Mux) string {
+	if len(muxes) == 0 {
+		return ""
+	}
+	phase := muxes[0].phase
+	for _, mm := range muxes[1:] {
+		if mm.phase != phase {
+			return ""
+		}
+	}
+	return phase
 }
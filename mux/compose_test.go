@@ -58,3 +58,90 @@ func TestMuxComposeOrUnMatch(t *testing.T) {
 	mx.Run("request", ctx)
 	st.Expect(t, ctx.Request.Header.Get("foo"), "")
 }
+
+func TestMuxComposeNot(t *testing.T) {
+	mx := New()
+	mx.Use(Not(Method("GET")).UseRequest(pass).UseResponse(pass))
+
+	ctx := context.New()
+	ctx.Request.Method = "POST"
+	mx.Run("request", ctx)
+	match(t, ctx, true)
+
+	ctx = context.New()
+	ctx.Request.Method = "GET"
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+
+	// Negated matchers only match in their own phase
+	ctx = context.New()
+	ctx.Request.Method = "POST"
+	mx.Run("response", ctx)
+	match(t, ctx, false)
+}
+
+func TestMuxComposeNotIf(t *testing.T) {
+	cases := []struct {
+		path    string
+		token   string
+		matches bool
+	}{
+		{"/public/docs", "", true},
+		{"/public/docs", "Bearer foo", false},
+		{"/private", "", false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(If(NoRequestHeader("Authorization"), Path("^/public")).UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.URL.Path = test.path
+		if test.token != "" {
+			ctx.Request.Header.Set("Authorization", test.token)
+		}
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+
+	mx := New()
+	mx.Use(If(Not(RequestHeader("Authorization", ".")), Path("^/public")).UseRequest(pass))
+	ctx := context.New()
+	ctx.Request.URL.Path = "/public"
+	mx.Run("request", ctx)
+	match(t, ctx, true)
+}
+
+func TestMuxComposeAnd(t *testing.T) {
+	cases := []struct {
+		method  string
+		path    string
+		host    string
+		matches bool
+	}{
+		{"POST", "/orders", "internal", true},
+		{"POST", "/users", "api.com", true},
+		{"POST", "/users", "internal", false},
+		{"GET", "/orders", "internal", false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(And(Method("POST"), Or(Path("^/orders"), Not(Host("internal")))).UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.Method = test.method
+		ctx.Request.URL.Path = test.path
+		ctx.Request.URL.Host = test.host
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+
+	// Composed matchers keep the phase of the muxes
+	mx := New()
+	mx.Use(Not(And(Method("POST"), Path("^/orders"))).UseRequest(pass).UseResponse(pass))
+	ctx := context.New()
+	ctx.Request.Method = "GET"
+	mx.Run("response", ctx)
+	match(t, ctx, false)
+	mx.Run("request", ctx)
+	match(t, ctx, true)
+}
@@ -1,15 +1,26 @@
 package mux
 
 import (
-	c "gopkg.in/h2non/gentleman.v2/context"
-	types "gopkg.in/h2non/gentleman.v2/plugins/bodytype"
+	"net/http"
 	"regexp"
 	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	types "gopkg.in/h2non/gentleman.v2/plugins/bodytype"
+	"gopkg.in/h2non/gentleman.v2/plugins/query"
 )
 
+// matchErrorKey is the context store key used to keep the error reported by a matcher.
+const matchErrorKey = "$mux.matchError"
+
 // Matcher represent the function interface implemented by matchers
 type Matcher func(ctx *c.Context) bool
 
+// ErrorMatcher represents a matcher function which can fail, such as when the request
+// body cannot be read or parsed. Errors are reported via the error phase
+// instead of silently evaluating to false.
+type ErrorMatcher func(ctx *c.Context) (bool, error)
+
 // Match creates a new multiplexer based on a given matcher function.
 func Match(matchers ...Matcher) *Mux {
 	mx := New()
@@ -17,12 +28,53 @@ func Match(matchers ...Matcher) *Mux {
 	return mx
 }
 
+// matchPhase creates a new multiplexer based on the given matcher function,
+// which is only evaluated in the given middleware phase. The phase is kept
+// apart from the matcher condition, so negated multiplexers still only match in the same phase.
+func matchPhase(phase string, matcher Matcher) *Mux {
+	mx := Match(func(ctx *c.Context) bool {
+		return ctx.GetString("$phase") == phase && matcher(ctx)
+	})
+	mx.phase = phase
+	return mx
+}
+
+// MatchError creates a new multiplexer based on the given error-returning matcher functions.
+func MatchError(matchers ...ErrorMatcher) *Mux {
+	mx := New()
+	mx.AddErrorMatcher(matchers...)
+	return mx
+}
+
+// matchPhaseError creates a new multiplexer based on the given error-returning matcher,
+// which is only evaluated in the given middleware phase. See matchPhase().
+func matchPhaseError(phase string, matcher ErrorMatcher) *Mux {
+	mx := MatchError(func(ctx *c.Context) (bool, error) {
+		if ctx.GetString("$phase") != phase {
+			return false, nil
+		}
+		return matcher(ctx)
+	})
+	mx.phase = phase
+	return mx
+}
+
+// matchPattern creates a new multiplexer who matches the value returned by the given
+// function against the given regexp pattern, only evaluated in the given phase.
+// The pattern is compiled once, reporting invalid patterns via the error phase.
+func matchPattern(phase, pattern string, value func(ctx *c.Context) string) *Mux {
+	re, err := regexp.Compile(pattern)
+	return matchPhaseError(phase, func(ctx *c.Context) (bool, error) {
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(value(ctx)), nil
+	})
+}
+
 // Method returns a new multiplexer who matches an HTTP request based on the given method/s.
 func Method(methods ...string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
+	return matchPhase("request", func(ctx *c.Context) bool {
 		for _, method := range methods {
 			if ctx.Request.Method == method {
 				return true
@@ -35,82 +87,85 @@ func Method(methods ...string) *Mux {
 // Path returns a new multiplexer who matches an HTTP request
 // path based on the given regexp pattern.
 func Path(pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Request.URL.Path)
-		return matched
+	return matchPattern("request", pattern, func(ctx *c.Context) string {
+		return ctx.Request.URL.Path
 	})
 }
 
 // URL returns a new multiplexer who matches an HTTP request
 // URL based on the given regexp pattern.
 func URL(pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Request.URL.String())
-		return matched
+	return matchPattern("request", pattern, func(ctx *c.Context) string {
+		return ctx.Request.URL.String()
 	})
 }
 
 // Host returns a new multiplexer who matches an HTTP request
 // URL host based on the given regexp pattern.
 func Host(pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Request.URL.Host)
-		return matched
+	return matchPattern("request", pattern, func(ctx *c.Context) string {
+		return ctx.Request.URL.Host
 	})
 }
 
 // Query returns a new multiplexer who matches an HTTP request
 // query param based on the given key and regexp pattern.
 func Query(key, pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Request.URL.Query().Get(key))
-		return matched
+	return matchPattern("request", pattern, func(ctx *c.Context) string {
+		query.Flush(ctx)
+		return ctx.Request.URL.Query().Get(key)
+	})
+}
+
+// QueryExists returns a new multiplexer who matches an HTTP request
+// with the given query param, even if empty.
+func QueryExists(key string) *Mux {
+	return matchPhase("request", func(ctx *c.Context) bool {
+		query.Flush(ctx)
+		_, ok := ctx.Request.URL.Query()[key]
+		return ok
+	})
+}
+
+// QueryEquals returns a new multiplexer who matches an HTTP request
+// query param equal to the given value.
+func QueryEquals(key, value string) *Mux {
+	return matchPhase("request", func(ctx *c.Context) bool {
+		query.Flush(ctx)
+		values, ok := ctx.Request.URL.Query()[key]
+		return ok && values[0] == value
 	})
 }
 
 // RequestHeader returns a new multiplexer who matches an HTTP request
 // header field based on the given key and regexp pattern.
 func RequestHeader(key, pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "request" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Request.Header.Get(key))
-		return matched
+	return matchPattern("request", pattern, func(ctx *c.Context) string {
+		return ctx.Request.Header.Get(key)
+	})
+}
+
+// NoRequestHeader returns a new multiplexer who matches an HTTP request
+// without the given header field.
+func NoRequestHeader(key string) *Mux {
+	return matchPhase("request", func(ctx *c.Context) bool {
+		_, ok := ctx.Request.Header[http.CanonicalHeaderKey(key)]
+		return !ok
 	})
 }
 
 // ResponseHeader returns a new multiplexer who matches an HTTP response
 // header field based on the given key and regexp pattern.
 func ResponseHeader(key, pattern string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "response" {
-			return false
-		}
-		matched, _ := regexp.MatchString(pattern, ctx.Response.Header.Get(key))
-		return matched
+	return matchPattern("response", pattern, func(ctx *c.Context) string {
+		return ctx.Response.Header.Get(key)
 	})
 }
 
 // Type returns a new multiplexer who matches an HTTP response
 // Content-Type header field based on the given type string.
 func Type(kind string) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "response" {
-			return false
-		}
+	return matchPhase("response", func(ctx *c.Context) bool {
 		if value, ok := types.Types[kind]; ok {
 			kind = value
 		}
@@ -121,10 +176,7 @@ func Type(kind string) *Mux {
 // Status returns a new multiplexer who matches an HTTP response
 // status code based on the given status codes.
 func Status(codes ...int) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		if ctx.GetString("$phase") != "response" {
-			return false
-		}
+	return matchPhase("response", func(ctx *c.Context) bool {
 		for _, code := range codes {
 			if ctx.Response.StatusCode == code {
 				return true
@@ -137,8 +189,8 @@ func Status(codes ...int) *Mux {
 // StatusRange returns a new multiplexer who matches an HTTP response
 // status code based on the given status range, including both numbers.
 func StatusRange(start, end int) *Mux {
-	return Match(func(ctx *c.Context) bool {
-		return ctx.GetString("$phase") == "response" && ctx.Response.StatusCode >= start && ctx.Response.StatusCode <= end
+	return matchPhase("response", func(ctx *c.Context) bool {
+		return ctx.Response.StatusCode >= start && ctx.Response.StatusCode <= end
 	})
 }
 
@@ -153,7 +205,7 @@ func Error() *Mux {
 
 // ServerError returns a new multiplexer who matches response errors by the server.
 func ServerError() *Mux {
-	return Match(func(ctx *c.Context) bool {
-		return ctx.GetString("$phase") == "response" && ctx.Response.StatusCode >= 500
+	return matchPhase("response", func(ctx *c.Context) bool {
+		return ctx.Response.StatusCode >= 500
 	})
 }
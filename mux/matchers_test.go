@@ -289,6 +289,105 @@ func TestMatchServerError(t *testing.T) {
 	}
 }
 
+func TestMatchQueryExists(t *testing.T) {
+	cases := []struct {
+		query   string
+		matches bool
+	}{
+		{"debug=1", true},
+		{"debug=", true},
+		{"debug", true},
+		{"page=1", false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(QueryExists("debug").UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.URL.RawQuery = test.query
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+}
+
+func TestMatchQueryEquals(t *testing.T) {
+	cases := []struct {
+		query   string
+		matches bool
+	}{
+		{"page=2", true},
+		{"page=2&page=3", true},
+		{"page=20", false},
+		{"page=", false},
+		{"debug=2", false},
+	}
+
+	for _, test := range cases {
+		mx := New()
+		mx.Use(QueryEquals("page", "2").UseRequest(pass))
+		ctx := context.New()
+		ctx.Request.URL.RawQuery = test.query
+		mx.Run("request", ctx)
+		match(t, ctx, test.matches)
+	}
+}
+
+func TestMatchNoRequestHeader(t *testing.T) {
+	mx := New()
+	mx.Use(NoRequestHeader("authorization").UseRequest(pass))
+	ctx := context.New()
+	mx.Run("request", ctx)
+	match(t, ctx, true)
+
+	ctx = context.New()
+	ctx.Request.Header.Set("Authorization", "")
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+
+	// Only matches in the request phase
+	ctx = context.New()
+	mx.Run("response", ctx)
+	match(t, ctx, false)
+}
+
+func TestMatchErrorMatcher(t *testing.T) {
+	fail := errors.New("mux: cannot match")
+	matched := false
+	mx := New()
+	mx.Use(MatchError(func(ctx *context.Context) (bool, error) {
+		if matched {
+			return true, nil
+		}
+		return false, fail
+	}).UseRequest(pass))
+
+	ctx := context.New()
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+	st.Expect(t, ctx.Error, fail)
+	st.Expect(t, ctx.Get(matchErrorKey), nil)
+
+	matched = true
+	ctx = context.New()
+	mx.Run("request", ctx)
+	match(t, ctx, true)
+	st.Expect(t, ctx.Error, nil)
+}
+
+func TestMatchInvalidPattern(t *testing.T) {
+	mx := New()
+	mx.Use(Path("/foo/(").UseRequest(pass))
+	ctx := context.New()
+	mx.Run("request", ctx)
+	match(t, ctx, false)
+	st.Reject(t, ctx.Error, nil)
+
+	// Not evaluated outside of its phase
+	ctx = context.New()
+	mx.Run("response", ctx)
+	st.Expect(t, ctx.Error, nil)
+}
+
 func pass(ctx *context.Context, h context.Handler) {
 	ctx.Set("foo", "bar")
 	h.Next(ctx)
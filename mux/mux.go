@@ -20,6 +20,9 @@ type Mux struct {
 
 	// Middleware stores the multiplexer middleware layer.
 	Middleware middleware.Middleware
+
+	// phase stores the middleware phase the matchers are evaluated in, if known.
+	phase string
 }
 
 // New creates a new multiplexer with default settings.
@@ -48,11 +51,34 @@ func (m *Mux) AddMatcher(matchers ...Matcher) *Mux {
 	return m
 }
 
+// AddErrorMatcher adds new error-returning matcher functions in the current multiplexer matchers stack.
+// A failed matcher does not match, and its error is reported via the error phase.
+func (m *Mux) AddErrorMatcher(matchers ...ErrorMatcher) *Mux {
+	for _, matcher := range matchers {
+		matcher := matcher
+		m.Matchers = append(m.Matchers, func(ctx *c.Context) bool {
+			matched, err := matcher(ctx)
+			if err != nil {
+				ctx.Set(matchErrorKey, err)
+				return false
+			}
+			return matched
+		})
+	}
+	return m
+}
+
 // Handler returns the function handler to match an incoming HTTP transacion
 // and trigger the equivalent middleware phase.
 func (m *Mux) Handler() c.HandlerFunc {
 	return func(ctx *c.Context, h c.Handler) {
-		if !m.Match(ctx) {
+		matched := m.Match(ctx)
+		if err, ok := ctx.Get(matchErrorKey).(error); ok {
+			ctx.Delete(matchErrorKey)
+			h.Error(ctx, err)
+			return
+		}
+		if !matched {
 			h.Next(ctx)
 			return
 		}
@@ -77,6 +103,26 @@ func (m *Mux) Use(p plugin.Plugin) *Mux {
 	return m
 }
 
+// Unuse removes the plugins registered with the given name,
+// if supported by the multiplexer middleware.
+func (m *Mux) Unuse(name string) *Mux {
+	if r, ok := m.Middleware.(middleware.Replacer); ok {
+		r.Unuse(name)
+	}
+	return m
+}
+
+// Replace replaces the plugins registered with the given name by the given plugin,
+// or registers it if the multiplexer middleware does not support replacing plugins.
+func (m *Mux) Replace(name string, p plugin.Plugin) *Mux {
+	if r, ok := m.Middleware.(middleware.Replacer); ok {
+		r.Replace(name, p)
+		return m
+	}
+	m.Middleware.Use(plugin.WithName(name, p))
+	return m
+}
+
 // UseResponse registers a new response phase middleware handler.
 func (m *Mux) UseResponse(fn c.HandlerFunc) *Mux {
 	m.Middleware.UseResponse(fn)
@@ -32,11 +32,28 @@ type Plugin interface {
 	Exec(string, *context.Context, context.Handler)
 }
 
+// Named is an optional interface implemented by plugins registered with a name.
+// Named plugins can be removed or replaced in the middleware stack by name.
+type Named interface {
+	// Name returns the plugin name.
+	Name() string
+}
+
+// Closer is an optional interface implemented by plugins holding resources,
+// such as connections or background goroutines, which are released via Client.Close().
+type Closer interface {
+	// Close releases the plugin resources.
+	Close() error
+}
+
 // Handlers represents a map to store middleware handler functions per phase.
 type Handlers map[string]context.HandlerFunc
 
 // Layer encapsulates an Error, Request and Response function handlers
 type Layer struct {
+	// name stores the optional plugin name
+	name string
+
 	// removed stores if the plugin was removed
 	removed bool
 
@@ -81,6 +98,16 @@ func (p *Layer) Removed() bool {
 	return p.removed
 }
 
+// Name returns the plugin name, if any.
+func (p *Layer) Name() string {
+	return p.name
+}
+
+// SetName defines the plugin name.
+func (p *Layer) SetName(name string) {
+	p.name = name
+}
+
 // SetHandler uses a new handler function for the given middleware phase.
 func (p *Layer) SetHandler(phase string, handler context.HandlerFunc) {
 	p.Handlers[phase] = handler
@@ -133,3 +160,75 @@ func NewRequestPlugin(handler context.HandlerFunc) Plugin {
 func NewErrorPlugin(handler context.HandlerFunc) Plugin {
 	return NewPhasePlugin("error", handler)
 }
+
+// named wraps a plugin which does not support naming.
+type named struct {
+	Plugin
+	name string
+}
+
+// Name returns the plugin name.
+func (n *named) Name() string {
+	return n.name
+}
+
+// Close releases the resources held by the given wrapped plugin, if it implements Closer.
+func (n *named) Close() error {
+	return Close(n.Plugin)
+}
+
+// WithName names the given plugin, so it can be removed or replaced by name.
+func WithName(name string, plugin Plugin) Plugin {
+	if p, ok := plugin.(interface{ SetName(string) }); ok {
+		p.SetName(name)
+		return plugin
+	}
+	return &named{Plugin: plugin, name: name}
+}
+
+// NameOf returns the name of the given plugin, if any.
+func NameOf(plugin Plugin) string {
+	if p, ok := plugin.(Named); ok {
+		return p.Name()
+	}
+	return ""
+}
+
+// closer wraps a plugin releasing its resources via the given function.
+type closer struct {
+	Plugin
+	name  string
+	close func() error
+}
+
+// Name returns the plugin name, if any.
+func (p *closer) Name() string {
+	if p.name != "" {
+		return p.name
+	}
+	return NameOf(p.Plugin)
+}
+
+// SetName defines the plugin name.
+func (p *closer) SetName(name string) {
+	p.name = name
+}
+
+// Close releases the plugin resources.
+func (p *closer) Close() error {
+	return p.close()
+}
+
+// WithCloser attaches the given function to the plugin, so it implements Closer
+// and its resources are released once the client is closed.
+func WithCloser(plugin Plugin, fn func() error) Plugin {
+	return &closer{Plugin: plugin, close: fn}
+}
+
+// Close releases the resources held by the given plugin, if it implements Closer.
+func Close(plugin Plugin) error {
+	if p, ok := plugin.(Closer); ok {
+		return p.Close()
+	}
+	return nil
+}
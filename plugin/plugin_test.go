@@ -82,3 +82,51 @@ func TestNewErrorPlugin(t *testing.T) {
 		t.Errorf("Handler not called")
 	}
 }
+
+type customPlugin struct {
+	Plugin
+}
+
+func TestPluginName(t *testing.T) {
+	layer := New()
+	if NameOf(layer) != "" {
+		t.Error("Plugin must not have a name")
+	}
+	if WithName("foo", layer) != layer || NameOf(layer) != "foo" {
+		t.Error("Invalid plugin name")
+	}
+
+	custom := &customPlugin{NewRequestPlugin(nil)}
+	named := WithName("bar", custom)
+	if NameOf(named) != "bar" {
+		t.Errorf("Invalid plugin name: %s", NameOf(named))
+	}
+	if named.Disabled() {
+		t.Error("Named plugin must delegate to the wrapped plugin")
+	}
+}
+
+func TestPluginCloser(t *testing.T) {
+	closed := 0
+	plugin := WithCloser(NewRequestPlugin(nil), func() error {
+		closed++
+		return nil
+	})
+	if _, ok := plugin.(Closer); !ok {
+		t.Fatal("Plugin must implement Closer")
+	}
+
+	named := WithName("foo", plugin)
+	if NameOf(named) != "foo" {
+		t.Errorf("Invalid plugin name: %s", NameOf(named))
+	}
+	if err := Close(named); err != nil || closed != 1 {
+		t.Errorf("Plugin not closed: %d", closed)
+	}
+	if err := Close(WithName("bar", &customPlugin{plugin})); err != nil || closed != 1 {
+		t.Error("Plugin not implementing Closer must be ignored")
+	}
+	if err := Close(When(func(*context.Context) bool { return true }, named)); err != nil || closed != 2 {
+		t.Errorf("Conditional plugin must delegate to the wrapped plugin: %d", closed)
+	}
+}
@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// RewriteFunc represents a response body rewriting function, which
+// returns the transformed body based on the original buffered body.
+type RewriteFunc func(*context.Context, []byte) ([]byte, error)
+
+// NewRewritePlugin creates a new plugin layer to handle the rewrite middleware phase,
+// which runs after the response phase with the response body buffered, before
+// the body is accessed by the user. Rewrite plugins run in registration order,
+// each one receiving the body returned by the previous one.
+func NewRewritePlugin(fn RewriteFunc) Plugin {
+	return NewPhasePlugin("rewrite", func(ctx *context.Context, h context.Handler) {
+		body, err := utils.ReplayBody(ctx.Response)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		body, err = fn(ctx, body)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		ctx.Response.Body = ioutil.NopCloser(bytes.NewReader(body))
+		ctx.Response.ContentLength = int64(len(body))
+		if ctx.Response.Header.Get("Content-Length") != "" {
+			ctx.Response.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		h.Next(ctx)
+	})
+}
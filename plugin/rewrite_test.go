@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+func TestRewritePlugin(t *testing.T) {
+	plugin := NewRewritePlugin(func(ctx *context.Context, body []byte) ([]byte, error) {
+		return bytes.ToUpper(body), nil
+	})
+
+	ctx := context.New()
+	utils.WriteBodyString(ctx.Response, "foo")
+	ctx.Response.Header.Set("Content-Length", "3")
+	plugin.Exec("rewrite", ctx, context.NewHandler(func(*context.Context) {}))
+
+	body, _ := ioutil.ReadAll(ctx.Response.Body)
+	if ctx.Error != nil || string(body) != "FOO" {
+		t.Errorf("Invalid body: %s", body)
+	}
+	if ctx.Response.ContentLength != 3 || ctx.Response.Header.Get("Content-Length") != "3" {
+		t.Errorf("Invalid content length: %d", ctx.Response.ContentLength)
+	}
+
+	// Other phases are skipped
+	utils.WriteBodyString(ctx.Response, "foo")
+	plugin.Exec("response", ctx, context.NewHandler(func(*context.Context) {}))
+	if body, _ := ioutil.ReadAll(ctx.Response.Body); string(body) != "foo" {
+		t.Errorf("Invalid body: %s", body)
+	}
+}
+
+func TestRewritePluginError(t *testing.T) {
+	plugin := NewRewritePlugin(func(ctx *context.Context, body []byte) ([]byte, error) {
+		return nil, errors.New("foo error")
+	})
+
+	ctx := context.New()
+	plugin.Exec("rewrite", ctx, context.NewHandler(func(*context.Context) {}))
+	if ctx.Error == nil || ctx.Error.Error() != "foo error" {
+		t.Errorf("Invalid error: %s", ctx.Error)
+	}
+}
@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// Predicate represents the function used to decide if a plugin must be executed.
+type Predicate func(ctx *context.Context) bool
+
+// conditional wraps a plugin which is only executed if the predicate matches.
+type conditional struct {
+	Plugin
+	predicate Predicate
+}
+
+// Name returns the wrapped plugin name, if any.
+func (p *conditional) Name() string {
+	return NameOf(p.Plugin)
+}
+
+// Close releases the resources held by the wrapped plugin, if it implements Closer.
+func (p *conditional) Close() error {
+	return Close(p.Plugin)
+}
+
+// pending is stored in the context when the request phase execution was deferred.
+type pending struct{}
+
+// Exec executes the wrapped plugin handler if the predicate matches.
+func (p *conditional) Exec(phase string, ctx *context.Context, h context.Handler) {
+	switch phase {
+	case "request":
+		// Client plugins run before the request level setters, such as the URL path,
+		// therefore the predicate evaluation is deferred until the request is complete.
+		ctx.Set(p, pending{})
+		h.Next(ctx)
+		return
+	case "before dial":
+		if _, ok := ctx.Get(p).(pending); ok {
+			p.deferred(ctx, h)
+			return
+		}
+	}
+
+	if !p.match(ctx) {
+		h.Next(ctx)
+		return
+	}
+	p.Plugin.Exec(phase, ctx, h)
+}
+
+// deferred executes the deferred request phase, followed by the before dial phase.
+func (p *conditional) deferred(ctx *context.Context, h context.Handler) {
+	ctx.Delete(p)
+	if !p.match(ctx) {
+		h.Next(ctx)
+		return
+	}
+
+	ctx.Set("$phase", "request")
+	p.Plugin.Exec("request", ctx, context.NewHandler(func(ctx *context.Context) {
+		ctx.Set("$phase", "before dial")
+		if ctx.Error != nil || ctx.Stopped {
+			h.Next(ctx)
+			return
+		}
+		p.Plugin.Exec("before dial", ctx, h)
+	}))
+}
+
+// match evaluates the predicate, which is evaluated only once per request
+// since the before dial phase, when the outgoing request is complete.
+func (p *conditional) match(ctx *context.Context) bool {
+	if matched, ok := ctx.Get(p).(bool); ok {
+		return matched
+	}
+	matched := p.predicate(ctx)
+	if ctx.GetString("$phase") != "request" {
+		ctx.Set(p, matched)
+	}
+	return matched
+}
+
+// When returns a plugin which executes the given plugin only if the predicate matches.
+// In order to match the complete outgoing request, the predicate is evaluated right before
+// dialing, and the plugin request phase handler is deferred until that moment.
+// The predicate result is then reused for the subsequent phases.
+func When(predicate Predicate, plugin Plugin) Plugin {
+	return &conditional{Plugin: plugin, predicate: predicate}
+}
+
+// Method returns a predicate that matches the given request methods.
+func Method(methods ...string) Predicate {
+	return func(ctx *context.Context) bool {
+		for _, method := range methods {
+			if strings.EqualFold(ctx.Request.Method, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Host returns a predicate that matches the given request hosts,
+// which can be host names or host:port pairs.
+func Host(hosts ...string) Predicate {
+	return func(ctx *context.Context) bool {
+		for _, host := range hosts {
+			if strings.EqualFold(ctx.Request.URL.Host, host) || strings.EqualFold(ctx.Request.URL.Hostname(), host) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathPrefix returns a predicate that matches the request paths starting with the given prefix.
+func PathPrefix(prefix string) Predicate {
+	return func(ctx *context.Context) bool {
+		return strings.HasPrefix(ctx.Request.URL.Path, prefix)
+	}
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"testing"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestWhen(t *testing.T) {
+	calls := 0
+	plugin := When(Method("POST"), NewRequestPlugin(func(c *context.Context, h context.Handler) {
+		calls++
+		h.Next(c)
+	}))
+
+	next := 0
+	run := func(ctx *context.Context) {
+		for _, phase := range []string{"request", "before dial", "response"} {
+			ctx.Set("$phase", phase)
+			plugin.Exec(phase, ctx, context.NewHandler(func(c *context.Context) { next++ }))
+		}
+	}
+
+	ctx := context.New()
+	ctx.Request.Method = "GET"
+	run(ctx)
+	if calls != 0 || next != 3 {
+		t.Errorf("Invalid calls: %d, %d", calls, next)
+	}
+
+	// The request phase is deferred until the request is complete
+	ctx = context.New()
+	ctx.Request.Method = "GET"
+	plugin.Exec("request", ctx, context.NewHandler(func(c *context.Context) { next++ }))
+	if calls != 0 {
+		t.Errorf("Invalid plugin calls: %d", calls)
+	}
+	ctx.Request.Method = "POST"
+	ctx.Set("$phase", "before dial")
+	plugin.Exec("before dial", ctx, context.NewHandler(func(c *context.Context) { next++ }))
+	if calls != 1 || next != 5 {
+		t.Errorf("Invalid calls: %d, %d", calls, next)
+	}
+	if ctx.GetString("$phase") != "before dial" {
+		t.Errorf("Invalid phase: %s", ctx.GetString("$phase"))
+	}
+}
+
+func TestWhenResponse(t *testing.T) {
+	calls := 0
+	plugin := When(Method("POST"), NewResponsePlugin(func(c *context.Context, h context.Handler) {
+		calls++
+		h.Next(c)
+	}))
+
+	ctx := context.New()
+	ctx.Request.Method = "POST"
+	ctx.Set("$phase", "response")
+	plugin.Exec("response", ctx, context.NewHandler(func(c *context.Context) {}))
+	if calls != 1 {
+		t.Errorf("Invalid plugin calls: %d", calls)
+	}
+}
+
+func TestWhenName(t *testing.T) {
+	plugin := When(Method("GET"), WithName("foo", New()))
+	if NameOf(plugin) != "foo" {
+		t.Errorf("Invalid plugin name: %s", NameOf(plugin))
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	ctx := context.New()
+	ctx.Request.Method = "POST"
+	ctx.Request.URL.Host = "foo.com:8080"
+	ctx.Request.URL.Path = "/admin/users"
+
+	cases := []struct {
+		predicate Predicate
+		matches   bool
+	}{
+		{Method("GET", "post"), true},
+		{Method("GET"), false},
+		{Host("foo.com"), true},
+		{Host("FOO.com:8080"), true},
+		{Host("bar.com", "foo.com:9090"), false},
+		{PathPrefix("/admin/"), true},
+		{PathPrefix("/api/"), false},
+	}
+	for _, test := range cases {
+		if test.predicate(ctx) != test.matches {
+			t.Errorf("Invalid predicate result: %#v", test)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// PrincipalKey is the context store key used to define the principal
+// performing the requests, overriding the logger default.
+const PrincipalKey = "$auditPrincipal"
+
+// DefaultMaxBodySize stores the default maximum number of body bytes recorded
+// when the body recording is enabled.
+const DefaultMaxBodySize = 64 * 1024
+
+// entryKey is the context store key used to keep the pending audit entry.
+type entryKey struct{}
+
+// Record represents the audit record of an outgoing request.
+type Record struct {
+	// Sequence stores the record sequence number, starting at 1.
+	// Records are written in strictly increasing sequence order.
+	Sequence uint64 `json:"sequence"`
+
+	// Principal stores who performed the request, if known.
+	Principal string `json:"principal,omitempty"`
+
+	// Method stores the request method.
+	Method string `json:"method"`
+
+	// URL stores the request URL, without the user credentials.
+	URL string `json:"url"`
+
+	// Host stores the target host.
+	Host string `json:"host"`
+
+	// Headers stores the recorded request headers, if any.
+	Headers http.Header `json:"headers,omitempty"`
+
+	// Start stores when the request was sent.
+	Start time.Time `json:"start"`
+
+	// Duration stores the time elapsed until the response body was consumed or the request failed.
+	Duration time.Duration `json:"duration"`
+
+	// Status stores the response status code, or zero if no response was received.
+	Status int `json:"status,omitempty"`
+
+	// RequestBytes stores the request body size.
+	RequestBytes int64 `json:"requestBytes"`
+
+	// ResponseBytes stores the number of response body bytes read.
+	ResponseBytes int64 `json:"responseBytes"`
+
+	// Error stores the request error message, if any.
+	Error string `json:"error,omitempty"`
+
+	// RequestBody stores the request body, only if body recording is enabled.
+	RequestBody []byte `json:"requestBody,omitempty"`
+
+	// ResponseBody stores the response body, only if body recording is enabled.
+	ResponseBody []byte `json:"responseBody,omitempty"`
+}
+
+// Sink represents the destination of the audit records.
+type Sink interface {
+	// Write writes the given record. Write is never called concurrently.
+	Write(Record) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(Record) error
+
+// Write calls the sink function.
+func (fn SinkFunc) Write(record Record) error {
+	return fn(record)
+}
+
+// JSONSink creates a new sink writing the records to the given writer as JSON lines.
+func JSONSink(w io.Writer) Sink {
+	encoder := json.NewEncoder(w)
+	return SinkFunc(func(record Record) error {
+		return encoder.Encode(record)
+	})
+}
+
+// Options represents the audit logger options.
+type Options struct {
+	// Principal defines the default principal performing the requests.
+	// It can be defined per client or request via the PrincipalKey context key.
+	Principal string
+
+	// Headers defines the request headers recorded, if any.
+	Headers []string
+
+	// Bodies enables recording the request and response bodies, up to MaxBodySize bytes.
+	Bodies bool
+
+	// MaxBodySize defines the maximum number of body bytes recorded.
+	// Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+
+	// OnError is called with the sink write errors, if defined.
+	OnError func(error)
+}
+
+// Logger records the outgoing requests observed via Log() in a sink.
+// Logger is safe for concurrent use.
+type Logger struct {
+	// mtx serializes the sink writes.
+	mtx sync.Mutex
+
+	// sequence stores the last written record sequence number.
+	sequence uint64
+
+	// sink stores the records destination.
+	sink Sink
+
+	// opts stores the logger options.
+	opts Options
+}
+
+// NewLogger creates a new audit logger writing to the given sink.
+func NewLogger(sink Sink, opts Options) *Logger {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	return &Logger{sink: sink, opts: opts}
+}
+
+// write writes the given record to the sink, assigning its sequence number.
+func (l *Logger) write(record Record) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.sequence++
+	record.Sequence = l.sequence
+	if err := l.sink.Write(record); err != nil && l.opts.OnError != nil {
+		l.opts.OnError(err)
+	}
+}
+
+// Log creates a new plugin which records the metadata of every outgoing request in the given logger:
+// who performed it, the method, URL and host, when and how long it took, the response status
+// and the body byte counts. Bodies are never recorded unless explicitly enabled.
+//
+// Records are written once the response body is fully read or closed, or once the request fails,
+// one at a time and with increasing sequence numbers, so the sink observes a total order.
+func Log(l *Logger) p.Plugin {
+	handlers := p.Handlers{
+		// Uses the "before dial" phase in order to record the final request,
+		// once the request phase had the chance to define it.
+		"before dial": func(ctx *c.Context, h c.Handler) {
+			e, err := l.newEntry(ctx)
+			if err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			ctx.Set(entryKey{}, e)
+			h.Next(ctx)
+		},
+		"response": func(ctx *c.Context, h c.Handler) {
+			e, ok := ctx.Get(entryKey{}).(*entry)
+			if !ok {
+				h.Next(ctx)
+				return
+			}
+			res := ctx.Response
+			e.record.Status = res.StatusCode
+			if res.Body == nil || res.Body == http.NoBody {
+				e.done(nil)
+			} else {
+				res.Body = &counter{rc: res.Body, entry: e}
+			}
+			h.Next(ctx)
+		},
+		"error": func(ctx *c.Context, h c.Handler) {
+			e, ok := ctx.Get(entryKey{}).(*entry)
+			if !ok {
+				var err error
+				if e, err = l.newEntry(ctx); err != nil {
+					h.Next(ctx)
+					return
+				}
+			}
+			e.done(ctx.Error)
+			h.Next(ctx)
+		},
+	}
+	return &p.Layer{Handlers: handlers}
+}
+
+// newEntry creates the pending audit entry of the context request.
+func (l *Logger) newEntry(ctx *c.Context) (*entry, error) {
+	req := ctx.Request
+	u := *req.URL
+	u.User = nil
+
+	principal := l.opts.Principal
+	if value := ctx.GetString(PrincipalKey); value != "" {
+		principal = value
+	}
+
+	record := Record{
+		Principal:    principal,
+		Method:       req.Method,
+		URL:          u.String(),
+		Host:         req.URL.Host,
+		Start:        time.Now(),
+		RequestBytes: req.ContentLength,
+	}
+	if req.Host != "" {
+		record.Host = req.Host
+	}
+	for _, name := range l.opts.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if values := req.Header[name]; len(values) > 0 {
+			if record.Headers == nil {
+				record.Headers = http.Header{}
+			}
+			record.Headers[name] = append([]string(nil), values...)
+		}
+	}
+
+	if l.opts.Bodies && req.Body != nil && req.Body != http.NoBody {
+		data, err := utils.ReplayRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		record.RequestBytes = int64(len(data))
+		record.RequestBody = truncate(data, l.opts.MaxBodySize)
+	}
+	if record.RequestBytes < 0 {
+		record.RequestBytes = 0
+	}
+
+	return &entry{logger: l, record: record}, nil
+}
+
+// entry represents a pending audit record, written only once.
+type entry struct {
+	once   sync.Once
+	logger *Logger
+	record Record
+	body   bytes.Buffer
+}
+
+// done completes the record with the given error, if any, and writes it.
+func (e *entry) done(err error) {
+	e.once.Do(func() {
+		e.record.Duration = time.Since(e.record.Start)
+		if err != nil {
+			e.record.Error = err.Error()
+		}
+		if e.logger.opts.Bodies && e.body.Len() > 0 {
+			e.record.ResponseBody = e.body.Bytes()
+		}
+		e.logger.write(e.record)
+	})
+}
+
+// counter wraps the response body stream counting the bytes read.
+type counter struct {
+	rc    io.ReadCloser
+	entry *entry
+	mtx   sync.Mutex
+}
+
+// Read reads from the underlying body stream.
+func (b *counter) Read(buf []byte) (int, error) {
+	n, err := b.rc.Read(buf)
+	b.mtx.Lock()
+	e := b.entry
+	e.record.ResponseBytes += int64(n)
+	if e.logger.opts.Bodies {
+		if room := e.logger.opts.MaxBodySize - int64(e.body.Len()); room > 0 {
+			e.body.Write(truncate(buf[:n], room))
+		}
+	}
+	b.mtx.Unlock()
+	if err == io.EOF {
+		e.done(nil)
+	} else if err != nil {
+		e.done(err)
+	}
+	return n, err
+}
+
+// Close writes the pending record and closes the underlying body stream.
+func (b *counter) Close() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.entry.done(nil)
+	return b.rc.Close()
+}
+
+// truncate returns at most the given number of bytes of data.
+func truncate(data []byte, max int64) []byte {
+	if int64(len(data)) > max {
+		return data[:max]
+	}
+	return data
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// DefaultRecorderSize stores the default number of records retained by a flight recorder.
+const DefaultRecorderSize = 100
+
+// RecorderOptions represents the flight recorder options.
+type RecorderOptions struct {
+	// Size defines the number of most recent records retained.
+	// Defaults to DefaultRecorderSize.
+	Size int
+
+	// DumpOnError defines an optional writer where the retained records are dumped
+	// every time a failed request, or a server error response, is recorded.
+	DumpOnError io.Writer
+}
+
+// Recorder implements a flight recorder sink, which retains the records of the
+// most recent requests in a ring buffer, so they can be dumped on demand or on error
+// to debug production incidents without always-on verbose logging.
+// Recorder is safe for concurrent use.
+type Recorder struct {
+	// mtx protects the ring buffer.
+	mtx sync.Mutex
+
+	// records stores the ring buffer records.
+	records []Record
+
+	// next stores the ring buffer position of the next record.
+	next int
+
+	// full stores if the ring buffer wrapped around.
+	full bool
+
+	// opts stores the recorder options.
+	opts RecorderOptions
+}
+
+// NewRecorder creates a new flight recorder with the given options.
+func NewRecorder(opts RecorderOptions) *Recorder {
+	if opts.Size <= 0 {
+		opts.Size = DefaultRecorderSize
+	}
+	return &Recorder{records: make([]Record, opts.Size), opts: opts}
+}
+
+// Write retains the given record, evicting the oldest one if the recorder is full.
+func (r *Recorder) Write(record Record) error {
+	r.mtx.Lock()
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mtx.Unlock()
+
+	if r.opts.DumpOnError != nil && (record.Error != "" || record.Status >= 500) {
+		return r.Dump(r.opts.DumpOnError)
+	}
+	return nil
+}
+
+// Records returns the retained records, from the oldest to the most recent one.
+func (r *Recorder) Records() []Record {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if !r.full {
+		return append([]Record(nil), r.records[:r.next]...)
+	}
+	return append(append([]Record(nil), r.records[r.next:]...), r.records[:r.next]...)
+}
+
+// Dump writes the retained records to the given writer as JSON lines,
+// from the oldest to the most recent one.
+func (r *Recorder) Dump(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range r.Records() {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset discards the retained records.
+func (r *Recorder) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records = make([]Record, len(r.records))
+	r.next, r.full = 0, false
+}
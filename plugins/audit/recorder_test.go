@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(RecorderOptions{Size: 3})
+	for i := 1; i <= 5; i++ {
+		st.Expect(t, recorder.Write(Record{Sequence: uint64(i)}), nil)
+	}
+
+	records := recorder.Records()
+	st.Expect(t, len(records), 3)
+	st.Expect(t, records[0].Sequence, uint64(3))
+	st.Expect(t, records[2].Sequence, uint64(5))
+
+	buf := &bytes.Buffer{}
+	st.Expect(t, recorder.Dump(buf), nil)
+	st.Expect(t, strings.Count(buf.String(), "\n"), 3)
+
+	recorder.Reset()
+	st.Expect(t, len(recorder.Records()), 0)
+}
+
+func TestRecorderDumpOnError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(503)
+		}
+		w.Write([]byte("body"))
+	}))
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	recorder := NewRecorder(RecorderOptions{Size: 10, DumpOnError: buf})
+	cli := gentleman.New().URL(ts.URL).Use(Log(NewLogger(recorder, Options{Bodies: true})))
+
+	res, err := cli.Request().Path("/ok").Send()
+	st.Expect(t, err, nil)
+	res.Close()
+	st.Expect(t, buf.Len(), 0)
+
+	res, err = cli.Request().Path("/fail").Send()
+	st.Expect(t, err, nil)
+	res.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	st.Expect(t, len(lines), 2)
+	st.Expect(t, strings.Contains(lines[0], ts.URL+"/ok"), true)
+	st.Expect(t, strings.Contains(lines[1], `"status":503`), true)
+	st.Expect(t, len(recorder.Records()), 2)
+}
@@ -1,9 +1,19 @@
 package auth
 
 import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
-	"testing"
 )
 
 func TestAuthBasic(t *testing.T) {
@@ -30,6 +40,149 @@ func TestAuthCustom(t *testing.T) {
 	st.Expect(t, ctx.Request.Header.Get("Authorization"), "Token foo")
 }
 
+func TestTokenManager(t *testing.T) {
+	var calls int32
+	source := TokenSourceFunc(func() (*Token, error) {
+		n := atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &Token{AccessToken: "token" + strconv.Itoa(int(n)), Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	m := NewTokenManager(source)
+
+	// Concurrent requests share the same refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.New()
+			Tokens(m).Exec("request", ctx, newHandler().fn)
+			st.Expect(t, ctx.Request.Header.Get("Authorization"), "Bearer token1")
+		}()
+	}
+	wg.Wait()
+	st.Expect(t, atomic.LoadInt32(&calls), int32(1))
+
+	// Tokens about to expire are refreshed
+	m.mtx.Lock()
+	m.issued, m.expiry = time.Now().Add(-time.Hour), time.Now().Add(10*time.Second)
+	m.mtx.Unlock()
+	token, err := m.Token()
+	st.Expect(t, err, nil)
+	st.Expect(t, token.AccessToken, "token2")
+
+	// Unauthorized responses invalidate the token
+	ctx := context.New()
+	ctx.Response.StatusCode = http.StatusUnauthorized
+	Tokens(m).Exec("response", ctx, newHandler().fn)
+	token, err = m.Token()
+	st.Expect(t, err, nil)
+	st.Expect(t, token.AccessToken, "token3")
+}
+
+func TestTokenManagerJWT(t *testing.T) {
+	exp := time.Now().Add(10 * time.Second).Unix()
+	jwt := func(exp int64) string {
+		claims := `{"sub":"foo","exp":` + strconv.FormatInt(exp, 10) + `}`
+		return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+	}
+	expiry, ok := JWTExpiry(jwt(exp))
+	st.Expect(t, ok, true)
+	st.Expect(t, expiry.Unix(), exp)
+	_, ok = JWTExpiry("opaque")
+	st.Expect(t, ok, false)
+
+	var calls int
+	m := NewTokenManager(TokenSourceFunc(func() (*Token, error) {
+		calls++
+		return &Token{AccessToken: jwt(exp), Type: "JWT"}, nil
+	}))
+	ctx := context.New()
+	Tokens(m).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.Header.Get("Authorization"), "JWT "+jwt(exp))
+
+	// Short-lived tokens are reused during the first half of their lifetime,
+	// although they expire within the default refresh window
+	m.Token()
+	st.Expect(t, calls, 1)
+	m.RefreshBefore = time.Second
+	m.Token()
+	st.Expect(t, calls, 1)
+
+	// Otherwise, they are refreshed
+	m.mtx.Lock()
+	m.issued = time.Now().Add(-time.Minute)
+	m.mtx.Unlock()
+	m.RefreshBefore = DefaultRefreshBefore
+	m.Token()
+	st.Expect(t, calls, 2)
+}
+
+func TestTokenManagerError(t *testing.T) {
+	fail := errors.New("token endpoint failure")
+	m := NewTokenManager(TokenSourceFunc(func() (*Token, error) {
+		return nil, fail
+	}))
+	ctx := context.New()
+	fn := newHandler()
+	Tokens(m).Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Error, fail)
+	st.Expect(t, ctx.Request.Header.Get("Authorization"), "")
+
+	m = NewTokenManager(TokenSourceFunc(func() (*Token, error) {
+		return nil, nil
+	}))
+	_, err := m.Token()
+	st.Expect(t, err, ErrMissingToken)
+}
+
+func TestCredentialsProviders(t *testing.T) {
+	creds := Credentials{Username: "foo", Password: "bar"}
+	ctx := context.New()
+	BasicFrom(StaticCredentials(creds)).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.Header.Get("Authorization"), "Basic Zm9vOmJhcg==")
+
+	os.Setenv("GENTLEMAN_TEST_TOKEN", "foo")
+	defer os.Unsetenv("GENTLEMAN_TEST_TOKEN")
+	env := EnvCredentials{TokenVar: "GENTLEMAN_TEST_TOKEN"}
+	ctx = context.New()
+	BearerFrom(env).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.Header.Get("Authorization"), "Bearer foo")
+
+	// The environment is read on every request
+	os.Setenv("GENTLEMAN_TEST_TOKEN", "bar")
+	ctx = context.New()
+	HeaderFrom("X-API-Key", env).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.Header.Get("X-API-Key"), "bar")
+
+	ctx = context.New()
+	BasicFrom(env).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Error, ErrMissingCredentials)
+}
+
+func TestFileCredentials(t *testing.T) {
+	file, err := ioutil.TempFile("", "gentleman-credentials")
+	st.Expect(t, err, nil)
+	defer os.Remove(file.Name())
+	file.WriteString("s3cr3t\n")
+	file.Close()
+
+	provider := FileCredentials(file.Name())
+	creds, err := provider.Credentials()
+	st.Expect(t, err, nil)
+	st.Expect(t, creds.Token, "s3cr3t")
+
+	// The rotated credentials are read again
+	st.Expect(t, ioutil.WriteFile(file.Name(), []byte(`{"username": "foo", "password": "bar"}`), 0600), nil)
+	os.Chtimes(file.Name(), time.Now(), time.Now().Add(time.Minute))
+	ctx := context.New()
+	BasicFrom(provider).Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.Header.Get("Authorization"), "Basic Zm9vOmJhcg==")
+
+	_, err = FileCredentials(file.Name() + ".missing").Credentials()
+	st.Reject(t, err, nil)
+}
+
 type handler struct {
 	fn     context.Handler
 	called bool
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// ErrMissingCredentials is returned when the credentials provider returns no credentials
+// usable by the plugin.
+var ErrMissingCredentials = errors.New("auth: missing credentials")
+
+// Credentials represents the authorization credentials used in the outgoing requests.
+type Credentials struct {
+	// Username and Password define the basic authorization credentials.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Token defines the bearer token or API key.
+	Token string `json:"token,omitempty"`
+
+	// KeyID and Signer define the key used by the request signing plugins.
+	KeyID  string        `json:"keyId,omitempty"`
+	Signer crypto.Signer `json:"-"`
+}
+
+// CredentialsProvider represents a source of credentials, which is queried
+// on every request, so the credentials can be rotated at runtime.
+// Implementations must be safe for concurrent use.
+type CredentialsProvider interface {
+	// Credentials returns the current credentials.
+	Credentials() (Credentials, error)
+}
+
+// CredentialsFunc represents a function implementing CredentialsProvider.
+type CredentialsFunc func() (Credentials, error)
+
+// Credentials returns the current credentials.
+func (fn CredentialsFunc) Credentials() (Credentials, error) {
+	return fn()
+}
+
+// StaticCredentials returns a provider of the given fixed credentials.
+func StaticCredentials(creds Credentials) CredentialsProvider {
+	return CredentialsFunc(func() (Credentials, error) {
+		return creds, nil
+	})
+}
+
+// EnvCredentials provides the credentials defined by environment variables,
+// read on every request. Empty variable names are ignored.
+type EnvCredentials struct {
+	// UsernameVar and PasswordVar define the basic authorization credentials variable names.
+	UsernameVar string
+	PasswordVar string
+
+	// TokenVar defines the token variable name.
+	TokenVar string
+}
+
+// Credentials returns the credentials defined by the environment variables.
+func (e EnvCredentials) Credentials() (Credentials, error) {
+	env := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return os.Getenv(name)
+	}
+	return Credentials{Username: env(e.UsernameVar), Password: env(e.PasswordVar), Token: env(e.TokenVar)}, nil
+}
+
+// FileCredentials returns a provider of the credentials stored in the given file,
+// such as a mounted secret, which is read again once modified. The file stores either
+// the JSON encoded credentials, such as {"username": "foo", "password": "bar"}, or a raw token.
+func FileCredentials(path string) CredentialsProvider {
+	return &fileCredentials{path: path}
+}
+
+// fileCredentials provides the credentials stored in a file.
+type fileCredentials struct {
+	path    string
+	mtx     sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   Credentials
+}
+
+// Credentials returns the credentials stored in the file, read again if modified.
+func (f *fileCredentials) Credentials() (Credentials, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.creds, nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	content := strings.TrimSpace(string(data))
+
+	var creds Credentials
+	if strings.HasPrefix(content, "{") {
+		if err := json.Unmarshal([]byte(content), &creds); err != nil {
+			return Credentials{}, err
+		}
+	} else {
+		creds.Token = content
+	}
+
+	f.creds, f.modTime, f.size = creds, info.ModTime(), info.Size()
+	return creds, nil
+}
+
+// BasicFrom defines an authorization basic header in the outgoing request
+// based on the credentials returned by the given provider.
+func BasicFrom(provider CredentialsProvider) p.Plugin {
+	return fromProvider(provider, func(ctx *c.Context, creds Credentials) error {
+		if creds.Username == "" {
+			return ErrMissingCredentials
+		}
+		ctx.Request.SetBasicAuth(creds.Username, creds.Password)
+		return nil
+	})
+}
+
+// BearerFrom defines an authorization bearer token header in the outgoing request
+// based on the token returned by the given provider.
+func BearerFrom(provider CredentialsProvider) p.Plugin {
+	return fromProvider(provider, func(ctx *c.Context, creds Credentials) error {
+		if creds.Token == "" {
+			return ErrMissingCredentials
+		}
+		ctx.Request.Header.Set("Authorization", "Bearer "+creds.Token)
+		return nil
+	})
+}
+
+// HeaderFrom defines the given header field, such as X-API-Key, in the outgoing request
+// based on the token returned by the given provider.
+func HeaderFrom(name string, provider CredentialsProvider) p.Plugin {
+	return fromProvider(provider, func(ctx *c.Context, creds Credentials) error {
+		if creds.Token == "" {
+			return ErrMissingCredentials
+		}
+		ctx.Request.Header.Set(name, creds.Token)
+		return nil
+	})
+}
+
+// fromProvider creates the authorization plugin applying the current provider credentials.
+func fromProvider(provider CredentialsProvider, apply func(*c.Context, Credentials) error) p.Plugin {
+	return p.WithName(Name, p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		creds, err := provider.Credentials()
+		if err == nil {
+			err = apply(ctx, creds)
+		}
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	}))
+}
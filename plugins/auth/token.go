@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// DefaultRefreshBefore defines the default amount of time before the token
+// expiration in which the token is proactively refreshed.
+const DefaultRefreshBefore = 30 * time.Second
+
+// ErrMissingToken is returned when the token source returns no token.
+var ErrMissingToken = errors.New("auth: missing token")
+
+// Token represents an access token.
+type Token struct {
+	// AccessToken stores the token sent in the Authorization header.
+	AccessToken string
+
+	// Type defines the Authorization header scheme. Defaults to "Bearer".
+	Type string
+
+	// Expiry defines the token expiration time.
+	// Defaults to the exp claim of JWT access tokens, if any.
+	// Tokens with no expiration are never refreshed, unless invalidated.
+	Expiry time.Time
+}
+
+// TokenSource represents a source of access tokens, such as an OAuth2 token endpoint.
+type TokenSource interface {
+	// Token returns a new access token.
+	Token() (*Token, error)
+}
+
+// TokenSourceFunc represents a function implementing TokenSource.
+type TokenSourceFunc func() (*Token, error)
+
+// Token returns a new access token.
+func (fn TokenSourceFunc) Token() (*Token, error) {
+	return fn()
+}
+
+// TokenManager caches the access tokens returned by a token source,
+// refreshing them before they expire. The refreshes are serialized,
+// so concurrent requests wait for the same new token.
+// TokenManager is safe for concurrent use.
+type TokenManager struct {
+	// RefreshBefore defines the amount of time before the token expiration
+	// in which the token is refreshed. Defaults to DefaultRefreshBefore.
+	// It is capped at half the token lifetime, so short-lived tokens are reused.
+	RefreshBefore time.Duration
+
+	// source stores the token source.
+	source TokenSource
+
+	// mtx protects the cached token and serializes the refreshes.
+	mtx sync.Mutex
+
+	// token stores the cached token.
+	token *Token
+
+	// expiry stores the cached token expiration time.
+	expiry time.Time
+
+	// issued stores the time the cached token was obtained.
+	issued time.Time
+}
+
+// NewTokenManager creates a new token manager based on the given token source.
+func NewTokenManager(source TokenSource) *TokenManager {
+	return &TokenManager{source: source, RefreshBefore: DefaultRefreshBefore}
+}
+
+// Token returns the cached token, refreshing it via the token source
+// if missing or about to expire.
+func (m *TokenManager) Token() (*Token, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.token != nil && m.fresh(time.Now()) {
+		return m.token, nil
+	}
+
+	token, err := m.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrMissingToken
+	}
+	m.token, m.expiry, m.issued = token, token.Expiry, time.Now()
+	if m.expiry.IsZero() {
+		m.expiry, _ = JWTExpiry(token.AccessToken)
+	}
+	return token, nil
+}
+
+// fresh returns true if the cached token is not within the refresh window at the given time.
+// The caller must hold the lock.
+func (m *TokenManager) fresh(now time.Time) bool {
+	if m.expiry.IsZero() {
+		return true
+	}
+	window := m.RefreshBefore
+	if lifetime := m.expiry.Sub(m.issued); window > lifetime/2 {
+		window = lifetime / 2
+	}
+	return now.Add(window).Before(m.expiry)
+}
+
+// Invalidate discards the cached token, which is refreshed on the next request.
+func (m *TokenManager) Invalidate() {
+	m.mtx.Lock()
+	m.token = nil
+	m.mtx.Unlock()
+}
+
+// Tokens creates a new plugin which defines the Authorization header of the outgoing
+// requests based on the given token manager. The cached token is invalidated
+// on 401 Unauthorized responses, so the next request uses a new one.
+func Tokens(m *TokenManager) p.Plugin {
+	handlers := p.Handlers{
+		"request": func(ctx *c.Context, h c.Handler) {
+			token, err := m.Token()
+			if err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			scheme := token.Type
+			if scheme == "" {
+				scheme = "Bearer"
+			}
+			ctx.Request.Header.Set("Authorization", scheme+" "+token.AccessToken)
+			h.Next(ctx)
+		},
+		"response": func(ctx *c.Context, h c.Handler) {
+			if ctx.Response.StatusCode == http.StatusUnauthorized {
+				m.Invalidate()
+			}
+			h.Next(ctx)
+		},
+	}
+	return p.WithName(Name, &p.Layer{Handlers: handlers})
+}
+
+// JWTExpiry returns the expiration time defined by the exp claim of the given JWT,
+// with no signature verification. Returns false if the token is not a JWT
+// or has no expiration.
+func JWTExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(exp), 0), true
+}
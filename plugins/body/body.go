@@ -7,12 +7,18 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"sync"
 
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
+// BufferKey is the context store key used to buffer the stream bodies defined via Reader(),
+// so they can be read again once the request is executed more than once, such as when
+// the request is built before being sent.
+const BufferKey = "$body.buffer"
+
 // String defines the HTTP request body based on the given string.
 func String(data string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
@@ -27,7 +33,7 @@ func String(data string) p.Plugin {
 // Supports strings, array of bytes or buffer.
 func JSON(data interface{}) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		buf := &bytes.Buffer{}
+		buf := utils.DefaultBufferPool.Get()
 
 		switch data.(type) {
 		case string:
@@ -36,13 +42,14 @@ func JSON(data interface{}) p.Plugin {
 			buf.Write(data.([]byte))
 		default:
 			if err := json.NewEncoder(buf).Encode(data); err != nil {
+				utils.DefaultBufferPool.Put(buf)
 				h.Error(ctx, err)
 				return
 			}
 		}
 
 		ctx.Request.Method = getMethod(ctx)
-		ctx.Request.Body = ioutil.NopCloser(buf)
+		ctx.Request.Body = utils.DefaultBufferPool.ReadCloser(buf)
 		ctx.Request.ContentLength = int64(buf.Len())
 		ctx.Request.Header.Set("Content-Type", "application/json")
 
@@ -54,7 +61,7 @@ func JSON(data interface{}) p.Plugin {
 // Supports strings, array of bytes or buffer.
 func XML(data interface{}) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		buf := &bytes.Buffer{}
+		buf := utils.DefaultBufferPool.Get()
 
 		switch data.(type) {
 		case string:
@@ -63,13 +70,14 @@ func XML(data interface{}) p.Plugin {
 			buf.Write(data.([]byte))
 		default:
 			if err := xml.NewEncoder(buf).Encode(data); err != nil {
+				utils.DefaultBufferPool.Put(buf)
 				h.Error(ctx, err)
 				return
 			}
 		}
 
 		ctx.Request.Method = getMethod(ctx)
-		ctx.Request.Body = ioutil.NopCloser(buf)
+		ctx.Request.Body = utils.DefaultBufferPool.ReadCloser(buf)
 		ctx.Request.ContentLength = int64(buf.Len())
 		ctx.Request.Header.Set("Content-Type", "application/xml")
 
@@ -79,8 +87,32 @@ func XML(data interface{}) p.Plugin {
 
 // Reader defines a io.Reader stream as request body.
 // Content-Type header won't be defined automatically, you have to declare it manually.
+// The stream is read into memory if BufferKey is enabled in the context, and the
+// buffered body is then used by every later request execution.
 func Reader(body io.Reader) p.Plugin {
+	var mtx sync.Mutex
+	var buffered []byte
+
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		mtx.Lock()
+		if buffer, _ := ctx.Get(BufferKey).(bool); buffer && buffered == nil && body != nil {
+			data, err := ioutil.ReadAll(body)
+			if rc, ok := body.(io.Closer); ok {
+				rc.Close()
+			}
+			if err != nil {
+				mtx.Unlock()
+				h.Error(ctx, err)
+				return
+			}
+			buffered = data
+		}
+		body := body
+		if buffered != nil {
+			body = bytes.NewReader(buffered)
+		}
+		mtx.Unlock()
+
 		rc, ok := body.(io.ReadCloser)
 		if !ok && body != nil {
 			rc = ioutil.NopCloser(body)
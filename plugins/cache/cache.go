@@ -0,0 +1,531 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// DefaultStaleTTL defines the default amount of time stale responses with validators
+// are kept after expiring, so they can be revalidated instead of downloaded again.
+var DefaultStaleTTL = time.Hour
+
+// MaxSize defines the maximum response body size to be cached.
+var MaxSize int64 = 10 * 1024 * 1024
+
+const (
+	// hitKey is the context store key used to flag the responses served from the cache.
+	hitKey = "$cache.hit"
+
+	// staleKey is the context store key used to keep the stale entry being revalidated.
+	staleKey = "$cache.stale"
+)
+
+// Options represents the HTTP cache options.
+type Options struct {
+	// Store defines the cache storage backend.
+	// Defaults to an in-memory LRU store with DefaultCapacity.
+	Store Store
+
+	// StaleTTL defines the amount of time stale responses with validators are kept
+	// for revalidation. Defaults to DefaultStaleTTL.
+	StaleTTL time.Duration
+
+	// RefreshBefore enables the background refresh of the cached responses: fresh responses
+	// served within the given amount of time before expiring, and the ones stored via Warm(),
+	// are fetched again in background, so hot resources never incur a cold miss.
+	RefreshBefore time.Duration
+
+	// Client is an optional HTTP client used to warm and refresh the cached responses.
+	// Defaults to the outgoing request http.Client, or http.DefaultClient for Warm().
+	Client *http.Client
+
+	// OnError is called with the store errors, if defined.
+	// Store errors never fail the requests: failed lookups are handled as cache misses.
+	OnError func(error)
+}
+
+// Cache implements a plugin that caches the GET and HEAD responses according to their
+// Cache-Control and Expires headers, revalidating stale responses via ETag and Last-Modified.
+//
+// Content negotiated responses are stored per variant, matching the request headers
+// listed by the response Vary header. Responses to requests with credentials are only
+// stored if explicitly allowed via the public, s-maxage or must-revalidate directives,
+// or if they vary by Authorization, in which case the credentials are never stored as is.
+type Cache struct {
+	// Cache implements the plugin interface.
+	*p.Layer
+
+	// opts stores the cache options.
+	opts Options
+
+	// mtx protects the background refresh state.
+	mtx sync.Mutex
+
+	// refreshing stores the keys of the responses being refreshed in background.
+	refreshing map[string]bool
+
+	// timers stores the refresh timers of the warmed URLs.
+	timers map[string]*time.Timer
+
+	// closed stores if the background refreshes were stopped.
+	closed bool
+}
+
+// entry represents a cached response, or the list of varying request headers of
+// the cached response variants.
+type entry struct {
+	Vary    []string    `json:"vary,omitempty"`
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Stored  time.Time   `json:"stored"`
+	Expires time.Time   `json:"expires"`
+}
+
+// New creates a new HTTP cache plugin.
+func New(opts Options) *Cache {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore(DefaultCapacity)
+	}
+	if opts.StaleTTL == 0 {
+		opts.StaleTTL = DefaultStaleTTL
+	}
+	cache := &Cache{Layer: p.New(), opts: opts, refreshing: make(map[string]bool), timers: make(map[string]*time.Timer)}
+	// Uses the "before dial" phase in order to use the final request URL and headers.
+	cache.SetHandler("before dial", cache.lookup)
+	cache.SetHandler("response", cache.store)
+	return cache
+}
+
+// Hit returns true if the response was served from the cache,
+// either because it was fresh or successfully revalidated.
+func Hit(ctx *c.Context) bool {
+	v, _ := ctx.Get(hitKey).(bool)
+	return v
+}
+
+// Delete deletes the cached responses of the given URL.
+func (cache *Cache) Delete(uri string) error {
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		if err := cache.opts.Store.Delete(method + " " + uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup serves the fresh cached responses, preparing the stale ones for revalidation.
+func (cache *Cache) lookup(ctx *c.Context, h c.Handler) {
+	req := ctx.Request
+	if !cacheable(req) {
+		h.Next(ctx)
+		return
+	}
+
+	e := cache.lookupVariant(req)
+	if e == nil {
+		h.Next(ctx)
+		return
+	}
+
+	directives := parseCacheControl(req.Header.Get("Cache-Control"))
+	_, noCache := directives["no-cache"]
+	if maxAge, ok := directives["max-age"]; ok && maxAge == "0" {
+		noCache = true
+	}
+	if !noCache && time.Now().Before(e.Expires) {
+		ctx.Set(hitKey, true)
+		ctx.Intercept(e.response(req))
+		if cache.opts.RefreshBefore > 0 && time.Until(e.Expires) < cache.opts.RefreshBefore {
+			cache.refreshAsync(req, e, ctx.Client)
+		}
+		h.Next(ctx)
+		return
+	}
+
+	if revalidate(req, e) {
+		ctx.Set(staleKey, e)
+	}
+	h.Next(ctx)
+}
+
+// revalidate defines the conditional headers of the given request based on the validators
+// of the given stale entry, returning false if the entry cannot be revalidated.
+// Conditional requests defined by the user are sent as is.
+func revalidate(req *http.Request, e *entry) bool {
+	etag, modified := e.Header.Get("ETag"), e.Header.Get("Last-Modified")
+	if etag == "" && modified == "" || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return false
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+	return true
+}
+
+// store caches the cacheable responses and handles the revalidated ones.
+func (cache *Cache) store(ctx *c.Context, h c.Handler) {
+	req, res := ctx.Request, ctx.Response
+	if Hit(ctx) {
+		h.Next(ctx)
+		return
+	}
+
+	// Invalidate the cached responses of the URL modified by unsafe methods
+	if !cacheable(req) {
+		if !safe(req.Method) && res.StatusCode < 400 {
+			cache.report(cache.Delete(req.URL.String()))
+		}
+		h.Next(ctx)
+		return
+	}
+
+	stale, _ := ctx.Get(staleKey).(*entry)
+	res, _, err := cache.update(req, res, stale)
+	if err != nil {
+		h.Error(ctx, err)
+		return
+	}
+	if res != ctx.Response {
+		ctx.Set(hitKey, true)
+		ctx.Response = res
+	}
+	h.Next(ctx)
+}
+
+// update stores the given response of the given request, if possible, returning
+// the final response: the stale entry response if successfully revalidated.
+// The returned entry is nil if the response was not stored.
+func (cache *Cache) update(req *http.Request, res *http.Response, stale *entry) (*http.Response, *entry, error) {
+	if stale != nil && res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		for name, values := range res.Header {
+			if name == "Content-Length" {
+				continue
+			}
+			stale.Header[name] = values
+		}
+		freshness, ok := lifetime(stale.Header)
+		if !ok {
+			return stale.response(req), nil, nil
+		}
+		stale.Stored, stale.Expires = time.Now(), time.Now().Add(freshness)
+		cache.save(req, stale, freshness)
+		return stale.response(req), stale, nil
+	}
+
+	if !storable(req, res) {
+		return res, nil, nil
+	}
+	freshness, ok := lifetime(res.Header)
+	if !ok {
+		return res, nil, nil
+	}
+
+	body, ok, err := readBody(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return res, nil, nil
+	}
+	e := &entry{Status: res.StatusCode, Header: res.Header.Clone(), Body: body, Stored: time.Now(), Expires: time.Now().Add(freshness)}
+	cache.save(req, e, freshness)
+	return res, e, nil
+}
+
+// lookupVariant returns the cached response matching the given request, if any.
+func (cache *Cache) lookupVariant(req *http.Request) *entry {
+	e := cache.get(key(req))
+	if e == nil || len(e.Vary) == 0 {
+		return e
+	}
+	return cache.get(variantKey(req, e.Vary))
+}
+
+// save stores the given response entry of the given request, storing
+// the list of varying headers at the primary key the response varies.
+func (cache *Cache) save(req *http.Request, e *entry, freshness time.Duration) {
+	vary := varyHeaders(e.Header)
+	if len(vary) == 0 {
+		cache.set(key(req), e, cache.ttl(e, freshness))
+		return
+	}
+	ttl := cache.ttl(e, freshness)
+	cache.set(key(req), &entry{Vary: vary}, ttl)
+	cache.set(variantKey(req, vary), e, ttl)
+}
+
+// ttl returns the storage TTL of the given entry with the given freshness lifetime.
+func (cache *Cache) ttl(e *entry, freshness time.Duration) time.Duration {
+	if e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != "" {
+		return freshness + cache.opts.StaleTTL
+	}
+	return freshness
+}
+
+// get returns the cached entry by the given key, if any.
+func (cache *Cache) get(key string) *entry {
+	data, ok, err := cache.opts.Store.Get(key)
+	if err != nil || !ok {
+		cache.report(err)
+		return nil
+	}
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		cache.report(err)
+		return nil
+	}
+	return e
+}
+
+// set stores the given entry by the given key for the given amount of time.
+func (cache *Cache) set(key string, e *entry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		cache.report(err)
+		return
+	}
+	cache.report(cache.opts.Store.Set(key, data, ttl))
+}
+
+// report reports the given store error, if any.
+func (cache *Cache) report(err error) {
+	if err != nil && cache.opts.OnError != nil {
+		cache.opts.OnError(err)
+	}
+}
+
+// response creates the cached response of the given request.
+func (e *entry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	age, _ := strconv.ParseInt(header.Get("Age"), 10, 64)
+	header.Set("Age", strconv.FormatInt(age+int64(time.Since(e.Stored)/time.Second), 10))
+	return &http.Response{
+		StatusCode:    e.Status,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// key returns the cache key of the given request.
+func key(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+// variantKey returns the cache key of the given request variant, hashing
+// the values of the given varying headers so credentials are never stored as is.
+func variantKey(req *http.Request, vary []string) string {
+	hash := sha256.New()
+	for _, name := range vary {
+		hash.Write([]byte(name + ":" + normalize(name, req.Header[http.CanonicalHeaderKey(name)]) + "\n"))
+	}
+	return key(req) + " " + hex.EncodeToString(hash.Sum(nil))
+}
+
+// varyHeaders returns the sorted canonical names of the headers listed by the Vary header.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header["Vary"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// normalize returns the normalized value of the given request header values, so equivalent
+// values, such as "gzip,br" and "gzip, br", match the same variant.
+func normalize(name string, values []string) string {
+	var parts []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, strings.Join(strings.Fields(part), ""))
+			}
+		}
+	}
+	value := strings.Join(parts, ",")
+	if name == "Accept" || strings.HasPrefix(name, "Accept-") {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// cacheable returns true if the response of the given request can be cached.
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	_, noStore := parseCacheControl(req.Header.Get("Cache-Control"))["no-store"]
+	return !noStore && req.Header.Get("Range") == ""
+}
+
+// safe returns true if the given method is safe.
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// storable returns true if the given response of the given request can be stored.
+func storable(req *http.Request, res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusNotFound,
+		http.StatusGone, http.StatusPermanentRedirect:
+	default:
+		return false
+	}
+	directives := parseCacheControl(res.Header.Get("Cache-Control"))
+	if _, noStore := directives["no-store"]; noStore {
+		return false
+	}
+
+	vary := varyHeaders(res.Header)
+	for _, name := range vary {
+		if name == "*" {
+			return false
+		}
+	}
+
+	// Responses to requests with credentials must be explicitly allowed
+	if req.Header.Get("Authorization") != "" && !contains(vary, "Authorization") {
+		_, public := directives["public"]
+		_, shared := directives["s-maxage"]
+		_, revalidate := directives["must-revalidate"]
+		if !public && !shared && !revalidate {
+			return false
+		}
+	}
+	return res.ContentLength <= MaxSize
+}
+
+// readBody buffers the given response body up to MaxSize bytes.
+// Returns false if the body is larger, in which case the response
+// body keeps streaming the buffered bytes followed by the rest of the body.
+func readBody(res *http.Response) ([]byte, bool, error) {
+	if res.Body == nil {
+		return nil, true, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxSize+1))
+	if err != nil {
+		res.Body.Close()
+		return nil, false, err
+	}
+	if int64(len(body)) > MaxSize {
+		res.Body = &partialBody{Reader: io.MultiReader(bytes.NewReader(body), res.Body), Closer: res.Body}
+		return nil, false, nil
+	}
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, true, nil
+}
+
+// partialBody represents a partially buffered response body.
+type partialBody struct {
+	io.Reader
+	io.Closer
+}
+
+// contains returns true if the given names contain the given name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lifetime returns the freshness lifetime defined by the given response headers,
+// if the response can be stored.
+func lifetime(header http.Header) (time.Duration, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return 0, false
+	}
+
+	var freshness time.Duration
+	defined := false
+	if value, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		freshness, defined = time.Duration(seconds)*time.Second, true
+	} else if expires := header.Get("Expires"); expires != "" {
+		at, err := http.ParseTime(expires)
+		date, dateErr := http.ParseTime(header.Get("Date"))
+		if dateErr != nil {
+			date = time.Now()
+		}
+		if err == nil {
+			freshness = at.Sub(date)
+		}
+		defined = true
+	}
+	if _, ok := directives["no-cache"]; ok {
+		freshness = 0
+	}
+
+	if age, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && age > 0 {
+		freshness -= time.Duration(age) * time.Second
+	}
+	if freshness < 0 {
+		freshness = 0
+	}
+
+	// Responses with no freshness information can only be stored for revalidation
+	hasValidators := header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+	return freshness, defined || hasValidators
+}
+
+// parseCacheControl parses the given Cache-Control header directives.
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value := part, ""
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			name, value = part[:i], strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+		}
+		directives[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return directives
+}
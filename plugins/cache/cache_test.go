@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+func TestCacheFresh(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{}))
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, Hit(res.Context), false)
+
+	res, err = cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, res.Header.Get("Cache-Control"), "max-age=60")
+	st.Expect(t, res.Header.Get("Age"), "0")
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(1))
+
+	// Requests with no-cache revalidate
+	res, err = cli.Request().SetHeader("Cache-Control", "no-cache").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, Hit(res.Context), false)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	var hits, revalidated int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{}))
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "hello")
+
+	res, err = cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+	st.Expect(t, atomic.LoadInt32(&revalidated), int32(1))
+
+	// User defined conditional requests are sent as is
+	res, err = cli.Request().SetHeader("If-None-Match", `"v1"`).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 304)
+}
+
+func TestCacheNotStored(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "*")
+		case "/error":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(500)
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{}))
+	for _, path := range []string{"/no-store", "/vary", "/error", "/"} {
+		for i := 0; i < 2; i++ {
+			res, err := cli.Request().Path(path).Send()
+			st.Expect(t, err, nil)
+			st.Expect(t, res.String(), "hello")
+		}
+	}
+	st.Expect(t, atomic.LoadInt32(&hits), int32(8))
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	defer func(size int64) { MaxSize = size }(MaxSize)
+	MaxSize = 1024
+
+	var hits int32
+	body := strings.Repeat("a", 8*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		// Flushing before writing the body forces a chunked response
+		w.(http.Flusher).Flush()
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{}))
+	for i := 0; i < 2; i++ {
+		res, err := cli.Request().Send()
+		st.Expect(t, err, nil)
+		st.Expect(t, res.RawResponse.ContentLength, int64(-1))
+		st.Expect(t, res.String(), body)
+		st.Expect(t, Hit(res.Context), false)
+	}
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer ts.Close()
+
+	store := NewMemoryStore(10)
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{Store: store}))
+	_, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, store.Len(), 1)
+
+	_, err = cli.Request().Method("POST").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, store.Len(), 0)
+
+	_, err = cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(3))
+}
+
+func TestCacheVary(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept, accept-encoding")
+		w.Write([]byte(r.Header.Get("Accept")))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{}))
+	send := func(accept, encoding string) *gentleman.Response {
+		res, err := cli.Request().SetHeader("Accept", accept).SetHeader("Accept-Encoding", encoding).Send()
+		st.Expect(t, err, nil)
+		return res
+	}
+
+	st.Expect(t, send("application/json", "gzip, br").String(), "application/json")
+	st.Expect(t, send("text/html", "gzip, br").String(), "text/html")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+
+	// Equivalent header values match the stored variant
+	res := send("Application/JSON", "gzip,br")
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "application/json")
+	res = send("text/html", "gzip, br")
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "text/html")
+
+	res = send("text/html", "identity")
+	st.Expect(t, Hit(res.Context), false)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(3))
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Authorization")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer ts.Close()
+
+	store := NewMemoryStore(10)
+	cli := gentleman.New().URL(ts.URL).Use(New(Options{Store: store}))
+	send := func(path, token string) *gentleman.Response {
+		res, err := cli.Request().Path(path).SetHeader("Authorization", token).Send()
+		st.Expect(t, err, nil)
+		return res
+	}
+
+	// Responses to authorized requests are not stored by default
+	send("/", "Bearer a")
+	st.Expect(t, Hit(send("/", "Bearer a").Context), false)
+	st.Expect(t, Hit(send("/public", "Bearer a").Context), false)
+	st.Expect(t, Hit(send("/public", "Bearer b").Context), true)
+
+	// Responses varying by Authorization are stored per credentials
+	send("/vary", "Bearer a")
+	st.Expect(t, send("/vary", "Bearer b").String(), "Bearer b")
+	res := send("/vary", "Bearer a")
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "Bearer a")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(5))
+
+	// Credentials are never part of the stored keys
+	for key := range store.entries {
+		st.Expect(t, strings.Contains(key, "Bearer"), false)
+	}
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRedisTimeout stores the default Redis dial and command timeout.
+const DefaultRedisTimeout = 5 * time.Second
+
+// ErrRedisReply is returned when the Redis server replies with an unexpected message.
+var ErrRedisReply = errors.New("cache: unexpected redis reply")
+
+// RedisOptions represents the Redis store options.
+type RedisOptions struct {
+	// Address defines the Redis server address, such as "localhost:6379".
+	Address string
+
+	// Password defines the optional password used to authenticate.
+	Password string
+
+	// DB defines the optional database number to select.
+	DB int
+
+	// Prefix defines the optional prefix of the stored keys, such as "myservice:http:".
+	Prefix string
+
+	// Timeout defines the dial and command timeout. Defaults to DefaultRedisTimeout.
+	Timeout time.Duration
+
+	// MaxIdle defines the maximum number of idle connections kept. Defaults to 2.
+	MaxIdle int
+}
+
+// RedisStore implements a store backed by a Redis server, so the cached responses
+// can be shared by multiple service instances. It speaks the Redis protocol directly,
+// requiring no client library.
+type RedisStore struct {
+	// opts stores the Redis options.
+	opts RedisOptions
+
+	// mtx protects the idle connections.
+	mtx sync.Mutex
+
+	// idle stores the idle connections.
+	idle []*redisConn
+}
+
+// redisConn represents a Redis server connection.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// RedisError represents an error reply returned by the Redis server.
+type RedisError string
+
+// Error returns the error message.
+func (e RedisError) Error() string {
+	return "cache: redis: " + string(e)
+}
+
+// NewRedisStore creates a new Redis store with the given options.
+// Connections are established lazily.
+func NewRedisStore(opts RedisOptions) *RedisStore {
+	if opts.Address == "" {
+		opts.Address = "localhost:6379"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultRedisTimeout
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = 2
+	}
+	return &RedisStore{opts: opts}
+}
+
+// Get returns the value stored by the given key, if any.
+func (s *RedisStore) Get(key string) ([]byte, bool, error) {
+	reply, err := s.do("GET", s.opts.Prefix+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, ErrRedisReply
+	}
+	return value, true, nil
+}
+
+// Set stores the given value by the given key for the given amount of time.
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", s.opts.Prefix + key, string(value)}
+	if ttl > 0 {
+		if ms := ttl.Milliseconds(); ms > 0 {
+			args = append(args, "PX", strconv.FormatInt(ms, 10))
+		}
+	}
+	_, err := s.do(args...)
+	return err
+}
+
+// Delete deletes the value stored by the given key, if any.
+func (s *RedisStore) Delete(key string) error {
+	_, err := s.do("DEL", s.opts.Prefix+key)
+	return err
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	s.mtx.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.mtx.Unlock()
+	for _, conn := range idle {
+		conn.Close()
+	}
+	return nil
+}
+
+// do sends the given command, returning its reply.
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	conn, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(s.opts.Timeout, args...)
+	if _, ok := err.(RedisError); err != nil && !ok {
+		conn.Close()
+		return nil, err
+	}
+	s.release(conn)
+	return reply, err
+}
+
+// conn returns an idle connection, or dials a new one.
+func (s *RedisStore) conn() (*redisConn, error) {
+	s.mtx.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mtx.Unlock()
+		return conn, nil
+	}
+	s.mtx.Unlock()
+
+	c, err := net.DialTimeout("tcp", s.opts.Address, s.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: c, reader: bufio.NewReader(c)}
+	if s.opts.Password != "" {
+		if _, err := conn.do(s.opts.Timeout, "AUTH", s.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.opts.DB != 0 {
+		if _, err := conn.do(s.opts.Timeout, "SELECT", strconv.Itoa(s.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// release returns the given connection to the idle pool.
+func (s *RedisStore) release(conn *redisConn) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.idle) >= s.opts.MaxIdle {
+		conn.Close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+}
+
+// do sends the given command as a RESP array of bulk strings, returning its reply.
+func (conn *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readReply(conn.reader)
+}
+
+// readReply reads a RESP reply.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, ErrRedisReply
+	}
+	kind, line := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, RedisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, ErrRedisReply
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, ErrRedisReply
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, size)
+		for i := range values {
+			if values[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("cache: unexpected redis reply type %q", kind)
+}
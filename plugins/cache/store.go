@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity stores the default maximum number of entries of the in-memory store.
+const DefaultCapacity = 1000
+
+// Store represents the storage backend of the cached responses.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored by the given key, if any and not expired.
+	Get(key string) (value []byte, ok bool, err error)
+
+	// Set stores the given value by the given key for the given amount of time.
+	// A zero TTL stores the value with no expiration.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Delete deletes the value stored by the given key, if any.
+	Delete(key string) error
+}
+
+// MemoryStore implements an in-memory least recently used store.
+type MemoryStore struct {
+	// mtx protects the store state.
+	mtx sync.Mutex
+
+	// capacity stores the maximum number of entries.
+	capacity int
+
+	// entries stores the list elements by key.
+	entries map[string]*list.Element
+
+	// lru stores the entries from the most to the least recently used.
+	lru *list.List
+}
+
+// memoryEntry represents an in-memory store entry.
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryStore creates a new in-memory LRU store with the given capacity,
+// evicting the least recently used entries once the capacity is exceeded.
+// Defaults to DefaultCapacity.
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &MemoryStore{capacity: capacity, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Get returns the value stored by the given key, if any and not expired.
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		s.remove(elem)
+		return nil, false, nil
+	}
+	s.lru.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set stores the given value by the given key for the given amount of time.
+func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.capacity {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Delete deletes the value stored by the given key, if any.
+func (s *MemoryStore) Delete(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	return nil
+}
+
+// Len returns the number of stored entries, including the expired ones not evicted yet.
+func (s *MemoryStore) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.lru.Len()
+}
+
+// remove removes the given list element.
+func (s *MemoryStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*memoryEntry).key)
+}
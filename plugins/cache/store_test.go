@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(2)
+	st.Expect(t, store.Set("a", []byte("1"), 0), nil)
+	st.Expect(t, store.Set("b", []byte("2"), 0), nil)
+
+	// Reading a promotes it, so b is evicted
+	value, ok, err := store.Get("a")
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	st.Expect(t, string(value), "1")
+	st.Expect(t, store.Set("c", []byte("3"), 0), nil)
+	_, ok, _ = store.Get("b")
+	st.Expect(t, ok, false)
+	st.Expect(t, store.Len(), 2)
+
+	// Expired entries are not returned
+	st.Expect(t, store.Set("d", []byte("4"), time.Millisecond), nil)
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = store.Get("d")
+	st.Expect(t, ok, false)
+
+	st.Expect(t, store.Delete("a"), nil)
+	_, ok, _ = store.Get("a")
+	st.Expect(t, ok, false)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := newRedisServer(t)
+	defer server.Close()
+
+	store := NewRedisStore(RedisOptions{Address: server.Addr().String(), Password: "secret", DB: 2, Prefix: "http:"})
+	defer store.Close()
+
+	_, ok, err := store.Get("a")
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, false)
+
+	st.Expect(t, store.Set("a", []byte("hello\r\nworld"), 1500*time.Millisecond), nil)
+	value, ok, err := store.Get("a")
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	st.Expect(t, string(value), "hello\r\nworld")
+
+	st.Expect(t, store.Delete("a"), nil)
+	_, ok, _ = store.Get("a")
+	st.Expect(t, ok, false)
+
+	// Error replies are returned
+	st.Expect(t, store.Set("fail", nil, 0), RedisError("ERR failed"))
+
+	server.mtx.Lock()
+	defer server.mtx.Unlock()
+	st.Expect(t, server.commands[:3], []string{"AUTH secret", "SELECT 2", "GET http:a"})
+	st.Expect(t, server.commands[3], "SET http:a hello\r\nworld PX 1500")
+}
+
+// redisServer implements a minimal in-memory Redis server.
+type redisServer struct {
+	net.Listener
+	mtx      sync.Mutex
+	commands []string
+	values   map[string]string
+}
+
+func newRedisServer(t *testing.T) *redisServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	st.Expect(t, err, nil)
+	s := &redisServer{Listener: ln, values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		s.mtx.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		var out string
+		switch {
+		case args[0] == "GET":
+			if value, ok := s.values[args[1]]; ok {
+				out = "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			} else {
+				out = "$-1\r\n"
+			}
+		case args[0] == "SET" && args[1] == "http:fail":
+			out = "-ERR failed\r\n"
+		case args[0] == "SET":
+			s.values[args[1]] = args[2]
+			out = "+OK\r\n"
+		case args[0] == "DEL":
+			delete(s.values, args[1])
+			out = ":1\r\n"
+		default:
+			out = "+OK\r\n"
+		}
+		s.mtx.Unlock()
+		conn.Write([]byte(out))
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	gocontext "context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Warm fetches and stores the responses of the given URLs, unless already fresh, so the first
+// requests are served from the cache. If RefreshBefore is defined, the warmed responses are
+// fetched again in background before expiring, until the cache is closed.
+// Returns the first request error, if any, once all the URLs were fetched.
+func (cache *Cache) Warm(ctx gocontext.Context, urls ...string) error {
+	var first error
+	for _, uri := range urls {
+		if err := cache.warm(ctx, uri); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close stops the background refreshes of the warmed responses.
+func (cache *Cache) Close() error {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	cache.closed = true
+	for uri, timer := range cache.timers {
+		timer.Stop()
+		delete(cache.timers, uri)
+	}
+	return nil
+}
+
+// warm fetches and stores the response of the given URL, unless already fresh.
+func (cache *Cache) warm(ctx gocontext.Context, uri string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	e := cache.lookupVariant(req)
+	if e == nil || time.Until(e.Expires) <= cache.opts.RefreshBefore {
+		if e, err = cache.refresh(req, e, cache.client(nil)); err != nil {
+			return err
+		}
+	}
+	if e != nil {
+		cache.schedule(uri, e.Expires)
+	}
+	return nil
+}
+
+// schedule schedules the background refresh of the given warmed URL before the given expiry.
+func (cache *Cache) schedule(uri string, expires time.Time) {
+	if cache.opts.RefreshBefore <= 0 {
+		return
+	}
+
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	if cache.closed {
+		return
+	}
+	if timer, ok := cache.timers[uri]; ok {
+		timer.Stop()
+	}
+
+	delay := time.Until(expires) - cache.opts.RefreshBefore
+	if delay < time.Second {
+		delay = time.Second
+	}
+	cache.timers[uri] = time.AfterFunc(delay, func() {
+		cache.mtx.Lock()
+		delete(cache.timers, uri)
+		closed := cache.closed
+		cache.mtx.Unlock()
+		if closed {
+			return
+		}
+
+		req, err := http.NewRequest(http.MethodGet, uri, nil)
+		if err != nil {
+			return
+		}
+		e, err := cache.refresh(req, cache.lookupVariant(req), cache.client(nil))
+		if err != nil {
+			cache.report(err)
+			return
+		}
+		if e != nil {
+			cache.schedule(uri, e.Expires)
+		}
+	})
+}
+
+// refreshAsync fetches again in background the given request cached response,
+// unless already being refreshed.
+func (cache *Cache) refreshAsync(req *http.Request, e *entry, client *http.Client) {
+	id := key(req)
+	if vary := varyHeaders(e.Header); len(vary) > 0 {
+		id = variantKey(req, vary)
+	}
+
+	cache.mtx.Lock()
+	if cache.closed || cache.refreshing[id] {
+		cache.mtx.Unlock()
+		return
+	}
+	cache.refreshing[id] = true
+	cache.mtx.Unlock()
+
+	req = req.Clone(gocontext.Background())
+	client = cache.client(client)
+	go func() {
+		defer func() {
+			cache.mtx.Lock()
+			delete(cache.refreshing, id)
+			cache.mtx.Unlock()
+		}()
+		if _, err := cache.refresh(req, e, client); err != nil {
+			cache.report(err)
+		}
+	}()
+}
+
+// refresh fetches and stores the response of the given request, revalidating
+// the given stale entry, if any. Returns the stored entry, if any.
+func (cache *Cache) refresh(req *http.Request, stale *entry, client *http.Client) (*entry, error) {
+	if stale != nil && !revalidate(req, stale) {
+		stale = nil
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	res, e, err := cache.update(req, res, stale)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	return e, nil
+}
+
+// client returns the HTTP client used to warm and refresh the cached responses.
+func (cache *Cache) client(outgoing *http.Client) *http.Client {
+	if cache.opts.Client != nil {
+		return cache.opts.Client
+	}
+	if outgoing != nil {
+		return outgoing
+	}
+	return http.DefaultClient
+}
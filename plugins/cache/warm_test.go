@@ -0,0 +1,101 @@
+package cache
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+func TestWarm(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	cache := New(Options{})
+	defer cache.Close()
+	st.Expect(t, cache.Warm(gocontext.Background(), ts.URL+"/a", ts.URL+"/b"), nil)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+
+	// Fresh responses are not fetched again
+	st.Expect(t, cache.Warm(gocontext.Background(), ts.URL+"/a"), nil)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+
+	cli := gentleman.New().URL(ts.URL).Use(cache)
+	res, err := cli.Request().Path("/b").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "/b")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+
+	st.Reject(t, cache.Warm(gocontext.Background(), "http://127.0.0.1:0/"), nil)
+}
+
+func TestWarmRefresh(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=2")
+		w.Write([]byte(strconv.Itoa(int(n))))
+	}))
+	defer ts.Close()
+
+	cache := New(Options{RefreshBefore: time.Second})
+	st.Expect(t, cache.Warm(gocontext.Background(), ts.URL), nil)
+
+	// The warmed response is refreshed in background before expiring
+	time.Sleep(1500 * time.Millisecond)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+	e := cache.lookupVariant(httptest.NewRequest("GET", ts.URL, nil))
+	st.Expect(t, string(e.Body), "2")
+
+	cache.Close()
+	time.Sleep(1500 * time.Millisecond)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestRefreshAhead(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(strconv.Itoa(int(n))))
+	}))
+	defer ts.Close()
+
+	cache := New(Options{RefreshBefore: time.Hour})
+	defer cache.Close()
+	cli := gentleman.New().URL(ts.URL).Use(cache)
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "1")
+
+	// The hit is served from the cache, while refreshed in background
+	res, err = cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "1")
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&hits) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+	time.Sleep(50 * time.Millisecond)
+
+	res, err = cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, Hit(res.Context), true)
+	st.Expect(t, res.String(), "2")
+}
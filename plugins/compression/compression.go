@@ -1,25 +1,18 @@
 package compression
 
 import (
-	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"net/http"
 )
 
-// Disable disables the authorization basic header in the outgoing request
-func Disable() p.Plugin {
-	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		// Assert http.Transport to work with the instance
-		transport, ok := ctx.Client.Transport.(*http.Transport)
-		if !ok {
-			h.Next(ctx)
-			return
-		}
-
-		// Override the http.Client transport
-		transport.DisableCompression = true
-		ctx.Client.Transport = transport
+// disableKey is the key of the transports derived by Disable().
+type disableKey struct{}
 
-		h.Next(ctx)
+// Disable disables the transparent gzip response compression in the outgoing request,
+// deriving an isolated copy of the request transport, shared by every plugin instance.
+func Disable() p.Plugin {
+	return transport.DeriveKey(disableKey{}, func(t *http.Transport) {
+		t.DisableCompression = true
 	})
 }
@@ -14,6 +14,7 @@ func TestDisableCompression(t *testing.T) {
 	st.Expect(t, fn.called, true)
 	transport := ctx.Client.Transport.(*http.Transport)
 	st.Expect(t, transport.DisableCompression, true)
+	st.Expect(t, http.DefaultTransport.(*http.Transport).DisableCompression, false)
 }
 
 type handler struct {
@@ -1,13 +1,14 @@
 package cookies
 
 import (
-	"golang.org/x/net/publicsuffix"
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 	"net/http"
-	"net/http/cookiejar"
 )
 
+// JarKey is the context store key used to store the cookie store.
+const JarKey = "$cookies.jar"
+
 // Add adds a cookie to the request. Per RFC 6265 section 5.4, AddCookie does not
 // attach more than one Cookie header field.
 // That means all cookies, if any, are written into the same line, separated by semicolon.
@@ -57,10 +58,26 @@ func AddMultiple(cookies []*http.Cookie) p.Plugin {
 }
 
 // Jar creates a cookie jar to store HTTP cookies when they are sent down.
+// The jar is shared by every request using the plugin, and can be inspected
+// via GetJar() from the request context.
 func Jar() p.Plugin {
+	jar, _ := NewStore(StoreOptions{})
+	return UseJar(jar)
+}
+
+// UseJar uses the given cookie jar, such as a Store, to store HTTP cookies when they are sent down.
+func UseJar(jar http.CookieJar) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 		ctx.Client.Jar = jar
+		if store, ok := jar.(*Store); ok {
+			ctx.Set(JarKey, store)
+		}
 		h.Next(ctx)
 	})
 }
+
+// GetJar returns the cookie store used by the given context or its parents, if any.
+func GetJar(ctx *c.Context) *Store {
+	store, _ := ctx.Get(JarKey).(*Store)
+	return store
+}
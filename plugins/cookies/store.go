@@ -0,0 +1,567 @@
+package cookies
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Format represents a cookie store file format.
+type Format int
+
+const (
+	// JSON stores the cookies as a JSON array of Entry.
+	JSON Format = iota
+
+	// Netscape stores the cookies in the Netscape cookies.txt format, as used by curl or wget.
+	Netscape
+)
+
+// ErrInvalidFormat is returned when loading a malformed cookie store file.
+var ErrInvalidFormat = errors.New("gentleman: invalid cookie store file format")
+
+// netscapeHeader defines the Netscape cookies.txt file header.
+const netscapeHeader = "# Netscape HTTP Cookie File\n"
+
+// httpOnlyPrefix defines the Netscape cookies.txt prefix of HTTP only cookies.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// DefaultSaveDelay defines the default time the cookie changes are batched
+// before saving the store file.
+const DefaultSaveDelay = time.Second
+
+// StoreOptions represents the cookie store options.
+type StoreOptions struct {
+	// Path defines the file used to persist the cookies, which is loaded
+	// on store creation and saved once cookies are received, batching the
+	// changes during SaveDelay. Cookies are only kept in memory if empty.
+	Path string
+
+	// SaveDelay defines the time the cookie changes are batched before saving
+	// the store file. Defaults to DefaultSaveDelay. If negative, the store file
+	// is only saved by calling Save() or Close().
+	SaveDelay time.Duration
+
+	// Format defines the store file format. Defaults to JSON.
+	Format Format
+
+	// PublicSuffixList defines the public suffix list used to reject
+	// cookies set for public suffixes. Defaults to publicsuffix.List.
+	PublicSuffixList cookiejar.PublicSuffixList
+
+	// AllowDomains defines the domains, including their subdomains,
+	// cookies may be stored for. Any domain is allowed if empty.
+	AllowDomains []string
+
+	// DenyDomains defines the domains, including their subdomains,
+	// cookies must never be stored for.
+	DenyDomains []string
+}
+
+// Entry represents a stored cookie.
+type Entry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"hostOnly,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"httpOnly,omitempty"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Expired returns true if the cookie expired at the given time.
+// Session cookies, with no expiration time, never expire.
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// Cookie returns the http.Cookie representation of the entry.
+func (e *Entry) Cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Path:     e.Path,
+		Expires:  e.Expires,
+		Secure:   e.Secure,
+		HttpOnly: e.HTTPOnly,
+	}
+	if !e.HostOnly {
+		cookie.Domain = e.Domain
+	}
+	return cookie
+}
+
+// url returns the URL which would have set the cookie.
+func (e *Entry) url() *url.URL {
+	scheme := "http"
+	if e.Secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: e.Domain, Path: e.Path}
+}
+
+// matchDomain returns true if the cookie must be sent to the given host.
+func (e *Entry) matchDomain(host string) bool {
+	if e.HostOnly {
+		return host == e.Domain
+	}
+	return host == e.Domain || strings.HasSuffix(host, "."+e.Domain)
+}
+
+// matchPath returns true if the cookie must be sent to the given URL path, as defined by RFC 6265.
+func (e *Entry) matchPath(path string) bool {
+	if path == e.Path {
+		return true
+	}
+	if !strings.HasPrefix(path, e.Path) {
+		return false
+	}
+	return strings.HasSuffix(e.Path, "/") || path[len(e.Path)] == '/'
+}
+
+// key returns the entry unique identifier.
+func (e *Entry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+// Store represents a cookie jar which keeps track of the stored cookies,
+// optionally persisting them to disk so sessions survive process restarts.
+// Store implements http.CookieJar and is safe for concurrent use.
+type Store struct {
+	// mtx protects the entries and the store file.
+	mtx sync.Mutex
+
+	// opts stores the store options.
+	opts StoreOptions
+
+	// jar stores the cookie jar used to match the cookies.
+	jar *cookiejar.Jar
+
+	// entries stores the stored cookies by key.
+	entries map[string]*Entry
+
+	// timer stores the pending store file save, if any.
+	timer *time.Timer
+}
+
+// NewStore creates a new cookie store, loading the cookies from the store file, if any.
+func NewStore(opts StoreOptions) (*Store, error) {
+	if opts.PublicSuffixList == nil {
+		opts.PublicSuffixList = publicsuffix.List
+	}
+	if opts.SaveDelay == 0 {
+		opts.SaveDelay = DefaultSaveDelay
+	}
+
+	store := &Store{opts: opts, entries: make(map[string]*Entry)}
+	store.jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: opts.PublicSuffixList})
+	if opts.Path == "" {
+		return store, nil
+	}
+
+	if err := store.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Cookies returns the cookies to send in a request for the given URL.
+func (s *Store) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// SetCookies stores the cookies received in a response from the given URL,
+// scheduling the store file save, if any. Save errors are ignored since http.CookieJar
+// cannot report them: call Save() or Close() explicitly to handle them.
+func (s *Store) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	cookies = s.allowed(u, cookies)
+	if len(cookies) == 0 {
+		return
+	}
+	s.jar.SetCookies(u, cookies)
+
+	s.mtx.Lock()
+	now := time.Now()
+	for _, cookie := range cookies {
+		s.record(u, cookie, now)
+	}
+	s.mtx.Unlock()
+
+	s.persist()
+}
+
+// All returns a copy of every stored cookie which did not expire yet, sorted by domain, path and name.
+func (s *Store) All() []*Entry {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.sorted() {
+		if !entry.Expired(now) {
+			clone := *entry
+			entries = append(entries, &clone)
+		}
+	}
+	return entries
+}
+
+// Get returns the cookie with the given name sent in a request for the given URL, if any.
+func (s *Store) Get(u *url.URL, name string) *http.Cookie {
+	for _, cookie := range s.jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Set stores the given cookie as if it was received in a response from the given URL.
+func (s *Store) Set(u *url.URL, cookie *http.Cookie) {
+	s.SetCookies(u, []*http.Cookie{cookie})
+}
+
+// Delete removes the cookies with the given name sent in a request for the given URL.
+func (s *Store) Delete(u *url.URL, name string) {
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	s.remove(func(entry *Entry) bool {
+		return entry.Name == name && entry.matchDomain(host) && entry.matchPath(path)
+	})
+}
+
+// Clear removes every stored cookie.
+func (s *Store) Clear() {
+	s.remove(func(*Entry) bool { return true })
+}
+
+// remove removes the stored cookies matching the given function, scheduling the store file save, if any.
+func (s *Store) remove(match func(*Entry) bool) {
+	s.mtx.Lock()
+	for key, entry := range s.entries {
+		if !match(entry) {
+			continue
+		}
+		cookie := entry.Cookie()
+		cookie.MaxAge = -1
+		s.jar.SetCookies(entry.url(), []*http.Cookie{cookie})
+		delete(s.entries, key)
+	}
+	s.mtx.Unlock()
+
+	s.persist()
+}
+
+// allowed filters the given cookies received from the given URL
+// based on the allowed and denied domains.
+func (s *Store) allowed(u *url.URL, cookies []*http.Cookie) []*http.Cookie {
+	if len(s.opts.AllowDomains) == 0 && len(s.opts.DenyDomains) == 0 {
+		return cookies
+	}
+
+	host := strings.ToLower(u.Hostname())
+	allowed := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		domain := host
+		if cookie.Domain != "" {
+			domain = strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		}
+		if len(s.opts.AllowDomains) > 0 && !matchDomains(s.opts.AllowDomains, domain) {
+			continue
+		}
+		if matchDomains(s.opts.DenyDomains, domain) {
+			continue
+		}
+		allowed = append(allowed, cookie)
+	}
+	return allowed
+}
+
+// persist schedules the store file save, if any, batching the changes during the save delay.
+func (s *Store) persist() {
+	if s.opts.Path == "" || s.opts.SaveDelay < 0 {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.opts.SaveDelay, s.flush)
+	}
+}
+
+// flush saves the store file once the save delay elapsed.
+func (s *Store) flush() {
+	s.mtx.Lock()
+	s.timer = nil
+	s.mtx.Unlock()
+	s.Save()
+}
+
+// Close saves the pending cookie changes to the store file, if any,
+// canceling the scheduled save. The store can still be used afterwards.
+func (s *Store) Close() error {
+	if s.opts.Path == "" {
+		return nil
+	}
+	s.mtx.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mtx.Unlock()
+	return s.Save()
+}
+
+// Save writes the non expired cookies to the store file.
+func (s *Store) Save() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.prune(time.Now())
+	data, err := s.encode()
+	if err != nil {
+		return err
+	}
+
+	// Write atomically to prevent corrupted files on crashes
+	tmp, err := ioutil.TempFile(filepath.Dir(s.opts.Path), ".cookies-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.opts.Path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Load reads the cookies from the store file, discarding the expired ones.
+func (s *Store) Load() error {
+	data, err := ioutil.ReadFile(s.opts.Path)
+	if err != nil {
+		return err
+	}
+
+	entries, err := decode(s.opts.Format, data)
+	if err != nil {
+		return err
+	}
+
+	s.add(entries)
+	return nil
+}
+
+// Add stores the given cookie entries, such as the ones returned by All()
+// from another store, discarding the expired ones.
+func (s *Store) Add(entries ...*Entry) {
+	s.add(entries)
+	s.persist()
+}
+
+// add stores the given cookie entries, discarding the expired ones.
+func (s *Store) add(entries []*Entry) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Expired(now) {
+			continue
+		}
+		u, cookie := entry.url(), entry.Cookie()
+		s.jar.SetCookies(u, []*http.Cookie{cookie})
+		s.record(u, cookie, now)
+	}
+}
+
+// record tracks the given cookie received from the given URL, mirroring
+// the cookie jar domain, path and expiration rules. The caller must hold the lock.
+func (s *Store) record(u *url.URL, cookie *http.Cookie, now time.Time) {
+	host := strings.ToLower(u.Hostname())
+	entry := &Entry{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   host,
+		Path:     cookie.Path,
+		HostOnly: true,
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HttpOnly,
+	}
+
+	if cookie.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+		if domain != host && !strings.HasSuffix(host, "."+domain) {
+			return
+		}
+		if s.opts.PublicSuffixList.PublicSuffix(domain) != domain {
+			entry.Domain, entry.HostOnly = domain, false
+		} else if domain != host {
+			return
+		}
+	}
+
+	if !strings.HasPrefix(entry.Path, "/") {
+		entry.Path = defaultPath(u.Path)
+	}
+
+	switch {
+	case cookie.MaxAge < 0:
+		entry.Expires = now
+	case cookie.MaxAge > 0:
+		entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	case !cookie.Expires.IsZero():
+		entry.Expires = cookie.Expires
+	}
+
+	if entry.Expired(now) {
+		delete(s.entries, entry.key())
+		return
+	}
+	s.entries[entry.key()] = entry
+}
+
+// prune removes the expired cookies. The caller must hold the lock.
+func (s *Store) prune(now time.Time) {
+	for key, entry := range s.entries {
+		if entry.Expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// encode serializes the stored cookies. The caller must hold the lock.
+func (s *Store) encode() ([]byte, error) {
+	entries := s.sorted()
+	if s.opts.Format != Netscape {
+		return json.MarshalIndent(entries, "", "  ")
+	}
+
+	buf := bytes.NewBufferString(netscapeHeader)
+	for _, entry := range entries {
+		domain, subdomains := entry.Domain, "FALSE"
+		if !entry.HostOnly {
+			domain, subdomains = "."+domain, "TRUE"
+		}
+		if entry.HTTPOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		expires := int64(0)
+		if !entry.Expires.IsZero() {
+			expires = entry.Expires.Unix()
+		}
+		fields := []string{domain, subdomains, entry.Path, netscapeBool(entry.Secure),
+			strconv.FormatInt(expires, 10), entry.Name, entry.Value}
+		buf.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// sorted returns the stored cookies sorted by key. The caller must hold the lock.
+func (s *Store) sorted() []*Entry {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key() < entries[j].key()
+	})
+	return entries
+}
+
+// decode parses the given store file data.
+func decode(format Format, data []byte) ([]*Entry, error) {
+	var entries []*Entry
+	if format != Netscape {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, ErrInvalidFormat
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+
+		entry := &Entry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: fields[1] != "TRUE",
+			Path:     fields[2],
+			Secure:   fields[3] == "TRUE",
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		}
+		if expires > 0 {
+			entry.Expires = time.Unix(expires, 0)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// matchDomains returns true if the given domain is any of the given domains or their subdomains.
+func matchDomains(domains []string, domain string) bool {
+	for _, match := range domains {
+		match = strings.ToLower(strings.TrimPrefix(match, "."))
+		if domain == match || strings.HasSuffix(domain, "."+match) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultPath returns the cookie default path of the given URL path, as defined by RFC 6265.
+func defaultPath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// netscapeBool returns the Netscape cookies.txt representation of the given boolean.
+func netscapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,197 @@
+package cookies
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestStorePersistence(t *testing.T) {
+	for _, format := range []Format{JSON, Netscape} {
+		dir, err := ioutil.TempDir("", "gentleman")
+		st.Expect(t, err, nil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "cookies")
+
+		store, err := NewStore(StoreOptions{Path: path, Format: format})
+		st.Expect(t, err, nil)
+
+		u, _ := url.Parse("http://www.foo.com/bar/baz")
+		store.SetCookies(u, []*http.Cookie{
+			{Name: "session", Value: "secret", HttpOnly: true},
+			{Name: "domain", Value: "foo", Domain: ".foo.com", Path: "/", MaxAge: 3600},
+			{Name: "expired", Value: "foo", Expires: time.Now().Add(-time.Hour)},
+			{Name: "tld", Value: "foo", Domain: "com"},
+		})
+		st.Expect(t, store.Close(), nil)
+
+		// Reload the persisted cookies
+		store, err = NewStore(StoreOptions{Path: path, Format: format})
+		st.Expect(t, err, nil)
+		st.Expect(t, len(store.entries), 2)
+
+		cookies := store.Cookies(u)
+		st.Expect(t, len(cookies), 2)
+		other, _ := url.Parse("http://api.foo.com/")
+		cookies = store.Cookies(other)
+		st.Expect(t, len(cookies), 1)
+		st.Expect(t, cookies[0].Name, "domain")
+
+		session := store.entries["www.foo.com;/bar;session"]
+		st.Reject(t, session, nil)
+		st.Expect(t, session.HTTPOnly, true)
+		st.Expect(t, session.HostOnly, true)
+		st.Expect(t, session.Expires.IsZero(), true)
+		st.Expect(t, store.entries["foo.com;/;domain"].Expires.After(time.Now()), true)
+
+		// Deleted cookies are removed from the store file
+		store.SetCookies(u, []*http.Cookie{{Name: "session", Value: "", MaxAge: -1}})
+		st.Expect(t, store.Close(), nil)
+		store, err = NewStore(StoreOptions{Path: path, Format: format})
+		st.Expect(t, err, nil)
+		st.Expect(t, len(store.entries), 1)
+	}
+}
+
+func TestStoreSaveDelay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gentleman")
+	st.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies")
+
+	store, err := NewStore(StoreOptions{Path: path, SaveDelay: 50 * time.Millisecond})
+	st.Expect(t, err, nil)
+	u, _ := url.Parse("http://foo.com/")
+	store.Set(u, &http.Cookie{Name: "foo", Value: "bar"})
+	store.Set(u, &http.Cookie{Name: "bar", Value: "baz"})
+
+	// The changes are batched during the save delay
+	_, err = os.Stat(path)
+	st.Expect(t, os.IsNotExist(err), true)
+	time.Sleep(200 * time.Millisecond)
+	reloaded, err := NewStore(StoreOptions{Path: path})
+	st.Expect(t, err, nil)
+	st.Expect(t, len(reloaded.All()), 2)
+
+	// Automatic saving is disabled by a negative save delay
+	path = filepath.Join(dir, "manual")
+	store, err = NewStore(StoreOptions{Path: path, SaveDelay: -1})
+	st.Expect(t, err, nil)
+	store.Set(u, &http.Cookie{Name: "foo", Value: "bar"})
+	time.Sleep(50 * time.Millisecond)
+	_, err = os.Stat(path)
+	st.Expect(t, os.IsNotExist(err), true)
+	st.Expect(t, store.Save(), nil)
+	_, err = os.Stat(path)
+	st.Expect(t, err, nil)
+}
+
+func TestStoreNetscapeFormat(t *testing.T) {
+	data := netscapeHeader +
+		"#HttpOnly_.foo.com\tTRUE\t/\tTRUE\t0\tsession\tsecret\n" +
+		"# comment\n\n" +
+		"bar.com\tFALSE\t/path\tFALSE\t1\texpired\tfoo\n"
+	entries, err := decode(Netscape, []byte(data))
+	st.Expect(t, err, nil)
+	st.Expect(t, len(entries), 2)
+	st.Expect(t, *entries[0], Entry{Name: "session", Value: "secret", Domain: "foo.com", Path: "/", Secure: true, HTTPOnly: true})
+	st.Expect(t, entries[1].HostOnly, true)
+	st.Expect(t, entries[1].Expired(time.Now()), true)
+
+	_, err = decode(Netscape, []byte("foo.com\tTRUE\n"))
+	st.Expect(t, err, ErrInvalidFormat)
+
+	store, err := NewStore(StoreOptions{Format: Netscape})
+	st.Expect(t, err, nil)
+	u, _ := url.Parse("https://foo.com/")
+	store.SetCookies(u, []*http.Cookie{{Name: "foo", Value: "bar", Domain: "foo.com", Secure: true, HttpOnly: true}})
+	encoded, err := store.encode()
+	st.Expect(t, err, nil)
+	st.Expect(t, string(encoded), netscapeHeader+"#HttpOnly_.foo.com\tTRUE\t/\tTRUE\t0\tfoo\tbar\n")
+}
+
+func TestUseJar(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	store, _ := NewStore(StoreOptions{})
+	UseJar(store).Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Client.Jar, store)
+}
+
+func TestStoreManipulation(t *testing.T) {
+	store, _ := NewStore(StoreOptions{})
+	u, _ := url.Parse("http://foo.com/bar")
+
+	store.Set(u, &http.Cookie{Name: "foo", Value: "bar", Path: "/"})
+	store.Set(u, &http.Cookie{Name: "baz", Value: "qux", Path: "/bar"})
+	store.Set(u, &http.Cookie{Name: "domain", Value: "1", Domain: "foo.com", Path: "/"})
+
+	st.Expect(t, len(store.All()), 3)
+	st.Expect(t, store.All()[0].Name, "domain")
+	st.Expect(t, store.Get(u, "foo").Value, "bar")
+	st.Expect(t, store.Get(u, "missing"), (*http.Cookie)(nil))
+
+	// Entries are copies
+	store.All()[0].Value = "modified"
+	st.Expect(t, store.All()[0].Value, "1")
+
+	root, _ := url.Parse("http://foo.com/")
+	store.Delete(root, "baz")
+	st.Expect(t, len(store.All()), 3)
+	store.Delete(u, "baz")
+	st.Expect(t, len(store.All()), 2)
+	st.Expect(t, store.Get(u, "baz"), (*http.Cookie)(nil))
+
+	sub, _ := url.Parse("http://api.foo.com/")
+	st.Expect(t, store.Get(sub, "domain").Value, "1")
+	store.Delete(sub, "domain")
+	st.Expect(t, store.Get(u, "domain"), (*http.Cookie)(nil))
+
+	store.Clear()
+	st.Expect(t, len(store.All()), 0)
+	st.Expect(t, len(store.Cookies(u)), 0)
+}
+
+func TestGetJar(t *testing.T) {
+	plugin := Jar()
+	ctx := context.New()
+	plugin.Exec("request", ctx, newHandler().fn)
+	store := GetJar(ctx)
+	st.Reject(t, store, nil)
+	st.Expect(t, ctx.Client.Jar, store)
+
+	// The jar is shared across requests
+	ctx = context.New()
+	plugin.Exec("request", ctx, newHandler().fn)
+	st.Expect(t, GetJar(ctx), store)
+}
+
+func TestStoreDomainPolicy(t *testing.T) {
+	store, _ := NewStore(StoreOptions{AllowDomains: []string{".foo.com"}, DenyDomains: []string{"ads.foo.com"}})
+
+	for _, rawurl := range []string{"http://foo.com", "http://api.foo.com", "http://ads.foo.com", "http://x.ads.foo.com", "http://bar.com"} {
+		u, _ := url.Parse(rawurl)
+		store.Set(u, &http.Cookie{Name: "foo", Value: "bar"})
+	}
+
+	var domains []string
+	for _, entry := range store.All() {
+		domains = append(domains, entry.Domain)
+	}
+	st.Expect(t, domains, []string{"api.foo.com", "foo.com"})
+
+	// Domain attributes are verified too
+	u, _ := url.Parse("http://x.ads.foo.com")
+	store.Set(u, &http.Cookie{Name: "domain", Value: "bar", Domain: "ads.foo.com"})
+	st.Expect(t, len(store.All()), 2)
+	store.Set(u, &http.Cookie{Name: "domain", Value: "bar", Domain: "foo.com"})
+	st.Expect(t, len(store.All()), 3)
+}
@@ -0,0 +1,118 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// Algorithm represents a RFC 9530 digest hashing algorithm key.
+type Algorithm string
+
+const (
+	// SHA256 represents the sha-256 digest algorithm.
+	SHA256 Algorithm = "sha-256"
+
+	// SHA512 represents the sha-512 digest algorithm.
+	SHA512 Algorithm = "sha-512"
+)
+
+const (
+	// ContentDigestHeader defines the header name of the message content digest.
+	ContentDigestHeader = "Content-Digest"
+
+	// ReprDigestHeader defines the header name of the representation digest.
+	ReprDigestHeader = "Repr-Digest"
+)
+
+// ErrUnsupportedAlgorithm is returned when using an unknown digest algorithm.
+var ErrUnsupportedAlgorithm = errors.New("digest: unsupported algorithm")
+
+// ContentDigest creates a new plugin which computes the request body digest
+// using the given algorithms, defaulting to sha-256, and defines it
+// in the Content-Digest header, such as "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:".
+func ContentDigest(algorithms ...Algorithm) p.Plugin {
+	return digestPlugin(ContentDigestHeader, algorithms)
+}
+
+// ReprDigest creates a new plugin which computes the request body digest
+// using the given algorithms, defaulting to sha-256, and defines it in the Repr-Digest header.
+// The representation data of request bodies is the message content, including any content coding,
+// hence the Repr-Digest and Content-Digest values are the same.
+func ReprDigest(algorithms ...Algorithm) p.Plugin {
+	return digestPlugin(ReprDigestHeader, algorithms)
+}
+
+// Compute returns the RFC 9530 digest field value of the given data
+// using the given algorithms, defaulting to sha-256.
+func Compute(data []byte, algorithms ...Algorithm) (string, error) {
+	return compute(bytes.NewReader(data), algorithms)
+}
+
+// digestPlugin creates the plugin defining the request body digest in the given header.
+func digestPlugin(header string, algorithms []Algorithm) p.Plugin {
+	// Uses the "before dial" phase in order to hash the final request body,
+	// once the request phase had the chance to define it.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		body, err := utils.ReplayRequestBody(ctx.Request)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		value, err := compute(bytes.NewReader(body), algorithms)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		ctx.Request.Header.Set(header, value)
+		h.Next(ctx)
+	})
+}
+
+// compute returns the digest field value of the data read from the given reader.
+func compute(reader io.Reader, algorithms []Algorithm) (string, error) {
+	if len(algorithms) == 0 {
+		algorithms = []Algorithm{SHA256}
+	}
+
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		h, err := newHash(algorithm)
+		if err != nil {
+			return "", err
+		}
+		hashes[i], writers[i] = h, h
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), reader); err != nil {
+		return "", err
+	}
+
+	fields := make([]string, len(algorithms))
+	for i, algorithm := range algorithms {
+		fields[i] = string(algorithm) + "=:" + base64.StdEncoding.EncodeToString(hashes[i].Sum(nil)) + ":"
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+// newHash returns a new hash of the given algorithm.
+func newHash(algorithm Algorithm) (hash.Hash, error) {
+	switch algorithm {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
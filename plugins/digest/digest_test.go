@@ -0,0 +1,80 @@
+package digest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/body"
+)
+
+func TestCompute(t *testing.T) {
+	data := []byte(`{"hello": "world"}`)
+	value, err := Compute(data)
+	st.Expect(t, err, nil)
+	st.Expect(t, value, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:")
+
+	value, err = Compute(data, SHA512, SHA256)
+	st.Expect(t, err, nil)
+	st.Expect(t, value, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:, "+
+		"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:")
+
+	_, err = Compute(data, "md5")
+	st.Expect(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestContentDigest(t *testing.T) {
+	var digest, received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		digest, received = r.Header.Get("Content-Digest"), string(data)
+	}))
+	defer ts.Close()
+
+	req := gentleman.NewRequest().URL(ts.URL).Method("POST")
+	req.Use(body.String(`{"hello": "world"}`))
+	req.Use(ContentDigest())
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, digest, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:")
+	st.Expect(t, received, `{"hello": "world"}`)
+
+	// The body can be sent again
+	replay, err := res.RawRequest.GetBody()
+	st.Expect(t, err, nil)
+	data, _ := ioutil.ReadAll(replay)
+	st.Expect(t, string(data), `{"hello": "world"}`)
+}
+
+func TestReprDigestEmptyBody(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	ReprDigest(SHA256).Exec("before dial", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header.Get("Repr-Digest"), "sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:")
+}
+
+func TestDigestInvalidAlgorithm(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	ContentDigest("crc32").Exec("before dial", ctx, fn.fn)
+	st.Expect(t, ctx.Error, ErrUnsupportedAlgorithm)
+}
+
+type handler struct {
+	fn     context.Handler
+	called bool
+}
+
+func newHandler() *handler {
+	h := &handler{}
+	h.fn = context.NewHandler(func(c *context.Context) {
+		h.called = true
+	})
+	return h
+}
@@ -0,0 +1,154 @@
+package digest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+var (
+	// ErrMismatch is returned when the response body does not match the expected digest.
+	ErrMismatch = errors.New("digest: response body digest mismatch")
+
+	// ErrMissingDigest is returned when a required response digest is not available.
+	ErrMissingDigest = errors.New("digest: missing response digest")
+
+	// ErrInvalidChecksum is returned when the expected checksum is not a valid hex string.
+	ErrInvalidChecksum = errors.New("digest: invalid checksum")
+)
+
+// verifierKey is the context store key used to store the response body verifier.
+type verifierKey struct{}
+
+// VerifyOptions represents the response body digest verification options.
+type VerifyOptions struct {
+	// Algorithm defines the algorithm of the expected checksum.
+	Algorithm Algorithm
+
+	// Checksum defines the expected response body checksum, such as the
+	// published checksum of a downloaded artifact, instead of the Content-Digest header.
+	Checksum []byte
+
+	// Required fails the requests whose response digest is not available.
+	// By default, responses without a supported Content-Digest are not verified.
+	Required bool
+}
+
+// Verify creates a new plugin which verifies the response body digest against
+// the expected checksum, if defined, or the Content-Digest header otherwise,
+// preferring sha-512 over sha-256.
+//
+// The digest is computed while the body is read, with no buffering: once the body is fully
+// read, a mismatch is returned as the body read error and reported via the error phase.
+// Bodies closed before being fully read are not verified. Responses transparently
+// decompressed by net/http cannot be verified against the Content-Digest header.
+func Verify(opts VerifyOptions) p.Plugin {
+	handlers := p.Handlers{
+		"response": func(ctx *c.Context, h c.Handler) {
+			res := ctx.Response
+			if res.Body == nil || res.Body == http.NoBody || ctx.Request.Method == http.MethodHead ||
+				res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusNotModified {
+				h.Next(ctx)
+				return
+			}
+
+			algorithm, expected := opts.Algorithm, opts.Checksum
+			if expected == nil && !res.Uncompressed {
+				algorithm, expected = parseDigest(res.Header.Get(ContentDigestHeader))
+			}
+			if expected == nil {
+				if opts.Required {
+					h.Error(ctx, ErrMissingDigest)
+					return
+				}
+				h.Next(ctx)
+				return
+			}
+
+			hasher, err := newHash(algorithm)
+			if err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			v := &verifier{ReadCloser: res.Body, hash: hasher, expected: expected}
+			ctx.Set(verifierKey{}, v)
+			res.Body = v
+			h.Next(ctx)
+		},
+		"after body": func(ctx *c.Context, h c.Handler) {
+			if v, ok := ctx.Get(verifierKey{}).(*verifier); ok && v.mismatch {
+				h.Error(ctx, ErrMismatch)
+				return
+			}
+			h.Next(ctx)
+		},
+	}
+	return &p.Layer{Handlers: handlers}
+}
+
+// VerifyChecksum creates a new plugin which verifies the response body against the given
+// hex-encoded checksum, such as the published sha-256 checksum of an artifact.
+// See Verify() for details.
+func VerifyChecksum(algorithm Algorithm, checksum string) p.Plugin {
+	sum, err := hex.DecodeString(checksum)
+	if err != nil || len(sum) == 0 {
+		return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+			h.Error(ctx, ErrInvalidChecksum)
+		})
+	}
+	return Verify(VerifyOptions{Algorithm: algorithm, Checksum: sum, Required: true})
+}
+
+// parseDigest returns the strongest supported algorithm digest of the given digest field value.
+func parseDigest(value string) (Algorithm, []byte) {
+	var algorithm Algorithm
+	var sum []byte
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		i := strings.IndexByte(member, '=')
+		if i <= 0 {
+			continue
+		}
+		key, raw := Algorithm(strings.ToLower(member[:i])), member[i+1:]
+		if key != SHA256 && key != SHA512 || algorithm == SHA512 {
+			continue
+		}
+		if len(raw) < 2 || raw[0] != ':' || raw[len(raw)-1] != ':' {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(raw[1 : len(raw)-1])
+		if err != nil {
+			continue
+		}
+		algorithm, sum = key, decoded
+	}
+	return algorithm, sum
+}
+
+// verifier wraps the response body computing its digest while read.
+type verifier struct {
+	io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+	mismatch bool
+}
+
+// Read reads from the response body, returning ErrMismatch instead of io.EOF
+// if the body does not match the expected digest.
+func (v *verifier) Read(buf []byte) (int, error) {
+	n, err := v.ReadCloser.Read(buf)
+	v.hash.Write(buf[:n])
+	if err == io.EOF && !bytes.Equal(v.hash.Sum(nil), v.expected) {
+		v.mismatch = true
+		return n, ErrMismatch
+	}
+	return n, err
+}
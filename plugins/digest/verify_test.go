@@ -0,0 +1,87 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestVerify(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `{"hello": "world"}`
+		digest, _ := Compute([]byte(body), SHA256, SHA512)
+		switch r.URL.Path {
+		case "/tampered":
+			body = `{"hello": "w0rld"}`
+		case "/missing":
+			digest = ""
+		}
+		if digest != "" {
+			w.Header().Set("Content-Digest", digest)
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(Verify(VerifyOptions{}))
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `{"hello": "world"}`)
+	st.Expect(t, res.Error, nil)
+
+	res, err = cli.Request().Path("/tampered").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `{"hello": "w0rld"}`)
+	st.Expect(t, res.Error, ErrMismatch)
+
+	// The mismatch is reported via the error phase
+	var reported error
+	res, err = cli.Request().Path("/tampered").UseError(func(ctx *context.Context, h context.Handler) {
+		reported = ctx.Error
+		h.Next(ctx)
+	}).Send()
+	st.Expect(t, err, nil)
+	_, err = io.Copy(ioutil.Discard, res.RawResponse.Body)
+	st.Expect(t, err, ErrMismatch)
+	st.Expect(t, res.RawResponse.Body.Close(), ErrMismatch)
+	st.Expect(t, reported, ErrMismatch)
+
+	res, err = cli.Request().Path("/missing").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `{"hello": "world"}`)
+
+	_, err = cli.Request().Path("/missing").Use(Verify(VerifyOptions{Required: true})).Send()
+	st.Expect(t, err, ErrMissingDigest)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("artifact"))
+	}))
+	defer ts.Close()
+
+	sum := sha256.Sum256([]byte("artifact"))
+	res, err := gentleman.NewRequest().URL(ts.URL).Use(VerifyChecksum(SHA256, hex.EncodeToString(sum[:]))).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "artifact")
+	st.Expect(t, res.Error, nil)
+
+	other := sha256.Sum256([]byte("other"))
+	res, err = gentleman.NewRequest().URL(ts.URL).Use(VerifyChecksum(SHA256, hex.EncodeToString(other[:]))).Send()
+	st.Expect(t, err, nil)
+	res.Bytes()
+	st.Expect(t, res.Error, ErrMismatch)
+
+	_, err = gentleman.NewRequest().URL(ts.URL).Use(VerifyChecksum(SHA256, "not hex")).Send()
+	st.Expect(t, err, ErrInvalidChecksum)
+}
@@ -0,0 +1,118 @@
+package headers
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
+)
+
+// PreserveCaseKey is the context store key used to enable the header case preservation mode.
+// See EnablePreserveCase().
+const PreserveCaseKey = "$preserveHeaderCase"
+
+// headerCase writes the exact header names on the wire.
+var headerCase = transport.HeaderCase()
+
+// EnablePreserveCase enables the header case preservation mode for the given context
+// or any context inheriting from it, in which the headers defined via Set(), Add(),
+// SetValues() and SetMap() are sent with the exact given name casing instead of the
+// canonical one, such as "x-api-KEY", replacing any header with the same case-insensitive name.
+//
+// The header fields keep their canonical name, so they are still found via http.Header.Get(),
+// while the exact names are stored in the context under transport.HeaderCaseKey and written
+// on the wire by the transport.HeaderCase() plugin, which PreserveCase() registers too.
+// Only HTTP/1.x requests preserve the name casing: HTTP/2 always sends lower-cased names.
+func EnablePreserveCase(ctx *c.Context) {
+	ctx.Set(PreserveCaseKey, true)
+}
+
+// PreserveCase creates a new plugin enabling the header case preservation mode
+// for the outgoing request and writing the exact header names on the wire.
+// See EnablePreserveCase().
+func PreserveCase() p.Plugin {
+	return &p.Layer{Handlers: p.Handlers{
+		"request": func(ctx *c.Context, h c.Handler) {
+			EnablePreserveCase(ctx)
+			h.Next(ctx)
+		},
+		"before dial": writeCase,
+	}}
+}
+
+// SetExact sets the header entries associated with the exact given key casing
+// to the single element value, regardless of the header case preservation mode.
+// It replaces any existing values associated with the case-insensitive key.
+func SetExact(key, value string) p.Plugin {
+	return &p.Layer{Handlers: p.Handlers{
+		"request": func(ctx *c.Context, h c.Handler) {
+			setExact(ctx, key, value)
+			h.Next(ctx)
+		},
+		"before dial": writeCase,
+	}}
+}
+
+// writeCase writes the exact header names of the outgoing request on the wire.
+func writeCase(ctx *c.Context, h c.Handler) {
+	headerCase.Exec("before dial", ctx, h)
+}
+
+// preserveCase returns true if the header case preservation mode is enabled for the given context.
+func preserveCase(ctx *c.Context) bool {
+	enabled, _ := ctx.Get(PreserveCaseKey).(bool)
+	return enabled
+}
+
+// setExact replaces the values of the case-insensitive key with the given value,
+// sent with the exact key casing.
+func setExact(ctx *c.Context, key, value string) {
+	delFold(ctx.Request.Header, key)
+	ctx.Request.Header.Set(key, value)
+	setCase(ctx, key)
+}
+
+// addExact appends the given value to the values of the case-insensitive key,
+// sent with the exact key casing.
+func addExact(ctx *c.Context, key, value string) {
+	values := delFold(ctx.Request.Header, key)
+	ctx.Request.Header[textproto.CanonicalMIMEHeaderKey(key)] = append(values, value)
+	setCase(ctx, key)
+}
+
+// setCase stores the exact casing of the given header name in the context,
+// copying the stored names, since they can be shared with other contexts.
+func setCase(ctx *c.Context, key string) {
+	canonical := textproto.CanonicalMIMEHeaderKey(key)
+	names, _ := ctx.Get(transport.HeaderCaseKey).(map[string]string)
+	if exact, ok := names[canonical]; exact == key || (!ok && canonical == key) {
+		return
+	}
+
+	updated := make(map[string]string, len(names)+1)
+	for name, exact := range names {
+		updated[name] = exact
+	}
+	if canonical == key {
+		delete(updated, canonical)
+	} else {
+		updated[canonical] = key
+	}
+	ctx.Set(transport.HeaderCaseKey, updated)
+}
+
+// delFold deletes the header fields matching the case-insensitive key,
+// returning their values.
+func delFold(header http.Header, key string) []string {
+	var values []string
+	for name, v := range header {
+		if strings.EqualFold(name, key) {
+			values = append(values, v...)
+			delete(header, name)
+		}
+	}
+	return values
+}
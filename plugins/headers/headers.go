@@ -1,41 +1,84 @@
 package headers
 
 import (
+	"net/textproto"
+
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 )
 
 // Set sets the header entries associated with key to the single element value.
 // It replaces any existing values associated with key.
+// The key casing is preserved if enabled via EnablePreserveCase().
 func Set(key, value string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		ctx.Request.Header.Set(key, value)
+		if preserveCase(ctx) {
+			setExact(ctx, key, value)
+		} else {
+			ctx.Request.Header.Set(key, value)
+		}
 		h.Next(ctx)
 	})
 }
 
 // Add adds the key, value pair to the header.
 // It appends to any existing values associated with key.
+// The key casing is preserved if enabled via EnablePreserveCase().
 func Add(key, value string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		ctx.Request.Header.Add(key, value)
+		if preserveCase(ctx) {
+			addExact(ctx, key, value)
+		} else {
+			ctx.Request.Header.Add(key, value)
+		}
+		h.Next(ctx)
+	})
+}
+
+// SetValues sets the header entries associated with key to the given values,
+// sent as multiple field lines. It replaces any existing values associated with key.
+// The key casing is preserved if enabled via EnablePreserveCase().
+func SetValues(key string, values ...string) p.Plugin {
+	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		name := textproto.CanonicalMIMEHeaderKey(key)
+		if preserveCase(ctx) {
+			delFold(ctx.Request.Header, key)
+			setCase(ctx, key)
+		} else {
+			ctx.Request.Header.Del(name)
+		}
+		if len(values) > 0 {
+			ctx.Request.Header[name] = append([]string(nil), values...)
+		}
 		h.Next(ctx)
 	})
 }
 
 // Del deletes the header fields associated with key.
+// Any case variant of key is deleted if the case preservation mode is enabled.
 func Del(key string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		ctx.Request.Header.Del(key)
+		if preserveCase(ctx) {
+			delFold(ctx.Request.Header, key)
+			setCase(ctx, textproto.CanonicalMIMEHeaderKey(key))
+		} else {
+			ctx.Request.Header.Del(key)
+		}
 		h.Next(ctx)
 	})
 }
 
 // SetMap sets a map of headers represented by key-value pair.
+// The keys casing is preserved if enabled via EnablePreserveCase().
 func SetMap(headers map[string]string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		exact := preserveCase(ctx)
 		for k, v := range headers {
-			ctx.Request.Header.Set(k, v)
+			if exact {
+				setExact(ctx, k, v)
+			} else {
+				ctx.Request.Header.Set(k, v)
+			}
 		}
 		h.Next(ctx)
 	})
@@ -1,9 +1,15 @@
 package headers
 
 import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/textproto"
+	"testing"
+
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
-	"testing"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 )
 
 func TestHeaderSet(t *testing.T) {
@@ -46,6 +52,125 @@ func TestHeaderSetMap(t *testing.T) {
 	st.Expect(t, ctx.Request.Header.Get("foo"), "bar")
 }
 
+func TestHeaderSetValues(t *testing.T) {
+	ctx := context.New()
+	ctx.Request.Header.Set("foo", "foo")
+	fn := newHandler()
+
+	SetValues("foo", "bar", "baz").Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header["Foo"], []string{"bar", "baz"})
+
+	SetValues("foo").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Request.Header["Foo"], []string(nil))
+}
+
+func TestHeaderValues(t *testing.T) {
+	header := http.Header{
+		"Accept":     {"text/html, application/json;q=0.9", "*/*;q=0.1"},
+		"Warning":    {`199 - "a, b", 299 - "c"`},
+		"Set-Cookie": {"a=b; Expires=Wed, 21 Oct 2015 07:28:00 GMT"},
+	}
+	st.Expect(t, Values(header, "accept"), []string{"text/html", "application/json;q=0.9", "*/*;q=0.1"})
+	st.Expect(t, Values(header, "Warning"), []string{`199 - "a, b"`, `299 - "c"`})
+	st.Expect(t, Values(header, "Set-Cookie"), []string{"a=b; Expires=Wed, 21 Oct 2015 07:28:00 GMT"})
+	st.Expect(t, Values(header, "Link"), []string(nil))
+}
+
+func TestHeaderPreserveCase(t *testing.T) {
+	ctx := context.New()
+	ctx.Request.Header.Set("X-Api-Key", "foo")
+	ctx.Request.Header.Set("Accept", "*/*")
+	EnablePreserveCase(ctx)
+	fn := newHandler()
+
+	Set("x-api-KEY", "bar").Exec("request", ctx, fn.fn)
+	Add("accept", "text/html").Exec("request", ctx, fn.fn)
+	SetMap(map[string]string{"X-TOKEN": "baz"}).Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header, http.Header{
+		"X-Api-Key": {"bar"},
+		"Accept":    {"*/*", "text/html"},
+		"X-Token":   {"baz"},
+	})
+	st.Expect(t, ctx.Get(transport.HeaderCaseKey), map[string]string{
+		"X-Api-Key": "x-api-KEY",
+		"Accept":    "accept",
+		"X-Token":   "X-TOKEN",
+	})
+
+	Del("X-Api-Key").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Request.Header.Get("X-Api-Key"), "")
+	st.Expect(t, ctx.Get(transport.HeaderCaseKey), map[string]string{"Accept": "accept", "X-Token": "X-TOKEN"})
+}
+
+func TestHeaderPreserveCasePlugin(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+
+	Set("x-foo", "bar").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Request.Header["X-Foo"], []string{"bar"})
+
+	PreserveCase().Exec("request", ctx, fn.fn)
+	Set("x-foo", "baz").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Request.Header["X-Foo"], []string{"baz"})
+	st.Expect(t, ctx.Get(transport.HeaderCaseKey), map[string]string{"X-Foo": "x-foo"})
+}
+
+func TestHeaderSetExact(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	st.Expect(t, err, nil)
+	defer ln.Close()
+
+	names := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		reader.ReadLine()
+		var lines []string
+		for {
+			line, err := reader.ReadLine()
+			if err != nil || line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n"))
+		names <- lines
+	}()
+
+	ctx := context.New()
+	ctx.Request.URL.Scheme = "http"
+	ctx.Request.URL.Host = ln.Addr().String()
+	fn := newHandler()
+
+	plugin := SetExact("x-legacy-TOKEN", "foo")
+	plugin.Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header.Get("X-Legacy-Token"), "foo")
+	plugin.Exec("before dial", ctx, fn.fn)
+	res, err := ctx.Client.Do(ctx.Request)
+	st.Expect(t, err, nil)
+	res.Body.Close()
+	st.Expect(t, res.StatusCode, 204)
+
+	lines := <-names
+	st.Expect(t, contains(lines, "x-legacy-TOKEN: foo"), true)
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
 type handler struct {
 	fn     context.Handler
 	called bool
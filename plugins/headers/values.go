@@ -0,0 +1,73 @@
+package headers
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// singleValued stores the headers whose values contain commas, such as HTTP dates,
+// and therefore are never split as comma-separated lists.
+var singleValued = map[string]bool{
+	"Date":                true,
+	"Expires":             true,
+	"If-Modified-Since":   true,
+	"If-Range":            true,
+	"If-Unmodified-Since": true,
+	"Last-Modified":       true,
+	"Retry-After":         true,
+	"Set-Cookie":          true,
+}
+
+// Values returns all the values of the given header name, splitting the comma-separated
+// lists of every field line, such as the multiple links of a Link header.
+// Commas within quoted strings or URI references enclosed by angle brackets are preserved.
+// The values of headers which cannot be comma-separated lists, such as Set-Cookie
+// or HTTP dates, are returned as is.
+func Values(header http.Header, name string) []string {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	lines := header[name]
+	if len(lines) == 0 {
+		return nil
+	}
+	if singleValued[name] {
+		return append([]string(nil), lines...)
+	}
+
+	var values []string
+	for _, line := range lines {
+		for _, value := range splitList(line) {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// splitList splits the given comma-separated list, ignoring the commas
+// within quoted strings and angle brackets.
+func splitList(line string) []string {
+	var values []string
+	quoted, bracketed, escaped := false, false, false
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch b := line[i]; {
+		case escaped:
+			escaped = false
+		case quoted && b == '\\':
+			escaped = true
+		case b == '"':
+			quoted = !quoted
+		case quoted:
+		case b == '<':
+			bracketed = true
+		case b == '>':
+			bracketed = false
+		case b == ',' && !bracketed:
+			values = append(values, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(values, line[start:])
+}
@@ -0,0 +1,167 @@
+package identity
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+)
+
+const (
+	// GCPMetadataEndpoint defines the default GCP metadata server endpoint.
+	GCPMetadataEndpoint = "http://metadata.google.internal"
+
+	// AzureIMDSEndpoint defines the default Azure Instance Metadata Service token endpoint.
+	AzureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// Auth creates a new plugin which defines the Authorization header of the outgoing
+// requests based on the tokens returned by the given source, such a cloud workload
+// identity token source, cached and refreshed before they expire via auth.TokenManager.
+func Auth(source auth.TokenSource) p.Plugin {
+	return auth.Tokens(auth.NewTokenManager(source))
+}
+
+// GCPOptions represents the GCP metadata server token source options.
+type GCPOptions struct {
+	// Endpoint defines the metadata server endpoint. Defaults to the GCE_METADATA_HOST
+	// environment variable, if defined, or GCPMetadataEndpoint otherwise.
+	Endpoint string
+
+	// ServiceAccount defines the service account email or alias. Defaults to "default".
+	ServiceAccount string
+
+	// Client defines the client used to reach the metadata server. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// GCPIDToken returns a token source of the Google-signed ID tokens of the given audience,
+// such as the URL of a Cloud Run service, issued by the GCP metadata server.
+// The token expiration is based on the JWT exp claim.
+func GCPIDToken(audience string, opts GCPOptions) auth.TokenSource {
+	return auth.TokenSourceFunc(func() (*auth.Token, error) {
+		res, err := opts.request("identity").AddQuery("audience", audience).AddQuery("format", "full").Send()
+		if err != nil {
+			return nil, err
+		}
+		return &auth.Token{AccessToken: strings.TrimSpace(res.String())}, nil
+	})
+}
+
+// GCPAccessToken returns a token source of the OAuth2 access tokens of the given scopes,
+// issued by the GCP metadata server. Defaults to the service account scopes.
+func GCPAccessToken(opts GCPOptions, scopes ...string) auth.TokenSource {
+	return auth.TokenSourceFunc(func() (*auth.Token, error) {
+		req := opts.request("token")
+		if len(scopes) > 0 {
+			req.AddQuery("scopes", strings.Join(scopes, ","))
+		}
+		res, err := req.Send()
+		if err != nil {
+			return nil, err
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int64  `json:"expires_in"`
+			TokenType   string `json:"token_type"`
+		}
+		if err := res.JSON(&token); err != nil {
+			return nil, err
+		}
+		return &auth.Token{
+			AccessToken: token.AccessToken,
+			Type:        token.TokenType,
+			Expiry:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+		}, nil
+	})
+}
+
+// request creates a new metadata server request of the given service account resource.
+func (opts GCPOptions) request(resource string) *gentleman.Request {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = GCPMetadataEndpoint
+		if host := os.Getenv("GCE_METADATA_HOST"); host != "" {
+			endpoint = "http://" + host
+		}
+	}
+	account := opts.ServiceAccount
+	if account == "" {
+		account = "default"
+	}
+
+	req := newRequest(opts.Client).URL(strings.TrimSuffix(endpoint, "/") +
+		"/computeMetadata/v1/instance/service-accounts/" + account + "/" + resource)
+	req.SetHeader("Metadata-Flavor", "Google")
+	return req
+}
+
+// AzureOptions represents the Azure managed identity token source options.
+type AzureOptions struct {
+	// Endpoint defines the token endpoint. Defaults to the App Service and Functions
+	// IDENTITY_ENDPOINT environment variable, if defined, or AzureIMDSEndpoint otherwise.
+	Endpoint string
+
+	// ClientID defines the client ID of the user-assigned managed identity, if any.
+	ClientID string
+
+	// Client defines the client used to reach the token endpoint. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// AzureToken returns a token source of the managed identity access tokens of the given
+// resource, such as "https://management.azure.com/", issued by the Azure Instance Metadata
+// Service or, in App Service and Functions, by the local identity endpoint.
+func AzureToken(resource string, opts AzureOptions) auth.TokenSource {
+	return auth.TokenSourceFunc(func() (*auth.Token, error) {
+		endpoint, version := opts.Endpoint, "2018-02-01"
+		identityHeader := os.Getenv("IDENTITY_HEADER")
+		if endpoint == "" {
+			endpoint = AzureIMDSEndpoint
+			if env := os.Getenv("IDENTITY_ENDPOINT"); env != "" && identityHeader != "" {
+				endpoint, version = env, "2019-08-01"
+			}
+		}
+
+		req := newRequest(opts.Client).URL(endpoint)
+		req.AddQuery("api-version", version).AddQuery("resource", resource)
+		if opts.ClientID != "" {
+			req.AddQuery("client_id", opts.ClientID)
+		}
+		req.SetHeader("Metadata", "true")
+		if version != "2018-02-01" {
+			req.SetHeader("X-IDENTITY-HEADER", identityHeader)
+		}
+		res, err := req.Send()
+		if err != nil {
+			return nil, err
+		}
+
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresOn   string `json:"expires_on"`
+			TokenType   string `json:"token_type"`
+		}
+		if err := res.JSON(&token); err != nil {
+			return nil, err
+		}
+		result := &auth.Token{AccessToken: token.AccessToken, Type: token.TokenType}
+		if expires, err := strconv.ParseInt(token.ExpiresOn, 10, 64); err == nil {
+			result.Expiry = time.Unix(expires, 0)
+		}
+		return result, nil
+	})
+}
+
+// newRequest creates a new token endpoint request failing on error responses.
+func newRequest(cli *gentleman.Client) *gentleman.Request {
+	if cli == nil {
+		cli = gentleman.New()
+	}
+	return gentleman.New().UseParent(cli).FailOnError().Request()
+}
@@ -0,0 +1,113 @@
+package identity
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+func TestGCPIDToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	jwt := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":`+strconv.FormatInt(exp, 10)+`}`)) + ".sig"
+
+	var calls int
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Metadata-Flavor") != "Google" ||
+			r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/identity" {
+			w.WriteHeader(404)
+			return
+		}
+		st.Expect(t, r.URL.Query().Get("audience"), "https://service.run.app")
+		st.Expect(t, r.URL.Query().Get("format"), "full")
+		w.Write([]byte(jwt))
+	}))
+	defer metadata.Close()
+
+	var authorization string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+
+	cli := gentleman.New().URL(api.URL)
+	cli.Use(Auth(GCPIDToken("https://service.run.app", GCPOptions{Endpoint: metadata.URL})))
+	for i := 0; i < 2; i++ {
+		_, err := cli.Request().Send()
+		st.Expect(t, err, nil)
+		st.Expect(t, authorization, "Bearer "+jwt)
+	}
+	st.Expect(t, calls, 1)
+}
+
+func TestGCPAccessToken(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.Expect(t, r.URL.Path, "/computeMetadata/v1/instance/service-accounts/robot@project.iam.gserviceaccount.com/token")
+		st.Expect(t, r.URL.Query().Get("scopes"), "https://www.googleapis.com/auth/cloud-platform,openid")
+		w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer metadata.Close()
+
+	source := GCPAccessToken(GCPOptions{Endpoint: metadata.URL, ServiceAccount: "robot@project.iam.gserviceaccount.com"},
+		"https://www.googleapis.com/auth/cloud-platform", "openid")
+	token, err := source.Token()
+	st.Expect(t, err, nil)
+	st.Expect(t, token.AccessToken, "ya29.token")
+	st.Expect(t, token.Type, "Bearer")
+	st.Expect(t, token.Expiry.After(time.Now().Add(59*time.Minute)), true)
+}
+
+func TestAzureToken(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(400)
+			return
+		}
+		query := r.URL.Query()
+		st.Expect(t, query.Get("api-version"), "2018-02-01")
+		st.Expect(t, query.Get("resource"), "https://management.azure.com/")
+		st.Expect(t, query.Get("client_id"), "client")
+		w.Write([]byte(`{"access_token":"eyJ0eXAi","expires_on":"` + strconv.FormatInt(expires, 10) + `","token_type":"Bearer"}`))
+	}))
+	defer imds.Close()
+
+	token, err := AzureToken("https://management.azure.com/", AzureOptions{Endpoint: imds.URL, ClientID: "client"}).Token()
+	st.Expect(t, err, nil)
+	st.Expect(t, token.AccessToken, "eyJ0eXAi")
+	st.Expect(t, token.Expiry.Unix(), expires)
+}
+
+func TestAzureAppServiceToken(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-IDENTITY-HEADER") != "secret" {
+			w.WriteHeader(401)
+			return
+		}
+		st.Expect(t, r.URL.Query().Get("api-version"), "2019-08-01")
+		w.Write([]byte(`{"access_token":"app-token","expires_on":"1700000000","token_type":"Bearer"}`))
+	}))
+	defer endpoint.Close()
+
+	os.Setenv("IDENTITY_ENDPOINT", endpoint.URL)
+	os.Setenv("IDENTITY_HEADER", "secret")
+	defer os.Unsetenv("IDENTITY_ENDPOINT")
+	defer os.Unsetenv("IDENTITY_HEADER")
+
+	token, err := AzureToken("https://vault.azure.net", AzureOptions{}).Token()
+	st.Expect(t, err, nil)
+	st.Expect(t, token.AccessToken, "app-token")
+
+	os.Setenv("IDENTITY_HEADER", "invalid")
+	_, err = AzureToken("https://vault.azure.net", AzureOptions{}).Token()
+	httpErr, ok := err.(*gentleman.HTTPError)
+	st.Expect(t, ok, true)
+	st.Expect(t, httpErr.StatusCode, 401)
+}
@@ -0,0 +1,375 @@
+package jws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Algorithm represents a JWS signature algorithm.
+type Algorithm string
+
+const (
+	// RS256 represents the RSASSA-PKCS1-v1_5 using SHA-256 algorithm.
+	RS256 Algorithm = "RS256"
+
+	// PS256 represents the RSASSA-PSS using SHA-256 algorithm.
+	PS256 Algorithm = "PS256"
+
+	// ES256 represents the ECDSA using P-256 and SHA-256 algorithm.
+	ES256 Algorithm = "ES256"
+
+	// ES384 represents the ECDSA using P-384 and SHA-384 algorithm.
+	ES384 Algorithm = "ES384"
+
+	// EdDSA represents the Ed25519 signature algorithm.
+	EdDSA Algorithm = "EdDSA"
+)
+
+// Serialization represents a JWS serialization format.
+type Serialization int
+
+const (
+	// Compact represents the JWS compact serialization, such as "header.payload.signature".
+	Compact Serialization = iota
+
+	// JSON represents the flattened JWS JSON serialization.
+	JSON
+
+	// Detached represents the compact serialization with a detached payload,
+	// such as "header..signature", which is sent along with the original content.
+	Detached
+)
+
+var (
+	// ErrInvalidKey is returned when the key type does not match the signature algorithm.
+	ErrInvalidKey = errors.New("jws: invalid key for the signature algorithm")
+
+	// ErrUnsupportedAlgorithm is returned when using an unknown signature algorithm.
+	ErrUnsupportedAlgorithm = errors.New("jws: unsupported algorithm")
+
+	// ErrInvalidSignature is returned when the JWS is malformed or the signature does not verify.
+	ErrInvalidSignature = errors.New("jws: invalid signature")
+
+	// ErrMissingSignature is returned when a required response signature is missing.
+	ErrMissingSignature = errors.New("jws: missing signature")
+)
+
+// Key represents a private key used to sign the request bodies.
+type Key struct {
+	// ID defines the key identifier sent in the kid header parameter, if any.
+	ID string
+
+	// Algorithm defines the signature algorithm.
+	Algorithm Algorithm
+
+	// Signer stores the private key, such as *rsa.PrivateKey, *ecdsa.PrivateKey
+	// or ed25519.PrivateKey, or any other crypto.Signer using them.
+	Signer crypto.Signer
+}
+
+// PublicKey represents a public key used to verify the response signatures.
+type PublicKey struct {
+	// ID defines the key identifier matched against the kid header parameter, if any.
+	ID string
+
+	// Algorithm defines the expected signature algorithm.
+	Algorithm Algorithm
+
+	// Key stores the public key, such as *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Key crypto.PublicKey
+}
+
+// Options represents the JWS signing options.
+type Options struct {
+	// Serialization defines the JWS serialization format. Defaults to Compact.
+	Serialization Serialization
+
+	// Header defines additional protected header parameters, such as "typ", "iat" or "crit"
+	// parameters required by the target API.
+	Header map[string]interface{}
+
+	// Unencoded enables the RFC 7797 unencoded payload option (b64=false),
+	// commonly required for detached signatures.
+	Unencoded bool
+}
+
+// Encode signs the given payload with the given key, returning the JWS in the given serialization.
+func Encode(payload []byte, key Key, opts Options) ([]byte, error) {
+	header := map[string]interface{}{}
+	for name, value := range opts.Header {
+		header[name] = value
+	}
+	header["alg"] = string(key.Algorithm)
+	if key.ID != "" {
+		header["kid"] = key.ID
+	}
+	if opts.Unencoded {
+		header["b64"] = false
+		header["crit"] = appendCrit(header["crit"], "b64")
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(data)
+	encoded := string(payload)
+	if !opts.Unencoded {
+		encoded = base64.RawURLEncoding.EncodeToString(payload)
+	}
+
+	sig, err := signBytes(key, []byte(protected+"."+encoded))
+	if err != nil {
+		return nil, err
+	}
+	signature := base64.RawURLEncoding.EncodeToString(sig)
+
+	switch opts.Serialization {
+	case JSON:
+		return json.Marshal(map[string]string{"protected": protected, "payload": encoded, "signature": signature})
+	case Detached:
+		return []byte(protected + ".." + signature), nil
+	}
+	return []byte(protected + "." + encoded + "." + signature), nil
+}
+
+// Decode verifies the given compact or JSON serialized JWS with the given key, returning
+// the signed payload. The detached payload must be given for detached signatures.
+func Decode(data, detached []byte, key PublicKey) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		return decodeJSON(data, detached, key)
+	}
+
+	parts := strings.Split(string(data), ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidSignature
+	}
+	return verify(parts[0], parts[1], parts[2], detached, key)
+}
+
+// decodeJSON verifies the given flattened or general JWS JSON serialization,
+// succeeding if any signature verifies.
+func decodeJSON(data, detached []byte, key PublicKey) ([]byte, error) {
+	type signature struct {
+		Protected string `json:"protected"`
+		Signature string `json:"signature"`
+	}
+	var jws struct {
+		signature
+		Payload    *string     `json:"payload"`
+		Signatures []signature `json:"signatures"`
+	}
+	if err := json.Unmarshal(data, &jws); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	payload := ""
+	if jws.Payload != nil {
+		payload = *jws.Payload
+	}
+	signatures := jws.Signatures
+	if len(signatures) == 0 {
+		signatures = []signature{jws.signature}
+	}
+
+	err := ErrInvalidSignature
+	for _, sig := range signatures {
+		var decoded []byte
+		if decoded, err = verify(sig.Protected, payload, sig.Signature, detached, key); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, err
+}
+
+// verify verifies the given serialized JWS parts, returning the decoded payload.
+func verify(protected, payload, signature string, detached []byte, key PublicKey) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var header struct {
+		Alg  string   `json:"alg"`
+		Kid  string   `json:"kid"`
+		B64  *bool    `json:"b64"`
+		Crit []string `json:"crit"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	// Prevent algorithm confusion and unknown critical extensions
+	if header.Alg != string(key.Algorithm) || key.ID != "" && header.Kid != "" && header.Kid != key.ID {
+		return nil, ErrInvalidSignature
+	}
+	for _, crit := range header.Crit {
+		if crit != "b64" {
+			return nil, ErrInvalidSignature
+		}
+	}
+	encoded := header.B64 == nil || *header.B64
+
+	var decoded []byte
+	switch {
+	case payload == "" && detached != nil:
+		decoded = detached
+		if encoded {
+			payload = base64.RawURLEncoding.EncodeToString(detached)
+		} else {
+			payload = string(detached)
+		}
+	case encoded:
+		if decoded, err = base64.RawURLEncoding.DecodeString(payload); err != nil {
+			return nil, ErrInvalidSignature
+		}
+	default:
+		decoded = []byte(payload)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if err := verifyBytes(key, []byte(protected+"."+payload), sig); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// appendCrit appends the given name to the crit header parameter.
+func appendCrit(crit interface{}, name string) []string {
+	var names []string
+	switch value := crit.(type) {
+	case []string:
+		names = append(names, value...)
+	case []interface{}:
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// signBytes signs the given signing input with the given key.
+func signBytes(key Key, input []byte) ([]byte, error) {
+	if key.Signer == nil {
+		return nil, ErrInvalidKey
+	}
+	public := key.Signer.Public()
+
+	switch key.Algorithm {
+	case RS256, PS256:
+		if _, ok := public.(*rsa.PublicKey); !ok {
+			return nil, ErrInvalidKey
+		}
+		digest := sha256.Sum256(input)
+		var opts crypto.SignerOpts = crypto.SHA256
+		if key.Algorithm == PS256 {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+		return key.Signer.Sign(rand.Reader, digest[:], opts)
+	case ES256, ES384:
+		curve, hash, digest := ecdsaParams(key.Algorithm, input)
+		if pub, ok := public.(*ecdsa.PublicKey); !ok || pub.Curve != curve {
+			return nil, ErrInvalidKey
+		}
+		der, err := key.Signer.Sign(rand.Reader, digest, hash)
+		if err != nil {
+			return nil, err
+		}
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		raw := make([]byte, 2*size)
+		r, s := sig.R.Bytes(), sig.S.Bytes()
+		copy(raw[size-len(r):size], r)
+		copy(raw[2*size-len(s):], s)
+		return raw, nil
+	case EdDSA:
+		if _, ok := public.(ed25519.PublicKey); !ok {
+			return nil, ErrInvalidKey
+		}
+		return key.Signer.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// verifyBytes verifies the signature of the given signing input with the given public key.
+func verifyBytes(key PublicKey, input, sig []byte) error {
+	switch key.Algorithm {
+	case RS256, PS256:
+		pub, ok := key.Key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		digest := sha256.Sum256(input)
+		var err error
+		if key.Algorithm == PS256 {
+			err = rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig)
+		}
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case ES256, ES384:
+		curve, _, digest := ecdsaParams(key.Algorithm, input)
+		pub, ok := key.Key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != curve {
+			return ErrInvalidKey
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case EdDSA:
+		pub, ok := key.Key.(ed25519.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		if !ed25519.Verify(pub, input, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlgorithm
+}
+
+// ecdsaParams returns the curve, hash and digest of the given signing input for the ECDSA algorithms.
+func ecdsaParams(algorithm Algorithm, input []byte) (elliptic.Curve, crypto.Hash, []byte) {
+	if algorithm == ES384 {
+		digest := sha512.Sum384(input)
+		return elliptic.P384(), crypto.SHA384, digest[:]
+	}
+	digest := sha256.Sum256(input)
+	return elliptic.P256(), crypto.SHA256, digest[:]
+}
@@ -0,0 +1,200 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/body"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	signers := map[Algorithm]crypto.Signer{RS256: rsaKey, PS256: rsaKey, ES256: p256, ES384: p384, EdDSA: edKey}
+	payload := []byte(`{"amount":"10.00"}`)
+	for algorithm, signer := range signers {
+		key := Key{ID: "key-1", Algorithm: algorithm, Signer: signer}
+		public := PublicKey{ID: "key-1", Algorithm: algorithm, Key: signer.Public()}
+
+		for _, serialization := range []Serialization{Compact, JSON} {
+			jws, err := Encode(payload, key, Options{Serialization: serialization})
+			st.Expect(t, err, nil)
+			decoded, err := Decode(jws, nil, public)
+			st.Expect(t, err, nil)
+			st.Expect(t, string(decoded), string(payload))
+		}
+
+		jws, err := Encode(payload, key, Options{Serialization: Detached, Unencoded: true})
+		st.Expect(t, err, nil)
+		st.Expect(t, strings.Contains(string(jws), ".."), true)
+		decoded, err := Decode(jws, payload, public)
+		st.Expect(t, err, nil)
+		st.Expect(t, string(decoded), string(payload))
+
+		_, err = Decode(jws, []byte(`{"amount":"99.00"}`), public)
+		st.Expect(t, err, ErrInvalidSignature)
+	}
+}
+
+func TestEncodeHeader(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	key := Key{ID: "key-1", Algorithm: EdDSA, Signer: edKey}
+	jws, err := Encode([]byte("{}"), key, Options{
+		Serialization: Detached,
+		Unencoded:     true,
+		Header:        map[string]interface{}{"typ": "JOSE", "crit": []string{"b64"}},
+	})
+	st.Expect(t, err, nil)
+
+	data, _ := base64.RawURLEncoding.DecodeString(strings.Split(string(jws), ".")[0])
+	var header map[string]interface{}
+	st.Expect(t, json.Unmarshal(data, &header), nil)
+	st.Expect(t, header["alg"], "EdDSA")
+	st.Expect(t, header["kid"], "key-1")
+	st.Expect(t, header["typ"], "JOSE")
+	st.Expect(t, header["b64"], false)
+	st.Expect(t, header["crit"], []interface{}{"b64"})
+}
+
+func TestDecodeRejects(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key := Key{ID: "key-1", Algorithm: ES256, Signer: p256}
+	jws, err := Encode([]byte("{}"), key, Options{})
+	st.Expect(t, err, nil)
+
+	// Algorithm and key identifier mismatches
+	_, err = Decode(jws, nil, PublicKey{Algorithm: ES384, Key: p256.Public()})
+	st.Expect(t, err, ErrInvalidSignature)
+	_, err = Decode(jws, nil, PublicKey{ID: "key-2", Algorithm: ES256, Key: p256.Public()})
+	st.Expect(t, err, ErrInvalidSignature)
+
+	// Unknown critical extensions
+	jws, err = Encode([]byte("{}"), key, Options{Header: map[string]interface{}{"crit": []string{"exp"}, "exp": 1}})
+	st.Expect(t, err, nil)
+	_, err = Decode(jws, nil, PublicKey{Algorithm: ES256, Key: p256.Public()})
+	st.Expect(t, err, ErrInvalidSignature)
+
+	_, err = Decode([]byte("invalid"), nil, PublicKey{Algorithm: ES256, Key: p256.Public()})
+	st.Expect(t, err, ErrInvalidSignature)
+
+	_, err = Encode([]byte("{}"), Key{Algorithm: RS256, Signer: p256}, Options{})
+	st.Expect(t, err, ErrInvalidKey)
+	_, err = Encode([]byte("{}"), Key{Algorithm: "HS256", Signer: p256}, Options{})
+	st.Expect(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestSign(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key := Key{ID: "key-1", Algorithm: ES256, Signer: p256}
+	public := PublicKey{ID: "key-1", Algorithm: ES256, Key: p256.Public()}
+
+	var contentType, received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		contentType, received = r.Header.Get("Content-Type"), string(data)
+	}))
+	defer ts.Close()
+
+	req := gentleman.NewRequest().URL(ts.URL).Method("POST")
+	req.Use(body.String(`{"amount":"10.00"}`))
+	req.Use(Sign(key, SignOptions{Options: Options{Serialization: JSON}}))
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, contentType, "application/jose+json")
+	payload, err := Decode([]byte(received), nil, public)
+	st.Expect(t, err, nil)
+	st.Expect(t, string(payload), `{"amount":"10.00"}`)
+}
+
+func TestSignDetached(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key := Key{Algorithm: ES256, Signer: p256}
+	public := PublicKey{Algorithm: ES256, Key: p256.Public()}
+
+	var signature, received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		signature, received = r.Header.Get("X-JWS-Signature"), string(data)
+	}))
+	defer ts.Close()
+
+	req := gentleman.NewRequest().URL(ts.URL).Method("POST")
+	req.Use(body.String(`{"amount":"10.00"}`))
+	req.Use(Sign(key, SignOptions{Options: Options{Serialization: Detached, Unencoded: true}}))
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, received, `{"amount":"10.00"}`)
+	_, err = Decode([]byte(signature), []byte(received), public)
+	st.Expect(t, err, nil)
+}
+
+func TestVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	key := Key{Algorithm: EdDSA, Signer: edKey}
+	public := PublicKey{Algorithm: EdDSA, Key: edKey.Public()}
+
+	jws, _ := Encode([]byte(`{"status":"ACCP"}`), key, Options{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jose")
+		if r.URL.Path == "/tampered" {
+			w.Write([]byte(strings.Replace(string(jws), ".", ".e30", 1)))
+			return
+		}
+		w.Write(jws)
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL).Use(Verify(public, VerifyOptions{}))
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `{"status":"ACCP"}`)
+
+	_, err = cli.Request().Path("/tampered").Send()
+	st.Expect(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyDetached(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	key := Key{Algorithm: EdDSA, Signer: edKey}
+	public := PublicKey{Algorithm: EdDSA, Key: edKey.Public()}
+
+	payload := []byte(`{"status":"ACCP"}`)
+	jws, _ := Encode(payload, key, Options{Serialization: Detached})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/unsigned" {
+			w.Header().Set("X-JWS-Signature", string(jws))
+		}
+		w.Write(payload)
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	res, err := cli.Request().Use(Verify(public, VerifyOptions{Serialization: Detached})).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), string(payload))
+
+	res, err = cli.Request().Path("/unsigned").Use(Verify(public, VerifyOptions{Serialization: Detached})).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+
+	_, err = cli.Request().Path("/unsigned").Use(Verify(public, VerifyOptions{Serialization: Detached, Required: true})).Send()
+	st.Expect(t, err, ErrMissingSignature)
+}
@@ -0,0 +1,151 @@
+package jws
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// DefaultDetachedHeader stores the default header used to send and receive detached signatures.
+const DefaultDetachedHeader = "X-JWS-Signature"
+
+// SignOptions represents the request body signing options.
+type SignOptions struct {
+	Options
+
+	// DetachedHeader defines the header used to send detached signatures.
+	// Defaults to DefaultDetachedHeader.
+	DetachedHeader string
+}
+
+// VerifyOptions represents the response signature verification options.
+type VerifyOptions struct {
+	// Serialization defines the expected JWS serialization format: Detached
+	// responses are verified against the DetachedHeader, while Compact and JSON
+	// responses are verified against the response body. Defaults to Compact.
+	Serialization Serialization
+
+	// DetachedHeader defines the header used to receive detached signatures.
+	// Defaults to DefaultDetachedHeader.
+	DetachedHeader string
+
+	// Required fails the requests whose response is not signed.
+	// By default, responses without a detached signature or with an empty body are not verified.
+	Required bool
+}
+
+// Sign creates a new plugin which signs the request body as JWS with the given key.
+// Using the Compact and JSON serializations, the request body is replaced by the JWS,
+// with the application/jose and application/jose+json content types respectively.
+// Using the Detached serialization, the original body is sent as is and the
+// signature is defined in the detached header.
+func Sign(key Key, opts SignOptions) p.Plugin {
+	header := opts.DetachedHeader
+	if header == "" {
+		header = DefaultDetachedHeader
+	}
+
+	// Uses the "before dial" phase in order to sign the final request body,
+	// once the request phase had the chance to define it.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		payload, err := utils.ReplayRequestBody(ctx.Request)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		jws, err := Encode(payload, key, opts.Options)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		switch opts.Serialization {
+		case Detached:
+			ctx.Request.Header.Set(header, string(jws))
+		case JSON:
+			setBody(ctx.Request, jws, "application/jose+json")
+		default:
+			setBody(ctx.Request, jws, "application/jose")
+		}
+		h.Next(ctx)
+	})
+}
+
+// Verify creates a new plugin which verifies the JWS signed responses with the given key.
+// Attached JWS response bodies are replaced by the verified payload, so they can be
+// read and decoded as usual. Failed verifications are reported via the error phase.
+func Verify(key PublicKey, opts VerifyOptions) p.Plugin {
+	header := opts.DetachedHeader
+	if header == "" {
+		header = DefaultDetachedHeader
+	}
+
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		res := ctx.Response
+		body, err := utils.ReplayBody(res)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		if opts.Serialization == Detached {
+			signature := res.Header.Get(header)
+			if signature == "" {
+				if opts.Required {
+					h.Error(ctx, ErrMissingSignature)
+					return
+				}
+				h.Next(ctx)
+				return
+			}
+			if body == nil {
+				body = []byte{}
+			}
+			if _, err := Decode([]byte(signature), body, key); err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			h.Next(ctx)
+			return
+		}
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			if opts.Required {
+				h.Error(ctx, ErrMissingSignature)
+				return
+			}
+			h.Next(ctx)
+			return
+		}
+
+		payload, err := Decode(body, nil, key)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(payload))
+		res.ContentLength = int64(len(payload))
+		res.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+		h.Next(ctx)
+	})
+}
+
+// setBody replaces the request body with the given data,
+// defining GetBody so it can be sent again on retries and redirects.
+func setBody(req *http.Request, data []byte, contentType string) {
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(data))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+}
@@ -0,0 +1,355 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto"
+	// Register the supported checksum algorithms
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+var (
+	// ErrNoMirrors is returned when no mirror URL is configured.
+	ErrNoMirrors = errors.New("mirror: missing mirror URLs")
+
+	// ErrInvalidChecksum is returned when parsing a malformed or unsupported checksum.
+	ErrInvalidChecksum = errors.New("mirror: invalid checksum")
+
+	// ErrInvalidRange is returned when a mirror replies with a content range not matching the requested one.
+	ErrInvalidRange = errors.New("mirror: invalid content range")
+
+	// ErrSizeMismatch is returned when a mirror announces a different artifact size than the previous mirrors.
+	ErrSizeMismatch = errors.New("mirror: artifact size mismatch")
+)
+
+// algorithms stores the supported checksum algorithms by name.
+var algorithms = map[string]crypto.Hash{
+	"md5":    crypto.MD5,
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+// Checksum represents the expected checksum of the downloaded artifact.
+type Checksum struct {
+	// Hash stores the checksum algorithm.
+	Hash crypto.Hash
+
+	// Sum stores the expected digest.
+	Sum []byte
+}
+
+// ParseChecksum parses the given checksum in "<algorithm>:<hex digest>" notation,
+// such as "sha256:9f86d08...". Supports the md5, sha1, sha256 and sha512 algorithms.
+func ParseChecksum(value string) (Checksum, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return Checksum{}, ErrInvalidChecksum
+	}
+	algorithm, ok := algorithms[strings.ToLower(parts[0])]
+	if !ok {
+		return Checksum{}, ErrInvalidChecksum
+	}
+	sum, err := hex.DecodeString(parts[1])
+	if err != nil || len(sum) != algorithm.Size() {
+		return Checksum{}, ErrInvalidChecksum
+	}
+	return Checksum{Hash: algorithm, Sum: sum}, nil
+}
+
+// ChecksumError is returned when the downloaded artifact does not match the expected checksum.
+type ChecksumError struct {
+	// Expected stores the expected digest.
+	Expected []byte
+
+	// Actual stores the digest of the downloaded artifact.
+	Actual []byte
+}
+
+// Error returns the error message.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("mirror: checksum mismatch: expected %x, got %x", e.Expected, e.Actual)
+}
+
+// MirrorError represents the failure of a single mirror.
+type MirrorError struct {
+	// URL stores the mirror URL.
+	URL string
+
+	// Err stores the mirror error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *MirrorError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+// Unwrap returns the mirror error.
+func (e *MirrorError) Unwrap() error {
+	return e.Err
+}
+
+// Error is returned when the artifact cannot be downloaded from any mirror.
+type Error struct {
+	// Errors stores the errors of the failed mirrors, in attempt order.
+	Errors []*MirrorError
+}
+
+// Error returns the error message, listing the mirror errors.
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "mirror: download failed: " + strings.Join(msgs, "; ")
+}
+
+// Options represents the downloader options.
+type Options struct {
+	// Mirrors defines the URLs serving the same artifact, tried in order.
+	Mirrors []string
+
+	// Checksum defines the expected artifact checksum, if any.
+	Checksum Checksum
+
+	// Progress defines the function called with the number of downloaded bytes
+	// and the artifact size, or -1 if unknown, once every chunk is written, if any.
+	Progress func(written, size int64)
+
+	// Client defines the client used to reach the mirrors,
+	// such as a client with authentication plugins. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// Result represents a completed download.
+type Result struct {
+	// URL stores the URL of the mirror which completed the download.
+	URL string
+
+	// Size stores the artifact size in bytes.
+	Size int64
+
+	// Sum stores the artifact digest, if a checksum was configured.
+	Sum []byte
+
+	// Errors stores the errors of the mirrors which failed before the download completed.
+	Errors []*MirrorError
+}
+
+// Downloader downloads an artifact served by multiple mirrors, switching to the next
+// mirror on failures and resuming the download from the received offset via Range requests.
+// Downloader is safe for concurrent use.
+type Downloader struct {
+	// opts stores the downloader options.
+	opts Options
+
+	// cli stores the mirrors client.
+	cli *gentleman.Client
+}
+
+// New creates a new downloader based on the given options.
+func New(opts Options) (*Downloader, error) {
+	if len(opts.Mirrors) == 0 {
+		return nil, ErrNoMirrors
+	}
+	if opts.Checksum.Hash != 0 && !opts.Checksum.Hash.Available() {
+		return nil, ErrInvalidChecksum
+	}
+
+	cli := opts.Client
+	if cli == nil {
+		cli = gentleman.New()
+	}
+	return &Downloader{opts: opts, cli: gentleman.New().UseParent(cli)}, nil
+}
+
+// Download downloads the artifact into the given writer, verifying its checksum, if any.
+// On checksum mismatch, the written data must be discarded.
+func (d *Downloader) Download(w io.Writer) (*Result, error) {
+	return d.download(w, 0, d.newHash())
+}
+
+// DownloadFile downloads the artifact into the given file path, verifying its checksum, if any.
+// The data is written into a "<path>.part" file, renamed once completed and verified,
+// and resumed from its size on later calls, so downloads survive process restarts.
+// The partial file is removed on checksum mismatch.
+func (d *Downloader) DownloadFile(path string) (*Result, error) {
+	part := path + ".part"
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash the previously downloaded data, if any, moving to the end of the file
+	var offset int64
+	h := d.newHash()
+	if h != nil {
+		offset, err = io.Copy(h, file)
+	} else {
+		offset, err = file.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	res, err := d.download(file, offset, h)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if _, ok := err.(*ChecksumError); ok {
+		os.Remove(part)
+	}
+	if err != nil {
+		return res, err
+	}
+	return res, os.Rename(part, path)
+}
+
+// newHash returns a new hash of the configured checksum algorithm, if any.
+func (d *Downloader) newHash() hash.Hash {
+	if d.opts.Checksum.Hash == 0 {
+		return nil
+	}
+	return d.opts.Checksum.Hash.New()
+}
+
+// download downloads the artifact from the given offset, switching mirrors on failures.
+func (d *Downloader) download(w io.Writer, offset int64, h hash.Hash) (*Result, error) {
+	if h != nil {
+		w = io.MultiWriter(w, h)
+	}
+
+	res := &Result{Size: -1}
+	for _, mirror := range d.opts.Mirrors {
+		err := d.fetch(mirror, w, &offset, res)
+		if err == nil {
+			res.URL = mirror
+			break
+		}
+		res.Errors = append(res.Errors, &MirrorError{URL: mirror, Err: err})
+	}
+	if res.URL == "" {
+		return res, &Error{Errors: res.Errors}
+	}
+	res.Size = offset
+
+	if h != nil {
+		res.Sum = h.Sum(nil)
+		if !bytes.Equal(res.Sum, d.opts.Checksum.Sum) {
+			return res, &ChecksumError{Expected: d.opts.Checksum.Sum, Actual: res.Sum}
+		}
+	}
+	return res, nil
+}
+
+// fetch downloads the remaining artifact data from the given mirror, starting from the given offset,
+// which is updated with the written data, so the next mirror can resume the download.
+func (d *Downloader) fetch(mirror string, w io.Writer, offset *int64, result *Result) error {
+	req := d.cli.Request().URL(mirror)
+	if *offset > 0 {
+		req.SetHeader("Range", "bytes="+strconv.FormatInt(*offset, 10)+"-")
+	}
+	res, err := req.Send()
+	if err != nil {
+		return err
+	}
+	// Close without draining the body, since the mirror may be stalled
+	defer res.RawResponse.Body.Close()
+
+	var start, size int64 = 0, -1
+	switch res.StatusCode {
+	case http.StatusOK:
+		size = res.RawResponse.ContentLength
+	case http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		var ok bool
+		if start, size, ok = parseContentRange(res.Header.Get("Content-Range")); !ok {
+			return ErrInvalidRange
+		}
+		// The artifact was already fully downloaded, as long as the sizes match
+		if res.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			start = size
+		}
+	default:
+		return fmt.Errorf("unexpected response status: %d", res.StatusCode)
+	}
+
+	if size >= 0 {
+		if result.Size >= 0 && result.Size != size {
+			return ErrSizeMismatch
+		}
+		result.Size = size
+	}
+
+	if res.StatusCode == http.StatusOK && *offset > 0 {
+		// The mirror ignored the range request, so skip the already written data
+		if _, err := io.CopyN(ioutil.Discard, res, *offset); err != nil {
+			return err
+		}
+	} else if start != *offset {
+		return ErrInvalidRange
+	}
+
+	buf := make([]byte, 32*1024)
+	for size < 0 || *offset < size {
+		n, err := res.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			*offset += int64(n)
+			if d.opts.Progress != nil {
+				d.opts.Progress(*offset, size)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if size >= 0 && *offset != size {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// parseContentRange parses the given "bytes <start>-<end>/<total>" or "bytes */<total>"
+// Content-Range header, returning the range start and the total size.
+func parseContentRange(value string) (start, total int64, ok bool) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	total, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if parts[0] == "*" {
+		return 0, total, true
+	}
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if start, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
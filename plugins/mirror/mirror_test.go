@@ -0,0 +1,166 @@
+package mirror
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+var artifact = bytes.Repeat([]byte("gentleman artifact "), 1000)
+
+// artifactChecksum returns the checksum of the given data.
+func artifactChecksum(data []byte) Checksum {
+	sum := sha256.Sum256(data)
+	return Checksum{Hash: crypto.SHA256, Sum: sum[:]}
+}
+
+// brokenServer serves the given artifact, aborting the connection after the given number of bytes.
+func brokenServer(data []byte, limit int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data[:limit])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+}
+
+// rangeServer serves the given artifact supporting range requests, recording the requested ranges.
+func rangeServer(data []byte, ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "artifact", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("test"))
+	checksum, err := ParseChecksum("SHA256:" + hex.EncodeToString(sum[:]))
+	st.Expect(t, err, nil)
+	st.Expect(t, checksum.Sum, sum[:])
+	st.Expect(t, checksum.Hash.Size(), sha256.Size)
+
+	for _, value := range []string{"", "sha256", "crc32:00", "sha256:zz", "sha256:00"} {
+		_, err := ParseChecksum(value)
+		st.Expect(t, err, ErrInvalidChecksum)
+	}
+}
+
+func TestDownloadMirrorFallback(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	broken := brokenServer(artifact, 5000)
+	defer broken.Close()
+	var ranges []string
+	healthy := rangeServer(artifact, &ranges)
+	defer healthy.Close()
+
+	var written int64
+	downloader, err := New(Options{
+		Mirrors:  []string{unavailable.URL, broken.URL, healthy.URL},
+		Checksum: artifactChecksum(artifact),
+		Progress: func(n, size int64) { written = n },
+	})
+	st.Expect(t, err, nil)
+
+	buf := &bytes.Buffer{}
+	res, err := downloader.Download(buf)
+	st.Expect(t, err, nil)
+	st.Expect(t, buf.Bytes(), artifact)
+	st.Expect(t, res.URL, healthy.URL)
+	st.Expect(t, res.Size, int64(len(artifact)))
+	st.Expect(t, len(res.Errors), 2)
+	st.Expect(t, res.Errors[0].Error(), unavailable.URL+": unexpected response status: 503")
+	st.Expect(t, res.Errors[1].URL, broken.URL)
+	st.Expect(t, written, int64(len(artifact)))
+
+	// The healthy mirror resumed the download from the broken mirror offset
+	st.Expect(t, ranges, []string{"bytes=5000-"})
+}
+
+func TestDownloadIgnoredRange(t *testing.T) {
+	broken := brokenServer(artifact, 100)
+	defer broken.Close()
+	full := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(artifact)
+	}))
+	defer full.Close()
+
+	downloader, _ := New(Options{Mirrors: []string{broken.URL, full.URL}, Checksum: artifactChecksum(artifact)})
+	buf := &bytes.Buffer{}
+	_, err := downloader.Download(buf)
+	st.Expect(t, err, nil)
+	st.Expect(t, buf.Bytes(), artifact)
+}
+
+func TestDownloadErrors(t *testing.T) {
+	_, err := New(Options{})
+	st.Expect(t, err, ErrNoMirrors)
+
+	var ranges []string
+	other := rangeServer([]byte("another artifact"), &ranges)
+	defer other.Close()
+	broken := brokenServer(artifact, 100)
+	defer broken.Close()
+
+	downloader, _ := New(Options{Mirrors: []string{broken.URL, other.URL}})
+	_, err = downloader.Download(ioutil.Discard)
+	mirrorErr, ok := err.(*Error)
+	st.Expect(t, ok, true)
+	st.Expect(t, len(mirrorErr.Errors), 2)
+	st.Expect(t, errors.Is(mirrorErr.Errors[1], ErrSizeMismatch), true)
+
+	downloader, _ = New(Options{Mirrors: []string{other.URL}, Checksum: artifactChecksum(artifact)})
+	_, err = downloader.Download(ioutil.Discard)
+	_, ok = err.(*ChecksumError)
+	st.Expect(t, ok, true)
+	st.Expect(t, strings.HasPrefix(err.Error(), "mirror: checksum mismatch"), true)
+}
+
+func TestDownloadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	st.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "artifact.bin")
+
+	// Previously downloaded data is resumed after a restart
+	st.Expect(t, ioutil.WriteFile(path+".part", artifact[:1000], 0644), nil)
+	var ranges []string
+	ts := rangeServer(artifact, &ranges)
+	defer ts.Close()
+
+	downloader, _ := New(Options{Mirrors: []string{ts.URL}, Checksum: artifactChecksum(artifact)})
+	res, err := downloader.DownloadFile(path)
+	st.Expect(t, err, nil)
+	st.Expect(t, res.Size, int64(len(artifact)))
+	st.Expect(t, ranges, []string{"bytes=1000-"})
+
+	data, err := ioutil.ReadFile(path)
+	st.Expect(t, err, nil)
+	st.Expect(t, data, artifact)
+	_, err = os.Stat(path + ".part")
+	st.Expect(t, os.IsNotExist(err), true)
+
+	// Corrupted partial downloads are removed
+	st.Expect(t, ioutil.WriteFile(path+".part", []byte("corrupted"), 0644), nil)
+	_, err = downloader.DownloadFile(path)
+	_, ok := err.(*ChecksumError)
+	st.Expect(t, ok, true)
+	_, err = os.Stat(path + ".part")
+	st.Expect(t, os.IsNotExist(err), true)
+}
@@ -1,7 +1,6 @@
 package multipart
 
 import (
-	"bytes"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -11,8 +10,12 @@ import (
 
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
+// ErrNilReader is returned when a form file has no reader.
+var ErrNilReader = errors.New("gentleman: file reader cannot be nil")
+
 // Values represents multiple multipart from values.
 type Values []string
 
@@ -74,11 +77,12 @@ func handle(ctx *c.Context, h c.Handler, data FormData) {
 }
 
 func createForm(data FormData, ctx *c.Context) error {
-	body := &bytes.Buffer{}
+	body := utils.DefaultBufferPool.Get()
 	multipartWriter := multipart.NewWriter(body)
 
 	for index, file := range data.Files {
 		if err := writeFile(multipartWriter, data, file, index); err != nil {
+			utils.DefaultBufferPool.Put(body)
 			return err
 		}
 	}
@@ -90,11 +94,12 @@ func createForm(data FormData, ctx *c.Context) error {
 		}
 	}
 	if err := multipartWriter.Close(); err != nil {
+		utils.DefaultBufferPool.Put(body)
 		return err
 	}
 
 	ctx.Request.Method = setMethod(ctx)
-	ctx.Request.Body = ioutil.NopCloser(body)
+	ctx.Request.Body = utils.DefaultBufferPool.ReadCloser(body)
 	ctx.Request.Header.Add("Content-Type", multipartWriter.FormDataContentType())
 
 	return nil
@@ -102,7 +107,7 @@ func createForm(data FormData, ctx *c.Context) error {
 
 func writeFile(multipartWriter *multipart.Writer, data FormData, file FormFile, index int) error {
 	if file.Reader == nil {
-		return errors.New("gentleman: file reader cannot be nil")
+		return ErrNilReader
 	}
 
 	rc, ok := file.Reader.(io.ReadCloser)
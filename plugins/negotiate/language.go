@@ -0,0 +1,59 @@
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// AcceptLanguageValue builds the Accept-Language header value from the given language
+// preferences, sorted by preference, generating decreasing q-values, such as
+// "es-ES, es;q=0.9, en;q=0.8". The q-values decrease by 0.1 down to 0.1. Undefined tags are ignored.
+func AcceptLanguageValue(tags ...language.Tag) string {
+	values := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == language.Und {
+			continue
+		}
+		value := tag.String()
+		if n := len(values); n > 0 {
+			q := 10 - n
+			if q < 1 {
+				q = 1
+			}
+			value += ";q=0." + strconv.Itoa(q)
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ", ")
+}
+
+// AcceptLanguage defines the Accept-Language header of the outgoing request
+// based on the given language preferences. See AcceptLanguageValue().
+func AcceptLanguage(tags ...language.Tag) p.Plugin {
+	value := AcceptLanguageValue(tags...)
+	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		if value != "" {
+			ctx.Request.Header.Set("Accept-Language", value)
+		}
+		h.Next(ctx)
+	})
+}
+
+// ParseContentLanguage parses the given Content-Language header value,
+// such as "de-DE, en-CA", ignoring the invalid language tags.
+func ParseContentLanguage(value string) []language.Tag {
+	var tags []language.Tag
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		if tag, err := language.Parse(part); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
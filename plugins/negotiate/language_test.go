@@ -0,0 +1,37 @@
+package negotiate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"golang.org/x/text/language"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestAcceptLanguageValue(t *testing.T) {
+	tags := []language.Tag{language.MustParse("es-ES"), language.Und, language.MustParse("es"), language.MustParse("en")}
+	st.Expect(t, AcceptLanguageValue(tags...), "es-ES, es;q=0.9, en;q=0.8")
+	st.Expect(t, AcceptLanguageValue(), "")
+
+	tags = nil
+	for i := 0; i < 12; i++ {
+		tags = append(tags, language.MustParse("en"))
+	}
+	value := AcceptLanguageValue(tags...)
+	st.Expect(t, strings.HasSuffix(value, "en;q=0.2, en;q=0.1, en;q=0.1, en;q=0.1"), true)
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	AcceptLanguage(language.MustParse("fr-CA"), language.MustParse("fr")).Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header.Get("Accept-Language"), "fr-CA, fr;q=0.9")
+}
+
+func TestParseContentLanguage(t *testing.T) {
+	tags := ParseContentLanguage("de-DE, en-CA, ,foo bar")
+	st.Expect(t, tags, []language.Tag{language.MustParse("de-DE"), language.MustParse("en-CA")})
+	st.Expect(t, len(ParseContentLanguage("")), 0)
+}
@@ -0,0 +1,97 @@
+package negotiate
+
+import (
+	"errors"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/bodytype"
+)
+
+// ErrInvalidQuality is returned when a q-value is not a number between 0 and 1.
+var ErrInvalidQuality = errors.New("gentleman: invalid q-value")
+
+// AcceptValue builds the Accept header value from the given media ranges, such as
+// "application/json" or "application/xml;q=0.8", optionally based on a MIME type
+// alias defined in bodytype.Types, like "json". The media ranges are validated
+// and the q-values normalized, preserving the given order.
+func AcceptValue(types ...string) (string, error) {
+	values := make([]string, 0, len(types))
+	for _, name := range types {
+		parts := strings.SplitN(name, ";", 2)
+		if alias, ok := bodytype.Types[strings.TrimSpace(parts[0])]; ok {
+			parts[0] = alias
+			name = strings.Join(parts, ";")
+		}
+
+		mediaType, params, err := mime.ParseMediaType(name)
+		if err != nil {
+			return "", err
+		}
+
+		q, err := quality(params)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, mediaType+formatParams(params)+formatQuality(q))
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// Accept defines the Accept header of the outgoing request based on the given
+// media ranges. See AcceptValue(). Invalid media ranges are reported as request error.
+func Accept(types ...string) p.Plugin {
+	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		value, err := AcceptValue(types...)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		ctx.Request.Header.Set("Accept", value)
+		h.Next(ctx)
+	})
+}
+
+// quality extracts the q-value from the given media range params.
+func quality(params map[string]string) (float64, error) {
+	value, ok := params["q"]
+	if !ok {
+		return 1, nil
+	}
+	delete(params, "q")
+
+	q, err := strconv.ParseFloat(value, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, ErrInvalidQuality
+	}
+	return q, nil
+}
+
+// formatQuality formats the given q-value rounded to three decimals,
+// or an empty string if the default value.
+func formatQuality(q float64) string {
+	if q == 1 {
+		return ""
+	}
+	value := strings.TrimRight(strconv.FormatFloat(q, 'f', 3, 64), "0")
+	return ";q=" + strings.TrimSuffix(value, ".")
+}
+
+// formatParams formats the given media range params sorted by name.
+func formatParams(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	for _, name := range names {
+		buf.WriteString(";" + name + "=" + params[name])
+	}
+	return buf.String()
+}
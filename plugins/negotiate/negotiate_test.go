@@ -0,0 +1,46 @@
+package negotiate
+
+import (
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestAcceptValue(t *testing.T) {
+	value, err := AcceptValue("json", "application/xml; q=0.8", "text/html;level=1;q=0.12345", "text/*;q=0.29", "*/*;q=0")
+	st.Expect(t, err, nil)
+	st.Expect(t, value, "application/json, application/xml;q=0.8, text/html;level=1;q=0.123, text/*;q=0.29, */*;q=0")
+
+	_, err = AcceptValue("application/json;q=2")
+	st.Expect(t, err, ErrInvalidQuality)
+
+	_, err = AcceptValue("json/")
+	st.Reject(t, err, nil)
+}
+
+func TestAccept(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	Accept("json", "xml;q=0.5").Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.Header.Get("Accept"), "application/json, application/xml;q=0.5")
+
+	ctx = context.New()
+	fn = newHandler()
+	Accept("application/json;q=foo").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Error, ErrInvalidQuality)
+}
+
+type handler struct {
+	fn     context.Handler
+	called bool
+}
+
+func newHandler() *handler {
+	h := &handler{}
+	h.fn = context.NewHandler(func(c *context.Context) {
+		h.called = true
+	})
+	return h
+}
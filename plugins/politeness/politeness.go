@@ -0,0 +1,163 @@
+package politeness
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// Scheduler enforces a minimum delay between consecutive requests to the same host.
+// Scheduler is safe for concurrent use and can be shared across clients.
+type Scheduler struct {
+	// mtx protects the scheduler state
+	mtx sync.Mutex
+
+	// delay stores the default delay between requests to the same host.
+	delay time.Duration
+
+	// hosts stores the per-host delay overrides.
+	hosts map[string]time.Duration
+
+	// next stores the next time slot available per host.
+	next map[string]time.Time
+
+	// limit stores the number of tracked hosts which triggers the pruning of the stale ones.
+	limit int
+
+	// now returns the current time.
+	now func() time.Time
+}
+
+// minPruneLimit is the minimum number of tracked hosts before pruning the stale ones.
+const minPruneLimit = 64
+
+// NewScheduler creates a new Scheduler with the given default delay per host.
+func NewScheduler(delay time.Duration) *Scheduler {
+	return &Scheduler{
+		delay: delay,
+		hosts: make(map[string]time.Duration),
+		next:  make(map[string]time.Time),
+		limit: minPruneLimit,
+		now:   time.Now,
+	}
+}
+
+// SetHostDelay overrides the delay for the given host.
+// host can be a host name or a host:port pair.
+func (s *Scheduler) SetHostDelay(host string, delay time.Duration) {
+	s.mtx.Lock()
+	s.hosts[strings.ToLower(host)] = delay
+	s.mtx.Unlock()
+}
+
+// HostDelay returns the delay used for the given host, which can be
+// a host name or a host:port pair.
+func (s *Scheduler) HostDelay(host string) time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.hostDelay(strings.ToLower(host))
+}
+
+func (s *Scheduler) hostDelay(host string) time.Duration {
+	if delay, ok := s.hosts[host]; ok {
+		return delay
+	}
+	if i := strings.LastIndex(host, ":"); i > 0 && !strings.HasSuffix(host, "]") {
+		if delay, ok := s.hosts[host[:i]]; ok {
+			return delay
+		}
+	}
+	return s.delay
+}
+
+// Reserve reserves the next time slot for the given host and returns
+// the amount of time to wait before the request can be sent.
+func (s *Scheduler) Reserve(host string) time.Duration {
+	_, wait := s.reserve(strings.ToLower(host))
+	return wait
+}
+
+func (s *Scheduler) reserve(host string) (time.Time, time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.now()
+	slot := s.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	s.next[host] = slot.Add(s.hostDelay(host))
+	s.prune(now)
+
+	return slot, slot.Sub(now)
+}
+
+// cancel releases the given time slot of the host, as long as no later slot was reserved.
+func (s *Scheduler) cancel(host string, slot time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if next, ok := s.next[host]; ok && next.Equal(slot.Add(s.hostDelay(host))) {
+		s.next[host] = slot
+	}
+}
+
+// prune removes the hosts whose next time slot already passed,
+// once the number of tracked hosts reaches the limit.
+// The caller must hold the lock.
+func (s *Scheduler) prune(now time.Time) {
+	if len(s.next) < s.limit {
+		return
+	}
+	for host, next := range s.next {
+		if !next.After(now) {
+			delete(s.next, host)
+		}
+	}
+	s.limit = 2 * len(s.next)
+	if s.limit < minPruneLimit {
+		s.limit = minPruneLimit
+	}
+}
+
+// Wait blocks until a request can be sent to the given host
+// or the given context is canceled, in which case the reserved
+// time slot is released if no later one was reserved.
+func (s *Scheduler) Wait(ctx *c.Context, host string) error {
+	host = strings.ToLower(host)
+	slot, wait := s.reserve(host)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		s.cancel(host, slot)
+		return ctx.Err()
+	}
+}
+
+// Delay enforces the given minimum delay between consecutive requests to the same host.
+func Delay(delay time.Duration) p.Plugin {
+	return New(NewScheduler(delay))
+}
+
+// New creates a plugin which waits for the given scheduler before sending every request.
+func New(s *Scheduler) p.Plugin {
+	// Uses the "before dial" phase in order to use the final request host.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		if err := s.Wait(ctx, ctx.Request.URL.Host); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
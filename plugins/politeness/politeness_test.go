@@ -0,0 +1,99 @@
+package politeness
+
+import (
+	gocontext "context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// fakeClock returns a scheduler clock which only moves when advanced.
+func fakeClock(s *Scheduler) func(time.Duration) {
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSchedulerReserve(t *testing.T) {
+	s := NewScheduler(100 * time.Millisecond)
+	s.SetHostDelay("foo.com", time.Second)
+	advance := fakeClock(s)
+
+	st.Expect(t, s.Reserve("bar.com"), time.Duration(0))
+	st.Expect(t, s.Reserve("bar.com"), 100*time.Millisecond)
+	st.Expect(t, s.Reserve("BAZ.com"), time.Duration(0))
+	advance(30 * time.Millisecond)
+	st.Expect(t, s.Reserve("bar.com"), 170*time.Millisecond)
+	advance(time.Second)
+	st.Expect(t, s.Reserve("bar.com"), time.Duration(0))
+
+	st.Expect(t, s.Reserve("foo.com:8080"), time.Duration(0))
+	st.Expect(t, s.Reserve("foo.com:8080"), time.Second)
+	st.Expect(t, s.HostDelay("foo.com:8080"), time.Second)
+	st.Expect(t, s.HostDelay("bar.com"), 100*time.Millisecond)
+}
+
+func TestSchedulerPrune(t *testing.T) {
+	s := NewScheduler(100 * time.Millisecond)
+	advance := fakeClock(s)
+
+	for i := 1; i < minPruneLimit; i++ {
+		s.Reserve(fmt.Sprintf("host%d.com", i))
+	}
+	st.Expect(t, len(s.next), minPruneLimit-1)
+
+	advance(time.Second)
+	s.Reserve("foo.com")
+	st.Expect(t, len(s.next), 1)
+	st.Expect(t, s.limit, minPruneLimit)
+}
+
+func TestSchedulerWaitCanceled(t *testing.T) {
+	s := NewScheduler(time.Second)
+	fakeClock(s)
+	s.Reserve("foo.com")
+
+	ctx := context.New()
+	cancelCtx, cancel := gocontext.WithCancel(gocontext.Background())
+	ctx.SetCancelContext(cancelCtx)
+	cancel()
+
+	// The canceled wait releases its time slot
+	st.Expect(t, s.Wait(ctx, "foo.com"), gocontext.Canceled)
+	st.Expect(t, s.Reserve("foo.com"), time.Second)
+
+	// Later reservations are kept in place
+	s.mtx.Lock()
+	slot := s.next["foo.com"]
+	s.mtx.Unlock()
+	s.Reserve("foo.com")
+	s.cancel("foo.com", slot.Add(-time.Second))
+	st.Expect(t, s.Reserve("foo.com"), 3*time.Second)
+}
+
+func TestDelay(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer ts.Close()
+
+	s := NewScheduler(50 * time.Millisecond)
+	fakeClock(s)
+	cli := gentleman.New()
+	cli.URL(ts.URL)
+	cli.Use(New(s))
+
+	// Every request reserved its own time slot, the next one being three delays later
+	_, err := gentleman.All(cli.Request(), cli.Request(), cli.Request())
+	st.Expect(t, err, nil)
+	st.Expect(t, atomic.LoadInt32(&calls), int32(3))
+	st.Expect(t, s.Reserve(ts.Listener.Addr().String()), 150*time.Millisecond)
+}
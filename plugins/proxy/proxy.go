@@ -1,33 +1,47 @@
 package proxy
 
 import (
-	c "gopkg.in/h2non/gentleman.v2/context"
+	"bytes"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"net/http"
 	"net/url"
+	"sort"
 )
 
-// Set defines the proxy servers to be used based on the transport scheme
+// serversKey is the key of the transports derived by Set(), listing the proxy servers.
+type serversKey string
+
+// Set defines the proxy servers to be used based on the transport scheme, deriving an isolated
+// copy of the request transport, shared by every plugin instance using the same servers.
 func Set(servers map[string]string) p.Plugin {
-	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		// Assert http.Transport to work with the instance
-		transport, ok := ctx.Client.Transport.(*http.Transport)
-		if !ok {
-			// If using a custom transport, just ignore it
-			h.Next(ctx)
-			return
-		}
+	// Copy the servers, since the derived transport is shared
+	servers, schemes := copyServers(servers), make([]string, 0, len(servers))
+	for scheme := range servers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	var key bytes.Buffer
+	for _, scheme := range schemes {
+		key.WriteString(scheme + "=" + servers[scheme] + "\n")
+	}
 
+	return transport.DeriveKey(serversKey(key.String()), func(t *http.Transport) {
 		// Define the proxy function to be used during the transport
-		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
 			if value, ok := servers[req.URL.Scheme]; ok {
 				return url.Parse(value)
 			}
 			return http.ProxyFromEnvironment(req)
 		}
-
-		// Override the transport
-		ctx.Client.Transport = transport
-		h.Next(ctx)
 	})
 }
+
+// copyServers returns a copy of the given proxy servers.
+func copyServers(servers map[string]string) map[string]string {
+	copied := make(map[string]string, len(servers))
+	for scheme, server := range servers {
+		copied[scheme] = server
+	}
+	return copied
+}
@@ -26,6 +26,7 @@ func TestProxy(t *testing.T) {
 	st.Expect(t, err, nil)
 	st.Expect(t, url.Host, "localhost:3128")
 	st.Expect(t, url.Scheme, "http")
+	st.Reject(t, transport, http.DefaultTransport)
 }
 
 func TestProxyParseError(t *testing.T) {
@@ -1,17 +1,63 @@
 package query
 
 import (
+	"net/url"
+
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 )
 
+// pendingKey is the context store key used to store the pending query changes.
+const pendingKey = "$query.pending"
+
+// pending stores the query changes of the request URL, deferred until
+// the request phase is complete, so the query is parsed and encoded once.
+type pending struct {
+	// url stores the request URL the changes apply to.
+	url *url.URL
+
+	// changes stores the query changes in order.
+	changes []func(url.Values)
+}
+
+// change defers the given query change until the request query is flushed.
+// The pending changes are discarded if the request URL is replaced.
+func change(ctx *c.Context, fn func(url.Values)) {
+	q, _ := ctx.Get(pendingKey).(*pending)
+	if q == nil || q.url != ctx.Request.URL {
+		q = &pending{url: ctx.Request.URL}
+		ctx.Set(pendingKey, q)
+	}
+	q.changes = append(q.changes, fn)
+}
+
+// Flush applies the pending query changes to the request URL, if any.
+// It is called by the dispatcher once the request phase is complete, so the "before dial"
+// plugins get the final query, and should be called by the request phase plugins reading it.
+func Flush(ctx *c.Context) {
+	q, _ := ctx.Get(pendingKey).(*pending)
+	if q == nil {
+		return
+	}
+	ctx.Delete(pendingKey)
+	if q.url != ctx.Request.URL {
+		return
+	}
+
+	query := q.url.Query()
+	for _, fn := range q.changes {
+		fn(query)
+	}
+	q.url.RawQuery = query.Encode()
+}
+
 // Set sets the query param key and value.
 // It replaces any existing values.
 func Set(key, value string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		query := ctx.Request.URL.Query()
-		query.Set(key, value)
-		ctx.Request.URL.RawQuery = query.Encode()
+		change(ctx, func(query url.Values) {
+			query.Set(key, value)
+		})
 		h.Next(ctx)
 	})
 }
@@ -20,9 +66,9 @@ func Set(key, value string) p.Plugin {
 // It appends to any existing values associated with key.
 func Add(key, value string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		query := ctx.Request.URL.Query()
-		query.Add(key, value)
-		ctx.Request.URL.RawQuery = query.Encode()
+		change(ctx, func(query url.Values) {
+			query.Add(key, value)
+		})
 		h.Next(ctx)
 	})
 }
@@ -30,9 +76,9 @@ func Add(key, value string) p.Plugin {
 // Del deletes the query param values associated with key.
 func Del(key string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		query := ctx.Request.URL.Query()
-		query.Del(key)
-		ctx.Request.URL.RawQuery = query.Encode()
+		change(ctx, func(query url.Values) {
+			query.Del(key)
+		})
 		h.Next(ctx)
 	})
 }
@@ -40,7 +86,11 @@ func Del(key string) p.Plugin {
 // DelAll deletes all the query params.
 func DelAll() p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		ctx.Request.URL.RawQuery = ""
+		change(ctx, func(query url.Values) {
+			for key := range query {
+				delete(query, key)
+			}
+		})
 		h.Next(ctx)
 	})
 }
@@ -48,11 +98,11 @@ func DelAll() p.Plugin {
 // SetMap sets a map of query params by key-value pair.
 func SetMap(params map[string]string) p.Plugin {
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		query := ctx.Request.URL.Query()
-		for k, v := range params {
-			query.Set(k, v)
-		}
-		ctx.Request.URL.RawQuery = query.Encode()
+		change(ctx, func(query url.Values) {
+			for k, v := range params {
+				query.Set(k, v)
+			}
+		})
 		h.Next(ctx)
 	})
 }
@@ -1,9 +1,11 @@
 package query
 
 import (
+	"net/url"
+	"testing"
+
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
-	"testing"
 )
 
 func TestQuerySet(t *testing.T) {
@@ -12,6 +14,7 @@ func TestQuerySet(t *testing.T) {
 	fn := newHandler()
 
 	Set("foo", "bar").Exec("request", ctx, fn.fn)
+	Flush(ctx)
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Request.URL.RawQuery, "baz=foo&foo=bar")
 }
@@ -22,6 +25,7 @@ func TestQueryAdd(t *testing.T) {
 	fn := newHandler()
 
 	Add("foo", "bar").Exec("request", ctx, fn.fn)
+	Flush(ctx)
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Request.URL.RawQuery, "foo=baz&foo=bar")
 }
@@ -32,6 +36,7 @@ func TestQueryDel(t *testing.T) {
 	fn := newHandler()
 
 	Del("foo").Exec("request", ctx, fn.fn)
+	Flush(ctx)
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Request.URL.RawQuery, "")
 }
@@ -42,6 +47,7 @@ func TestQueryDelAll(t *testing.T) {
 	fn := newHandler()
 
 	DelAll().Exec("request", ctx, fn.fn)
+	Flush(ctx)
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Request.URL.RawQuery, "")
 }
@@ -53,10 +59,47 @@ func TestQuerySetMap(t *testing.T) {
 	params := map[string]string{"foo": "bar"}
 
 	SetMap(params).Exec("request", ctx, fn.fn)
+	Flush(ctx)
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Request.URL.RawQuery, "baz=foo&foo=bar")
 }
 
+func TestQueryFlush(t *testing.T) {
+	ctx := context.New()
+	ctx.Request.URL.RawQuery = "foo=baz"
+	fn := newHandler()
+
+	// The changes are applied in order once flushed
+	Set("foo", "bar").Exec("request", ctx, fn.fn)
+	Add("foo", "qux").Exec("request", ctx, fn.fn)
+	Del("baz").Exec("request", ctx, fn.fn)
+	st.Expect(t, ctx.Request.URL.RawQuery, "foo=baz")
+	Flush(ctx)
+	st.Expect(t, ctx.Request.URL.RawQuery, "foo=bar&foo=qux")
+	Flush(ctx)
+	st.Expect(t, ctx.Request.URL.RawQuery, "foo=bar&foo=qux")
+
+	// The changes are discarded if the URL is replaced
+	Set("foo", "baz").Exec("request", ctx, fn.fn)
+	ctx.Request.URL = &url.URL{Path: "/", RawQuery: "bar=foo"}
+	Set("baz", "foo").Exec("request", ctx, fn.fn)
+	Flush(ctx)
+	st.Expect(t, ctx.Request.URL.RawQuery, "bar=foo&baz=foo")
+}
+
+func BenchmarkQuery(b *testing.B) {
+	plugins := []interface {
+		Exec(string, *context.Context, context.Handler)
+	}{Set("foo", "bar"), Add("bar", "baz"), Set("baz", "foo"), Del("foo")}
+	for n := 0; n < b.N; n++ {
+		ctx := context.New()
+		for _, plugin := range plugins {
+			plugin.Exec("request", ctx, context.NewHandler(func(*context.Context) {}))
+		}
+		Flush(ctx)
+	}
+}
+
 type handler struct {
 	fn     context.Handler
 	called bool
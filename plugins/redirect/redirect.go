@@ -7,6 +7,7 @@ import (
 
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
 var (
@@ -14,6 +15,26 @@ var (
 	// with too many redirects
 	ErrRedirectLimitExceeded = errors.New("gentleman: Request exceeded redirect count")
 
+	// ErrRedirectScheme is the error returned when the redirect location
+	// uses a disallowed URL scheme
+	ErrRedirectScheme = errors.New("gentleman: redirect URL scheme is not allowed")
+
+	// ErrRedirectHost is the error returned when the redirect location
+	// points to a different host in same host mode
+	ErrRedirectHost = errors.New("gentleman: redirect to a different host is not allowed")
+
+	// CredentialHeaders is a list of HTTP headers carrying credentials which are
+	// removed when a redirect crosses to a different host, unless trusted
+	CredentialHeaders = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Cookie2",
+	}
+
+	// Schemes defines the URL schemes allowed in redirect locations by default
+	Schemes = []string{"http", "https"}
+
 	// RedirectLimit defines the maximum number of redirects to follow in a request
 	RedirectLimit = 10
 
@@ -26,6 +47,12 @@ var (
 	}
 )
 
+// Approver approves a redirect hop to the given request, based on
+// the previous requests, oldest first. Returning an error stops
+// the redirect, and returning http.ErrUseLastResponse returns the
+// redirect response without error.
+type Approver func(req *http.Request, via []*http.Request) error
+
 // Options store the redirect policy options
 type Options struct {
 	// Limit is the acceptable amount of redirects that we should expect
@@ -51,17 +78,56 @@ type Options struct {
 	// SensitiveHeaders is a map of sensitive HTTP headers that a user
 	// doesn't want passed on a redirect
 	SensitiveHeaders []string
+
+	// Schemes is a list of URL schemes allowed in redirect locations.
+	// Defaults to the `Schemes` variable
+	Schemes []string
+
+	// SameHost only allows redirects to the host of the original request
+	SameHost bool
+
+	// Approve is an optional function called to approve each redirect hop,
+	// once the policy checks passed
+	Approve Approver
+
+	// PreserveMethod preserves the request method and body on 301 and 302
+	// redirects, instead of downgrading them to GET requests. Implies ReplayBody.
+	// 303 redirects are always downgraded to GET requests
+	PreserveMethod bool
+
+	// KeepCredentials disables the removal of the `CredentialHeaders` on
+	// redirects to a different host than the original request one, which
+	// prevents leaking credentials to third parties. Trusted hosts always
+	// keep the credentials
+	KeepCredentials bool
+
+	// ReplayBody buffers the request body, if not replayable yet, so 307 and 308
+	// redirects are always followed resending the body. Otherwise net/http
+	// returns the redirect response when the body cannot be sent again
+	ReplayBody bool
 }
 
 // Config defines in the request http.Client the redirect
 // policy based on the given options.
 func Config(opts Options) p.Plugin {
-	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		ctx.Client.CheckRedirect = func(req *http.Request, pool []*http.Request) error {
-			return redirectPolicy(opts, req, pool)
-		}
-		h.Next(ctx)
-	})
+	handlers := p.Handlers{
+		"request": func(ctx *c.Context, h c.Handler) {
+			ctx.Client.CheckRedirect = func(req *http.Request, pool []*http.Request) error {
+				return redirectPolicy(opts, req, pool)
+			}
+			h.Next(ctx)
+		},
+		"before dial": func(ctx *c.Context, h c.Handler) {
+			if opts.PreserveMethod || opts.ReplayBody {
+				if _, err := utils.ReplayRequestBody(ctx.Request); err != nil {
+					h.Error(ctx, err)
+					return
+				}
+			}
+			h.Next(ctx)
+		},
+	}
+	return &p.Layer{Handlers: handlers}
 }
 
 // Limit defines in the maximum number of redirects that http.Client should follow.
@@ -74,6 +140,12 @@ func Limit(limit int) p.Plugin {
 	})
 }
 
+// Approve defines a function to approve each redirect hop, using the
+// default redirect policy options.
+func Approve(fn Approver) p.Plugin {
+	return Config(Options{Approve: fn})
+}
+
 func redirectPolicy(opts Options, req *http.Request, pool []*http.Request) error {
 	if opts.Limit == 0 {
 		opts.Limit = RedirectLimit
@@ -83,6 +155,10 @@ func redirectPolicy(opts Options, req *http.Request, pool []*http.Request) error
 		return ErrRedirectLimitExceeded
 	}
 
+	if err := checkLocation(opts, req, pool[0]); err != nil {
+		return err
+	}
+
 	if opts.SensitiveHeaders == nil {
 		opts.SensitiveHeaders = SensitiveHeaders
 	}
@@ -91,9 +167,85 @@ func redirectPolicy(opts Options, req *http.Request, pool []*http.Request) error
 		copyHeaders(k, vv, opts, req)
 	}
 
+	if !opts.KeepCredentials && !opts.Trusted && !isTrustedHost(opts, req) && crossHost(req, pool[0]) {
+		for _, k := range CredentialHeaders {
+			req.Header.Del(k)
+		}
+	}
+
+	if opts.PreserveMethod {
+		if err := preserveMethod(req, pool[len(pool)-1]); err != nil {
+			return err
+		}
+	}
+
+	if opts.Approve != nil {
+		return opts.Approve(req, pool)
+	}
+
+	return nil
+}
+
+// preserveMethod restores the method and body of the previous request
+// on 301 and 302 redirects downgraded to GET requests.
+func preserveMethod(req, prev *http.Request) error {
+	res := req.Response
+	if res == nil || (res.StatusCode != http.StatusMovedPermanently && res.StatusCode != http.StatusFound) {
+		return nil
+	}
+	// Depending on the Go version, net/http either downgrades the method
+	// of requests like QUERY or keeps it, but always drops the body
+	req.Method = prev.Method
+	if prev.GetBody == nil || (req.Body != nil && req.Body != http.NoBody) {
+		return nil
+	}
+
+	body, err := prev.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = prev.GetBody
+	req.ContentLength = prev.ContentLength
+	return nil
+}
+
+func checkLocation(opts Options, req, origin *http.Request) error {
+	if req.URL == nil {
+		return nil
+	}
+
+	schemes := opts.Schemes
+	if schemes == nil {
+		schemes = Schemes
+	}
+	if !allowedScheme(schemes, req.URL.Scheme) {
+		return ErrRedirectScheme
+	}
+
+	if opts.SameHost && origin.URL != nil && !strings.EqualFold(req.URL.Host, origin.URL.Host) {
+		return ErrRedirectHost
+	}
+
 	return nil
 }
 
+func crossHost(req, origin *http.Request) bool {
+	if req.URL == nil || origin.URL == nil {
+		return false
+	}
+	return !strings.EqualFold(req.URL.Host, origin.URL.Host)
+}
+
+func allowedScheme(schemes []string, scheme string) bool {
+	for _, v := range schemes {
+		if strings.EqualFold(v, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
 func copyHeaders(k string, vv []string, opts Options, req *http.Request) {
 	trustedHost := isTrustedHost(opts, req)
 	if !opts.Trusted && !trustedHost {
@@ -1,10 +1,15 @@
 package redirect
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
 	"gopkg.in/h2non/gentleman.v2/context"
 )
 
@@ -115,3 +120,135 @@ func newHandler() *handler {
 	})
 	return h
 }
+
+func TestRedirectPolicySchemes(t *testing.T) {
+	prevReq := &http.Request{Header: make(http.Header), URL: mustParse("http://foo.com")}
+	pool := []*http.Request{prevReq}
+
+	req := &http.Request{Header: make(http.Header), URL: mustParse("ftp://foo.com/file")}
+	st.Expect(t, redirectPolicy(Options{}, req, pool), ErrRedirectScheme)
+
+	opts := Options{Schemes: []string{"ftp"}}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+
+	req = &http.Request{Header: make(http.Header), URL: mustParse("https://foo.com")}
+	st.Expect(t, redirectPolicy(opts, req, pool), ErrRedirectScheme)
+}
+
+func TestRedirectPolicySameHost(t *testing.T) {
+	prevReq := &http.Request{Header: make(http.Header), URL: mustParse("http://foo.com")}
+	pool := []*http.Request{prevReq}
+	opts := Options{SameHost: true}
+
+	req := &http.Request{Header: make(http.Header), URL: mustParse("https://FOO.com/bar")}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+
+	req = &http.Request{Header: make(http.Header), URL: mustParse("http://bar.com")}
+	st.Expect(t, redirectPolicy(opts, req, pool), ErrRedirectHost)
+}
+
+func TestRedirectApprove(t *testing.T) {
+	var hops []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.Write([]byte(r.URL.Path))
+		}
+	}))
+	defer ts.Close()
+
+	approve := func(req *http.Request, via []*http.Request) error {
+		hops = append(hops, req.URL.Path)
+		if req.URL.Path == "/c" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+
+	res, err := gentleman.New().URL(ts.URL + "/a").Use(Approve(approve)).Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 302)
+	st.Expect(t, hops, []string{"/b", "/c"})
+}
+
+func mustParse(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestRedirectPreserveMethod(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/found":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, "/echo", http.StatusSeeOther)
+		case "/temporary":
+			http.Redirect(w, r, "/echo", http.StatusTemporaryRedirect)
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(r.Method + " " + string(body)))
+		}
+	}))
+	defer ts.Close()
+
+	send := func(method, path string, opts Options) string {
+		res, err := gentleman.New().URL(ts.URL + path).Use(Config(opts)).
+			Request().Method(method).Body(strings.NewReader("foo")).Send()
+		st.Expect(t, err, nil)
+		if res.StatusCode != 200 {
+			return res.RawResponse.Status
+		}
+		return res.String()
+	}
+
+	// Default net/http behavior
+	st.Expect(t, send("POST", "/found", Options{}), "GET ")
+	st.Expect(t, send("POST", "/temporary", Options{}), "307 Temporary Redirect")
+
+	st.Expect(t, send("POST", "/found", Options{PreserveMethod: true}), "POST foo")
+	st.Expect(t, send("POST", "/other", Options{PreserveMethod: true}), "GET ")
+	st.Expect(t, send("POST", "/temporary", Options{ReplayBody: true}), "POST foo")
+
+	st.Expect(t, send("QUERY", "/found", Options{PreserveMethod: true}), "QUERY foo")
+	st.Expect(t, send("QUERY", "/temporary", Options{ReplayBody: true}), "QUERY foo")
+}
+
+func TestRedirectPolicyStripsCredentialsCrossHost(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("foo", "bar")
+	headers.Set("Cookie", "session=secret")
+	headers.Set("Authorization", "Bearer secret")
+	prevReq := &http.Request{Header: headers, URL: mustParse("http://foo.com")}
+	pool := []*http.Request{prevReq}
+	opts := Options{SensitiveHeaders: []string{}}
+
+	// Same host keeps the credentials
+	req := &http.Request{Header: make(http.Header), URL: mustParse("http://foo.com/bar")}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+	st.Expect(t, req.Header.Get("Cookie"), "session=secret")
+	st.Expect(t, req.Header.Get("Authorization"), "Bearer secret")
+
+	req = &http.Request{Header: make(http.Header), URL: mustParse("http://evil.com")}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+	st.Expect(t, req.Header.Get("foo"), "bar")
+	st.Expect(t, req.Header.Get("Cookie"), "")
+	st.Expect(t, req.Header.Get("Authorization"), "")
+
+	opts.KeepCredentials = true
+	req = &http.Request{Header: make(http.Header), URL: mustParse("http://evil.com")}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+	st.Expect(t, req.Header.Get("Cookie"), "session=secret")
+
+	opts = Options{SensitiveHeaders: []string{}, TrustedHostSuffixes: []string{".foo.com"}}
+	req = &http.Request{Header: make(http.Header), Host: "api.foo.com", URL: mustParse("http://api.foo.com")}
+	st.Expect(t, redirectPolicy(opts, req, pool), nil)
+	st.Expect(t, req.Header.Get("Cookie"), "session=secret")
+}
@@ -0,0 +1,157 @@
+package referer
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// RedirectLimit defines the maximum number of redirects followed when
+// no custom redirect policy is defined, matching net/http defaults.
+var RedirectLimit = 10
+
+// Policy represents a referrer policy, as defined by the W3C Referrer Policy specification.
+type Policy int
+
+const (
+	// NoReferrerWhenDowngrade sends the full URL, except when navigating from HTTPS to HTTP.
+	NoReferrerWhenDowngrade Policy = iota
+
+	// NoReferrer never sends the Referer header.
+	NoReferrer
+
+	// Origin only sends the origin of the referring URL.
+	Origin
+
+	// OriginWhenCrossOrigin sends the full URL to the same origin,
+	// and only the origin otherwise.
+	OriginWhenCrossOrigin
+
+	// SameOrigin only sends the full URL to the same origin.
+	SameOrigin
+
+	// StrictOrigin only sends the origin, except when navigating from HTTPS to HTTP.
+	StrictOrigin
+
+	// StrictOriginWhenCrossOrigin sends the full URL to the same origin, only the origin
+	// to other origins, and nothing when navigating from HTTPS to HTTP.
+	StrictOriginWhenCrossOrigin
+
+	// UnsafeURL always sends the full URL.
+	UnsafeURL
+)
+
+// Referer returns the Referer header value used when navigating from the given URL
+// to the given target URL, or an empty string if the referrer must be suppressed.
+// URL fragments and user credentials are never sent.
+func (policy Policy) Referer(from, to *url.URL) string {
+	if from == nil || to == nil || (from.Scheme != "http" && from.Scheme != "https") {
+		return ""
+	}
+
+	full := *from
+	full.User = nil
+	full.Fragment = ""
+	origin := from.Scheme + "://" + from.Host + "/"
+	sameOrigin := from.Scheme == to.Scheme && strings.EqualFold(from.Host, to.Host)
+	downgrade := from.Scheme == "https" && to.Scheme != "https"
+
+	switch policy {
+	case NoReferrer:
+		return ""
+	case Origin:
+		return origin
+	case OriginWhenCrossOrigin:
+		if sameOrigin {
+			return full.String()
+		}
+		return origin
+	case SameOrigin:
+		if sameOrigin {
+			return full.String()
+		}
+		return ""
+	case StrictOrigin:
+		if downgrade {
+			return ""
+		}
+		return origin
+	case StrictOriginWhenCrossOrigin:
+		if sameOrigin {
+			return full.String()
+		}
+		if downgrade {
+			return ""
+		}
+		return origin
+	case UnsafeURL:
+		return full.String()
+	}
+
+	// Defaults to NoReferrerWhenDowngrade
+	if downgrade {
+		return ""
+	}
+	return full.String()
+}
+
+// New creates a new plugin which applies the given referrer policy to the Referer
+// header defined in the request, such as via From(), and to every redirect hop.
+func New(policy Policy) p.Plugin {
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		req := ctx.Request
+		if ref := req.Header.Get("Referer"); ref != "" {
+			from, _ := url.Parse(ref)
+			setReferer(req.Header, policy, from, req.URL)
+		}
+
+		next := ctx.Client.CheckRedirect
+		ctx.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if next != nil {
+				if err := next(req, via); err != nil {
+					return err
+				}
+			} else if len(via) >= RedirectLimit {
+				return errors.New("gentleman: stopped after " + strconv.Itoa(RedirectLimit) + " redirects")
+			}
+			setReferer(req.Header, policy, via[len(via)-1].URL, req.URL)
+			return nil
+		}
+
+		h.Next(ctx)
+	})
+}
+
+// Disable creates a new plugin which never sends the Referer header.
+func Disable() p.Plugin {
+	return New(NoReferrer)
+}
+
+// From defines the URL of the page the request navigates from as Referer header.
+// URL fragments and user credentials are removed.
+// Use it along with New() to apply a referrer policy.
+func From(rawurl string) p.Plugin {
+	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		from, err := url.Parse(rawurl)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		setReferer(ctx.Request.Header, UnsafeURL, from, ctx.Request.URL)
+		h.Next(ctx)
+	})
+}
+
+// setReferer sets or removes the Referer header based on the given policy.
+func setReferer(header http.Header, policy Policy, from, to *url.URL) {
+	if ref := policy.Referer(from, to); ref != "" {
+		header.Set("Referer", ref)
+	} else {
+		header.Del("Referer")
+	}
+}
@@ -0,0 +1,259 @@
+package retry
+
+import (
+	"bytes"
+	gocontext "context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	g "gopkg.in/h2non/gentleman.v2"
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// DefaultAttempts stores the default maximum number of attempts per request.
+const DefaultAttempts = 3
+
+// DefaultMinDelay stores the default delay before the first retry.
+var DefaultMinDelay = 100 * time.Millisecond
+
+// DefaultMaxDelay stores the default maximum delay between retries.
+var DefaultMaxDelay = 5 * time.Second
+
+// Backoff represents the function used to calculate the delay before
+// the given retry attempt, starting from 1.
+type Backoff func(attempt int) time.Duration
+
+// Evaluator represents the function used to decide if the given
+// request must be retried based on the received response or error.
+type Evaluator func(req *http.Request, res *http.Response, err error) bool
+
+// Options represents the retry plugin options.
+type Options struct {
+	// Attempts stores the maximum number of attempts per request,
+	// including the first one. Defaults to DefaultAttempts.
+	Attempts int
+
+	// Backoff stores the function used to calculate the delay between retries.
+	// Defaults to Exponential(DefaultMinDelay, MaxDelay).
+	Backoff Backoff
+
+	// MaxDelay stores the maximum delay between retries, also bounding
+	// the Retry-After response header delay. Defaults to DefaultMaxDelay.
+	MaxDelay time.Duration
+
+	// Retry stores the function deciding if the request must be retried.
+	// Defaults to DefaultEvaluator.
+	Retry Evaluator
+}
+
+// Exponential returns a Backoff doubling the delay on every retry,
+// starting from min and never exceeding max.
+func Exponential(min, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := min
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// DefaultEvaluator retries idempotent requests failed due to network errors
+// or replied with a 429, 502, 503 or 504 response status.
+func DefaultEvaluator(req *http.Request, res *http.Response, err error) bool {
+	if !idempotent(req.Method) || req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// contextKey is the request context key used to store the gentleman context of the retried request.
+type contextKey struct{}
+
+// New creates a new plugin retrying the failed requests.
+// The request body is buffered in order to be sent again, unless replayable via GetBody.
+// The Retry-After response header, if present, takes precedence over the backoff delay,
+// bounded by the maximum delay.
+func New(opts Options) p.Plugin {
+	if opts.Attempts <= 0 {
+		opts.Attempts = DefaultAttempts
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = Exponential(DefaultMinDelay, opts.MaxDelay)
+	}
+	if opts.Retry == nil {
+		opts.Retry = DefaultEvaluator
+	}
+
+	// The retrying transport is created once per plugin instance and original transport,
+	// as long as the original transport is comparable, otherwise it is created per request.
+	var mtx sync.Mutex
+	transports := make(map[http.RoundTripper]*transport)
+
+	// Uses the "before dial" phase in order to wrap the final transport,
+	// once the request phase had the chance to define a custom one.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		base := ctx.Client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+
+		rt := &transport{base: base, opts: opts}
+		if reflect.TypeOf(base).Comparable() {
+			mtx.Lock()
+			if cached, ok := transports[base]; ok {
+				rt = cached
+			} else {
+				transports[base] = rt
+			}
+			mtx.Unlock()
+		}
+
+		// The request context carries the gentleman context, since the transport is shared
+		ctx.Request = ctx.Request.WithContext(gocontext.WithValue(ctx.Request.Context(), contextKey{}, ctx))
+		ctx.Client.Transport = rt
+		h.Next(ctx)
+	})
+}
+
+// transport implements a http.RoundTripper retrying the failed requests.
+type transport struct {
+	base http.RoundTripper
+	opts Options
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+// Every retry is sent via a copy of the given request, which is never modified.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, _ := req.Context().Value(contextKey{}).(*c.Context)
+	getBody, buffered, err := rewindable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		outreq := req
+		if buffered || attempt > 1 {
+			outreq = req.Clone(req.Context())
+			if getBody != nil {
+				if outreq.Body, err = getBody(); err != nil {
+					return nil, err
+				}
+				outreq.GetBody = getBody
+			}
+		}
+
+		start := time.Now()
+		res, err := t.base.RoundTrip(outreq)
+		if ctx != nil {
+			g.RecordAttempt(ctx, g.NewAttempt(start, res, err))
+		}
+		if attempt >= t.opts.Attempts || !t.opts.Retry(outreq, res, err) {
+			return res, err
+		}
+
+		delay := t.opts.Backoff(attempt)
+		if res != nil {
+			if after, ok := retryAfter(res); ok {
+				delay = after
+			}
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		if delay > t.opts.MaxDelay {
+			delay = t.opts.MaxDelay
+		}
+
+		if ctx != nil {
+			g.EmitEvent(ctx, g.Event{Type: g.RetryScheduled, Request: outreq, Response: res, Error: err, Delay: delay})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		// Count the retried dial attempt
+		if ctx != nil {
+			count, _ := ctx.GetInt(g.AttemptKey)
+			ctx.Set(g.AttemptKey, count+1)
+			g.EmitEvent(ctx, g.Event{Type: g.RequestStarted, Request: outreq})
+		}
+	}
+}
+
+// rewindable returns the function used to replay the request body, if any.
+// The body is buffered, consuming the request body, unless replayable via GetBody.
+func rewindable(req *http.Request) (getBody func() (io.ReadCloser, error), buffered bool, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false, nil
+	}
+	if req.GetBody != nil {
+		return req.GetBody, false, nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}, true, nil
+}
+
+// retryAfter returns the delay announced by the Retry-After response header, if any.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	return ParseRetryAfter(res.Header.Get("Retry-After"))
+}
+
+// ParseRetryAfter returns the delay announced by the given Retry-After header value,
+// either in seconds or as an HTTP date, if valid.
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// idempotent returns true if the given HTTP method is idempotent.
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE", g.MethodQuery:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package retry
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	c "gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Backoff: Exponential(time.Millisecond, time.Millisecond)}))
+
+	res, err := cli.Put().BodyString("data").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.String(), "ok")
+	st.Expect(t, bodies, []string{"data", "data", "data"})
+	attempts, _ := res.Context.GetInt(gentleman.AttemptKey)
+	st.Expect(t, attempts, 3)
+	st.Expect(t, len(res.Attempts()), 3)
+	st.Expect(t, res.Attempts()[0].Status, 503)
+	st.Expect(t, res.Attempts()[2].Number, 3)
+	st.Expect(t, res.Attempts()[2].Status, 200)
+
+	// Non idempotent requests are not retried
+	atomic.StoreInt32(&calls, 0)
+	res, err = cli.Post().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 503)
+	st.Expect(t, atomic.LoadInt32(&calls), int32(1))
+}
+
+func TestRetryAttempts(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(429)
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Attempts: 2, Backoff: Exponential(time.Hour, time.Hour)}))
+	var events []gentleman.EventType
+	cli.Events().Subscribe(func(event gentleman.Event) {
+		events = append(events, event.Type)
+	}, gentleman.RequestStarted, gentleman.RetryScheduled)
+
+	res, err := cli.Get().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 429)
+	st.Expect(t, atomic.LoadInt32(&calls), int32(2))
+	st.Expect(t, events, []gentleman.EventType{gentleman.RequestStarted, gentleman.RetryScheduled, gentleman.RequestStarted})
+}
+
+func TestRetryAfterBounded(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{MaxDelay: time.Millisecond}))
+	var delay time.Duration
+	cli.Events().Subscribe(func(event gentleman.Event) { delay = event.Delay }, gentleman.RetryScheduled)
+
+	res, err := cli.Get().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, delay, time.Millisecond)
+}
+
+func TestRetryRequestUnmodified(t *testing.T) {
+	var calls int32
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+
+	var transports []http.RoundTripper
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Backoff: Exponential(time.Millisecond, time.Millisecond)}))
+	cli.UseHandler("validate", func(ctx *c.Context, h c.Handler) {
+		transports = append(transports, ctx.Client.Transport)
+		h.Next(ctx)
+	})
+
+	var sent *http.Request
+	var body io.ReadCloser
+	cli.OnBeforeSend(func(req *http.Request) error {
+		sent, body = req, req.Body
+		return nil
+	})
+
+	res, err := cli.Put().BodyString("data").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, bodies, []string{"data", "data"})
+
+	// The caller request is never modified by the retries
+	st.Expect(t, sent.Body == body, true)
+	st.Expect(t, sent.GetBody == nil, true)
+
+	// The retrying transport is shared by the requests
+	_, err = cli.Get().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, len(transports), 2)
+	st.Expect(t, transports[0] == transports[1], true)
+}
+
+func TestExponential(t *testing.T) {
+	backoff := Exponential(100*time.Millisecond, time.Second)
+	st.Expect(t, backoff(1), 100*time.Millisecond)
+	st.Expect(t, backoff(2), 200*time.Millisecond)
+	st.Expect(t, backoff(4), 800*time.Millisecond)
+	st.Expect(t, backoff(5), time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	delay, ok := ParseRetryAfter(" 3 ")
+	st.Expect(t, ok, true)
+	st.Expect(t, delay, 3*time.Second)
+
+	delay, ok = ParseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	st.Expect(t, ok, true)
+	st.Expect(t, delay, time.Duration(0))
+
+	_, ok = ParseRetryAfter("soon")
+	st.Expect(t, ok, false)
+	_, ok = ParseRetryAfter("")
+	st.Expect(t, ok, false)
+}
@@ -0,0 +1,180 @@
+package rewrite
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// appliedKey is the context store key used to store the names of the applied rules.
+const appliedKey = "$rewrite.applied"
+
+// Rule represents a URL rewrite rule. A rule applies if all of its defined conditions
+// match the request URL, rewriting the defined URL parts.
+type Rule struct {
+	// Name defines the rule name reported once applied.
+	Name string
+
+	// Host matches the URL host exactly, such as "api.vendor.com" or "api.vendor.com:8443".
+	Host string
+
+	// HostPattern matches the URL host via a regular expression.
+	HostPattern *regexp.Regexp
+
+	// PathPrefix matches the URL path prefix, such as "/v1/".
+	PathPrefix string
+
+	// PathPattern matches the URL path via a regular expression.
+	PathPattern *regexp.Regexp
+
+	// Scheme defines the new URL scheme, if any.
+	Scheme string
+
+	// NewHost defines the new URL host, if any. If HostPattern is defined,
+	// the submatches can be referenced, such as "$1.internal".
+	NewHost string
+
+	// NewPathPrefix replaces the matched PathPrefix, if any.
+	NewPathPrefix string
+
+	// NewPath defines the new URL path, if any. If PathPattern is defined,
+	// only the matched text is replaced and the submatches can be referenced, such as "/v2/$1".
+	NewPath string
+
+	// SetQuery defines the query params to set, if any.
+	SetQuery map[string]string
+
+	// DelQuery defines the query params to delete, if any.
+	DelQuery []string
+
+	// PreserveHost keeps the original Host header when the URL host is rewritten,
+	// which is useful when rewriting to proxies routing by the Host header.
+	PreserveHost bool
+}
+
+// Event represents an applied rewrite rule.
+type Event struct {
+	// Rule stores the applied rule name.
+	Rule string
+
+	// From stores the URL before the rule was applied.
+	From *url.URL
+
+	// To stores the URL once the rule was applied.
+	To *url.URL
+}
+
+// Options represents the URL rewrite options.
+type Options struct {
+	// Rules defines the rewrite rules, applied in order.
+	Rules []Rule
+
+	// First stops rewriting once a rule was applied.
+	// By default, every matching rule is applied to the URL rewritten by the previous ones.
+	First bool
+
+	// OnRewrite is called every time a rule is applied, such as to log the applied rules.
+	OnRewrite func(Event)
+}
+
+// Rules creates a new plugin which rewrites the request URL with the given rules.
+func Rules(rules ...Rule) p.Plugin {
+	return New(Options{Rules: rules})
+}
+
+// New creates a new plugin which rewrites the request URL with the given options.
+func New(opts Options) p.Plugin {
+	// Uses the "before dial" phase in order to rewrite the final request URL,
+	// once the request phase had the chance to define it.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		req := ctx.Request
+		for _, rule := range opts.Rules {
+			from := *req.URL
+			if !rule.apply(req.URL) {
+				continue
+			}
+
+			if req.URL.Host != from.Host {
+				if rule.PreserveHost && req.Host == "" {
+					req.Host = from.Host
+				} else if !rule.PreserveHost {
+					req.Host = ""
+				}
+			}
+
+			applied, _ := ctx.Get(appliedKey).([]string)
+			ctx.Set(appliedKey, append(applied, rule.Name))
+			if opts.OnRewrite != nil {
+				to := *req.URL
+				opts.OnRewrite(Event{Rule: rule.Name, From: &from, To: &to})
+			}
+			if opts.First {
+				break
+			}
+		}
+		h.Next(ctx)
+	})
+}
+
+// Applied returns the names of the rules applied to the request URL, if any.
+func Applied(ctx *c.Context) []string {
+	applied, _ := ctx.Get(appliedKey).([]string)
+	return applied
+}
+
+// apply rewrites the given URL if the rule matches, returning true if applied.
+func (r Rule) apply(u *url.URL) bool {
+	if r.Host != "" && !strings.EqualFold(r.Host, u.Host) {
+		return false
+	}
+	if r.HostPattern != nil && !r.HostPattern.MatchString(u.Host) {
+		return false
+	}
+	if r.PathPrefix != "" && !strings.HasPrefix(u.Path, r.PathPrefix) {
+		return false
+	}
+	if r.PathPattern != nil && !r.PathPattern.MatchString(u.Path) {
+		return false
+	}
+
+	if r.Scheme != "" {
+		u.Scheme = r.Scheme
+	}
+	if r.NewHost != "" {
+		if r.HostPattern != nil {
+			u.Host = r.HostPattern.ReplaceAllString(u.Host, r.NewHost)
+		} else {
+			u.Host = r.NewHost
+		}
+	}
+
+	path := u.Path
+	if r.PathPrefix != "" && r.NewPathPrefix != "" {
+		path = r.NewPathPrefix + strings.TrimPrefix(path, r.PathPrefix)
+	}
+	if r.NewPath != "" {
+		if r.PathPattern != nil {
+			path = r.PathPattern.ReplaceAllString(path, r.NewPath)
+		} else {
+			path = r.NewPath
+		}
+	}
+	if path != u.Path {
+		u.Path, u.RawPath = path, ""
+	}
+
+	if len(r.SetQuery) > 0 || len(r.DelQuery) > 0 {
+		query := u.Query()
+		for name, value := range r.SetQuery {
+			query.Set(name, value)
+		}
+		for _, name := range r.DelQuery {
+			query.Del(name)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package rewrite
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestRules(t *testing.T) {
+	ctx := context.New()
+	ctx.Request.URL, _ = url.Parse("https://api.vendor.com/v1/users?debug=1&page=2")
+	fn := newHandler()
+
+	Rules(
+		Rule{Name: "proxy", Host: "api.vendor.com", Scheme: "http", NewHost: "proxy.internal:8080", PreserveHost: true},
+		Rule{Name: "version", PathPrefix: "/v1/", NewPathPrefix: "/v2/", DelQuery: []string{"debug"}, SetQuery: map[string]string{"source": "gentleman"}},
+		Rule{Name: "unmatched", Host: "other.com", NewHost: "foo"},
+	).Exec("before dial", ctx, fn.fn)
+
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Request.URL.String(), "http://proxy.internal:8080/v2/users?page=2&source=gentleman")
+	st.Expect(t, ctx.Request.Host, "api.vendor.com")
+	st.Expect(t, Applied(ctx), []string{"proxy", "version"})
+}
+
+func TestRegexpRules(t *testing.T) {
+	var events []Event
+	plugin := New(Options{
+		Rules: []Rule{
+			{Name: "region", HostPattern: regexp.MustCompile(`^(\w+)\.vendor\.com$`), NewHost: "$1.eu.vendor.com"},
+			{Name: "ids", PathPattern: regexp.MustCompile(`/items/(\d+)$`), NewPath: "/products/$1"},
+		},
+		First:     true,
+		OnRewrite: func(e Event) { events = append(events, e) },
+	})
+
+	ctx := context.New()
+	ctx.Request.URL, _ = url.Parse("http://api.vendor.com/items/12")
+	ctx.Request.Host = "api.vendor.com"
+	plugin.Exec("before dial", ctx, newHandler().fn)
+
+	st.Expect(t, ctx.Request.URL.String(), "http://api.eu.vendor.com/items/12")
+	st.Expect(t, ctx.Request.Host, "")
+	st.Expect(t, len(events), 1)
+	st.Expect(t, events[0].Rule, "region")
+	st.Expect(t, events[0].From.Host, "api.vendor.com")
+	st.Expect(t, events[0].To.Host, "api.eu.vendor.com")
+
+	ctx = context.New()
+	ctx.Request.URL, _ = url.Parse("http://localhost/items/12")
+	plugin.Exec("before dial", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.URL.String(), "http://localhost/products/12")
+	st.Expect(t, Applied(ctx), []string{"ids"})
+}
+
+type handler struct {
+	fn     context.Handler
+	called bool
+}
+
+func newHandler() *handler {
+	h := &handler{}
+	h.fn = context.NewHandler(func(c *context.Context) {
+		h.called = true
+	})
+	return h
+}
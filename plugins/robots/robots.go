@@ -0,0 +1,432 @@
+package robots
+
+import (
+	"bufio"
+	gocontext "context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/politeness"
+)
+
+var (
+	// ErrDisallowed is returned when the request URL is disallowed by the host robots.txt rules.
+	ErrDisallowed = errors.New("gentleman: URL disallowed by robots.txt")
+
+	// DefaultTTL defines the default amount of time a robots.txt file is cached.
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultErrorTTL defines the default amount of time an unreachable robots.txt file is cached.
+	DefaultErrorTTL = time.Minute
+
+	// DefaultTimeout defines the default robots.txt fetch timeout.
+	DefaultTimeout = 10 * time.Second
+
+	// MaxSize defines the maximum robots.txt body size to be parsed.
+	MaxSize int64 = 500 * 1024
+)
+
+// disallowedKey is the context store key used to flag disallowed requests.
+const disallowedKey = "$robots.disallowed"
+
+// Options represents the robots.txt compliance options.
+type Options struct {
+	// UserAgent defines the user agent used to match the robots.txt rules.
+	// Defaults to the outgoing request User-Agent header.
+	UserAgent string
+
+	// Flag flags disallowed requests in the context instead of rejecting them.
+	// See Disallowed().
+	Flag bool
+
+	// TTL defines the amount of time a robots.txt file is cached per host.
+	// Defaults to DefaultTTL.
+	TTL time.Duration
+
+	// ErrorTTL defines the amount of time an unreachable robots.txt file, due to a network
+	// error or a 5xx response, is cached per host, disallowing everything meanwhile.
+	// Defaults to DefaultErrorTTL.
+	ErrorTTL time.Duration
+
+	// Timeout defines the robots.txt fetch timeout, which is independent of the
+	// request triggering the fetch. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Client is an optional HTTP client used to fetch robots.txt files.
+	// Defaults to the outgoing request http.Client.
+	Client *http.Client
+
+	// Scheduler is an optional politeness scheduler that will be configured with
+	// the host Crawl-delay directive. The politeness plugin based on the same scheduler
+	// must be registered after the robots plugin.
+	Scheduler *politeness.Scheduler
+}
+
+// Robots implements a plugin that fetches, caches and evaluates robots.txt rules per host.
+type Robots struct {
+	// Robots implements the plugin interface.
+	*p.Layer
+
+	// opts stores the robots options.
+	opts Options
+
+	// mtx protects the hosts cache.
+	mtx sync.Mutex
+
+	// hosts stores the cached robots.txt files by scheme and host.
+	hosts map[string]*entry
+}
+
+// entry represents a cached robots.txt file.
+type entry struct {
+	ready   chan struct{}
+	file    *File
+	expires time.Time
+}
+
+// New creates a new robots.txt compliance plugin.
+func New(opts Options) *Robots {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.ErrorTTL == 0 {
+		opts.ErrorTTL = DefaultErrorTTL
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	r := &Robots{Layer: p.New(), opts: opts, hosts: make(map[string]*entry)}
+	// Uses the "before dial" phase in order to use the final request URL.
+	r.SetHandler("before dial", r.handler)
+	return r
+}
+
+// Disallowed returns true if the request was flagged as disallowed by robots.txt rules.
+func Disallowed(ctx *c.Context) bool {
+	v, _ := ctx.Get(disallowedKey).(bool)
+	return v
+}
+
+func (r *Robots) handler(ctx *c.Context, h c.Handler) {
+	if ctx.Request.URL.Path == "/robots.txt" {
+		h.Next(ctx)
+		return
+	}
+
+	file := r.fetch(ctx, ctx.Request.URL)
+	agent := r.opts.UserAgent
+	if agent == "" {
+		agent = ctx.Request.Header.Get("User-Agent")
+	}
+
+	if r.opts.Scheduler != nil {
+		if delay, ok := file.CrawlDelay(agent); ok {
+			r.opts.Scheduler.SetHostDelay(ctx.Request.URL.Host, delay)
+		}
+	}
+
+	if !file.Allowed(agent, ctx.Request.URL) {
+		if !r.opts.Flag {
+			h.Error(ctx, ErrDisallowed)
+			return
+		}
+		ctx.Set(disallowedKey, true)
+	}
+
+	h.Next(ctx)
+}
+
+// Allowed returns true if the given URL can be fetched according to the
+// host robots.txt rules. robots.txt files are fetched using http.DefaultClient
+// unless a custom client is defined in the options.
+func (r *Robots) Allowed(uri, agent string) (bool, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false, err
+	}
+	ctx := c.New()
+	ctx.Client = http.DefaultClient
+	return r.fetch(ctx, u).Allowed(agent, u), nil
+}
+
+// CrawlDelay returns the cached Crawl-delay directive for the given URL host and user agent.
+func (r *Robots) CrawlDelay(uri, agent string) (time.Duration, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return 0, false
+	}
+	r.mtx.Lock()
+	e, ok := r.hosts[u.Scheme+"://"+u.Host]
+	r.mtx.Unlock()
+	if !ok {
+		return 0, false
+	}
+	<-e.ready
+	return e.file.CrawlDelay(agent)
+}
+
+// fetch returns the robots.txt file for the given URL host, fetching it only once
+// per host even with concurrent requests.
+func (r *Robots) fetch(ctx *c.Context, u *url.URL) *File {
+	key := u.Scheme + "://" + u.Host
+
+	r.mtx.Lock()
+	e, ok := r.hosts[key]
+	if ok && time.Now().After(e.expires) {
+		select {
+		case <-e.ready:
+			ok = false
+		default:
+		}
+	}
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		r.hosts[key] = e
+	}
+	r.mtx.Unlock()
+
+	if ok {
+		<-e.ready
+		return e.file
+	}
+
+	var ttl time.Duration
+	e.file, ttl = r.download(ctx, key+"/robots.txt")
+	e.expires = time.Now().Add(ttl)
+	close(e.ready)
+	return e.file
+}
+
+// download fetches and parses the given robots.txt URL, returning the file and the amount
+// of time it is cached, following RFC 9309: unavailable files (4xx) allow everything,
+// unreachable files (5xx or network errors) disallow everything, but are cached briefly.
+// The file is fetched with its own timeout, regardless of the triggering request.
+func (r *Robots) download(ctx *c.Context, uri string) (*File, time.Duration) {
+	cli := r.opts.Client
+	if cli == nil {
+		cli = ctx.Client
+	}
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return disallowAll, r.opts.ErrorTTL
+	}
+	reqCtx, cancel := gocontext.WithTimeout(gocontext.Background(), r.opts.Timeout)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	if agent := ctx.Request.Header.Get("User-Agent"); agent != "" {
+		req.Header.Set("User-Agent", agent)
+	}
+
+	res, err := cli.Do(req)
+	if err != nil {
+		return disallowAll, r.opts.ErrorTTL
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return Parse(io.LimitReader(res.Body, MaxSize)), r.opts.TTL
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return allowAll, r.opts.TTL
+	default:
+		return disallowAll, r.opts.ErrorTTL
+	}
+}
+
+// allowAll represents an empty robots.txt file, which allows everything.
+var allowAll = &File{}
+
+// disallowAll represents a robots.txt file which disallows everything.
+var disallowAll = &File{groups: []*group{{agents: []string{"*"}, rules: []rule{{allow: false, pattern: "/"}}}}}
+
+// File represents a parsed robots.txt file.
+type File struct {
+	groups []*group
+}
+
+// group represents a robots.txt group of rules for a set of user agents.
+type group struct {
+	agents   []string
+	rules    []rule
+	delay    time.Duration
+	hasDelay bool
+}
+
+// rule represents a single allow or disallow rule.
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// Parse parses the given robots.txt file stream.
+func Parse(r io.Reader) *File {
+	file := &File{}
+	var current *group
+	var collecting bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			if !collecting {
+				current = &group{}
+				file.groups = append(file.groups, current)
+				collecting = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			collecting = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			collecting = false
+			if current == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs >= 0 {
+				current.delay = time.Duration(secs * float64(time.Second))
+				current.hasDelay = true
+			}
+		}
+	}
+
+	return file
+}
+
+// Allowed returns true if the given user agent is allowed to fetch the given URL.
+func (f *File) Allowed(agent string, u *url.URL) bool {
+	g := f.match(agent)
+	if g == nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, length := true, -1
+	for _, rule := range g.rules {
+		if !matchPattern(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > length || (n == length && rule.allow) {
+			allowed, length = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay directive for the given user agent, if any.
+func (f *File) CrawlDelay(agent string) (time.Duration, bool) {
+	if g := f.match(agent); g != nil {
+		return g.delay, g.hasDelay
+	}
+	return 0, false
+}
+
+// match returns the group matching the product token of the given user agent
+// case-insensitively, combining the matching groups as per RFC 9309,
+// or the wildcard groups if none matches.
+func (f *File) match(agent string) *group {
+	token := strings.ToLower(agent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var wildcard, matched []*group
+	for _, g := range f.groups {
+		if token != "" && containsAgent(g.agents, token) {
+			matched = append(matched, g)
+		} else if containsAgent(g.agents, "*") {
+			wildcard = append(wildcard, g)
+		}
+	}
+
+	if len(matched) > 0 {
+		return combine(matched)
+	}
+	return combine(wildcard)
+}
+
+func containsAgent(agents []string, agent string) bool {
+	for _, name := range agents {
+		if name == agent {
+			return true
+		}
+	}
+	return false
+}
+
+// combine returns a group combining the rules of the given groups, if any.
+// The first Crawl-delay directive is used.
+func combine(groups []*group) *group {
+	switch len(groups) {
+	case 0:
+		return nil
+	case 1:
+		return groups[0]
+	}
+	combined := &group{}
+	for _, g := range groups {
+		combined.rules = append(combined.rules, g.rules...)
+		if g.hasDelay && !combined.hasDelay {
+			combined.delay, combined.hasDelay = g.delay, true
+		}
+	}
+	return combined
+}
+
+// matchPattern matches the given URL path against a robots.txt rule pattern,
+// supporting the "*" wildcard and the "$" end anchor.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
@@ -0,0 +1,260 @@
+package robots
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/politeness"
+)
+
+const robotsFile = `
+# Sample robots.txt
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Crawl-delay: 0.2
+
+User-agent: FooBot
+User-agent: barbot
+Disallow: /
+Allow: /foo
+`
+
+func newServer(robots string, status int, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(hits, 1)
+			w.WriteHeader(status)
+			w.Write([]byte(robots))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+}
+
+func allowed(f *File, agent, path string) bool {
+	u, _ := url.Parse("http://foo.com" + path)
+	return f.Allowed(agent, u)
+}
+
+func TestParse(t *testing.T) {
+	f := Parse(strings.NewReader(robotsFile))
+	st.Expect(t, len(f.groups), 2)
+
+	st.Expect(t, allowed(f, "gentleman/2.0", "/"), true)
+	st.Expect(t, allowed(f, "gentleman/2.0", "/private/foo"), false)
+	st.Expect(t, allowed(f, "gentleman/2.0", "/private/public/foo"), true)
+	st.Expect(t, allowed(f, "gentleman/2.0", "/docs/file.pdf"), false)
+	st.Expect(t, allowed(f, "gentleman/2.0", "/docs/file.pdf?foo=bar"), true)
+
+	st.Expect(t, allowed(f, "FooBot/1.0", "/"), false)
+	st.Expect(t, allowed(f, "foobot", "/foo/bar"), true)
+	st.Expect(t, allowed(f, "BarBot/2.1 (+http://bar.com)", "/private"), false)
+
+	// User agents are matched exactly by product token
+	st.Expect(t, allowed(f, "FooBotPlus/1.0", "/"), true)
+	st.Expect(t, allowed(f, "Foo/1.0", "/"), true)
+
+	delay, ok := f.CrawlDelay("gentleman")
+	st.Expect(t, ok, true)
+	st.Expect(t, delay, 200*time.Millisecond)
+	_, ok = f.CrawlDelay("foobot")
+	st.Expect(t, ok, false)
+}
+
+func TestParseCombine(t *testing.T) {
+	f := Parse(strings.NewReader(`
+User-agent: foobot
+Disallow: /private
+
+User-agent: *
+Disallow: /
+
+User-agent: FooBot
+Allow: /private/public
+Crawl-delay: 1
+`))
+
+	st.Expect(t, allowed(f, "foobot", "/"), true)
+	st.Expect(t, allowed(f, "foobot", "/private"), false)
+	st.Expect(t, allowed(f, "foobot", "/private/public"), true)
+	st.Expect(t, allowed(f, "gentleman", "/"), false)
+
+	delay, ok := f.CrawlDelay("FooBot")
+	st.Expect(t, ok, true)
+	st.Expect(t, delay, time.Second)
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/", "/foo", true},
+		{"/foo", "/foo/bar", true},
+		{"/foo", "/bar", false},
+		{"/foo$", "/foo", true},
+		{"/foo$", "/foo/bar", false},
+		{"/*/bar", "/foo/bar", true},
+		{"/*/bar", "/foo/baz", false},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php5", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+	}
+	for _, test := range cases {
+		st.Expect(t, matchPattern(test.pattern, test.path), test.match)
+	}
+}
+
+func TestRobotsReject(t *testing.T) {
+	var hits int32
+	ts := newServer(robotsFile, 200, &hits)
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{UserAgent: "gentleman"}))
+
+	res, err := cli.Request().Path("/foo").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "ok")
+
+	_, err = cli.Request().Path("/private/foo").Send()
+	st.Expect(t, err, ErrDisallowed)
+
+	res, err = cli.Request().Path("/private/public").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+
+	st.Expect(t, atomic.LoadInt32(&hits), int32(1))
+}
+
+func TestRobotsFlag(t *testing.T) {
+	var hits int32
+	ts := newServer(robotsFile, 200, &hits)
+	defer ts.Close()
+
+	var flagged bool
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Flag: true}))
+	cli.SetHeader("User-Agent", "FooBot/1.0")
+	cli.UseResponse(func(ctx *context.Context, h context.Handler) {
+		flagged = Disallowed(ctx)
+		h.Next(ctx)
+	})
+
+	res, err := cli.Request().Path("/bar").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, flagged, true)
+
+	_, err = cli.Request().Path("/foo").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, flagged, false)
+}
+
+func TestRobotsUnavailable(t *testing.T) {
+	var hits int32
+	ts := newServer("", 404, &hits)
+	defer ts.Close()
+
+	res, err := gentleman.New().URL(ts.URL).Use(New(Options{})).Request().Path("/private").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+
+	ts = newServer("", 503, &hits)
+	defer ts.Close()
+
+	_, err = gentleman.New().URL(ts.URL).Use(New(Options{})).Request().Path("/foo").Send()
+	st.Expect(t, err, ErrDisallowed)
+}
+
+func TestRobotsErrorTTL(t *testing.T) {
+	var hits int32
+	ts := newServer("", 503, &hits)
+	defer ts.Close()
+
+	r := New(Options{ErrorTTL: 50 * time.Millisecond})
+	ok, _ := r.Allowed(ts.URL+"/foo", "gentleman")
+	st.Expect(t, ok, false)
+	r.Allowed(ts.URL+"/foo", "gentleman")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(1))
+
+	time.Sleep(60 * time.Millisecond)
+	r.Allowed(ts.URL+"/foo", "gentleman")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestRobotsCanceledRequest(t *testing.T) {
+	var hits int32
+	ts := newServer(robotsFile, 200, &hits)
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{UserAgent: "gentleman"}))
+
+	// The robots.txt file is fetched regardless of the triggering request context
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	req := cli.Request().Path("/foo")
+	req.Context.SetCancelContext(ctx)
+	_, err := req.Send()
+	st.Reject(t, err, nil)
+
+	res, err := cli.Request().Path("/foo").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(1))
+}
+
+func TestRobotsTTL(t *testing.T) {
+	var hits int32
+	ts := newServer(robotsFile, 200, &hits)
+	defer ts.Close()
+
+	r := New(Options{TTL: 50 * time.Millisecond})
+	ok, err := r.Allowed(ts.URL+"/foo", "gentleman")
+	st.Expect(t, err, nil)
+	st.Expect(t, ok, true)
+	ok, _ = r.Allowed(ts.URL+"/private", "gentleman")
+	st.Expect(t, ok, false)
+	st.Expect(t, atomic.LoadInt32(&hits), int32(1))
+
+	time.Sleep(60 * time.Millisecond)
+	r.Allowed(ts.URL+"/foo", "gentleman")
+	st.Expect(t, atomic.LoadInt32(&hits), int32(2))
+}
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	var hits int32
+	ts := newServer(robotsFile, 200, &hits)
+	defer ts.Close()
+
+	scheduler := politeness.NewScheduler(0)
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{UserAgent: "gentleman", Scheduler: scheduler}))
+	cli.Use(politeness.New(scheduler))
+
+	start := time.Now()
+	_, err := cli.Request().Path("/foo").Send()
+	st.Expect(t, err, nil)
+	_, err = cli.Request().Path("/bar").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, time.Since(start) >= 200*time.Millisecond, true)
+
+	u, _ := url.Parse(ts.URL)
+	st.Expect(t, scheduler.HostDelay(u.Host), 200*time.Millisecond)
+
+	r := New(Options{})
+	_, ok := r.CrawlDelay(ts.URL, "gentleman")
+	st.Expect(t, ok, false)
+}
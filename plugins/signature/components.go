@@ -0,0 +1,234 @@
+package signature
+
+import (
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// message represents the HTTP message components source.
+type message struct {
+	// req stores the signed request, if any.
+	req *http.Request
+
+	// status stores the response status code, if any.
+	status int
+
+	// header stores the message header fields.
+	header http.Header
+}
+
+// requestMessage returns the message components of the given request.
+func requestMessage(req *http.Request) message {
+	return message{req: req, header: req.Header}
+}
+
+// responseMessage returns the message components of the given response.
+func responseMessage(res *http.Response) message {
+	return message{status: res.StatusCode, header: res.Header}
+}
+
+// signatureBase returns the signature base of the given message covered components
+// and serialized signature parameters.
+func signatureBase(msg message, components []string, params string) ([]byte, error) {
+	var b strings.Builder
+	for _, component := range components {
+		value, err := msg.component(component)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(strconv.Quote(component) + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return []byte(b.String()), nil
+}
+
+// component returns the value of the given component identifier.
+func (msg message) component(name string) (string, error) {
+	if name == "" || name != strings.ToLower(name) {
+		return "", ErrUnsupportedComponent
+	}
+	if name[0] != '@' {
+		values := msg.header[textproto.CanonicalMIMEHeaderKey(name)]
+		if len(values) == 0 {
+			return "", ErrMissingComponent
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.TrimSpace(value)
+		}
+		return strings.Join(trimmed, ", "), nil
+	}
+
+	if name == "@status" {
+		if msg.status == 0 {
+			return "", ErrUnsupportedComponent
+		}
+		return strconv.Itoa(msg.status), nil
+	}
+	if msg.req == nil {
+		return "", ErrUnsupportedComponent
+	}
+
+	target := targetURI(msg.req)
+	switch name {
+	case "@method":
+		return strings.ToUpper(msg.req.Method), nil
+	case "@target-uri":
+		return target.String(), nil
+	case "@authority":
+		return authority(target), nil
+	case "@scheme":
+		return strings.ToLower(target.Scheme), nil
+	case "@request-target":
+		return target.RequestURI(), nil
+	case "@path":
+		if path := target.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + target.RawQuery, nil
+	}
+	return "", ErrUnsupportedComponent
+}
+
+// targetURI returns the absolute target URI of the given request,
+// resolving the relative URIs of server requests.
+func targetURI(req *http.Request) *url.URL {
+	target := *req.URL
+	if target.Host == "" {
+		target.Host = req.Host
+	}
+	if target.Scheme == "" {
+		target.Scheme = "http"
+		if req.TLS != nil {
+			target.Scheme = "https"
+		}
+	}
+	return &target
+}
+
+// authority returns the normalized authority of the given URI, in lower case
+// and without the default scheme port.
+func authority(target *url.URL) string {
+	host := strings.ToLower(target.Host)
+	switch strings.ToLower(target.Scheme) {
+	case "http":
+		return strings.TrimSuffix(host, ":80")
+	case "https":
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// parseDictionary returns the member keys, in order, and raw values
+// of the given dictionary structured field value.
+func parseDictionary(value string) ([]string, map[string]string) {
+	var keys []string
+	members := make(map[string]string)
+	for _, member := range splitTopLevel(value, ',') {
+		member = strings.TrimSpace(member)
+		i := strings.IndexByte(member, '=')
+		if i <= 0 {
+			continue
+		}
+		key := member[:i]
+		if _, ok := members[key]; !ok {
+			keys = append(keys, key)
+		}
+		members[key] = member[i+1:]
+	}
+	return keys, members
+}
+
+// parseSignatureParams parses the given serialized signature parameters,
+// returning the covered components and the parameters.
+func parseSignatureParams(value string) ([]string, map[string]string, bool) {
+	if !strings.HasPrefix(value, "(") {
+		return nil, nil, false
+	}
+	end := closingParen(value)
+	if end < 0 {
+		return nil, nil, false
+	}
+
+	var components []string
+	for _, item := range strings.Fields(value[1:end]) {
+		component, err := strconv.Unquote(item)
+		if err != nil || !strings.HasPrefix(item, `"`) {
+			// Components with parameters are not supported
+			return nil, nil, false
+		}
+		components = append(components, component)
+	}
+
+	params := make(map[string]string)
+	for _, param := range splitTopLevel(value[end+1:], ';') {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		i := strings.IndexByte(param, '=')
+		if i <= 0 {
+			params[param] = "?1"
+			continue
+		}
+		key, raw := param[:i], param[i+1:]
+		if strings.HasPrefix(raw, `"`) {
+			unquoted, err := strconv.Unquote(raw)
+			if err != nil {
+				return nil, nil, false
+			}
+			raw = unquoted
+		}
+		params[key] = raw
+	}
+	return components, params, true
+}
+
+// splitTopLevel splits the given value by the separator outside quoted strings and inner lists.
+func splitTopLevel(value string, sep byte) []string {
+	var parts []string
+	quoted, escaped, depth, start := false, false, 0, 0
+	for i := 0; i < len(value); i++ {
+		switch b := value[i]; {
+		case escaped:
+			escaped = false
+		case quoted && b == '\\':
+			escaped = true
+		case b == '"':
+			quoted = !quoted
+		case quoted:
+		case b == '(':
+			depth++
+		case b == ')':
+			depth--
+		case b == sep && depth == 0:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// closingParen returns the index of the parenthesis closing the inner list
+// at the start of the given value, or -1.
+func closingParen(value string) int {
+	quoted, escaped := false, false
+	for i := 1; i < len(value); i++ {
+		switch b := value[i]; {
+		case escaped:
+			escaped = false
+		case quoted && b == '\\':
+			escaped = true
+		case b == '"':
+			quoted = !quoted
+		case !quoted && b == ')':
+			return i
+		}
+	}
+	return -1
+}
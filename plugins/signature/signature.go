@@ -0,0 +1,311 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+)
+
+// Algorithm represents a RFC 9421 signature algorithm.
+type Algorithm string
+
+const (
+	// Ed25519 represents the EdDSA signature algorithm using the Curve25519 curve.
+	Ed25519 Algorithm = "ed25519"
+
+	// ECDSAP256SHA256 represents the ECDSA signature algorithm using the P-256 curve and SHA-256.
+	ECDSAP256SHA256 Algorithm = "ecdsa-p256-sha256"
+
+	// ECDSAP384SHA384 represents the ECDSA signature algorithm using the P-384 curve and SHA-384.
+	ECDSAP384SHA384 Algorithm = "ecdsa-p384-sha384"
+
+	// RSAPSSSHA512 represents the RSASSA-PSS signature algorithm using SHA-512.
+	RSAPSSSHA512 Algorithm = "rsa-pss-sha512"
+)
+
+// DefaultLabel defines the default signature label.
+const DefaultLabel = "sig1"
+
+// DefaultComponents defines the default covered components of request signatures.
+var DefaultComponents = []string{"@method", "@target-uri"}
+
+var (
+	// ErrInvalidKey is returned when the key type does not match the signature algorithm.
+	ErrInvalidKey = errors.New("signature: invalid key for the signature algorithm")
+
+	// ErrUnsupportedAlgorithm is returned when using an unknown signature algorithm.
+	ErrUnsupportedAlgorithm = errors.New("signature: unsupported algorithm")
+
+	// ErrUnsupportedComponent is returned when covering an unknown derived component
+	// or a component with parameters.
+	ErrUnsupportedComponent = errors.New("signature: unsupported component")
+
+	// ErrMissingComponent is returned when a covered header field is not present in the message.
+	ErrMissingComponent = errors.New("signature: missing component")
+
+	// ErrMissingSignature is returned when the response has no signature matching the verification key.
+	ErrMissingSignature = errors.New("signature: missing signature")
+
+	// ErrInvalidSignature is returned when the response signature is malformed or does not verify.
+	ErrInvalidSignature = errors.New("signature: invalid signature")
+
+	// ErrExpiredSignature is returned when the response signature expired or is too old.
+	ErrExpiredSignature = errors.New("signature: expired signature")
+)
+
+// Key represents a private key used to sign the requests.
+type Key struct {
+	// ID defines the key identifier sent in the keyid signature parameter.
+	ID string
+
+	// Algorithm defines the signature algorithm.
+	Algorithm Algorithm
+
+	// Signer stores the private key, such as ed25519.PrivateKey,
+	// *ecdsa.PrivateKey or *rsa.PrivateKey, or any other crypto.Signer using them.
+	Signer crypto.Signer
+}
+
+// Options represents the request signature options.
+type Options struct {
+	// Label defines the signature label. Defaults to DefaultLabel.
+	Label string
+
+	// Components defines the covered components, as lower-case header field names
+	// or derived components, such as "@method", "@authority", "@path" or "content-digest".
+	// Defaults to DefaultComponents.
+	Components []string
+
+	// Expires defines the signature validity, sent in the expires signature parameter.
+	Expires time.Duration
+
+	// Nonce enables the random nonce signature parameter.
+	Nonce bool
+
+	// Tag defines the application specific tag signature parameter.
+	Tag string
+}
+
+// Sign creates a new plugin which signs the outgoing requests with the given key,
+// defining the Signature-Input and Signature headers. Existing signatures with
+// other labels are preserved.
+//
+// The plugin uses the "before dial" phase in order to sign the final request,
+// hence plugins defining covered headers in the same phase, such as the digest plugin,
+// must be registered before.
+func Sign(key Key, opts Options) p.Plugin {
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		if err := SignRequest(ctx.Request, key, opts); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
+
+// SignWith creates a new plugin which signs the outgoing requests with the key
+// returned by the given credentials provider, so the key can be rotated at runtime,
+// using the given algorithm. Fails with auth.ErrMissingCredentials if the provider
+// returns no signer. See Sign() for details.
+func SignWith(provider auth.CredentialsProvider, algorithm Algorithm, opts Options) p.Plugin {
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		creds, err := provider.Credentials()
+		if err == nil && creds.Signer == nil {
+			err = auth.ErrMissingCredentials
+		}
+		if err == nil {
+			err = SignRequest(ctx.Request, Key{ID: creds.KeyID, Algorithm: algorithm, Signer: creds.Signer}, opts)
+		}
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
+
+// SignRequest signs the given request with the given key, defining the Signature-Input
+// and Signature headers.
+func SignRequest(req *http.Request, key Key, opts Options) error {
+	if opts.Label == "" {
+		opts.Label = DefaultLabel
+	}
+	if len(opts.Components) == 0 {
+		opts.Components = DefaultComponents
+	}
+
+	params, err := serializeParams(key, opts, time.Now())
+	if err != nil {
+		return err
+	}
+	base, err := signatureBase(requestMessage(req), opts.Components, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signBytes(key, base)
+	if err != nil {
+		return err
+	}
+
+	addMember(req.Header, "Signature-Input", opts.Label, params)
+	addMember(req.Header, "Signature", opts.Label, ":"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// serializeParams returns the serialized signature parameters.
+func serializeParams(key Key, opts Options, now time.Time) (string, error) {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, component := range opts.Components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(component))
+	}
+	b.WriteByte(')')
+
+	created := now.Unix()
+	b.WriteString(";created=" + strconv.FormatInt(created, 10))
+	if opts.Expires > 0 {
+		b.WriteString(";expires=" + strconv.FormatInt(created+int64(opts.Expires/time.Second), 10))
+	}
+	if opts.Nonce {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		b.WriteString(`;nonce="` + base64.RawURLEncoding.EncodeToString(nonce) + `"`)
+	}
+	if key.ID != "" {
+		b.WriteString(";keyid=" + strconv.Quote(key.ID))
+	}
+	b.WriteString(";alg=" + strconv.Quote(string(key.Algorithm)))
+	if opts.Tag != "" {
+		b.WriteString(";tag=" + strconv.Quote(opts.Tag))
+	}
+	return b.String(), nil
+}
+
+// addMember adds the given member to the dictionary structured header,
+// replacing any member with the same label.
+func addMember(header http.Header, name, label, value string) {
+	members, raw := parseDictionary(strings.Join(header[textproto.CanonicalMIMEHeaderKey(name)], ", "))
+	var fields []string
+	for _, member := range members {
+		if member != label {
+			fields = append(fields, member+"="+raw[member])
+		}
+	}
+	header.Set(name, strings.Join(append(fields, label+"="+value), ", "))
+}
+
+// signBytes signs the given signature base with the given key.
+func signBytes(key Key, base []byte) ([]byte, error) {
+	if key.Signer == nil {
+		return nil, ErrInvalidKey
+	}
+	public := key.Signer.Public()
+
+	switch key.Algorithm {
+	case Ed25519:
+		if _, ok := public.(ed25519.PublicKey); !ok {
+			return nil, ErrInvalidKey
+		}
+		return key.Signer.Sign(rand.Reader, base, crypto.Hash(0))
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		curve, hash, digest := ecdsaParams(key.Algorithm, base)
+		if pub, ok := public.(*ecdsa.PublicKey); !ok || pub.Curve != curve {
+			return nil, ErrInvalidKey
+		}
+		der, err := key.Signer.Sign(rand.Reader, digest, hash)
+		if err != nil {
+			return nil, err
+		}
+		var sig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &sig); err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		raw := make([]byte, 2*size)
+		r, s := sig.R.Bytes(), sig.S.Bytes()
+		copy(raw[size-len(r):size], r)
+		copy(raw[2*size-len(s):], s)
+		return raw, nil
+	case RSAPSSSHA512:
+		if _, ok := public.(*rsa.PublicKey); !ok {
+			return nil, ErrInvalidKey
+		}
+		digest := sha512.Sum512(base)
+		return key.Signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// verifyBytes verifies the signature of the given signature base with the given public key.
+func verifyBytes(algorithm Algorithm, key crypto.PublicKey, base, sig []byte) error {
+	switch algorithm {
+	case Ed25519:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		if !ed25519.Verify(pub, base, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		curve, _, digest := ecdsaParams(algorithm, base)
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != curve {
+			return ErrInvalidKey
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case RSAPSSSHA512:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		digest := sha512.Sum512(base)
+		if rsa.VerifyPSS(pub, crypto.SHA512, digest[:], sig, &rsa.PSSOptions{SaltLength: 64}) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlgorithm
+}
+
+// ecdsaParams returns the curve, hash and digest of the given signature base for the ECDSA algorithms.
+func ecdsaParams(algorithm Algorithm, base []byte) (elliptic.Curve, crypto.Hash, []byte) {
+	if algorithm == ECDSAP384SHA384 {
+		digest := sha512.Sum384(base)
+		return elliptic.P384(), crypto.SHA384, digest[:]
+	}
+	digest := sha256.Sum256(base)
+	return elliptic.P256(), crypto.SHA256, digest[:]
+}
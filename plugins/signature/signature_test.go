@@ -0,0 +1,211 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+	"gopkg.in/h2non/gentleman.v2/plugins/digest"
+)
+
+func TestSignAlgorithms(t *testing.T) {
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	cases := []struct {
+		alg    Algorithm
+		signer crypto.Signer
+		public crypto.PublicKey
+	}{
+		{Ed25519, edKey, edPub},
+		{ECDSAP256SHA256, p256, &p256.PublicKey},
+		{ECDSAP384SHA384, p384, &p384.PublicKey},
+		{RSAPSSSHA512, rsaKey, &rsaKey.PublicKey},
+	}
+
+	for _, test := range cases {
+		var verified error
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			verified = verifyMessage(requestMessage(r), PublicKey{ID: "test-key", Algorithm: test.alg, Key: test.public},
+				VerifyOptions{Required: []string{"@method", "@authority", "content-digest"}}, time.Now())
+		}))
+
+		req := gentleman.NewRequest().URL(ts.URL + "/foo?bar=baz").Method("POST").BodyString(`{"hello": "world"}`)
+		req.Use(digest.ContentDigest())
+		req.Use(Sign(Key{ID: "test-key", Algorithm: test.alg, Signer: test.signer}, Options{
+			Components: []string{"@method", "@authority", "@path", "@query", "content-digest"},
+			Expires:    time.Minute,
+			Nonce:      true,
+			Tag:        "app",
+		}))
+		res, err := req.Send()
+		st.Expect(t, err, nil)
+		st.Expect(t, res.StatusCode, 200)
+		st.Expect(t, verified, nil)
+
+		input := res.RawRequest.Header.Get("Signature-Input")
+		st.Expect(t, strings.HasPrefix(input, `sig1=("@method" "@authority" "@path" "@query" "content-digest");created=`), true)
+		st.Expect(t, strings.Contains(input, `;keyid="test-key";alg="`+string(test.alg)+`";tag="app"`), true)
+		ts.Close()
+	}
+}
+
+func TestSignRequestBase(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	req, _ := http.NewRequest("POST", "https://Example.com:443/foo?param=Value&Pet=dog", nil)
+	req.Header.Add("X-Values", " a ")
+	req.Header.Add("X-Values", "b")
+
+	err := SignRequest(req, Key{Algorithm: Ed25519, Signer: key}, Options{
+		Label:      "sig-b21",
+		Components: []string{"@method", "@authority", "@scheme", "@target-uri", "@request-target", "@path", "@query", "x-values"},
+	})
+	st.Expect(t, err, nil)
+
+	_, inputs := parseDictionary(req.Header.Get("Signature-Input"))
+	base, err := signatureBase(requestMessage(req), []string{"@method", "@authority", "@scheme", "@target-uri", "@request-target", "@path", "@query", "x-values"}, inputs["sig-b21"])
+	st.Expect(t, err, nil)
+	st.Expect(t, string(base), `"@method": POST
+"@authority": example.com
+"@scheme": https
+"@target-uri": https://Example.com:443/foo?param=Value&Pet=dog
+"@request-target": /foo?param=Value&Pet=dog
+"@path": /foo
+"@query": ?param=Value&Pet=dog
+"x-values": a, b
+"@signature-params": `+inputs["sig-b21"])
+
+	// Signing again with another label preserves the existing signature
+	st.Expect(t, SignRequest(req, Key{Algorithm: Ed25519, Signer: key}, Options{Label: "sig2"}), nil)
+	labels, _ := parseDictionary(req.Header.Get("Signature"))
+	st.Expect(t, labels, []string{"sig-b21", "sig2"})
+}
+
+func TestSignErrors(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	st.Expect(t, SignRequest(req, Key{Algorithm: RSAPSSSHA512, Signer: edKey}, Options{}), ErrInvalidKey)
+	st.Expect(t, SignRequest(req, Key{Algorithm: "hmac-sha256", Signer: edKey}, Options{}), ErrUnsupportedAlgorithm)
+	st.Expect(t, SignRequest(req, Key{Algorithm: Ed25519, Signer: edKey}, Options{Components: []string{"content-digest"}}), ErrMissingComponent)
+	st.Expect(t, SignRequest(req, Key{Algorithm: Ed25519, Signer: edKey}, Options{Components: []string{"@status"}}), ErrUnsupportedComponent)
+	st.Expect(t, SignRequest(req, Key{Algorithm: Ed25519, Signer: edKey}, Options{Components: []string{"Host"}}), ErrUnsupportedComponent)
+
+	ctx := context.New()
+	Sign(Key{Algorithm: Ed25519}, Options{}).Exec("before dial", ctx, newHandler().fn)
+	st.Expect(t, ctx.Error, ErrInvalidKey)
+}
+
+func TestSignWith(t *testing.T) {
+	pub1, key1, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, key2, _ := ed25519.GenerateKey(rand.Reader)
+	creds := auth.Credentials{KeyID: "key1", Signer: key1}
+	plugin := SignWith(auth.CredentialsFunc(func() (auth.Credentials, error) {
+		return creds, nil
+	}), Ed25519, Options{})
+
+	verify := func(id string, pub ed25519.PublicKey) error {
+		ctx := context.New()
+		ctx.Request, _ = http.NewRequest("GET", "http://example.com/foo", nil)
+		plugin.Exec("before dial", ctx, newHandler().fn)
+		st.Expect(t, ctx.Error, nil)
+		return verifyMessage(requestMessage(ctx.Request), PublicKey{ID: id, Algorithm: Ed25519, Key: pub}, VerifyOptions{}, time.Now())
+	}
+	st.Expect(t, verify("key1", pub1), nil)
+
+	// The key is rotated at runtime
+	creds = auth.Credentials{KeyID: "key2", Signer: key2}
+	st.Expect(t, verify("key2", pub2), nil)
+	st.Expect(t, verify("key1", pub1), ErrMissingSignature)
+
+	creds = auth.Credentials{Token: "foo"}
+	ctx := context.New()
+	plugin.Exec("before dial", ctx, newHandler().fn)
+	st.Expect(t, ctx.Error, auth.ErrMissingCredentials)
+}
+
+func TestVerifyResponse(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		signResponse(t, w.Header(), 201, Key{ID: "server", Algorithm: Ed25519, Signer: key}, r.URL.Query().Get("expires"))
+		if r.URL.Query().Get("tamper") != "" {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+
+	public := PublicKey{ID: "server", Algorithm: Ed25519, Key: pub}
+	res, err := gentleman.NewRequest().URL(ts.URL).Use(Verify(public, VerifyOptions{Required: []string{"@status"}})).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 201)
+
+	_, err = gentleman.NewRequest().URL(ts.URL + "?tamper=1").Use(Verify(public, VerifyOptions{})).Send()
+	st.Expect(t, err, ErrInvalidSignature)
+
+	_, err = gentleman.NewRequest().URL(ts.URL + "?expires=1").Use(Verify(public, VerifyOptions{})).Send()
+	st.Expect(t, err, ErrExpiredSignature)
+
+	_, err = gentleman.NewRequest().URL(ts.URL).Use(Verify(public, VerifyOptions{Required: []string{"content-digest"}})).Send()
+	st.Expect(t, err, ErrInvalidSignature)
+
+	other := PublicKey{ID: "other", Algorithm: Ed25519, Key: pub}
+	_, err = gentleman.NewRequest().URL(ts.URL).Use(Verify(other, VerifyOptions{})).Send()
+	st.Expect(t, err, ErrMissingSignature)
+}
+
+func TestVerifyMaxAge(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	header := http.Header{"Content-Type": {"text/plain"}}
+	signResponse(t, header, 200, Key{Algorithm: Ed25519, Signer: key}, "")
+	res := &http.Response{StatusCode: 200, Header: header}
+
+	public := PublicKey{Algorithm: Ed25519, Key: pub}
+	st.Expect(t, verifyMessage(responseMessage(res), public, VerifyOptions{MaxAge: time.Minute}, time.Now()), nil)
+	st.Expect(t, verifyMessage(responseMessage(res), public, VerifyOptions{MaxAge: time.Minute}, time.Now().Add(time.Hour)), ErrExpiredSignature)
+}
+
+// signResponse signs the given response header and status code, as a server would.
+func signResponse(t *testing.T, header http.Header, status int, key Key, expires string) {
+	components := []string{"@status", "content-type"}
+	params, err := serializeParams(key, Options{Components: components}, time.Now())
+	st.Expect(t, err, nil)
+	if expires != "" {
+		params += ";expires=" + expires
+	}
+	base, err := signatureBase(message{status: status, header: header}, components, params)
+	st.Expect(t, err, nil)
+	sig, err := signBytes(key, base)
+	st.Expect(t, err, nil)
+	header.Set("Signature-Input", "sig1="+params)
+	header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(sig)+":")
+}
+
+type handler struct {
+	fn     context.Handler
+	called bool
+}
+
+func newHandler() *handler {
+	h := &handler{}
+	h.fn = context.NewHandler(func(c *context.Context) {
+		h.called = true
+	})
+	return h
+}
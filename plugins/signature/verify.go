@@ -0,0 +1,133 @@
+package signature
+
+import (
+	"crypto"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// PublicKey represents a public key used to verify the response signatures.
+type PublicKey struct {
+	// ID defines the key identifier matched against the keyid signature parameter, if any.
+	ID string
+
+	// Algorithm defines the signature algorithm.
+	Algorithm Algorithm
+
+	// Key stores the public key, such as ed25519.PublicKey, *ecdsa.PublicKey or *rsa.PublicKey.
+	Key crypto.PublicKey
+}
+
+// VerifyOptions represents the response signature verification options.
+type VerifyOptions struct {
+	// Label defines the label of the signature to verify.
+	// Defaults to any signature matching the key identifier and algorithm.
+	Label string
+
+	// Required defines the components which must be covered by the signature,
+	// such as "@status" or "content-digest".
+	Required []string
+
+	// MaxAge defines the maximum age of the signature, based on the created parameter.
+	// Expired signatures, based on the expires parameter, are always rejected.
+	MaxAge time.Duration
+}
+
+// Verify creates a new plugin which verifies the signature of the received responses
+// with the given public key, failing with the verification error otherwise.
+func Verify(key PublicKey, opts VerifyOptions) p.Plugin {
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		if err := VerifyResponse(ctx.Response, key, opts); err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		h.Next(ctx)
+	})
+}
+
+// VerifyResponse verifies the signature of the given response with the given public key.
+// Returns ErrMissingSignature if no signature matches the key, or the verification error.
+func VerifyResponse(res *http.Response, key PublicKey, opts VerifyOptions) error {
+	return verifyMessage(responseMessage(res), key, opts, time.Now())
+}
+
+// verifyMessage verifies the signature of the given message with the given public key.
+func verifyMessage(msg message, key PublicKey, opts VerifyOptions, now time.Time) error {
+	labels, inputs := parseDictionary(strings.Join(msg.header["Signature-Input"], ", "))
+	_, signatures := parseDictionary(strings.Join(msg.header["Signature"], ", "))
+
+	err := ErrMissingSignature
+	for _, label := range labels {
+		if opts.Label != "" && label != opts.Label {
+			continue
+		}
+		components, params, ok := parseSignatureParams(inputs[label])
+		if !ok {
+			err = ErrInvalidSignature
+			continue
+		}
+		if id, ok := params["keyid"]; ok && key.ID != "" && id != key.ID {
+			continue
+		}
+		if alg, ok := params["alg"]; ok && alg != string(key.Algorithm) {
+			continue
+		}
+
+		if err = verifySignature(msg, key, opts, now, inputs[label], signatures[label], components, params); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// verifySignature verifies the given signature of the message.
+func verifySignature(msg message, key PublicKey, opts VerifyOptions, now time.Time,
+	input, signature string, components []string, params map[string]string) error {
+	for _, required := range opts.Required {
+		if !contains(components, required) {
+			return ErrInvalidSignature
+		}
+	}
+
+	if expires, ok := params["expires"]; ok {
+		if at, err := strconv.ParseInt(expires, 10, 64); err != nil || now.Unix() > at {
+			return ErrExpiredSignature
+		}
+	}
+	if opts.MaxAge > 0 {
+		created, err := strconv.ParseInt(params["created"], 10, 64)
+		if err != nil || now.Sub(time.Unix(created, 0)) > opts.MaxAge {
+			return ErrExpiredSignature
+		}
+	}
+
+	if len(signature) < 2 || signature[0] != ':' || signature[len(signature)-1] != ':' {
+		return ErrInvalidSignature
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature[1 : len(signature)-1])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	base, err := signatureBase(msg, components, input)
+	if err != nil {
+		return err
+	}
+	return verifyBytes(key.Algorithm, key.Key, base, sig)
+}
+
+// contains returns true if the given components include the given one.
+func contains(components []string, component string) bool {
+	for _, covered := range components {
+		if covered == component {
+			return true
+		}
+	}
+	return false
+}
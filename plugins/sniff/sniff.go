@@ -0,0 +1,233 @@
+package sniff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/bodytype"
+)
+
+// SniffLen defines the maximum amount of body bytes used to detect the body type.
+const SniffLen = 512
+
+// mismatchKey is the context store key used to store warned mismatches.
+const mismatchKey = "$sniff.mismatch"
+
+// aliases maps the media types to the equivalent type compared on sniffing.
+var aliases = map[string]string{
+	"text/json":                    "application/json",
+	"application/x-ndjson":         "application/json",
+	"application/x-gzip":           "application/gzip",
+	"application/x-zip-compressed": "application/zip",
+	"application/java-archive":     "application/zip",
+}
+
+// zipPrefixes stores the prefixes of the ZIP based document media types,
+// which are sniffed as application/zip.
+var zipPrefixes = []string{
+	"application/vnd.openxmlformats-officedocument.",
+	"application/vnd.oasis.opendocument.",
+}
+
+// Error represents a response body type mismatch error.
+type Error struct {
+	// Declared stores the response declared media type, if any.
+	Declared string
+
+	// Expected stores the media type expected by the request, if any.
+	Expected string
+
+	// Detected stores the sniffed body media type.
+	Detected string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	want := e.Expected
+	if want == "" {
+		want = e.Declared
+	}
+	return "gentleman: response body type mismatch: expected " + want + ", detected " + e.Detected
+}
+
+// Options represents the body sniffing options.
+type Options struct {
+	// Expect defines the media type expected by the request, optionally
+	// based on a bodytype alias, such as "json" or "html".
+	Expect string
+
+	// Warn stores the mismatch in the context instead of failing the request.
+	// See Mismatch().
+	Warn bool
+}
+
+// Verify verifies that the sniffed response body type matches the declared Content-Type.
+func Verify() p.Plugin {
+	return New(Options{})
+}
+
+// Expect verifies that the sniffed response body type matches the given media type
+// and the declared Content-Type.
+func Expect(mediaType string) p.Plugin {
+	return New(Options{Expect: mediaType})
+}
+
+// New creates a new body sniffing plugin based on the given options.
+func New(opts Options) p.Plugin {
+	expected := opts.Expect
+	if match, ok := bodytype.Types[expected]; ok {
+		expected = match
+	}
+
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		detected, err := sniff(ctx.Response)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		if detected == "" {
+			h.Next(ctx)
+			return
+		}
+
+		declared := mediaType(ctx.Response.Header.Get("Content-Type"))
+		if compatible(mediaType(expected), detected) && compatible(declared, detected) {
+			h.Next(ctx)
+			return
+		}
+
+		mismatch := &Error{Declared: declared, Expected: mediaType(expected), Detected: detected}
+		if opts.Warn {
+			ctx.Set(mismatchKey, mismatch)
+			h.Next(ctx)
+			return
+		}
+		h.Error(ctx, mismatch)
+	})
+}
+
+// Mismatch returns the warned body type mismatch, if any.
+func Mismatch(ctx *c.Context) *Error {
+	err, _ := ctx.Get(mismatchKey).(*Error)
+	return err
+}
+
+// Detect returns the media type of the given body prefix.
+// Detect extends http.DetectContentType with JSON detection.
+func Detect(data []byte) string {
+	if isJSON(data) {
+		return "application/json"
+	}
+	return mediaType(http.DetectContentType(data))
+}
+
+// body restores the sniffed bytes in front of the original body stream.
+type body struct {
+	io.Reader
+	io.Closer
+}
+
+// sniff reads the response body prefix, restoring it, and returns the detected
+// media type. Returns an empty string for empty bodies.
+func sniff(res *http.Response) (string, error) {
+	if res.Body == nil {
+		return "", nil
+	}
+
+	buf := make([]byte, SniffLen)
+	n, err := io.ReadFull(res.Body, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	buf = buf[:n]
+	res.Body = &body{Reader: io.MultiReader(bytes.NewReader(buf), res.Body), Closer: res.Body}
+
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return "", nil
+	}
+	return Detect(buf), nil
+}
+
+// mediaType returns the lowercase media type of the given Content-Type value.
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(contentType)
+	}
+	return mt
+}
+
+// isJSON returns true if the given data is valid JSON, or a valid JSON prefix
+// truncated by the sniffing limit, such as a JSON stream.
+func isJSON(data []byte) bool {
+	truncated := len(data) >= SniffLen
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for depth, tokens := 0, 0; ; tokens++ {
+		token, err := dec.Token()
+		switch {
+		case err == io.EOF:
+			return tokens > 0 && (depth == 0 || truncated)
+		case err == io.ErrUnexpectedEOF:
+			return truncated
+		case err != nil:
+			return false
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// category returns the type family used to compare media types.
+func category(mt string) string {
+	if alias, ok := aliases[mt]; ok {
+		mt = alias
+	}
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return "json"
+	case mt == "text/html" || mt == "text/xml" || mt == "application/xml" || strings.HasSuffix(mt, "+xml"):
+		return "markup"
+	case strings.HasSuffix(mt, "+zip"):
+		return "application/zip"
+	}
+	for _, prefix := range zipPrefixes {
+		if strings.HasPrefix(mt, prefix) {
+			return "application/zip"
+		}
+	}
+	return mt
+}
+
+// generic returns true if the given media type is a sniffing fallback
+// that does not identify a specific format.
+func generic(mt string) bool {
+	return mt == "text/plain" || mt == "application/octet-stream"
+}
+
+// compatible returns true if the detected media type is compatible with the wanted one.
+func compatible(want, detected string) bool {
+	if want == "" {
+		return true
+	}
+	// JSON decoders require a JSON body
+	if category(want) == "json" {
+		return category(detected) == "json"
+	}
+	if generic(want) || generic(detected) || (category(detected) == "json" && strings.HasPrefix(category(want), "text/")) {
+		return true
+	}
+	return category(want) == category(detected)
+}
@@ -0,0 +1,128 @@
+package sniff
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func newServer(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestDetect(t *testing.T) {
+	st.Expect(t, Detect([]byte(` {"foo": "bar"}`)), "application/json")
+	st.Expect(t, Detect([]byte(`[1, 2]`)), "application/json")
+	st.Expect(t, Detect([]byte(`null`)), "application/json")
+	st.Expect(t, Detect([]byte(`<!DOCTYPE html><html></html>`)), "text/html")
+	st.Expect(t, Detect([]byte(`hello world`)), "text/plain")
+	st.Expect(t, Detect([]byte(`404 page not found`)), "text/plain")
+	st.Expect(t, Detect([]byte(`true story`)), "text/plain")
+	st.Expect(t, Detect([]byte(`{"foo": bar}`)), "text/plain")
+	st.Expect(t, Detect([]byte(`{"foo": "bar"`)), "text/plain")
+	st.Expect(t, Detect([]byte("{\"id\": 1}\n{\"id\": 2}\n")), "application/json")
+	st.Expect(t, Detect([]byte(`{"foo": "` + strings.Repeat("a", SniffLen) + `"}`)[:SniffLen]), "application/json")
+	st.Expect(t, Detect([]byte("\x89PNG\x0D\x0A\x1A\x0A")), "image/png")
+}
+
+func TestCompatible(t *testing.T) {
+	cases := []struct {
+		want, detected string
+		ok             bool
+	}{
+		{"", "text/html", true},
+		{"application/json", "application/json", true},
+		{"application/problem+json", "application/json", true},
+		{"application/json", "text/html", false},
+		{"application/json", "text/plain", false},
+		{"text/html", "application/json", false},
+		{"text/html", "text/plain", true},
+		{"application/xhtml+xml", "text/xml", true},
+		{"text/plain", "application/json", true},
+		{"application/octet-stream", "image/png", true},
+		{"image/jpeg", "image/png", false},
+		{"application/gzip", "application/x-gzip", true},
+		{"application/x-ndjson", "application/json", true},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/zip", true},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/zip", true},
+		{"application/epub+zip", "application/zip", true},
+		{"application/zip", "application/x-gzip", false},
+	}
+	for _, test := range cases {
+		st.Expect(t, compatible(test.want, test.detected), test.ok)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ts := newServer("application/json", `{"foo":"bar"}`)
+	defer ts.Close()
+
+	res, err := gentleman.New().URL(ts.URL).Use(Verify()).Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `{"foo":"bar"}`)
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	ts := newServer("application/json", `<!DOCTYPE html><html><body>Gateway error</body></html>`)
+	defer ts.Close()
+
+	_, err := gentleman.New().URL(ts.URL).Use(Verify()).Request().Send()
+	st.Reject(t, err, nil)
+	mismatch, ok := err.(*Error)
+	st.Expect(t, ok, true)
+	st.Expect(t, mismatch.Declared, "application/json")
+	st.Expect(t, mismatch.Detected, "text/html")
+	st.Expect(t, err.Error(), "gentleman: response body type mismatch: expected application/json, detected text/html")
+}
+
+func TestExpect(t *testing.T) {
+	ts := newServer("", `<html><body>Login</body></html>`)
+	defer ts.Close()
+
+	cli := gentleman.New().URL(ts.URL)
+	_, err := cli.Request().Use(Expect("json")).Send()
+	st.Reject(t, err, nil)
+	st.Expect(t, err.(*Error).Expected, "application/json")
+
+	res, err := cli.Request().Use(Expect("html")).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `<html><body>Login</body></html>`)
+}
+
+func TestWarn(t *testing.T) {
+	ts := newServer("application/json", `<html></html>`)
+	defer ts.Close()
+
+	var mismatch *Error
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Warn: true}))
+	cli.UseResponse(func(ctx *context.Context, h context.Handler) {
+		mismatch = Mismatch(ctx)
+		h.Next(ctx)
+	})
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), `<html></html>`)
+	st.Reject(t, mismatch, nil)
+	st.Expect(t, mismatch.Detected, "text/html")
+}
+
+func TestEmptyBody(t *testing.T) {
+	ts := newServer("application/json", "")
+	defer ts.Close()
+
+	res, err := gentleman.New().URL(ts.URL).Use(Expect("json")).Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+}
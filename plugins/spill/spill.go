@@ -0,0 +1,148 @@
+package spill
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// DefaultThreshold defines the default maximum body size kept in memory.
+const DefaultThreshold = 1 << 20
+
+// bodyKey is the context store key used to store the buffered body.
+const bodyKey = "$spill.body"
+
+// Options represents the body buffering options.
+type Options struct {
+	// Threshold defines the maximum body size kept in memory.
+	// Larger bodies are spilled to a temporary file.
+	// Defaults to DefaultThreshold.
+	Threshold int64
+
+	// Dir defines the directory used to create the temporary files.
+	// Defaults to the system temporary directory.
+	Dir string
+}
+
+// Body represents a buffered response body, stored in memory or in a temporary file.
+// Body implements io.ReadCloser and can be read again via Rewind() or NewReader().
+// Closing the body removes the temporary file, if any.
+type Body struct {
+	// data stores the in-memory body.
+	data []byte
+
+	// file stores the temporary file of spilled bodies.
+	file *os.File
+
+	// size stores the body size.
+	size int64
+
+	// reader stores the current body reader.
+	reader io.Reader
+}
+
+// Read reads from the current body position.
+func (b *Body) Read(buf []byte) (int, error) {
+	return b.reader.Read(buf)
+}
+
+// Close releases the body, removing the temporary file, if any.
+func (b *Body) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	file := b.file
+	b.file = nil
+	b.reader = bytes.NewReader(nil)
+	err := file.Close()
+	os.Remove(file.Name())
+	return err
+}
+
+// Rewind rewinds the body to the beginning.
+func (b *Body) Rewind() {
+	b.reader = b.NewReader()
+}
+
+// NewReader returns a new independent reader of the whole body.
+func (b *Body) NewReader() io.Reader {
+	if b.file != nil {
+		return io.NewSectionReader(b.file, 0, b.size)
+	}
+	return bytes.NewReader(b.data)
+}
+
+// Size returns the body size.
+func (b *Body) Size() int64 {
+	return b.size
+}
+
+// Spilled returns true if the body is stored in a temporary file.
+func (b *Body) Spilled() bool {
+	return b.file != nil
+}
+
+// New creates a new response body buffering plugin, which keeps small bodies in memory
+// and spills bodies above the given threshold to temporary files.
+func New(opts Options) p.Plugin {
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		if ctx.Response.Body == nil {
+			h.Next(ctx)
+			return
+		}
+
+		body, err := Buffer(ctx.Response.Body, opts)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		ctx.Response.Body = body
+		ctx.Set(bodyKey, body)
+		h.Next(ctx)
+	})
+}
+
+// Get returns the buffered response body of the given context, if any.
+func Get(ctx *c.Context) *Body {
+	body, _ := ctx.Get(bodyKey).(*Body)
+	return body
+}
+
+// Buffer reads and closes the given body, buffering it in memory
+// or in a temporary file if larger than the threshold.
+func Buffer(rc io.ReadCloser, opts Options) (*Body, error) {
+	defer rc.Close()
+
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, rc, threshold+1)
+	if err == io.EOF {
+		return &Body{data: buf.Bytes(), size: n, reader: bytes.NewReader(buf.Bytes())}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile(opts.Dir, "gentleman-")
+	if err != nil {
+		return nil, err
+	}
+
+	body := &Body{file: file}
+	if body.size, err = io.Copy(file, io.MultiReader(buf, rc)); err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	body.Rewind()
+	return body, nil
+}
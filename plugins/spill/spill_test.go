@@ -0,0 +1,71 @@
+package spill
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestBufferMemory(t *testing.T) {
+	body, err := Buffer(ioutil.NopCloser(strings.NewReader("hello")), Options{Threshold: 5})
+	st.Expect(t, err, nil)
+	st.Expect(t, body.Spilled(), false)
+	st.Expect(t, body.Size(), int64(5))
+
+	data, _ := ioutil.ReadAll(body)
+	st.Expect(t, string(data), "hello")
+	body.Rewind()
+	data, _ = ioutil.ReadAll(body)
+	st.Expect(t, string(data), "hello")
+	st.Expect(t, body.Close(), nil)
+}
+
+func TestBufferSpill(t *testing.T) {
+	body, err := Buffer(ioutil.NopCloser(strings.NewReader("hello world")), Options{Threshold: 5})
+	st.Expect(t, err, nil)
+	st.Expect(t, body.Spilled(), true)
+	st.Expect(t, body.Size(), int64(11))
+
+	name := body.file.Name()
+	data, _ := ioutil.ReadAll(body.NewReader())
+	st.Expect(t, string(data), "hello world")
+	data, _ = ioutil.ReadAll(body)
+	st.Expect(t, string(data), "hello world")
+
+	st.Expect(t, body.Close(), nil)
+	_, err = os.Stat(name)
+	st.Expect(t, os.IsNotExist(err), true)
+}
+
+func TestSpill(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("foo", 100)))
+	}))
+	defer ts.Close()
+
+	var spilled bool
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(New(Options{Threshold: 10}))
+	cli.UseResponse(func(ctx *context.Context, h context.Handler) {
+		body := Get(ctx)
+		spilled = body.Spilled()
+		data, _ := ioutil.ReadAll(body.NewReader())
+		if len(data) != 300 {
+			h.Error(ctx, os.ErrInvalid)
+			return
+		}
+		h.Next(ctx)
+	})
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, spilled, true)
+	st.Expect(t, res.String(), strings.Repeat("foo", 100))
+}
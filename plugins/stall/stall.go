@@ -0,0 +1,128 @@
+package stall
+
+import (
+	gocontext "context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// cancelKey is the context store key used to keep the cancel function
+// of the stall-aware request context.
+type cancelKey struct{}
+
+// Error is returned while reading the response body when no bytes
+// were received during the configured stall timeout.
+// Implements the net.Error interface.
+type Error struct {
+	// Duration stores the stall timeout that was exceeded.
+	Duration time.Duration
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return "gentleman: response body transfer stalled for more than " + e.Duration.String()
+}
+
+// Timeout returns true, since a stall is a timeout condition.
+func (e *Error) Timeout() bool { return true }
+
+// Temporary returns true, since stalled transfers can be retried.
+func (e *Error) Temporary() bool { return true }
+
+// Timeout aborts the request if no response body bytes are received
+// during the given amount of time while downloading the body.
+// This is independent of the overall request timeout, which is useful
+// for long streaming downloads that must not be cut off while progressing.
+func Timeout(timeout time.Duration) p.Plugin {
+	handlers := p.Handlers{
+		// Uses the "before dial" phase in order to wrap the final request context,
+		// once the request phase had the chance to define a custom one.
+		"before dial": func(ctx *c.Context, h c.Handler) {
+			reqCtx, cancel := gocontext.WithCancel(ctx.Request.Context())
+			ctx.Request = ctx.Request.WithContext(reqCtx)
+			ctx.Set(cancelKey{}, gocontext.CancelFunc(cancel))
+			h.Next(ctx)
+		},
+		// Intercepted responses are not transferred, so there is nothing to watch.
+		"intercept": func(ctx *c.Context, h c.Handler) {
+			cancelRequest(ctx)
+			h.Next(ctx)
+		},
+		"response": func(ctx *c.Context, h c.Handler) {
+			cancel, ok := ctx.Get(cancelKey{}).(gocontext.CancelFunc)
+			if ok && ctx.Response.Body != nil {
+				ctx.Response.Body = newBody(ctx.Response.Body, timeout, cancel)
+			} else if ok {
+				cancelRequest(ctx)
+			}
+			h.Next(ctx)
+		},
+		"error": func(ctx *c.Context, h c.Handler) {
+			cancelRequest(ctx)
+			h.Next(ctx)
+		},
+	}
+	return &p.Layer{Handlers: handlers}
+}
+
+// cancelRequest releases the stall-aware request context, if any.
+func cancelRequest(ctx *c.Context) {
+	if cancel, ok := ctx.Get(cancelKey{}).(gocontext.CancelFunc); ok {
+		ctx.Delete(cancelKey{})
+		cancel()
+	}
+}
+
+// body wraps the response body stream resetting the stall watchdog
+// every time new bytes are received.
+type body struct {
+	rc      io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stalled int32
+	cancel  gocontext.CancelFunc
+	once    sync.Once
+}
+
+func newBody(rc io.ReadCloser, timeout time.Duration, cancel gocontext.CancelFunc) *body {
+	b := &body{rc: rc, timeout: timeout, cancel: cancel}
+	b.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&b.stalled, 1)
+		cancel()
+	})
+	return b
+}
+
+// Read reads from the underlying body stream.
+func (b *body) Read(buf []byte) (int, error) {
+	n, err := b.rc.Read(buf)
+	if atomic.LoadInt32(&b.stalled) == 1 {
+		b.stop()
+		return n, &Error{Duration: b.timeout}
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil {
+		b.stop()
+	}
+	return n, err
+}
+
+// Close stops the watchdog and closes the underlying body stream.
+func (b *body) Close() error {
+	b.stop()
+	return b.rc.Close()
+}
+
+func (b *body) stop() {
+	b.once.Do(func() {
+		b.timer.Stop()
+		b.cancel()
+	})
+}
@@ -0,0 +1,78 @@
+package stall
+
+import (
+	gocontext "context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestStallTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foo"))
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("bar"))
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New()
+	cli.Use(Timeout(50 * time.Millisecond))
+
+	res, err := cli.Request().URL(ts.URL).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+
+	_, err = ioutil.ReadAll(res)
+	var stallErr *Error
+	st.Expect(t, errors.As(err, &stallErr), true)
+	st.Expect(t, stallErr.Duration, 50*time.Millisecond)
+	st.Expect(t, stallErr.Timeout(), true)
+}
+
+func TestStallProgressingTransfer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			w.Write([]byte("foo"))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	cli := gentleman.New()
+	cli.Use(Timeout(80 * time.Millisecond))
+
+	res, err := cli.Request().URL(ts.URL).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "foofoofoofoofoo")
+	st.Expect(t, res.Error, nil)
+}
+
+func TestStallInterceptedResponse(t *testing.T) {
+	for _, res := range []*http.Response{
+		{StatusCode: 204},
+		{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("foo"))},
+	} {
+		res := res
+		cli := gentleman.New()
+		cli.Use(Timeout(50 * time.Millisecond))
+		cli.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+			ctx.Intercept(res)
+			h.Next(ctx)
+		})
+
+		out, err := cli.Request().URL("http://localhost").Send()
+		st.Expect(t, err, nil)
+		st.Expect(t, out.StatusCode, res.StatusCode)
+		st.Expect(t, out.Context.Request.Context().Err(), gocontext.Canceled)
+	}
+}
@@ -0,0 +1,204 @@
+package stream
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// bodyKey is the context store key used to store the response body hooks.
+const bodyKey = "$stream.body"
+
+// Event represents a response body stream event.
+type Event struct {
+	// Read stores the amount of body bytes read so far.
+	Read int64
+
+	// Size stores the declared body size, or -1 if unknown.
+	Size int64
+
+	// Elapsed stores the time elapsed since the response headers were received.
+	Elapsed time.Duration
+
+	// Error stores the error which aborted the body stream, if any.
+	// Only defined for body done events.
+	Error error
+}
+
+// Hook represents a response body stream event handler.
+// Hooks are called synchronously from the body reader.
+type Hook func(Event)
+
+// progressHook represents a hook fired every given amount of bytes.
+type progressHook struct {
+	every int64
+	next  int64
+	fn    Hook
+}
+
+// Body represents a response body stream which notifies the registered hooks
+// on first byte received, every N bytes and once the body is fully read.
+// Body implements io.ReadCloser.
+type Body struct {
+	// mtx protects the hooks and the stream state.
+	mtx sync.Mutex
+
+	// rc stores the original body stream.
+	rc io.ReadCloser
+
+	// size stores the declared body size.
+	size int64
+
+	// start stores the time the body stream started.
+	start time.Time
+
+	// read stores the amount of bytes read.
+	read int64
+
+	// done stores if the body stream ended.
+	done bool
+
+	// first, progress and complete store the registered hooks.
+	first    []Hook
+	progress []*progressHook
+	complete []Hook
+}
+
+// Get returns the response body stream of the given context, wrapping the response
+// body once, so multiple plugins can register hooks sharing the same body reader.
+// Must be called once the response is available, such as in the "response" phase.
+func Get(ctx *c.Context) *Body {
+	body, ok := ctx.Get(bodyKey).(*Body)
+	if ok && ctx.Response.Body == body {
+		return body
+	}
+	rc := ctx.Response.Body
+	if rc == nil {
+		rc = utils.NopCloser()
+	}
+	body = &Body{rc: rc, size: ctx.Response.ContentLength, start: time.Now()}
+	ctx.Response.Body = body
+	ctx.Set(bodyKey, body)
+	return body
+}
+
+// OnFirstByte registers a hook fired once the first body byte is received.
+func (b *Body) OnFirstByte(fn Hook) *Body {
+	b.mtx.Lock()
+	b.first = append(b.first, fn)
+	b.mtx.Unlock()
+	return b
+}
+
+// OnProgress registers a hook fired every time the given amount of body bytes is received.
+func (b *Body) OnProgress(every int64, fn Hook) *Body {
+	if every <= 0 {
+		every = 1
+	}
+	b.mtx.Lock()
+	b.progress = append(b.progress, &progressHook{every: every, next: b.read + every, fn: fn})
+	b.mtx.Unlock()
+	return b
+}
+
+// OnDone registers a hook fired once the body is fully read, fails or is closed.
+func (b *Body) OnDone(fn Hook) *Body {
+	b.mtx.Lock()
+	b.complete = append(b.complete, fn)
+	b.mtx.Unlock()
+	return b
+}
+
+// Read reads from the original body stream, notifying the registered hooks.
+func (b *Body) Read(buf []byte) (int, error) {
+	n, err := b.rc.Read(buf)
+
+	b.mtx.Lock()
+	first := n > 0 && b.read == 0
+	b.read += int64(n)
+	event := b.event(nil)
+
+	var hooks []Hook
+	if first {
+		hooks = append(hooks, b.first...)
+	}
+	for _, progress := range b.progress {
+		if b.read >= progress.next {
+			hooks = append(hooks, progress.fn)
+		}
+		for b.read >= progress.next {
+			progress.next += progress.every
+		}
+	}
+	b.mtx.Unlock()
+
+	for _, fn := range hooks {
+		fn(event)
+	}
+
+	if err == io.EOF {
+		b.finish(nil)
+	} else if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+// Close closes the original body stream, notifying the done hooks if pending.
+func (b *Body) Close() error {
+	err := b.rc.Close()
+	b.finish(nil)
+	return err
+}
+
+// finish notifies the done hooks once.
+func (b *Body) finish(err error) {
+	b.mtx.Lock()
+	if b.done {
+		b.mtx.Unlock()
+		return
+	}
+	b.done = true
+	event := b.event(err)
+	hooks := b.complete
+	b.mtx.Unlock()
+
+	for _, fn := range hooks {
+		fn(event)
+	}
+}
+
+// event creates a new stream event. The caller must hold the lock.
+func (b *Body) event(err error) Event {
+	return Event{Read: b.read, Size: b.size, Elapsed: time.Since(b.start), Error: err}
+}
+
+// FirstByte creates a new plugin registering a hook fired once the first body byte is received.
+func FirstByte(fn Hook) p.Plugin {
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		Get(ctx).OnFirstByte(fn)
+		h.Next(ctx)
+	})
+}
+
+// Progress creates a new plugin registering a hook fired every time
+// the given amount of body bytes is received.
+func Progress(every int64, fn Hook) p.Plugin {
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		Get(ctx).OnProgress(every, fn)
+		h.Next(ctx)
+	})
+}
+
+// Done creates a new plugin registering a hook fired once the body is fully read,
+// fails or is closed.
+func Done(fn Hook) p.Plugin {
+	return p.NewResponsePlugin(func(ctx *c.Context, h c.Handler) {
+		Get(ctx).OnDone(fn)
+		h.Next(ctx)
+	})
+}
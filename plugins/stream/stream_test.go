@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+func TestBodyHooks(t *testing.T) {
+	ctx := context.New()
+	utils.WriteBodyString(ctx.Response, "hello world")
+	ctx.Response.Body = ioutil.NopCloser(iotest.OneByteReader(ctx.Response.Body))
+
+	var first, progress, done []Event
+	Get(ctx).OnFirstByte(func(e Event) { first = append(first, e) })
+	Get(ctx).OnProgress(5, func(e Event) { progress = append(progress, e) })
+	Get(ctx).OnDone(func(e Event) { done = append(done, e) })
+
+	body, err := ioutil.ReadAll(ctx.Response.Body)
+	st.Expect(t, err, nil)
+	st.Expect(t, string(body), "hello world")
+	ctx.Response.Body.Close()
+
+	st.Expect(t, len(first), 1)
+	st.Expect(t, first[0].Read, int64(1))
+	st.Expect(t, len(progress), 2)
+	st.Expect(t, progress[0].Read, int64(5))
+	st.Expect(t, progress[1].Read, int64(10))
+	st.Expect(t, len(done), 1)
+	st.Expect(t, done[0].Read, int64(11))
+	st.Expect(t, done[0].Size, int64(11))
+	st.Expect(t, done[0].Error, nil)
+}
+
+func TestBodyError(t *testing.T) {
+	ctx := context.New()
+	ctx.Response.Body = ioutil.NopCloser(iotest.TimeoutReader(strings.NewReader("hello world")))
+
+	var done Event
+	Get(ctx).OnDone(func(e Event) { done = e })
+	_, err := ioutil.ReadAll(ctx.Response.Body)
+	st.Reject(t, err, nil)
+	st.Expect(t, errors.Is(done.Error, iotest.ErrTimeout), true)
+}
+
+func TestPlugins(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("foo", 100)))
+	}))
+	defer ts.Close()
+
+	var first, done bool
+	var progress int64
+	cli := gentleman.New().URL(ts.URL)
+	cli.Use(FirstByte(func(Event) { first = true }))
+	cli.Use(Progress(100, func(e Event) { progress = e.Read }))
+	cli.Use(Done(func(e Event) { done = e.Read == 300 }))
+
+	res, err := cli.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), strings.Repeat("foo", 100))
+	st.Expect(t, first, true)
+	st.Expect(t, progress >= 100, true)
+	st.Expect(t, done, true)
+}
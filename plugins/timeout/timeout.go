@@ -1,9 +1,11 @@
 package timeout
 
 import (
+	gocontext "context"
 	g "gopkg.in/h2non/gentleman.v2"
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"net"
 	"net/http"
 	"time"
@@ -43,27 +45,33 @@ func Dial(timeout, keepAlive time.Duration) p.Plugin {
 	return All(Timeouts{Dial: timeout, KeepAlive: keepAlive})
 }
 
-// All defines all the timeout types for the outgoing request
+// transportKey is the key of the transports derived by All().
+type transportKey Timeouts
+
+// All defines all the timeout types for the outgoing request, deriving an isolated copy
+// of the request transport, shared by every plugin instance using the same timeouts.
 func All(timeouts Timeouts) p.Plugin {
-	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		defineTimeouts(timeouts, ctx)
-		h.Next(ctx)
+	// The request timeout does not affect the derived transport
+	key := timeouts
+	key.Request = 0
+	derive := transport.DeriveKey(transportKey(key), func(t *http.Transport) {
+		defineTimeouts(timeouts, t)
 	})
+	plugin := p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		timeout := timeouts.Request
+		if timeout == 0 {
+			timeout = g.RequestTimeout
+		}
+		ctx.Client.Timeout = timeout
+		derive.Exec("request", ctx, h)
+	})
+	return p.WithCloser(plugin, func() error { return p.Close(derive) })
 }
 
-func defineTimeouts(timeouts Timeouts, ctx *c.Context) {
-	if timeouts.Request == 0 {
-		timeouts.Request = g.RequestTimeout
-	}
-	ctx.Client.Timeout = timeouts.Request
-
-	// Assert http.Transport to work with the instance
-	transport, ok := ctx.Client.Transport.(*http.Transport)
-	if !ok {
-		// If using custom transport, just ignore it
-		return
-	}
-
+// defineTimeouts defines the given timeouts in the given transport. Custom dial functions,
+// such as the ones defined via transport.Dialer(), are preserved and bounded by the dial timeout,
+// while the default dialer is replaced by one using the dial and keep-alive timeouts.
+func defineTimeouts(timeouts Timeouts, transport *http.Transport) {
 	if timeouts.TLS == 0 {
 		timeouts.TLS = g.TLSHandshakeTimeout
 	}
@@ -76,11 +84,19 @@ func defineTimeouts(timeouts Timeouts, ctx *c.Context) {
 		timeouts.KeepAlive = g.DialKeepAlive
 	}
 
-	transport.Dial = (&net.Dialer{
+	if dial := transport.DialContext; dial != nil {
+		timeout := timeouts.Dial
+		transport.DialContext = func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+			ctx, cancel := gocontext.WithTimeout(ctx, timeout)
+			defer cancel()
+			return dial(ctx, network, addr)
+		}
+		return
+	}
+
+	transport.Dial = nil
+	transport.DialContext = (&net.Dialer{
 		Timeout:   timeouts.Dial,
 		KeepAlive: timeouts.KeepAlive,
-	}).Dial
-
-	// Finally expose the transport to be used
-	ctx.Client.Transport = transport
+	}).DialContext
 }
@@ -1,10 +1,14 @@
 package timeout
 
 import (
+	gocontext "context"
+	"errors"
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
+	"net"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestTimeout(t *testing.T) {
@@ -25,6 +29,7 @@ func TestTimeoutTLS(t *testing.T) {
 
 	transport := ctx.Client.Transport.(*http.Transport)
 	st.Expect(t, int(transport.TLSHandshakeTimeout), 1000)
+	st.Reject(t, transport, http.DefaultTransport)
 }
 
 func TestTimeoutAll(t *testing.T) {
@@ -38,6 +43,26 @@ func TestTimeoutAll(t *testing.T) {
 	st.Expect(t, int(transport.TLSHandshakeTimeout), 1000)
 }
 
+func TestTimeoutCustomDialer(t *testing.T) {
+	var dialed bool
+	base := &http.Transport{DialContext: func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		dialed = true
+		_, ok := ctx.Deadline()
+		st.Expect(t, ok, true)
+		return nil, errors.New("dial error")
+	}}
+
+	ctx := context.New()
+	ctx.Client.Transport = base
+	All(Timeouts{Dial: time.Second}).Exec("request", ctx, newHandler().fn)
+
+	// The custom dialer is preserved, bounded by the dial timeout
+	transport := ctx.Client.Transport.(*http.Transport)
+	st.Reject(t, transport, base)
+	transport.DialContext(gocontext.Background(), "tcp", "localhost:80")
+	st.Expect(t, dialed, true)
+}
+
 type handler struct {
 	fn     context.Handler
 	called bool
@@ -0,0 +1,181 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
+)
+
+var (
+	// ErrNonCompliant is returned when the request cannot be sent using the FIPS profile,
+	// such as plain HTTP requests or requests using a custom transport.
+	ErrNonCompliant = errors.New("tls: request not compliant with the FIPS profile")
+
+	// ErrNonCompliantPeer is returned when the server negotiated a cipher suite or TLS version,
+	// or presented a certificate, not allowed by the FIPS profile.
+	ErrNonCompliantPeer = errors.New("tls: server not compliant with the FIPS profile")
+)
+
+// FIPSCipherSuites defines the FIPS 140 approved TLS 1.2 cipher suites.
+var FIPSCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// FIPSCurves defines the FIPS 140 approved key exchange curves.
+var FIPSCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// fipsTLS13CipherSuites defines the FIPS 140 approved TLS 1.3 cipher suites,
+// which cannot be configured but are verified once negotiated.
+var fipsTLS13CipherSuites = []uint16{
+	tls.TLS_AES_128_GCM_SHA256,
+	tls.TLS_AES_256_GCM_SHA384,
+}
+
+// FIPSConfig returns a copy of the given TLS config, which can be nil, restricted to the
+// FIPS 140 approved TLS versions, cipher suites and curves. The connections negotiating
+// other TLS 1.3 cipher suites, such as ChaCha20-Poly1305, or whose server certificates
+// use non approved keys, such as RSA keys under 2048 bits or Ed25519 keys, are refused.
+//
+// Before Go 1.15, the negotiated connections cannot be verified, hence TLS 1.3 is disabled.
+//
+// This restricts the negotiated algorithms only: a FIPS validated cryptographic module
+// must be used in order to achieve FIPS 140 compliance, such as the Go FIPS 140 mode.
+func FIPSConfig(base *tls.Config) *tls.Config {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	config.InsecureSkipVerify = false
+	if config.MinVersion < tls.VersionTLS12 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	config.MaxVersion = tls.VersionTLS13
+	config.CipherSuites = append([]uint16{}, FIPSCipherSuites...)
+	config.CurvePreferences = append([]tls.CurveID{}, FIPSCurves...)
+	verifyConnection(config)
+	return config
+}
+
+// fipsKey is the key of the transports derived by FIPS().
+type fipsKey struct{}
+
+// FIPS creates a new plugin restricting the outgoing requests to the FIPS 140 approved
+// TLS versions, cipher suites and curves via FIPSConfig(), deriving an isolated copy of the
+// request transport. Plain HTTP requests, and requests whose transport TLS configuration
+// is no longer compliant or cannot be inspected, fail with ErrNonCompliant before dialing.
+func FIPS() p.Plugin {
+	derive := transport.DeriveKey(fipsKey{}, func(t *http.Transport) {
+		t.TLSClientConfig = FIPSConfig(t.TLSClientConfig)
+	})
+
+	handlers := p.Handlers{
+		"request": func(ctx *c.Context, h c.Handler) {
+			derive.Exec("request", ctx, h)
+		},
+		"before dial": func(ctx *c.Context, h c.Handler) {
+			t, ok := ctx.Client.Transport.(*http.Transport)
+			if ctx.Request.URL.Scheme != "https" || !ok || !compliant(t.TLSClientConfig) {
+				h.Error(ctx, ErrNonCompliant)
+				return
+			}
+			h.Next(ctx)
+		},
+	}
+	return p.WithCloser(&p.Layer{Handlers: handlers}, func() error { return p.Close(derive) })
+}
+
+// compliant returns true if the given TLS config is restricted to the FIPS profile.
+func compliant(config *tls.Config) bool {
+	if config == nil || config.InsecureSkipVerify || config.MinVersion < tls.VersionTLS12 ||
+		!verified(config) || len(config.CipherSuites) == 0 || len(config.CurvePreferences) == 0 {
+		return false
+	}
+	for _, suite := range config.CipherSuites {
+		if !containsSuite(FIPSCipherSuites, suite) {
+			return false
+		}
+	}
+	for _, curve := range config.CurvePreferences {
+		if !containsCurve(FIPSCurves, curve) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyFIPS verifies the negotiated connection parameters and server certificates.
+func verifyFIPS(state tls.ConnectionState) error {
+	switch state.Version {
+	case tls.VersionTLS12:
+		if !containsSuite(FIPSCipherSuites, state.CipherSuite) {
+			return ErrNonCompliantPeer
+		}
+	case tls.VersionTLS13:
+		if !containsSuite(fipsTLS13CipherSuites, state.CipherSuite) {
+			return ErrNonCompliantPeer
+		}
+	default:
+		return ErrNonCompliantPeer
+	}
+
+	for _, cert := range state.PeerCertificates {
+		if !approvedCertificate(cert) {
+			return ErrNonCompliantPeer
+		}
+	}
+	return nil
+}
+
+// approvedCertificate returns true if the certificate key and signature algorithms are FIPS approved.
+func approvedCertificate(cert *x509.Certificate) bool {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return false
+		}
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() && key.Curve != elliptic.P384() && key.Curve != elliptic.P521() {
+			return false
+		}
+	default:
+		return false
+	}
+
+	switch cert.SignatureAlgorithm {
+	case x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+		x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS,
+		x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512:
+		return true
+	}
+	return false
+}
+
+func containsSuite(suites []uint16, suite uint16) bool {
+	for _, s := range suites {
+		if s == suite {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCurve(curves []tls.CurveID, curve tls.CurveID) bool {
+	for _, id := range curves {
+		if id == curve {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+//go:build go1.15
+// +build go1.15
+
+package tls
+
+import "crypto/tls"
+
+// verifyConnection verifies the negotiated connections of the given TLS config via verifyFIPS(),
+// chaining any previous VerifyConnection function.
+func verifyConnection(config *tls.Config) {
+	verify := config.VerifyConnection
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if err := verifyFIPS(state); err != nil {
+			return err
+		}
+		if verify != nil {
+			return verify(state)
+		}
+		return nil
+	}
+}
+
+// verified returns true if the negotiated connections of the given TLS config are verified.
+func verified(config *tls.Config) bool {
+	return config.VerifyConnection != nil
+}
@@ -0,0 +1,34 @@
+//go:build !go1.15
+// +build !go1.15
+
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// verifyConnection restricts the given TLS config to TLS 1.2, whose negotiated cipher suites
+// are bounded by the config ones, and verifies the server certificates, chaining any previous
+// VerifyPeerCertificate function, since VerifyConnection is only available since Go 1.15.
+func verifyConnection(config *tls.Config) {
+	config.MaxVersion = tls.VersionTLS12
+	verify := config.VerifyPeerCertificate
+	config.VerifyPeerCertificate = func(raw [][]byte, chains [][]*x509.Certificate) error {
+		for _, der := range raw {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil || !approvedCertificate(cert) {
+				return ErrNonCompliantPeer
+			}
+		}
+		if verify != nil {
+			return verify(raw, chains)
+		}
+		return nil
+	}
+}
+
+// verified returns true if the negotiated connections of the given TLS config are verified.
+func verified(config *tls.Config) bool {
+	return config.MaxVersion != 0 && config.MaxVersion <= tls.VersionTLS12 && config.VerifyPeerCertificate != nil
+}
@@ -2,26 +2,20 @@ package tls
 
 import (
 	"crypto/tls"
-	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"net/http"
 )
 
-// Config defines the request TLS connection config
-func Config(config *tls.Config) p.Plugin {
-	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		// Assert http.Transport to work with the instance
-		transport, ok := ctx.Client.Transport.(*http.Transport)
-		if !ok {
-			// If using a custom transport, just ignore it
-			h.Next(ctx)
-			return
-		}
-
-		// Override the http.Client transport
-		transport.TLSClientConfig = config
-		ctx.Client.Transport = transport
+// configKey is the key of the transports derived by Config().
+type configKey struct {
+	config *tls.Config
+}
 
-		h.Next(ctx)
+// Config defines the request TLS connection config, deriving an isolated copy
+// of the request transport, shared by every plugin instance using the same config.
+func Config(config *tls.Config) p.Plugin {
+	return transport.DeriveKey(configKey{config}, func(t *http.Transport) {
+		t.TLSClientConfig = config
 	})
 }
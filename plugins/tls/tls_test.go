@@ -2,10 +2,12 @@ package tls
 
 import (
 	"crypto/tls"
-	"github.com/nbio/st"
-	"gopkg.in/h2non/gentleman.v2/context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
 )
 
 func TestAuthBasic(t *testing.T) {
@@ -17,6 +19,78 @@ func TestAuthBasic(t *testing.T) {
 
 	transport := ctx.Client.Transport.(*http.Transport)
 	st.Expect(t, transport.TLSClientConfig, config)
+	st.Reject(t, http.DefaultTransport.(*http.Transport).TLSClientConfig, config)
+}
+
+func TestFIPSConfig(t *testing.T) {
+	base := &tls.Config{ServerName: "example.com", MinVersion: tls.VersionTLS10, InsecureSkipVerify: true}
+	config := FIPSConfig(base)
+	st.Expect(t, config.ServerName, "example.com")
+	st.Expect(t, config.MinVersion, uint16(tls.VersionTLS12))
+	st.Expect(t, config.InsecureSkipVerify, false)
+	st.Expect(t, config.CipherSuites, FIPSCipherSuites)
+	st.Expect(t, config.CurvePreferences, FIPSCurves)
+	st.Expect(t, compliant(config), true)
+	st.Expect(t, base.InsecureSkipVerify, true)
+	st.Expect(t, compliant(base), false)
+
+	st.Expect(t, verifyFIPS(tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_256_GCM_SHA384}), nil)
+	st.Expect(t, verifyFIPS(tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_CHACHA20_POLY1305_SHA256}), ErrNonCompliantPeer)
+	st.Expect(t, verifyFIPS(tls.ConnectionState{Version: tls.VersionTLS11, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}), ErrNonCompliantPeer)
+}
+
+func TestFIPS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	send := func(base http.RoundTripper, url string) (*http.Response, error) {
+		ctx := context.New()
+		ctx.Client.Transport = base
+		ctx.Request, _ = http.NewRequest("GET", url, nil)
+		plugin := FIPS()
+		fn := newHandler()
+		plugin.Exec("request", ctx, fn.fn)
+		plugin.Exec("before dial", ctx, fn.fn)
+		if ctx.Error != nil {
+			return nil, ctx.Error
+		}
+		return ctx.Client.Do(ctx.Request)
+	}
+
+	base := ts.Client().Transport.(*http.Transport)
+	res, err := send(base, ts.URL)
+	st.Expect(t, err, nil)
+	res.Body.Close()
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, containsSuite(append(FIPSCipherSuites, fipsTLS13CipherSuites...), res.TLS.CipherSuite), true)
+	st.Expect(t, compliant(base.TLSClientConfig), false)
+
+	_, err = send(base, "http://example.com")
+	st.Expect(t, err, ErrNonCompliant)
+	_, err = send(roundTripper(func(*http.Request) (*http.Response, error) { return nil, nil }), ts.URL)
+	st.Expect(t, err, ErrNonCompliant)
+}
+
+func TestFIPSNonCompliantServer(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	ctx := context.New()
+	ctx.Client.Transport = ts.Client().Transport
+	FIPS().Exec("request", ctx, newHandler().fn)
+	_, err := ctx.Client.Get(ts.URL)
+	st.Reject(t, err, nil)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
 }
 
 type handler struct {
@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// Builder builds isolated HTTP transports derived from a base transport,
+// such as the one used by default, applying the configured tuning options.
+// Use it via Plugin() in order to derive an isolated transport per client.
+type Builder struct {
+	options []func(*http.Transport)
+}
+
+// NewBuilder creates a new transport builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Configure registers a custom function to configure the built transports.
+func (b *Builder) Configure(fn func(*http.Transport)) *Builder {
+	b.options = append(b.options, fn)
+	return b
+}
+
+// MaxIdleConns defines the maximum number of idle connections across all hosts.
+func (b *Builder) MaxIdleConns(n int) *Builder {
+	return b.Configure(func(t *http.Transport) { t.MaxIdleConns = n })
+}
+
+// MaxIdleConnsPerHost defines the maximum number of idle connections per host.
+func (b *Builder) MaxIdleConnsPerHost(n int) *Builder {
+	return b.Configure(func(t *http.Transport) { t.MaxIdleConnsPerHost = n })
+}
+
+// MaxConnsPerHost defines the maximum number of connections per host,
+// including the connections in the dialing, active and idle states.
+func (b *Builder) MaxConnsPerHost(n int) *Builder {
+	return b.Configure(func(t *http.Transport) { t.MaxConnsPerHost = n })
+}
+
+// IdleConnTimeout defines the maximum amount of time an idle connection remains in the pool.
+func (b *Builder) IdleConnTimeout(timeout time.Duration) *Builder {
+	return b.Configure(func(t *http.Transport) { t.IdleConnTimeout = timeout })
+}
+
+// TLSHandshakeTimeout defines the maximum amount of time waiting for a TLS handshake.
+func (b *Builder) TLSHandshakeTimeout(timeout time.Duration) *Builder {
+	return b.Configure(func(t *http.Transport) { t.TLSHandshakeTimeout = timeout })
+}
+
+// ResponseHeaderTimeout defines the maximum amount of time waiting for the
+// response headers once the request is fully written.
+func (b *Builder) ResponseHeaderTimeout(timeout time.Duration) *Builder {
+	return b.Configure(func(t *http.Transport) { t.ResponseHeaderTimeout = timeout })
+}
+
+// ExpectContinueTimeout defines the maximum amount of time waiting for the
+// server first response headers when sending the "Expect: 100-continue" header.
+func (b *Builder) ExpectContinueTimeout(timeout time.Duration) *Builder {
+	return b.Configure(func(t *http.Transport) { t.ExpectContinueTimeout = timeout })
+}
+
+// DisableCompression disables the transparent gzip response compression.
+func (b *Builder) DisableCompression() *Builder {
+	return b.Configure(func(t *http.Transport) { t.DisableCompression = true })
+}
+
+// DisableKeepAlives disables the HTTP keep-alive, using every connection for a single request.
+func (b *Builder) DisableKeepAlives() *Builder {
+	return b.Configure(func(t *http.Transport) { t.DisableKeepAlives = true })
+}
+
+// ForceAttemptHTTP2 enables HTTP/2 even if a custom dialer or TLS config is used.
+func (b *Builder) ForceAttemptHTTP2() *Builder {
+	return b.Configure(func(t *http.Transport) { t.ForceAttemptHTTP2 = true })
+}
+
+// Build returns a new transport copied from the given base transport,
+// or from http.DefaultTransport if nil, applying the configured options.
+// The base transport is never mutated.
+func (b *Builder) Build(base *http.Transport) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	b.apply(transport)
+	return transport
+}
+
+// Plugin creates a new plugin which derives, once per client, an isolated copy
+// of the request transport applying the configured options. See Derive().
+func (b *Builder) Plugin() p.Plugin {
+	snapshot := &Builder{options: append([]func(*http.Transport){}, b.options...)}
+	return Derive(snapshot.apply)
+}
+
+// apply applies the configured options to the given transport.
+func (b *Builder) apply(transport *http.Transport) {
+	for _, option := range b.options {
+		option(transport)
+	}
+}
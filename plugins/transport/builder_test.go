@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder().
+		MaxIdleConns(50).
+		MaxIdleConnsPerHost(10).
+		MaxConnsPerHost(20).
+		IdleConnTimeout(time.Second).
+		TLSHandshakeTimeout(2 * time.Second).
+		ResponseHeaderTimeout(3 * time.Second).
+		ExpectContinueTimeout(4 * time.Second).
+		DisableCompression().
+		DisableKeepAlives().
+		ForceAttemptHTTP2()
+
+	transport := builder.Build(nil)
+	st.Reject(t, transport, http.DefaultTransport)
+	st.Expect(t, transport.MaxIdleConns, 50)
+	st.Expect(t, transport.MaxIdleConnsPerHost, 10)
+	st.Expect(t, transport.MaxConnsPerHost, 20)
+	st.Expect(t, transport.IdleConnTimeout, time.Second)
+	st.Expect(t, transport.TLSHandshakeTimeout, 2*time.Second)
+	st.Expect(t, transport.ResponseHeaderTimeout, 3*time.Second)
+	st.Expect(t, transport.ExpectContinueTimeout, 4*time.Second)
+	st.Expect(t, transport.DisableCompression, true)
+	st.Expect(t, transport.DisableKeepAlives, true)
+	st.Expect(t, transport.ForceAttemptHTTP2, true)
+	st.Expect(t, http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost, 0)
+}
+
+func TestBuilderPlugin(t *testing.T) {
+	base := &http.Transport{}
+	plugin := NewBuilder().MaxIdleConnsPerHost(10).Plugin()
+
+	ctx := context.New()
+	ctx.Client.Transport = base
+	fn := newHandler()
+	plugin.Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+
+	transport := ctx.Client.Transport.(*http.Transport)
+	st.Reject(t, transport, base)
+	st.Expect(t, transport.MaxIdleConnsPerHost, 10)
+	st.Expect(t, base.MaxIdleConnsPerHost, 0)
+}
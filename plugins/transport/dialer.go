@@ -0,0 +1,197 @@
+package transport
+
+import (
+	gocontext "context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+var (
+	// DefaultDialTimeout defines the default maximum amount of time a dial can take.
+	DefaultDialTimeout = 30 * time.Second
+
+	// DefaultKeepAlive defines the default TCP keep-alive period of the dialed connections.
+	DefaultKeepAlive = 30 * time.Second
+)
+
+// ErrNoInterfaceAddr is returned when the network interface used to dial has no IP address.
+var ErrNoInterfaceAddr = errors.New("gentleman: network interface has no IP address")
+
+// Family represents the IP address family preference used when dialing.
+type Family int
+
+const (
+	// AnyFamily dials any IP address family, as defined by the system resolver.
+	AnyFamily Family = iota
+
+	// PreferIPv4 dials IPv4 addresses first, falling back to IPv6.
+	PreferIPv4
+
+	// PreferIPv6 dials IPv6 addresses first, falling back to IPv4.
+	PreferIPv6
+
+	// IPv4Only only dials IPv4 addresses.
+	IPv4Only
+
+	// IPv6Only only dials IPv6 addresses.
+	IPv6Only
+)
+
+// networks returns the networks to dial in order for the given family.
+func (family Family) networks() []string {
+	switch family {
+	case PreferIPv4:
+		return []string{"tcp4", "tcp6"}
+	case PreferIPv6:
+		return []string{"tcp6", "tcp4"}
+	case IPv4Only:
+		return []string{"tcp4"}
+	case IPv6Only:
+		return []string{"tcp6"}
+	}
+	return nil
+}
+
+// ipv6 returns true if the family prefers or forces IPv6.
+func (family Family) ipv6() bool {
+	return family == PreferIPv6 || family == IPv6Only
+}
+
+// DialContextFunc represents the function used to dial the network connections,
+// such as net.Dialer.DialContext.
+type DialContextFunc func(ctx gocontext.Context, network, addr string) (net.Conn, error)
+
+// DialOptions represents the network dialer options.
+type DialOptions struct {
+	// Timeout defines the maximum amount of time a dial can take.
+	// Defaults to DefaultDialTimeout.
+	Timeout time.Duration
+
+	// KeepAlive defines the TCP keep-alive period of the dialed connections.
+	// Defaults to DefaultKeepAlive, while a negative value disables TCP keep-alive.
+	KeepAlive time.Duration
+
+	// LocalAddr defines the local IP address the outgoing connections are bound to,
+	// such as "10.0.0.2", in order to select the egress IP on multi-homed hosts.
+	LocalAddr string
+
+	// Interface defines the name of the network interface, such as "eth1",
+	// whose IP address the outgoing connections are bound to.
+	// IPv4 addresses are preferred, unless Family prefers IPv6.
+	// Ignored if LocalAddr is defined.
+	Interface string
+
+	// Family defines the IP address family preference, such as PreferIPv4 or IPv4Only,
+	// useful on environments with broken IPv6 routes. Defaults to AnyFamily.
+	Family Family
+}
+
+// NewDialer creates a new dial function based on the given options.
+func NewDialer(opts DialOptions) (DialContextFunc, error) {
+	dialer := &net.Dialer{Timeout: opts.Timeout, KeepAlive: opts.KeepAlive}
+	if dialer.Timeout == 0 {
+		dialer.Timeout = DefaultDialTimeout
+	}
+	if dialer.KeepAlive == 0 {
+		dialer.KeepAlive = DefaultKeepAlive
+	}
+
+	ip, err := localIP(opts)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	networks := opts.Family.networks()
+	if len(networks) == 0 {
+		return dialer.DialContext, nil
+	}
+
+	return func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		if network != "tcp" {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		var err error
+		for _, tcp := range networks {
+			var conn net.Conn
+			if conn, err = dialer.DialContext(ctx, tcp, addr); err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}, nil
+}
+
+// Dial creates a new plugin which dials the network connections of the outgoing
+// requests based on the given options, deriving an isolated copy of the request transport.
+// Invalid options are reported as request error.
+func Dial(opts DialOptions) p.Plugin {
+	dial, err := NewDialer(opts)
+	if err != nil {
+		return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+			h.Error(ctx, err)
+		})
+	}
+	return Dialer(dial)
+}
+
+// Dialer defines the function used to dial the network connections of the outgoing
+// requests, such as a proxy, Tor, overlay network or in-memory test dialer, deriving an
+// isolated copy of the request transport, therefore its other options are preserved.
+func Dialer(dial DialContextFunc) p.Plugin {
+	return Derive(func(t *http.Transport) {
+		t.Dial = nil
+		t.DialContext = dial
+	})
+}
+
+// localIP returns the local IP address the connections must be bound to, if any.
+func localIP(opts DialOptions) (net.IP, error) {
+	if opts.LocalAddr != "" {
+		ip := net.ParseIP(opts.LocalAddr)
+		if ip == nil {
+			return nil, &net.AddrError{Err: "invalid local IP address", Addr: opts.LocalAddr}
+		}
+		return ip, nil
+	}
+	if opts.Interface == "" {
+		return nil, nil
+	}
+
+	iface, err := net.InterfaceByName(opts.Interface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ip net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if (ipnet.IP.To4() == nil) == opts.Family.ipv6() {
+			return ipnet.IP, nil
+		}
+		if ip == nil {
+			ip = ipnet.IP
+		}
+	}
+	if ip == nil {
+		return nil, ErrNoInterfaceAddr
+	}
+	return ip, nil
+}
@@ -0,0 +1,82 @@
+package transport
+
+import (
+	gocontext "context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestDialer(t *testing.T) {
+	var dialed string
+	base := &http.Transport{}
+	ctx := context.New()
+	ctx.Client.Transport = base
+	ctx.Request.URL, _ = url.Parse("http://foo.test")
+	Dialer(func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return nil, errors.New("dial error")
+	}).Exec("request", ctx, newHandler().fn)
+
+	_, err := ctx.Client.Do(ctx.Request)
+	st.Expect(t, strings.Contains(err.Error(), "dial error"), true)
+	st.Expect(t, dialed, "foo.test:80")
+	st.Expect(t, base.DialContext == nil, true)
+}
+
+func TestDialLocalAddr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		w.Write([]byte(host))
+	}))
+	defer ts.Close()
+
+	st.Expect(t, send(t, Dial(DialOptions{LocalAddr: "127.0.0.1"}), &http.Transport{}, ts.URL), "127.0.0.1")
+
+	if iface, err := net.InterfaceByName("lo"); err == nil && iface.Flags&net.FlagLoopback != 0 {
+		st.Expect(t, send(t, Dial(DialOptions{Interface: "lo"}), &http.Transport{}, ts.URL), "127.0.0.1")
+	}
+}
+
+func TestDialInvalidOptions(t *testing.T) {
+	_, err := NewDialer(DialOptions{LocalAddr: "foo"})
+	st.Expect(t, err.Error(), "address foo: invalid local IP address")
+
+	_, err = NewDialer(DialOptions{Interface: "gentleman0"})
+	st.Reject(t, err, nil)
+
+	ctx := context.New()
+	fn := newHandler()
+	Dial(DialOptions{LocalAddr: "foo"}).Exec("request", ctx, fn.fn)
+	st.Reject(t, ctx.Error, nil)
+}
+
+func TestDialFamily(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	// The test server only listens on IPv4
+	dial, err := NewDialer(DialOptions{Family: PreferIPv6})
+	st.Expect(t, err, nil)
+	conn, err := dial(gocontext.Background(), "tcp", "127.0.0.1:"+port)
+	st.Expect(t, err, nil)
+	st.Expect(t, conn.RemoteAddr().String(), "127.0.0.1:"+port)
+	conn.Close()
+
+	dial, _ = NewDialer(DialOptions{Family: IPv6Only})
+	_, err = dial(gocontext.Background(), "tcp", "127.0.0.1:"+port)
+	st.Reject(t, err, nil)
+
+	dial, _ = NewDialer(DialOptions{Family: IPv4Only})
+	conn, err = dial(gocontext.Background(), "tcp", "127.0.0.1:"+port)
+	st.Expect(t, err, nil)
+	conn.Close()
+}
@@ -0,0 +1,125 @@
+package transport
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// HTTP2Options represents the HTTP/2 transport options.
+type HTTP2Options struct {
+	// ReadIdleTimeout defines the amount of time without receiving frames after which
+	// a health check ping is sent, closing stale connections, such as the ones
+	// silently dropped by load balancers. Zero disables health checks.
+	ReadIdleTimeout time.Duration
+
+	// PingTimeout defines the maximum amount of time waiting for the health check
+	// ping response before closing the connection. Defaults to 15 seconds.
+	PingTimeout time.Duration
+
+	// WriteByteTimeout defines the maximum amount of time a connection write can
+	// take without progressing before closing the connection. Zero means no timeout.
+	WriteByteTimeout time.Duration
+
+	// MaxHeaderListSize defines the maximum size of the response headers.
+	// Zero means the default limit.
+	MaxHeaderListSize uint32
+
+	// MaxReadFrameSize defines the largest frame the client is willing to read.
+	// Zero means the default size.
+	MaxReadFrameSize uint32
+
+	// StrictMaxConcurrentStreams enforces the server concurrent streams limit
+	// across the connections, instead of opening new connections.
+	StrictMaxConcurrentStreams bool
+
+	// AllowHTTP enables cleartext HTTP/2 (h2c) with prior knowledge for
+	// plain "http" URLs, such as gRPC-like internal services.
+	AllowHTTP bool
+}
+
+// ConfigureHTTP2 enables HTTP/2 in the given transport based on the given options,
+// returning the underlying http2.Transport, which can be further customized.
+func ConfigureHTTP2(transport *http.Transport, opts HTTP2Options) (*http2.Transport, error) {
+	// Transports copied from one already supporting HTTP/2 share its protocol handler
+	if _, ok := transport.TLSNextProto["h2"]; ok {
+		protos := make(map[string]func(string, *tls.Conn) http.RoundTripper, len(transport.TLSNextProto))
+		for proto, fn := range transport.TLSNextProto {
+			if proto != "h2" {
+				protos[proto] = fn
+			}
+		}
+		transport.TLSNextProto = protos
+	}
+
+	t2, err := http2.ConfigureTransports(transport)
+	if err != nil {
+		return nil, err
+	}
+	configureHTTP2(t2, opts)
+
+	if opts.AllowHTTP {
+		dial := dialContext(transport)
+		h2c := &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: transport.DisableCompression,
+			DialTLSContext: func(ctx gocontext.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+		configureHTTP2(h2c, opts)
+		transport.RegisterProtocol("http", h2c)
+	}
+
+	return t2, nil
+}
+
+// HTTP2 enables HTTP/2 in the outgoing requests based on the given options,
+// deriving an isolated copy of the request transport. See ConfigureHTTP2().
+// Configuration errors are reported as request error.
+func HTTP2(opts HTTP2Options) p.Plugin {
+	var mtx sync.Mutex
+	failed := make(map[*http.Transport]error)
+	derive := Derive(func(t *http.Transport) {
+		if _, err := ConfigureHTTP2(t, opts); err != nil {
+			mtx.Lock()
+			failed[t] = err
+			mtx.Unlock()
+		}
+	})
+
+	plugin := p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		derive.Exec("request", ctx, c.NewHandler(func(ctx *c.Context) {
+			transport, _ := ctx.Client.Transport.(*http.Transport)
+			mtx.Lock()
+			err := failed[transport]
+			mtx.Unlock()
+			if err != nil {
+				h.Error(ctx, err)
+				return
+			}
+			h.Next(ctx)
+		}))
+	})
+
+	return p.WithCloser(plugin, func() error {
+		return p.Close(derive)
+	})
+}
+
+// configureHTTP2 applies the given options to the HTTP/2 transport.
+func configureHTTP2(t2 *http2.Transport, opts HTTP2Options) {
+	t2.ReadIdleTimeout = opts.ReadIdleTimeout
+	t2.PingTimeout = opts.PingTimeout
+	t2.WriteByteTimeout = opts.WriteByteTimeout
+	t2.MaxHeaderListSize = opts.MaxHeaderListSize
+	t2.MaxReadFrameSize = opts.MaxReadFrameSize
+	t2.StrictMaxConcurrentStreams = opts.StrictMaxConcurrentStreams
+}
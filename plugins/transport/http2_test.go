@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// newHTTP2Server creates a new TLS server supporting HTTP/2 which replies with the request protocol.
+func newHTTP2Server() *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	http2.ConfigureServer(ts.Config, nil)
+	ts.StartTLS()
+	return ts
+}
+
+// protoMajor sends a request to the given URL via the given transport and returns the response protocol version.
+func protoMajor(t *testing.T, transport http.RoundTripper, url string) int {
+	res, err := (&http.Client{Transport: transport}).Get(url)
+	st.Expect(t, err, nil)
+	res.Body.Close()
+	return res.ProtoMajor
+}
+
+func TestConfigureHTTP2(t *testing.T) {
+	ts := newHTTP2Server()
+	defer ts.Close()
+
+	base := ts.Client().Transport.(*http.Transport)
+	transport := base.Clone()
+	opts := HTTP2Options{
+		ReadIdleTimeout:   30 * time.Second,
+		PingTimeout:       5 * time.Second,
+		MaxHeaderListSize: 1 << 20,
+	}
+
+	t2, err := ConfigureHTTP2(transport, opts)
+	st.Expect(t, err, nil)
+	st.Expect(t, t2.ReadIdleTimeout, 30*time.Second)
+	st.Expect(t, t2.PingTimeout, 5*time.Second)
+	st.Expect(t, t2.MaxHeaderListSize, uint32(1<<20))
+	st.Expect(t, protoMajor(t, transport, ts.URL), 2)
+
+	// Copies of a configured transport can be configured again
+	copied := transport.Clone()
+	t2, err = ConfigureHTTP2(copied, HTTP2Options{PingTimeout: time.Second})
+	st.Expect(t, err, nil)
+	st.Expect(t, t2.PingTimeout, time.Second)
+	st.Expect(t, protoMajor(t, copied, ts.URL), 2)
+}
+
+func TestHTTP2(t *testing.T) {
+	ts := newHTTP2Server()
+	defer ts.Close()
+
+	base := ts.Client().Transport.(*http.Transport)
+	st.Expect(t, protoMajor(t, base.Clone(), ts.URL), 1)
+
+	ctx := context.New()
+	ctx.Client.Transport = base
+	fn := newHandler()
+	HTTP2(HTTP2Options{ReadIdleTimeout: time.Second}).Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Error, nil)
+	st.Reject(t, ctx.Client.Transport, base)
+	st.Expect(t, protoMajor(t, ctx.Client.Transport, ts.URL), 2)
+}
+
+func TestHTTP2Cleartext(t *testing.T) {
+	ts := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer ts.Close()
+
+	ctx := context.New()
+	ctx.Client.Transport = &http.Transport{}
+	fn := newHandler()
+	HTTP2(HTTP2Options{AllowHTTP: true}).Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, protoMajor(t, ctx.Client.Transport, ts.URL), 2)
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	gocontext "context"
+	"net"
+	"net/http"
+	"time"
+
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// KeepAliveOptions represents the connection keep-alive options.
+type KeepAliveOptions struct {
+	// Disabled disables the HTTP keep-alive, using every connection for a single request.
+	Disabled bool
+
+	// Period defines the TCP keep-alive probes period of the dialed connections.
+	// Zero keeps the dialer default, while a negative value disables TCP keep-alive.
+	Period time.Duration
+
+	// IdleTimeout defines the maximum amount of time an idle connection
+	// remains in the pool before being closed. Zero keeps the transport default.
+	IdleTimeout time.Duration
+}
+
+// KeepAlive defines the connection keep-alive options of the outgoing requests,
+// deriving an isolated copy of the request transport. See Derive().
+// The TCP keep-alive period is applied on top of the transport dialer,
+// therefore custom dialers are preserved.
+func KeepAlive(opts KeepAliveOptions) p.Plugin {
+	return Derive(func(transport *http.Transport) {
+		if opts.Disabled {
+			transport.DisableKeepAlives = true
+		}
+		if opts.IdleTimeout != 0 {
+			transport.IdleConnTimeout = opts.IdleTimeout
+		}
+		if opts.Period != 0 {
+			keepAlivePeriod(transport, opts.Period)
+		}
+	})
+}
+
+// DisableKeepAlive disables the HTTP keep-alive of the outgoing requests.
+func DisableKeepAlive() p.Plugin {
+	return KeepAlive(KeepAliveOptions{Disabled: true})
+}
+
+// IdleTimeout defines the maximum amount of time an idle connection remains in the pool.
+func IdleTimeout(timeout time.Duration) p.Plugin {
+	return KeepAlive(KeepAliveOptions{IdleTimeout: timeout})
+}
+
+// keepAlivePeriod wraps the transport dialer in order to define the TCP keep-alive
+// period of the dialed connections, or disable it if the period is negative.
+func keepAlivePeriod(transport *http.Transport, period time.Duration) {
+	dial := dialContext(transport)
+	transport.Dial = nil
+	transport.DialContext = func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			if period < 0 {
+				tcp.SetKeepAlive(false)
+			} else {
+				tcp.SetKeepAlive(true)
+				tcp.SetKeepAlivePeriod(period)
+			}
+		}
+		return conn, nil
+	}
+}
+
+// dialContext returns the dial function used by the given transport.
+func dialContext(transport *http.Transport) DialContextFunc {
+	if transport.DialContext != nil {
+		return transport.DialContext
+	}
+	if dial := transport.Dial; dial != nil {
+		return func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+			return dial(network, addr)
+		}
+	}
+	return (&net.Dialer{}).DialContext
+}
@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestDerive(t *testing.T) {
+	base := &http.Transport{}
+	plugin := Derive(func(transport *http.Transport) {
+		transport.MaxIdleConnsPerHost = 10
+	})
+
+	ctx := context.New()
+	ctx.Client.Transport = base
+	fn := newHandler()
+	plugin.Exec("request", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	derived := ctx.Client.Transport.(*http.Transport)
+	st.Reject(t, derived, base)
+	st.Expect(t, derived.MaxIdleConnsPerHost, 10)
+	st.Expect(t, base.MaxIdleConnsPerHost, 0)
+
+	// The derived transport is reused
+	ctx = context.New()
+	ctx.Client.Transport = base
+	plugin.Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Client.Transport, derived)
+
+	// Custom transports are ignored
+	ctx = context.New()
+	custom := &customTransport{}
+	ctx.Client.Transport = custom
+	plugin.Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Client.Transport, custom)
+}
+
+func TestDeriveKey(t *testing.T) {
+	type key struct{ size int }
+	base := &http.Transport{}
+	derive := func(k interface{}) *http.Transport {
+		ctx := context.New()
+		ctx.Client.Transport = base
+		DeriveKey(k, func(transport *http.Transport) {
+			transport.MaxIdleConnsPerHost = 10
+		}).Exec("request", ctx, newHandler().fn)
+		return ctx.Client.Transport.(*http.Transport)
+	}
+
+	// Plugin instances created with the same key share the derived transport
+	derived := derive(key{10})
+	st.Reject(t, derived, base)
+	st.Expect(t, derived.MaxIdleConnsPerHost, 10)
+	st.Expect(t, derived.IdleConnTimeout, DefaultIdleConnTimeout)
+	st.Expect(t, derive(key{10}), derived)
+	st.Expect(t, derive(key{20}) == derived, false)
+
+	// Non-comparable keys derive a transport per plugin instance
+	st.Expect(t, derive([]int{10}) == derive([]int{10}), false)
+}
+
+func TestKeepAlive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	base := &http.Transport{}
+	ctx := context.New()
+	ctx.Client.Transport = base
+	KeepAlive(KeepAliveOptions{Disabled: true, Period: time.Minute, IdleTimeout: time.Second}).Exec("request", ctx, newHandler().fn)
+
+	transport := ctx.Client.Transport.(*http.Transport)
+	st.Expect(t, transport.DisableKeepAlives, true)
+	st.Expect(t, transport.IdleConnTimeout, time.Second)
+	st.Reject(t, transport.DialContext, nil)
+	st.Expect(t, base.DisableKeepAlives, false)
+	st.Expect(t, base.DialContext == nil, true)
+
+	res, err := ctx.Client.Get(ts.URL)
+	st.Expect(t, err, nil)
+	res.Body.Close()
+	st.Expect(t, res.Close, true)
+}
+
+type customTransport struct{}
+
+func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, nil
+}
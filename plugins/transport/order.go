@@ -0,0 +1,292 @@
+package transport
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// MaxHeaderOrderSize defines the maximum request head size in bytes reordered by HeaderOrder().
+// Larger request heads are written as is.
+var MaxHeaderOrderSize = 64 << 10
+
+// HeaderOrder creates a new plugin which writes the request headers on the wire in the given
+// order, matching the header names case-insensitively, for servers sensitive to the header
+// order. The listed headers are written first, followed by the remaining ones in the
+// default net/http order, which is alphabetical after the Host header.
+//
+// The header order can only be controlled for HTTP/1.x requests, hence HTTP/2 is disabled.
+// In order to rewrite the request heads, the http.Transport used by the request is replaced
+// by a copy of it via Derive(), which establishes the TLS connections on its own, unless
+// DialTLS is defined, and sends the bodies without waiting for 100 Continue responses.
+func HeaderOrder(names ...string) p.Plugin {
+	order := append([]string{}, names...)
+	return Derive(func(t *http.Transport) {
+		rewriteHeads(t, order)
+	})
+}
+
+// HeaderCaseKey is the context store key used to store the request header names written
+// on the wire with an exact casing by HeaderCase(), as a map of the exact names indexed
+// by the canonical ones, such as {"X-Api-Key": "x-api-KEY"}.
+const HeaderCaseKey = "$headerCase"
+
+// headerCaseApplied is the context store key used to flag the requests
+// whose transport was already derived by HeaderCase().
+const headerCaseApplied = "$headerCase.applied"
+
+// headerCaseTransport is the DeriveKey() key of the transports derived by HeaderCase().
+type headerCaseTransport struct{}
+
+// HeaderCase creates a new plugin which writes the request header names stored under
+// HeaderCaseKey with their exact casing on the wire, instead of the canonical one,
+// for legacy servers rejecting canonical header names. The request header fields keep
+// their canonical names, so they are still found via http.Header.Get() by net/http and
+// the plugins, and the header fields defined by net/http itself, such as User-Agent,
+// are replaced instead of duplicated.
+//
+// Like HeaderOrder(), the name casing can only be controlled for HTTP/1.x requests, hence
+// the requests defining exact header names use a transport derived via DeriveKey() with
+// HTTP/2 disabled. Custom http.RoundTripper implementations send the canonical names.
+func HeaderCase() p.Plugin {
+	derive := DeriveKey(headerCaseTransport{}, func(t *http.Transport) {
+		rewriteHeads(t, nil)
+	})
+
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		names, _ := ctx.Get(HeaderCaseKey).(map[string]string)
+		if applied, _ := ctx.Get(headerCaseApplied).(bool); applied || len(names) == 0 {
+			h.Next(ctx)
+			return
+		}
+		ctx.Set(headerCaseApplied, true)
+
+		// The connection writing the request head is exclusively owned by the request once obtained
+		ctx.Request = ctx.Request.WithContext(httptrace.WithClientTrace(ctx.Request.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if conn, ok := info.Conn.(*orderedConn); ok {
+					conn.setHeaderCase(names)
+				}
+			},
+		}))
+		derive.Exec("request", ctx, h)
+	})
+}
+
+// rewriteHeads configures the given transport to establish the HTTP/1.x connections
+// rewriting the request heads, writing the headers in the given order.
+func rewriteHeads(t *http.Transport, order []string) {
+	dial := dialContext(t)
+	t.Dial = nil
+	t.DialContext = func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return newOrderedConn(conn, order), nil
+	}
+
+	dialTLS := t.DialTLS
+	if dialTLS == nil {
+		dialTLS = tlsDialer(t, dial)
+	}
+	t.DialTLS = func(network, addr string) (net.Conn, error) {
+		conn, err := dialTLS(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return newOrderedConn(conn, order), nil
+	}
+
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	t.ExpectContinueTimeout = 0
+}
+
+// tlsDialer returns the function establishing the HTTP/1.1 TLS connections based on the
+// transport TLS configuration and handshake timeout.
+func tlsDialer(t *http.Transport, dial DialContextFunc) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		ctx := gocontext.Background()
+		if t.TLSHandshakeTimeout > 0 {
+			var cancel gocontext.CancelFunc
+			ctx, cancel = gocontext.WithTimeout(ctx, t.TLSHandshakeTimeout)
+			defer cancel()
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			config = t.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		config.NextProtos = []string{"http/1.1"}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return tlsConn, nil
+	}
+}
+
+// orderedConn wraps a connection reordering and renaming the headers of the written request heads.
+// A new request head is expected once the previous response started to be read.
+type orderedConn struct {
+	net.Conn
+
+	// order stores the header names order.
+	order []string
+
+	// names stores the exact header names of the next request head, indexed by the canonical name.
+	names map[string]string
+
+	// mtx protects the connection writing state.
+	mtx sync.Mutex
+
+	// head stores if a request head is expected in the next write.
+	head bool
+
+	// buf stores the partially written request head.
+	buf []byte
+}
+
+// newOrderedConn creates a new connection writing the request headers in the given order.
+func newOrderedConn(conn net.Conn, order []string) *orderedConn {
+	return &orderedConn{Conn: conn, order: order, head: true}
+}
+
+// setHeaderCase defines the exact header names of the next written request head.
+func (conn *orderedConn) setHeaderCase(names map[string]string) {
+	conn.mtx.Lock()
+	conn.names = names
+	conn.mtx.Unlock()
+}
+
+// Read reads data from the connection, expecting a new request head on the next write.
+func (conn *orderedConn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	if n > 0 {
+		conn.mtx.Lock()
+		if len(conn.buf) == 0 {
+			conn.head = true
+		}
+		conn.mtx.Unlock()
+	}
+	return n, err
+}
+
+// Write writes data to the connection, buffering the request heads until
+// complete in order to write them with the headers reordered.
+func (conn *orderedConn) Write(b []byte) (int, error) {
+	conn.mtx.Lock()
+	defer conn.mtx.Unlock()
+
+	if !conn.head {
+		return conn.Conn.Write(b)
+	}
+
+	conn.buf = append(conn.buf, b...)
+	end := bytes.Index(conn.buf, []byte("\r\n\r\n"))
+	switch {
+	case !requestLine(conn.buf), end < 0 && len(conn.buf) > MaxHeaderOrderSize:
+		// Not a request head: write it as is
+	case end < 0:
+		return len(b), nil
+	default:
+		head := reorderHead(conn.buf[:end], conn.order)
+		conn.buf = append(renameHead(head, conn.names), conn.buf[end:]...)
+	}
+
+	buf := conn.buf
+	conn.buf, conn.head, conn.names = nil, false, nil
+	if _, err := conn.Conn.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// NetConn returns the underlying connection.
+func (conn *orderedConn) NetConn() net.Conn {
+	return conn.Conn
+}
+
+// requestLine returns false if the given data cannot start with a request line method.
+func requestLine(data []byte) bool {
+	for i, b := range data {
+		if b == ' ' {
+			return i > 0
+		}
+		if b <= ' ' || b >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, b) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reorderHead returns the given request line and header lines with the headers sorted in the given order.
+func reorderHead(head []byte, order []string) []byte {
+	lines := bytes.Split(head, []byte("\r\n"))
+	fields := lines[1:]
+
+	rank := func(line []byte) int {
+		name := line
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			name = line[:i]
+		}
+		for i, ordered := range order {
+			if strings.EqualFold(string(name), ordered) {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return rank(fields[i]) < rank(fields[j])
+	})
+
+	return bytes.Join(lines, []byte("\r\n"))
+}
+
+// renameHead returns the given request line and header lines with the header names
+// replaced by the given exact names, indexed by the canonical name.
+func renameHead(head []byte, names map[string]string) []byte {
+	if len(names) == 0 {
+		return head
+	}
+
+	lines := bytes.Split(head, []byte("\r\n"))
+	for i, line := range lines[1:] {
+		colon := bytes.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		if name, ok := names[textproto.CanonicalMIMEHeaderKey(string(line[:colon]))]; ok {
+			lines[i+1] = append([]byte(name), line[colon:]...)
+		}
+	}
+
+	return bytes.Join(lines, []byte("\r\n"))
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestHeaderOrder(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	st.Expect(t, err, nil)
+	defer ln.Close()
+
+	// Captures the header names in wire order of two requests sent over the same connection
+	heads := make(chan []string, 2)
+	bodies := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		for i := 0; i < 2; i++ {
+			reader.ReadLine()
+			var names []string
+			length := 0
+			for {
+				line, err := reader.ReadLine()
+				if err != nil {
+					return
+				}
+				if line == "" {
+					break
+				}
+				parts := strings.SplitN(line, ": ", 2)
+				names = append(names, parts[0])
+				if parts[0] == "Content-Length" {
+					length, _ = strconv.Atoi(parts[1])
+				}
+			}
+			body := make([]byte, length)
+			io.ReadFull(reader.R, body)
+			heads <- names
+			bodies <- string(body)
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+		}
+	}()
+
+	base := &http.Transport{}
+	plugin := HeaderOrder("user-agent", "X-Token", "Host")
+	send := func(method, body string) {
+		ctx := context.New()
+		ctx.Client.Transport = base
+		fn := newHandler()
+		plugin.Exec("request", ctx, fn.fn)
+		st.Expect(t, fn.called, true)
+
+		req, _ := http.NewRequest(method, "http://"+ln.Addr().String()+"/", strings.NewReader(body))
+		req.Header.Set("Accept", "*/*")
+		req.Header.Set("X-Token", "foo")
+		req.Header.Set("User-Agent", "test")
+		res, err := ctx.Client.Do(req)
+		st.Expect(t, err, nil)
+		data, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		st.Expect(t, string(data), "ok")
+	}
+
+	send("GET", "")
+	st.Expect(t, <-heads, []string{"User-Agent", "X-Token", "Host", "Accept", "Accept-Encoding"})
+	st.Expect(t, <-bodies, "")
+	send("POST", "hello")
+	st.Expect(t, <-heads, []string{"User-Agent", "X-Token", "Host", "Content-Length", "Accept", "Accept-Encoding"})
+	st.Expect(t, <-bodies, "hello")
+	st.Expect(t, base.DialContext == nil, true)
+}
+
+func TestHeaderOrderTLS(t *testing.T) {
+	var proto string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		w.Write([]byte(r.Header.Get("X-Token")))
+	}))
+	defer ts.Close()
+
+	base := ts.Client().Transport.(*http.Transport)
+	ctx := context.New()
+	ctx.Client.Transport = base
+	HeaderOrder("X-Token").Exec("request", ctx, newHandler().fn)
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	req.Header.Set("X-Token", "foo")
+	res, err := ctx.Client.Do(req)
+	st.Expect(t, err, nil)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	st.Expect(t, string(body), "foo")
+	st.Expect(t, proto, "HTTP/1.1")
+}
+
+func TestReorderHead(t *testing.T) {
+	head := "GET / HTTP/1.1\r\nHost: foo\r\nAccept: */*\r\nCookie: a=b\r\nUser-Agent: test"
+	st.Expect(t, string(reorderHead([]byte(head), []string{"cookie", "host"})),
+		"GET / HTTP/1.1\r\nCookie: a=b\r\nHost: foo\r\nAccept: */*\r\nUser-Agent: test")
+	st.Expect(t, string(renameHead([]byte(head), map[string]string{"User-Agent": "user-agent", "Cookie": "COOKIE"})),
+		"GET / HTTP/1.1\r\nHost: foo\r\nAccept: */*\r\nCOOKIE: a=b\r\nuser-agent: test")
+	st.Expect(t, requestLine([]byte("GET / HTTP/1.1")), true)
+	st.Expect(t, requestLine([]byte("PROPF")), true)
+	st.Expect(t, requestLine([]byte("{\"foo\": 1}")), false)
+	st.Expect(t, requestLine([]byte(" GET")), false)
+}
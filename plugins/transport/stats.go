@@ -0,0 +1,238 @@
+package transport
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// Stats represents a snapshot of the connection pool statistics.
+type Stats struct {
+	// Open stores the number of currently open connections.
+	Open int64
+
+	// Idle stores the number of open connections waiting in the idle pool.
+	// Only HTTP/1.x connections are tracked.
+	Idle int64
+
+	// Reused stores the number of requests served by a previously used connection.
+	Reused int64
+
+	// Handshakes stores the number of successful TLS handshakes performed.
+	Handshakes int64
+
+	// Dials stores the number of connections dialed per network address.
+	Dials map[string]int64
+}
+
+// Monitor collects the connection pool statistics of the requests observed via Observe().
+// Monitor is safe for concurrent use.
+type Monitor struct {
+	// mtx protects the monitor state.
+	mtx sync.Mutex
+
+	// stats stores the collected statistics.
+	stats Stats
+
+	// transports stores the monitored transports derived from the original ones.
+	transports map[*http.Transport]*http.Transport
+}
+
+// NewMonitor creates a new connection pool statistics monitor.
+func NewMonitor() *Monitor {
+	return &Monitor{
+		stats:      Stats{Dials: make(map[string]int64)},
+		transports: make(map[*http.Transport]*http.Transport),
+	}
+}
+
+// Stats returns a snapshot of the collected statistics.
+func (m *Monitor) Stats() Stats {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	stats := m.stats
+	stats.Dials = make(map[string]int64, len(m.stats.Dials))
+	for addr, dials := range m.stats.Dials {
+		stats.Dials[addr] = dials
+	}
+	return stats
+}
+
+// Report calls the given function with a statistics snapshot every interval,
+// until the returned stop function is called.
+func (m *Monitor) Report(interval time.Duration, fn func(Stats)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				fn(m.Stats())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the monitored transports.
+func (m *Monitor) CloseIdleConnections() {
+	m.mtx.Lock()
+	transports := make([]*http.Transport, 0, len(m.transports))
+	for _, transport := range m.transports {
+		transports = append(transports, transport)
+	}
+	m.mtx.Unlock()
+
+	for _, transport := range transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// Observe creates a new plugin which collects the connection pool statistics
+// of the outgoing requests in the given monitor.
+//
+// In order to track the dialed and open connections, the http.Transport used by
+// the request is replaced by a monitored copy of it, created once per monitor,
+// so the original transport is never mutated and the monitored connection pool
+// is isolated. Custom http.RoundTripper implementations are kept as is,
+// in which case only the reused connections and TLS handshakes are tracked.
+// The idle connections of the monitored transports are closed on Client.Close().
+func Observe(m *Monitor) p.Plugin {
+	// Uses the "before dial" phase in order to observe the final transport,
+	// once the request phase had the chance to define a custom one.
+	plugin := p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		if transport, ok := ctx.Client.Transport.(*http.Transport); ok {
+			ctx.Client.Transport = m.transport(transport)
+		}
+		ctx.Request = ctx.Request.WithContext(httptrace.WithClientTrace(ctx.Request.Context(), m.trace()))
+		h.Next(ctx)
+	})
+	return p.WithCloser(plugin, func() error {
+		m.CloseIdleConnections()
+		return nil
+	})
+}
+
+// transport returns the monitored copy of the given transport.
+func (m *Monitor) transport(base *http.Transport) *http.Transport {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if transport, ok := m.transports[base]; ok {
+		return transport
+	}
+	for _, transport := range m.transports {
+		if transport == base {
+			return base
+		}
+	}
+
+	dial := dialContext(base)
+	transport := base.Clone()
+	transport.Dial = nil
+	transport.DialContext = func(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		m.mtx.Lock()
+		m.stats.Dials[addr]++
+		m.stats.Open++
+		m.mtx.Unlock()
+		return &monitoredConn{Conn: conn, monitor: m}, nil
+	}
+
+	m.transports[base] = transport
+	return transport
+}
+
+// trace creates the request trace collecting the connection usage statistics.
+func (m *Monitor) trace() *httptrace.ClientTrace {
+	var got *monitoredConn
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			if info.Reused {
+				m.stats.Reused++
+			}
+			got = lookupConn(info.Conn)
+			if got != nil && got.idle {
+				got.idle = false
+				m.stats.Idle--
+			}
+		},
+		PutIdleConn: func(err error) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			if err == nil && got != nil && !got.idle && !got.closed {
+				got.idle = true
+				m.stats.Idle++
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				return
+			}
+			m.mtx.Lock()
+			m.stats.Handshakes++
+			m.mtx.Unlock()
+		},
+	}
+}
+
+// monitoredConn wraps a dialed connection tracking its pool state.
+// The state is protected by the monitor mutex.
+type monitoredConn struct {
+	net.Conn
+	monitor *Monitor
+	idle    bool
+	closed  bool
+}
+
+// Close closes the connection, updating the monitor statistics.
+func (conn *monitoredConn) Close() error {
+	m := conn.monitor
+	m.mtx.Lock()
+	if !conn.closed {
+		conn.closed = true
+		m.stats.Open--
+		if conn.idle {
+			conn.idle = false
+			m.stats.Idle--
+		}
+	}
+	m.mtx.Unlock()
+	return conn.Conn.Close()
+}
+
+// lookupConn returns the monitored connection wrapped by the given one, if any.
+func lookupConn(conn net.Conn) *monitoredConn {
+	for conn != nil {
+		if monitored, ok := conn.(*monitoredConn); ok {
+			return monitored
+		}
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil
+		}
+		conn = wrapper.NetConn()
+	}
+	return nil
+}
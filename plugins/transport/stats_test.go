@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// send performs a request to the given URL via the given plugin and base transport,
+// reading the whole response body.
+func send(t *testing.T, plugin p.Plugin, base http.RoundTripper, rawurl string) string {
+	ctx := context.New()
+	ctx.Client.Transport = base
+	ctx.Request.URL, _ = url.Parse(rawurl)
+	plugin.Exec("before dial", ctx, newHandler().fn)
+
+	res, err := ctx.Client.Do(ctx.Request)
+	st.Expect(t, err, nil)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	return string(body)
+}
+
+func TestObserve(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	monitor := NewMonitor()
+	plugin := Observe(monitor)
+	base := &http.Transport{}
+
+	for i := 0; i < 3; i++ {
+		st.Expect(t, send(t, plugin, base, ts.URL), "hello")
+	}
+
+	stats := monitor.Stats()
+	st.Expect(t, stats.Dials[strings.TrimPrefix(ts.URL, "http://")], int64(1))
+	st.Expect(t, stats.Open, int64(1))
+	st.Expect(t, stats.Idle, int64(1))
+	st.Expect(t, stats.Reused, int64(2))
+	st.Expect(t, stats.Handshakes, int64(0))
+
+	// The original transport is never mutated
+	st.Expect(t, base.DialContext == nil, true)
+
+	st.Expect(t, p.Close(plugin), nil)
+	stats = monitor.Stats()
+	st.Expect(t, stats.Open, int64(0))
+	st.Expect(t, stats.Idle, int64(0))
+}
+
+func TestObserveTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	monitor := NewMonitor()
+	plugin := Observe(monitor)
+	base := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+
+	for i := 0; i < 2; i++ {
+		send(t, plugin, base, ts.URL)
+	}
+
+	stats := monitor.Stats()
+	st.Expect(t, stats.Handshakes, int64(1))
+	st.Expect(t, stats.Reused, int64(1))
+	st.Expect(t, stats.Open, int64(1))
+	st.Expect(t, stats.Idle, int64(1))
+}
+
+func TestMonitorReport(t *testing.T) {
+	monitor := NewMonitor()
+	reports := make(chan Stats, 10)
+	stop := monitor.Report(5*time.Millisecond, func(stats Stats) {
+		reports <- stats
+	})
+
+	select {
+	case stats := <-reports:
+		st.Expect(t, stats.Open, int64(0))
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for report")
+	}
+	stop()
+	stop()
+}
@@ -4,13 +4,144 @@ import (
 	c "gopkg.in/h2non/gentleman.v2/context"
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 	"net/http"
+	"reflect"
+	"sync"
+	"time"
 )
 
-// Set sets a new HTTP transport for the outgoing request
+// DefaultIdleConnTimeout stores the idle connections timeout of the derived transports,
+// unless defined by the original transport, so the idle connections of the derived
+// transports no longer used are eventually closed.
+var DefaultIdleConnTimeout = 90 * time.Second
+
+// MaxShared stores the maximum number of transports derived via DeriveKey()
+// shared across the plugin instances. The idle connections of the evicted
+// transports are closed.
+var MaxShared = 256
+
+// sharedKey represents the key of a transport derived via DeriveKey().
+type sharedKey struct {
+	base *http.Transport
+	key  interface{}
+}
+
+// shared stores the transports derived via DeriveKey(), shared across the plugin instances.
+var shared = struct {
+	sync.Mutex
+	transports map[sharedKey]*http.Transport
+}{transports: make(map[sharedKey]*http.Transport)}
+
+// Set sets a new HTTP transport for the outgoing request.
+// The plugin implements plugin.Closer, closing the idle connections of the
+// transport once the client is closed, unless it is http.DefaultTransport.
 func Set(transport http.RoundTripper) p.Plugin {
+	plugin := p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		// Override the http.Client transport
+		ctx.Client.Transport = transport
+		h.Next(ctx)
+	})
+
+	return p.WithCloser(plugin, func() error {
+		if t, ok := transport.(interface{ CloseIdleConnections() }); ok && transport != http.DefaultTransport {
+			t.CloseIdleConnections()
+		}
+		return nil
+	})
+}
+
+// Derive creates a new plugin which replaces the http.Transport used by the outgoing
+// request by a copy of it configured via the given function, so the original transport,
+// which is usually shared, is never mutated. The copy is created once per plugin instance
+// and original transport, so the requests of the client using the plugin share the
+// same isolated connection pool. Custom http.RoundTripper implementations are ignored.
+// The plugin implements plugin.Closer, closing the idle connections of the derived
+// transports once the client is closed.
+func Derive(configure func(*http.Transport)) p.Plugin {
+	var mtx sync.Mutex
+	derived := make(map[*http.Transport]*http.Transport)
+
+	plugin := p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		// Assert http.Transport to work with the instance
+		base, ok := ctx.Client.Transport.(*http.Transport)
+		if !ok {
+			// If using a custom transport, just ignore it
+			h.Next(ctx)
+			return
+		}
+
+		mtx.Lock()
+		transport, ok := derived[base]
+		if !ok {
+			transport = derive(base, configure)
+			derived[base] = transport
+		}
+		mtx.Unlock()
+
+		// Override the http.Client transport
+		ctx.Client.Transport = transport
+		h.Next(ctx)
+	})
+
+	return p.WithCloser(plugin, func() error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, transport := range derived {
+			transport.CloseIdleConnections()
+		}
+		return nil
+	})
+}
+
+// DeriveKey creates a new plugin like Derive(), but sharing the derived transport across
+// every plugin instance created with the same key and used with the same original transport.
+// It is intended for plugins registered per request, such as via request.Use(), so they
+// reuse the same connection pool instead of deriving a new transport on every request.
+// The key must identify the configuration applied by the given function, such as
+// a comparable options struct. Non-comparable keys behave like Derive().
+// The shared transports are never closed by the clients, since other clients may use them.
+func DeriveKey(key interface{}, configure func(*http.Transport)) p.Plugin {
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return Derive(configure)
+	}
+
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
+		// Assert http.Transport to work with the instance
+		base, ok := ctx.Client.Transport.(*http.Transport)
+		if !ok {
+			// If using a custom transport, just ignore it
+			h.Next(ctx)
+			return
+		}
+
+		id := sharedKey{base: base, key: key}
+		shared.Lock()
+		transport, ok := shared.transports[id]
+		if !ok {
+			// Evict an arbitrary transport once the limit is reached
+			for evicted, rt := range shared.transports {
+				if len(shared.transports) < MaxShared {
+					break
+				}
+				delete(shared.transports, evicted)
+				rt.CloseIdleConnections()
+			}
+			transport = derive(base, configure)
+			shared.transports[id] = transport
+		}
+		shared.Unlock()
+
 		// Override the http.Client transport
 		ctx.Client.Transport = transport
 		h.Next(ctx)
 	})
 }
+
+// derive returns a copy of the given transport configured via the given function.
+func derive(base *http.Transport, configure func(*http.Transport)) *http.Transport {
+	transport := base.Clone()
+	if transport.IdleConnTimeout == 0 {
+		transport.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	configure(transport)
+	return transport
+}
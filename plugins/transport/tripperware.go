@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// Tripperware represents the common net/http client middleware interface,
+// which decorates a http.RoundTripper.
+type Tripperware func(http.RoundTripper) http.RoundTripper
+
+// Wrap creates a new plugin which decorates the transport used by the outgoing request
+// with the given tripperware, so existing net/http client middleware can be reused as is.
+// The first tripperware is the outermost one, receiving the request first.
+//
+// The decorated transport is created once per plugin instance and original transport,
+// as long as the original transport is comparable, otherwise it is decorated per request.
+func Wrap(tripperware ...Tripperware) p.Plugin {
+	var mtx sync.Mutex
+	wrapped := make(map[http.RoundTripper]http.RoundTripper)
+
+	wrap := func(base http.RoundTripper) http.RoundTripper {
+		transport := base
+		for i := len(tripperware) - 1; i >= 0; i-- {
+			transport = tripperware[i](transport)
+		}
+		return transport
+	}
+
+	// Uses the "before dial" phase in order to decorate the final transport,
+	// once the request phase had the chance to define a custom one.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		base := ctx.Client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+
+		if !reflect.TypeOf(base).Comparable() {
+			ctx.Client.Transport = wrap(base)
+			h.Next(ctx)
+			return
+		}
+
+		mtx.Lock()
+		transport, ok := wrapped[base]
+		if !ok {
+			transport = wrap(base)
+			wrapped[base] = transport
+		}
+		mtx.Unlock()
+
+		ctx.Client.Transport = transport
+		h.Next(ctx)
+	})
+}
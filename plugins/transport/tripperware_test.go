@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestWrap(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header["X-Trip"]
+	}))
+	defer ts.Close()
+
+	var created int
+	trip := func(name string) Tripperware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			created++
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Add("X-Trip", name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	plugin := Wrap(trip("outer"), trip("inner"))
+
+	base := &http.Transport{}
+	for i := 0; i < 2; i++ {
+		ctx := context.New()
+		ctx.Client.Transport = base
+		fn := newHandler()
+		plugin.Exec("before dial", ctx, fn.fn)
+		st.Expect(t, fn.called, true)
+
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		res, err := ctx.Client.Do(req)
+		st.Expect(t, err, nil)
+		res.Body.Close()
+		st.Expect(t, received, []string{"outer", "inner"})
+	}
+
+	// The decorated transport is reused
+	st.Expect(t, created, 2)
+
+	// Non comparable transports are decorated per request
+	ctx := context.New()
+	ctx.Client.Transport = roundTripperFunc(http.DefaultTransport.RoundTrip)
+	plugin.Exec("before dial", ctx, newHandler().fn)
+	st.Expect(t, created, 4)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
@@ -0,0 +1,142 @@
+package tus
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store represents the storage of the upload URLs by upload fingerprint,
+// used to resume the uploads interrupted by failures or process restarts.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the upload URL stored by the given fingerprint, if any.
+	Get(fingerprint string) (url string, ok bool, err error)
+
+	// Set stores the given upload URL by the given fingerprint.
+	Set(fingerprint, url string) error
+
+	// Delete deletes the upload URL stored by the given fingerprint, if any.
+	Delete(fingerprint string) error
+}
+
+// MemoryStore implements an in-memory upload URL store,
+// which only resumes the uploads within the same process.
+type MemoryStore struct {
+	mtx  sync.Mutex
+	urls map[string]string
+}
+
+// NewMemoryStore creates a new in-memory upload URL store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{urls: make(map[string]string)}
+}
+
+// Get returns the upload URL stored by the given fingerprint, if any.
+func (s *MemoryStore) Get(fingerprint string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	url, ok := s.urls[fingerprint]
+	return url, ok, nil
+}
+
+// Set stores the given upload URL by the given fingerprint.
+func (s *MemoryStore) Set(fingerprint, url string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.urls[fingerprint] = url
+	return nil
+}
+
+// Delete deletes the upload URL stored by the given fingerprint, if any.
+func (s *MemoryStore) Delete(fingerprint string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.urls, fingerprint)
+	return nil
+}
+
+// FileStore implements an upload URL store persisted as a JSON file,
+// so the uploads can be resumed across process restarts.
+type FileStore struct {
+	// mtx protects the store state and file.
+	mtx sync.Mutex
+
+	// path stores the store file path.
+	path string
+
+	// urls stores the upload URLs by fingerprint.
+	urls map[string]string
+}
+
+// NewFileStore creates a new upload URL store persisted in the given file,
+// loading the stored upload URLs, if any.
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{path: path, urls: make(map[string]string)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.urls); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Get returns the upload URL stored by the given fingerprint, if any.
+func (s *FileStore) Get(fingerprint string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	url, ok := s.urls[fingerprint]
+	return url, ok, nil
+}
+
+// Set stores the given upload URL by the given fingerprint, saving the store file.
+func (s *FileStore) Set(fingerprint, url string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.urls[fingerprint] = url
+	return s.save()
+}
+
+// Delete deletes the upload URL stored by the given fingerprint, if any, saving the store file.
+func (s *FileStore) Delete(fingerprint string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.urls[fingerprint]; !ok {
+		return nil
+	}
+	delete(s.urls, fingerprint)
+	return s.save()
+}
+
+// save writes the store file. The caller must hold the store mutex.
+func (s *FileStore) save() error {
+	data, err := json.Marshal(s.urls)
+	if err != nil {
+		return err
+	}
+
+	// Write atomically to prevent corrupted files on crashes
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".tus-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,339 @@
+package tus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+// Version stores the supported tus protocol version.
+const Version = "1.0.0"
+
+// DefaultChunkSize stores the default maximum number of bytes sent per PATCH request.
+const DefaultChunkSize int64 = 4 << 20
+
+// offsetContentType stores the content type of the upload chunks.
+const offsetContentType = "application/offset+octet-stream"
+
+var (
+	// ErrMissingEndpoint is returned when no upload creation endpoint is configured.
+	ErrMissingEndpoint = errors.New("tus: missing upload endpoint")
+
+	// ErrMissingLocation is returned when the created upload URL is not announced by the server.
+	ErrMissingLocation = errors.New("tus: missing upload location")
+
+	// ErrInvalidOffset is returned when the server replies with a missing or invalid upload offset.
+	ErrInvalidOffset = errors.New("tus: invalid upload offset")
+
+	// ErrOffsetMismatch is returned when the server upload offset does not match
+	// the offset of the sent chunk, and the upload cannot be synchronized.
+	ErrOffsetMismatch = errors.New("tus: upload offset mismatch")
+
+	// ErrUploadNotFound is returned when the upload does not exist or expired.
+	ErrUploadNotFound = errors.New("tus: upload not found")
+)
+
+// Upload represents a resumable upload source.
+type Upload struct {
+	// Reader stores the upload data source, read by offset.
+	Reader io.ReaderAt
+
+	// Size stores the upload size in bytes.
+	Size int64
+
+	// Fingerprint stores the upload unique identifier, used to resume
+	// the upload via the uploader store. Uploads with no fingerprint are not resumed.
+	Fingerprint string
+
+	// Metadata stores the upload metadata sent on creation, such as the file name.
+	Metadata map[string]string
+}
+
+// NewUpload creates a new upload of the given data source and size.
+func NewUpload(reader io.ReaderAt, size int64, fingerprint string, metadata map[string]string) *Upload {
+	return &Upload{Reader: reader, Size: size, Fingerprint: fingerprint, Metadata: metadata}
+}
+
+// NewUploadFromFile creates a new upload of the given file, fingerprinted
+// by its absolute path, size and modification time, with the file name as "filename" metadata.
+func NewUploadFromFile(file *os.File) (*Upload, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := fmt.Sprintf("%s-%d-%d", path, info.Size(), info.ModTime().UnixNano())
+	return NewUpload(file, info.Size(), fingerprint, map[string]string{"filename": info.Name()}), nil
+}
+
+// Options represents the tus uploader options.
+type Options struct {
+	// Endpoint defines the upload creation endpoint URL.
+	Endpoint string
+
+	// ChunkSize defines the maximum number of bytes sent per PATCH request,
+	// buffered in memory. Defaults to DefaultChunkSize.
+	ChunkSize int64
+
+	// Store defines the storage of the upload URLs used to resume the uploads,
+	// such as a FileStore in order to survive process restarts. Uploads are not resumed if nil.
+	Store Store
+
+	// Progress defines the function called with the upload offset once every chunk is sent, if any.
+	Progress func(offset, size int64)
+
+	// Client defines the client used to reach the tus server,
+	// such as a client with authentication plugins. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// Uploader implements a tus resumable upload protocol client,
+// supporting the core protocol and the creation and termination extensions.
+// Uploader is safe for concurrent use.
+type Uploader struct {
+	// opts stores the uploader options.
+	opts Options
+
+	// cli stores the tus client.
+	cli *gentleman.Client
+}
+
+// New creates a new tus uploader based on the given options.
+func New(opts Options) (*Uploader, error) {
+	if opts.Endpoint == "" {
+		return nil, ErrMissingEndpoint
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	cli := opts.Client
+	if cli == nil {
+		cli = gentleman.New()
+	}
+	cli = gentleman.New().UseParent(cli)
+	cli.SetHeader("Tus-Resumable", Version)
+	return &Uploader{opts: opts, cli: cli}, nil
+}
+
+// Upload uploads the given upload, resuming it from the server offset if its URL is
+// stored by fingerprint, or creating it otherwise. Returns the upload URL, which is also
+// returned on transfer failures, so the upload can be resumed by a later call.
+func (u *Uploader) Upload(upload *Upload) (string, error) {
+	location, offset, err := u.resume(upload)
+	if err != nil {
+		return "", err
+	}
+	if location == "" {
+		if location, err = u.Create(upload); err != nil {
+			return "", err
+		}
+	}
+
+	if err := u.transfer(location, upload, offset); err != nil {
+		return location, err
+	}
+	if u.opts.Store != nil && upload.Fingerprint != "" {
+		if err := u.opts.Store.Delete(upload.Fingerprint); err != nil {
+			return location, err
+		}
+	}
+	return location, nil
+}
+
+// Create creates the given upload on the server, returning the upload URL.
+// The upload URL is stored by fingerprint, if any.
+func (u *Uploader) Create(upload *Upload) (string, error) {
+	req := u.cli.Request().Method("POST").URL(u.opts.Endpoint).Body(http.NoBody)
+	req.SetHeader("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		req.SetHeader("Upload-Metadata", encodeMetadata(upload.Metadata))
+	}
+
+	res, err := req.Send()
+	if err != nil {
+		return "", err
+	}
+	res.Close()
+	if res.StatusCode != http.StatusCreated {
+		return "", statusError(res.StatusCode)
+	}
+
+	location := res.Header.Get("Location")
+	if location == "" {
+		return "", ErrMissingLocation
+	}
+	base, err := url.Parse(u.opts.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	location = base.ResolveReference(ref).String()
+
+	if u.opts.Store != nil && upload.Fingerprint != "" {
+		if err := u.opts.Store.Set(upload.Fingerprint, location); err != nil {
+			return "", err
+		}
+	}
+	return location, nil
+}
+
+// Offset returns the current offset of the given upload URL, as reported by the server.
+func (u *Uploader) Offset(location string) (int64, error) {
+	res, err := u.cli.Request().Method("HEAD").URL(location).Send()
+	if err != nil {
+		return 0, err
+	}
+	res.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return 0, statusError(res.StatusCode)
+	}
+	return parseOffset(res)
+}
+
+// Terminate terminates the given upload URL, deleting the uploaded data from the server.
+func (u *Uploader) Terminate(location string) error {
+	res, err := u.cli.Request().Method("DELETE").URL(location).Send()
+	if err != nil {
+		return err
+	}
+	res.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return statusError(res.StatusCode)
+	}
+	return nil
+}
+
+// resume returns the stored upload URL of the given upload and its server offset, if any.
+// Stored uploads no longer found on the server are discarded.
+func (u *Uploader) resume(upload *Upload) (string, int64, error) {
+	if u.opts.Store == nil || upload.Fingerprint == "" {
+		return "", 0, nil
+	}
+	location, ok, err := u.opts.Store.Get(upload.Fingerprint)
+	if err != nil || !ok {
+		return "", 0, err
+	}
+
+	offset, err := u.Offset(location)
+	if err == ErrUploadNotFound {
+		return "", 0, u.opts.Store.Delete(upload.Fingerprint)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return location, offset, nil
+}
+
+// transfer sends the given upload data to the given upload URL, starting from the given offset.
+func (u *Uploader) transfer(location string, upload *Upload, offset int64) error {
+	for offset < upload.Size {
+		size := upload.Size - offset
+		if size > u.opts.ChunkSize {
+			size = u.opts.ChunkSize
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(io.NewSectionReader(upload.Reader, offset, size), chunk); err != nil {
+			return err
+		}
+
+		next, err := u.patch(location, offset, chunk)
+		if err == ErrOffsetMismatch {
+			// Synchronize with the server offset, such as after a chunk
+			// received by the server but not acknowledged to the client
+			synced, syncErr := u.Offset(location)
+			if syncErr != nil {
+				return syncErr
+			}
+			if synced == offset {
+				return err
+			}
+			next, err = synced, nil
+		}
+		if err != nil {
+			return err
+		}
+		if next <= offset || next > upload.Size {
+			return ErrInvalidOffset
+		}
+
+		offset = next
+		if u.opts.Progress != nil {
+			u.opts.Progress(offset, upload.Size)
+		}
+	}
+	return nil
+}
+
+// patch sends the given chunk at the given offset, returning the new server offset.
+func (u *Uploader) patch(location string, offset int64, chunk []byte) (int64, error) {
+	req := u.cli.Request().Method("PATCH").URL(location).Body(bytes.NewReader(chunk))
+	req.SetHeader("Content-Type", offsetContentType)
+	req.SetHeader("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	res, err := req.Send()
+	if err != nil {
+		return 0, err
+	}
+	res.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return 0, statusError(res.StatusCode)
+	}
+	return parseOffset(res)
+}
+
+// parseOffset returns the upload offset announced by the given response.
+func parseOffset(res *gentleman.Response) (int64, error) {
+	offset, err := strconv.ParseInt(res.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	return offset, nil
+}
+
+// statusError returns the error of the given unexpected response status.
+func statusError(status int) error {
+	switch status {
+	case http.StatusNotFound, http.StatusGone, http.StatusForbidden:
+		return ErrUploadNotFound
+	case http.StatusConflict:
+		return ErrOffsetMismatch
+	}
+	return fmt.Errorf("tus: unexpected response status: %d", status)
+}
+
+// encodeMetadata encodes the given upload metadata as
+// comma-separated key and base64 encoded value pairs, sorted by key.
+func encodeMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key
+		if value := metadata[key]; value != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(value))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
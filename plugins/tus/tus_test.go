@@ -0,0 +1,201 @@
+package tus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+// server implements a minimal in-memory tus server.
+type server struct {
+	mtx      sync.Mutex
+	uploads  map[string][]byte
+	metadata string
+	patches  int
+	failAt   int
+}
+
+func newServer() (*server, *httptest.Server) {
+	s := &server{uploads: make(map[string][]byte)}
+	return s, httptest.NewServer(s)
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/files/")
+	switch r.Method {
+	case "POST":
+		id = strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = []byte{}
+		s.metadata = r.Header.Get("Upload-Metadata")
+		w.Header().Set("Location", "/files/"+id)
+		w.WriteHeader(http.StatusCreated)
+	case "HEAD":
+		data, ok := s.uploads[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+	case "PATCH":
+		data, ok := s.uploads[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Type") != offsetContentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if r.Header.Get("Upload-Offset") != strconv.Itoa(len(data)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.patches++
+		if s.patches == s.failAt {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		chunk, _ := ioutil.ReadAll(r.Body)
+		s.uploads[id] = append(data, chunk...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.uploads[id])))
+		w.WriteHeader(http.StatusNoContent)
+	case "DELETE":
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	s, ts := newServer()
+	defer ts.Close()
+
+	var progress []int64
+	uploader, err := New(Options{
+		Endpoint:  ts.URL + "/files/",
+		ChunkSize: 4,
+		Progress:  func(offset, size int64) { progress = append(progress, offset) },
+	})
+	st.Expect(t, err, nil)
+
+	data := []byte("hello tus world")
+	location, err := uploader.Upload(NewUpload(bytes.NewReader(data), int64(len(data)), "", map[string]string{"filename": "hello.txt", "private": ""}))
+	st.Expect(t, err, nil)
+	st.Expect(t, location, ts.URL+"/files/1")
+	st.Expect(t, s.uploads["1"], data)
+	st.Expect(t, s.metadata, "filename aGVsbG8udHh0,private")
+	st.Expect(t, progress, []int64{4, 8, 12, 15})
+
+	offset, err := uploader.Offset(location)
+	st.Expect(t, err, nil)
+	st.Expect(t, offset, int64(15))
+
+	st.Expect(t, uploader.Terminate(location), nil)
+	_, err = uploader.Offset(location)
+	st.Expect(t, err, ErrUploadNotFound)
+}
+
+func TestUploadResume(t *testing.T) {
+	s, ts := newServer()
+	defer ts.Close()
+	s.failAt = 2
+
+	dir, err := ioutil.TempDir("", "tus")
+	st.Expect(t, err, nil)
+	defer os.RemoveAll(dir)
+	data := []byte("resumable upload data")
+	path := filepath.Join(dir, "data.bin")
+	st.Expect(t, ioutil.WriteFile(path, data, 0600), nil)
+
+	file, err := os.Open(path)
+	st.Expect(t, err, nil)
+	defer file.Close()
+	upload, err := NewUploadFromFile(file)
+	st.Expect(t, err, nil)
+	st.Expect(t, upload.Size, int64(len(data)))
+	st.Expect(t, upload.Metadata["filename"], "data.bin")
+
+	storePath := filepath.Join(dir, "uploads.json")
+	store, err := NewFileStore(storePath)
+	st.Expect(t, err, nil)
+	uploader, _ := New(Options{Endpoint: ts.URL + "/files/", ChunkSize: 8, Store: store})
+
+	location, err := uploader.Upload(upload)
+	st.Expect(t, err.Error(), "tus: unexpected response status: 500")
+	st.Expect(t, location, ts.URL+"/files/1")
+	st.Expect(t, len(s.uploads["1"]), 8)
+
+	// Resume after a restart via the persisted store
+	store, err = NewFileStore(storePath)
+	st.Expect(t, err, nil)
+	stored, ok, _ := store.Get(upload.Fingerprint)
+	st.Expect(t, ok, true)
+	st.Expect(t, stored, location)
+
+	uploader, _ = New(Options{Endpoint: ts.URL + "/files/", ChunkSize: 8, Store: store})
+	location, err = uploader.Upload(upload)
+	st.Expect(t, err, nil)
+	st.Expect(t, location, ts.URL+"/files/1")
+	st.Expect(t, s.uploads["1"], data)
+	st.Expect(t, len(s.uploads), 1)
+	_, ok, _ = store.Get(upload.Fingerprint)
+	st.Expect(t, ok, false)
+}
+
+func TestUploadExpired(t *testing.T) {
+	s, ts := newServer()
+	defer ts.Close()
+
+	store := NewMemoryStore()
+	store.Set("expired", ts.URL+"/files/missing")
+	uploader, _ := New(Options{Endpoint: ts.URL + "/files/", Store: store})
+
+	location, err := uploader.Upload(NewUpload(strings.NewReader("data"), 4, "expired", nil))
+	st.Expect(t, err, nil)
+	st.Expect(t, location, ts.URL+"/files/1")
+	st.Expect(t, string(s.uploads["1"]), "data")
+}
+
+func TestUploadOffsetSync(t *testing.T) {
+	s, ts := newServer()
+	defer ts.Close()
+
+	// The first chunk was received by the server but not acknowledged
+	s.uploads["1"] = []byte("hello")
+	uploader, _ := New(Options{Endpoint: ts.URL + "/files/", ChunkSize: 5})
+
+	upload := NewUpload(strings.NewReader("hello world"), 11, "", nil)
+	st.Expect(t, uploader.transfer(ts.URL+"/files/1", upload, 0), nil)
+	st.Expect(t, string(s.uploads["1"]), "hello world")
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(Options{})
+	st.Expect(t, err, ErrMissingEndpoint)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	uploader, _ := New(Options{Endpoint: ts.URL})
+	_, err = uploader.Create(NewUpload(strings.NewReader(""), 0, "", nil))
+	st.Expect(t, err, ErrMissingLocation)
+	st.Expect(t, statusError(http.StatusGone), ErrUploadNotFound)
+}
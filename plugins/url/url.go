@@ -9,24 +9,37 @@ import (
 	p "gopkg.in/h2non/gentleman.v2/plugin"
 )
 
-// URL parses and defines a new URL in the outgoing request
+// schemeRegexp matches the supported URL schemes.
+var schemeRegexp = regexp.MustCompile("^http[s]?://")
+
+// Parse parses the given URL the same way the URL plugins do,
+// defaulting to the http scheme if missing.
+func Parse(uri string) (*url.URL, error) {
+	return url.Parse(normalize(uri))
+}
+
+// URL parses and defines a new URL in the outgoing request.
+// The URL is parsed once, when the plugin is created.
 func URL(uri string) p.Plugin {
+	u, err := Parse(uri)
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		u, err := url.Parse(normalize(uri))
 		if err != nil {
 			h.Error(ctx, err)
 			return
 		}
 
-		ctx.Request.URL = u
+		// Copy the parsed URL, since it may be mutated by subsequent plugins
+		uri := *u
+		ctx.Request.URL = &uri
 		h.Next(ctx)
 	})
 }
 
-// BaseURL parses and defines a schema and host URL values in the outgoing request
+// BaseURL parses and defines a schema and host URL values in the outgoing request.
+// The URL is parsed once, when the plugin is created.
 func BaseURL(uri string) p.Plugin {
+	u, err := Parse(uri)
 	return p.NewRequestPlugin(func(ctx *c.Context, h c.Handler) {
-		u, err := url.Parse(normalize(uri))
 		if err != nil {
 			h.Error(ctx, err)
 			return
@@ -97,8 +110,7 @@ func normalizePath(path string) string {
 }
 
 func normalize(uri string) string {
-	match, _ := regexp.MatchString("^http[s]?://", uri)
-	if match {
+	if schemeRegexp.MatchString(uri) {
 		return uri
 	}
 	return "http://" + uri
@@ -147,6 +147,32 @@ func TestPathParams(t *testing.T) {
 	}
 }
 
+func TestURLParsedOnce(t *testing.T) {
+	plugin := URL("http://foo/bar")
+
+	ctx := context.New()
+	plugin.Exec("request", ctx, newHandler().fn)
+	ctx.Request.URL.Path = "/baz"
+
+	// Mutations do not affect subsequent requests
+	ctx = context.New()
+	plugin.Exec("request", ctx, newHandler().fn)
+	st.Expect(t, ctx.Request.URL.Path, "/bar")
+
+	// Parse errors are reported on every request
+	plugin = URL("http://foo/%zz")
+	ctx = context.New()
+	plugin.Exec("request", ctx, newHandler().fn)
+	st.Reject(t, ctx.Error, nil)
+}
+
+func BenchmarkURL(b *testing.B) {
+	plugin := URL("http://foo/bar")
+	for n := 0; n < b.N; n++ {
+		plugin.Exec("request", context.New(), context.NewHandler(func(*context.Context) {}))
+	}
+}
+
 func assert(t *testing.T, fn *handler, ctx *context.Context, test test) {
 	st.Expect(t, fn.called, true)
 	st.Expect(t, ctx.Error, nil)
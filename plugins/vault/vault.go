@@ -0,0 +1,378 @@
+package vault
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+)
+
+// DefaultRetryInterval defines the default amount of time waited before retrying
+// a failed background lease renewal or secret read.
+const DefaultRetryInterval = 10 * time.Second
+
+var (
+	// ErrMissingAddress is returned when no Vault server address is configured.
+	ErrMissingAddress = errors.New("vault: missing server address")
+
+	// ErrInvalidPrivateKey is returned when the secret private key is not a valid PEM encoded key.
+	ErrInvalidPrivateKey = errors.New("vault: invalid private key")
+
+	// ErrProviderClosed is returned when reading credentials from a closed provider.
+	ErrProviderClosed = errors.New("vault: provider closed")
+)
+
+// Fields represents the secret data field names mapped to the credentials.
+type Fields struct {
+	// Username defines the username field name. Defaults to "username".
+	Username string
+
+	// Password defines the password field name. Defaults to "password".
+	Password string
+
+	// Token defines the token or API key field name. Defaults to "token".
+	Token string
+
+	// PrivateKey defines the PEM encoded private key field name, such as the private key
+	// issued by the PKI secrets engine. Defaults to "private_key".
+	PrivateKey string
+
+	// KeyID defines the signing key identifier field name. Defaults to "serial_number".
+	KeyID string
+}
+
+// Options represents the Vault credentials provider options.
+type Options struct {
+	// Address defines the Vault server address. Defaults to the VAULT_ADDR environment variable.
+	Address string
+
+	// Token defines the Vault token. Defaults to the VAULT_TOKEN environment variable.
+	Token string
+
+	// Namespace defines the Vault Enterprise namespace, if any.
+	// Defaults to the VAULT_NAMESPACE environment variable.
+	Namespace string
+
+	// Path defines the secret path, such as "database/creds/readonly" or "secret/data/api".
+	// The KV version 2 nested data is supported.
+	Path string
+
+	// Fields defines the secret data field names mapped to the credentials.
+	Fields Fields
+
+	// RefreshInterval defines how often the secrets with no lease, such as KV secrets,
+	// are read again in order to pick up rotated values. Disabled by default.
+	RefreshInterval time.Duration
+
+	// RetryInterval defines the amount of time waited before retrying a failed
+	// background renewal. Defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+
+	// Client defines the client used to reach the Vault server,
+	// such as a client with a custom TLS configuration. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// secret represents a Vault secret response.
+type secret struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+// Provider implements auth.CredentialsProvider fetching short-lived credentials from Vault.
+// The secret lease is renewed in the background once two thirds of it elapsed,
+// and the secret is read again once the lease can no longer be renewed.
+// Provider is safe for concurrent use.
+type Provider struct {
+	// opts stores the provider options.
+	opts Options
+
+	// cli stores the Vault client.
+	cli *gentleman.Client
+
+	// mtx protects the provider state.
+	mtx sync.Mutex
+
+	// creds stores the current credentials.
+	creds *auth.Credentials
+
+	// leaseID stores the current secret lease identifier.
+	leaseID string
+
+	// renewable stores if the current lease can be renewed.
+	renewable bool
+
+	// lease stores the current lease duration.
+	lease time.Duration
+
+	// expiry stores the current credentials expiration time, if any.
+	expiry time.Time
+
+	// retryAt stores the time of the next retry of a failed background renewal, if any.
+	retryAt time.Time
+
+	// wake is used to notify the background renewal of a new secret.
+	wake chan struct{}
+
+	// done is closed once the provider is closed.
+	done chan struct{}
+
+	// closeOnce ensures the provider is closed once.
+	closeOnce sync.Once
+}
+
+// NewProvider creates a new Vault credentials provider based on the given options.
+// The secret is read on the first request, after which the background renewal starts.
+func NewProvider(opts Options) (*Provider, error) {
+	if opts.Address == "" {
+		opts.Address = os.Getenv("VAULT_ADDR")
+	}
+	if opts.Address == "" {
+		return nil, ErrMissingAddress
+	}
+	if opts.Token == "" {
+		opts.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	opts.Fields = opts.Fields.withDefaults()
+
+	cli := opts.Client
+	if cli == nil {
+		cli = gentleman.New()
+	}
+	cli = gentleman.New().UseParent(cli).BaseURL(strings.TrimSuffix(opts.Address, "/")).FailOnError()
+	cli.SetHeader("X-Vault-Token", opts.Token)
+	if opts.Namespace != "" {
+		cli.SetHeader("X-Vault-Namespace", opts.Namespace)
+	}
+
+	p := &Provider{opts: opts, cli: cli, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go p.renewLoop()
+	return p, nil
+}
+
+// Credentials returns the current credentials, reading the secret
+// if missing or expired.
+func (p *Provider) Credentials() (auth.Credentials, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	select {
+	case <-p.done:
+		return auth.Credentials{}, ErrProviderClosed
+	default:
+	}
+
+	if p.creds == nil || !p.expiry.IsZero() && !time.Now().Before(p.expiry) {
+		if err := p.read(); err != nil {
+			return auth.Credentials{}, err
+		}
+	}
+	return *p.creds, nil
+}
+
+// Close stops the background renewal.
+func (p *Provider) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+// read reads the secret, replacing the current credentials.
+// The caller must hold the provider mutex.
+func (p *Provider) read() error {
+	var s secret
+	res, err := p.cli.Request().Path("/v1/" + strings.TrimPrefix(p.opts.Path, "/")).Send()
+	if err != nil {
+		return err
+	}
+	if err := res.JSON(&s); err != nil {
+		return err
+	}
+
+	data := s.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	creds, err := p.opts.Fields.credentials(data)
+	if err != nil {
+		return err
+	}
+
+	p.creds, p.leaseID, p.renewable = &creds, s.LeaseID, s.Renewable
+	p.lease = time.Duration(s.LeaseDuration) * time.Second
+	p.expiry, p.retryAt = time.Time{}, time.Time{}
+	if p.lease > 0 {
+		p.expiry = time.Now().Add(p.lease)
+	}
+	p.notify()
+	return nil
+}
+
+// renew renews the current secret lease, falling back to reading the secret again
+// if the lease cannot be renewed or the renewal did not extend it.
+// The caller must hold the provider mutex.
+func (p *Provider) renew() error {
+	if p.leaseID == "" || !p.renewable {
+		return p.read()
+	}
+
+	var s secret
+	res, err := p.cli.Request().Method("PUT").Path("/v1/sys/leases/renew").
+		JSON(map[string]interface{}{"lease_id": p.leaseID}).Send()
+	if err == nil {
+		err = res.JSON(&s)
+	}
+	if err != nil || s.LeaseDuration <= 0 {
+		return p.read()
+	}
+
+	lease := time.Duration(s.LeaseDuration) * time.Second
+	expiry := time.Now().Add(lease)
+	if !expiry.After(p.expiry) {
+		// The lease reached its maximum TTL
+		return p.read()
+	}
+	p.lease, p.expiry, p.renewable = lease, expiry, s.Renewable
+	return nil
+}
+
+// renewLoop renews the secret lease, or refreshes the secret, in the background until closed.
+func (p *Provider) renewLoop() {
+	var timer *time.Timer
+	for {
+		var wait <-chan time.Time
+		p.mtx.Lock()
+		if delay, ok := p.nextRenewal(); ok {
+			timer = time.NewTimer(delay)
+			wait = timer.C
+		}
+		p.mtx.Unlock()
+
+		select {
+		case <-p.done:
+			return
+		case <-p.wake:
+		case <-wait:
+			p.mtx.Lock()
+			p.retryAt = time.Time{}
+			if err := p.renew(); err != nil {
+				// Keep the current credentials until expired, retrying meanwhile
+				p.retryAt = time.Now().Add(p.opts.RetryInterval)
+			}
+			p.mtx.Unlock()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// nextRenewal returns the amount of time until the next background renewal.
+// The caller must hold the provider mutex.
+func (p *Provider) nextRenewal() (time.Duration, bool) {
+	if p.creds == nil {
+		return 0, false
+	}
+	if !p.retryAt.IsZero() {
+		return time.Until(p.retryAt), true
+	}
+	if p.expiry.IsZero() {
+		return p.opts.RefreshInterval, p.opts.RefreshInterval > 0
+	}
+	if !time.Now().Before(p.expiry) {
+		return 0, false
+	}
+	if delay := time.Until(p.expiry) - p.lease/3; delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// notify wakes up the background renewal.
+func (p *Provider) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// withDefaults returns the field names with the default values.
+func (f Fields) withDefaults() Fields {
+	if f.Username == "" {
+		f.Username = "username"
+	}
+	if f.Password == "" {
+		f.Password = "password"
+	}
+	if f.Token == "" {
+		f.Token = "token"
+	}
+	if f.PrivateKey == "" {
+		f.PrivateKey = "private_key"
+	}
+	if f.KeyID == "" {
+		f.KeyID = "serial_number"
+	}
+	return f
+}
+
+// credentials maps the given secret data to credentials.
+func (f Fields) credentials(data map[string]interface{}) (auth.Credentials, error) {
+	field := func(name string) string {
+		value, _ := data[name].(string)
+		return value
+	}
+	creds := auth.Credentials{
+		Username: field(f.Username),
+		Password: field(f.Password),
+		Token:    field(f.Token),
+		KeyID:    field(f.KeyID),
+	}
+	if key := field(f.PrivateKey); key != "" {
+		signer, err := parsePrivateKey(key)
+		if err != nil {
+			return auth.Credentials{}, err
+		}
+		creds.Signer = signer
+	}
+	return creds, nil
+}
+
+// parsePrivateKey parses the given PEM encoded PKCS #8, PKCS #1 or EC private key.
+func parsePrivateKey(value string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, ErrInvalidPrivateKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, ErrInvalidPrivateKey
+}
@@ -0,0 +1,181 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/auth"
+)
+
+// server represents a fake Vault server.
+type server struct {
+	mtx    sync.Mutex
+	reads  int
+	renews int
+	leases []int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "root" {
+		w.WriteHeader(403)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/database/creds/app":
+		s.reads++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       "database/creds/app/" + strconv.Itoa(s.reads),
+			"lease_duration": 1,
+			"renewable":      true,
+			"data":           map[string]string{"username": "user" + strconv.Itoa(s.reads), "password": "pass"},
+		})
+	case "/v1/sys/leases/renew":
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		lease := 0
+		if s.renews < len(s.leases) {
+			lease = s.leases[s.renews]
+		}
+		s.renews++
+		json.NewEncoder(w).Encode(map[string]interface{}{"lease_id": body.LeaseID, "lease_duration": lease, "renewable": true})
+	case "/v1/secret/data/api":
+		s.reads++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]string{"api_key": "key" + strconv.Itoa(s.reads)},
+				"metadata": map[string]interface{}{"version": s.reads},
+			},
+		})
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (s *server) stats() (int, int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.reads, s.renews
+}
+
+func TestProviderLeaseRenewal(t *testing.T) {
+	vault := &server{leases: []int{1}}
+	ts := httptest.NewServer(vault)
+	defer ts.Close()
+
+	provider, err := NewProvider(Options{Address: ts.URL, Token: "root", Path: "database/creds/app"})
+	st.Expect(t, err, nil)
+	defer provider.Close()
+
+	// The credentials feed the auth plugins
+	var authorization string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+	_, err = gentleman.NewRequest().URL(api.URL).Use(auth.BasicFrom(provider)).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, authorization, "Basic dXNlcjE6cGFzcw==")
+
+	// The lease is renewed once, then the secret is read again once it can no longer be renewed
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if reads, _ := vault.stats(); reads == 2 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	reads, renews := vault.stats()
+	st.Expect(t, reads, 2)
+	st.Expect(t, renews, 2)
+
+	creds, err := provider.Credentials()
+	st.Expect(t, err, nil)
+	st.Expect(t, creds.Username, "user2")
+
+	provider.Close()
+	_, err = provider.Credentials()
+	st.Expect(t, err, ErrProviderClosed)
+}
+
+func TestProviderRefreshInterval(t *testing.T) {
+	vault := &server{}
+	ts := httptest.NewServer(vault)
+	defer ts.Close()
+
+	provider, err := NewProvider(Options{
+		Address:         ts.URL,
+		Token:           "root",
+		Path:            "/secret/data/api",
+		Fields:          Fields{Token: "api_key"},
+		RefreshInterval: 50 * time.Millisecond,
+	})
+	st.Expect(t, err, nil)
+	defer provider.Close()
+
+	creds, err := provider.Credentials()
+	st.Expect(t, err, nil)
+	st.Expect(t, creds.Token, "key1")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for creds.Token == "key1" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		creds, _ = provider.Credentials()
+	}
+	st.Expect(t, creds.Token, "key2")
+}
+
+func TestProviderErrors(t *testing.T) {
+	ts := httptest.NewServer(&server{})
+	defer ts.Close()
+
+	addr := os.Getenv("VAULT_ADDR")
+	os.Unsetenv("VAULT_ADDR")
+	_, err := NewProvider(Options{Path: "secret/data/api"})
+	os.Setenv("VAULT_ADDR", addr)
+	st.Expect(t, err, ErrMissingAddress)
+
+	provider, err := NewProvider(Options{Address: ts.URL, Token: "invalid", Path: "secret/data/api"})
+	st.Expect(t, err, nil)
+	defer provider.Close()
+	_, err = provider.Credentials()
+	httpErr, ok := err.(*gentleman.HTTPError)
+	st.Expect(t, ok, true)
+	st.Expect(t, httpErr.StatusCode, 403)
+}
+
+func TestFieldsPrivateKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	data := map[string]interface{}{
+		"private_key":   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"serial_number": "39:dd:2e",
+	}
+
+	creds, err := Fields{}.withDefaults().credentials(data)
+	st.Expect(t, err, nil)
+	st.Expect(t, creds.KeyID, "39:dd:2e")
+	st.Expect(t, creds.Signer.(*ecdsa.PrivateKey).D.Cmp(key.D), 0)
+
+	_, err = Fields{}.withDefaults().credentials(map[string]interface{}{"private_key": "invalid"})
+	st.Expect(t, err, ErrInvalidPrivateKey)
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+	"time"
+
+	c "gopkg.in/h2non/gentleman.v2/context"
+	p "gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// DefaultHeader stores the default header used to send the webhook signatures.
+const DefaultHeader = "X-Signature"
+
+// DefaultScheme stores the default signature scheme name.
+const DefaultScheme = "v1"
+
+// ErrMissingSecret is returned when signing without a secret.
+var ErrMissingSecret = errors.New("webhook: missing signing secret")
+
+// Options represents the webhook signing options.
+type Options struct {
+	// Secret defines the shared HMAC signing secret.
+	Secret []byte
+
+	// Header defines the signature header. Defaults to DefaultHeader.
+	Header string
+
+	// Scheme defines the signature scheme name. Defaults to DefaultScheme.
+	Scheme string
+
+	// Hash defines the HMAC hash function. Defaults to sha256.New.
+	Hash func() hash.Hash
+
+	// Now defines the clock used to timestamp the signatures. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Sign creates a new plugin which signs the request body with the given options,
+// defining a signature header such as "X-Signature: t=1492774577,v1=5257a869...",
+// where v1 is the hex encoded HMAC over the timestamp and the body, joined by a dot.
+func Sign(opts Options) p.Plugin {
+	header := opts.Header
+	if header == "" {
+		header = DefaultHeader
+	}
+
+	// Uses the "before dial" phase in order to sign the final request body,
+	// once the request phase had the chance to define it.
+	return p.NewPhasePlugin("before dial", func(ctx *c.Context, h c.Handler) {
+		body, err := utils.ReplayRequestBody(ctx.Request)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+
+		now := time.Now
+		if opts.Now != nil {
+			now = opts.Now
+		}
+		value, err := Signature(opts, now(), body)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		ctx.Request.Header.Set(header, value)
+		h.Next(ctx)
+	})
+}
+
+// Signature returns the signature header value of the given body at the given time.
+func Signature(opts Options, timestamp time.Time, body []byte) (string, error) {
+	if len(opts.Secret) == 0 {
+		return "", ErrMissingSecret
+	}
+	scheme := opts.Scheme
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+	newHash := opts.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(newHash, opts.Secret)
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + "," + scheme + "=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
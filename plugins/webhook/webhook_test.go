@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/sha512"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/body"
+)
+
+func TestSignature(t *testing.T) {
+	timestamp := time.Unix(1492774577, 0)
+	value, err := Signature(Options{Secret: []byte("secret")}, timestamp, []byte(`{"event":"ping"}`))
+	st.Expect(t, err, nil)
+	st.Expect(t, value, "t=1492774577,v1=6c22cca6c3a75ae0ba281e8098b4d06e187ce90b7dd8c1e0d1ee57839db87ff5")
+
+	value, err = Signature(Options{Secret: []byte("secret"), Scheme: "v2", Hash: sha512.New}, timestamp, nil)
+	st.Expect(t, err, nil)
+	st.Expect(t, value[:16], "t=1492774577,v2=")
+	st.Expect(t, len(value), 16+128)
+
+	_, err = Signature(Options{}, timestamp, nil)
+	st.Expect(t, err, ErrMissingSecret)
+}
+
+func TestSign(t *testing.T) {
+	var signature, received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		signature, received = r.Header.Get("Webhook-Signature"), string(data)
+	}))
+	defer ts.Close()
+
+	opts := Options{
+		Secret: []byte("secret"),
+		Header: "Webhook-Signature",
+		Now:    func() time.Time { return time.Unix(1492774577, 0) },
+	}
+	req := gentleman.NewRequest().URL(ts.URL).Method("POST")
+	req.Use(body.String(`{"event":"ping"}`))
+	req.Use(Sign(opts))
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, received, `{"event":"ping"}`)
+	expected, _ := Signature(opts, time.Unix(1492774577, 0), []byte(`{"event":"ping"}`))
+	st.Expect(t, signature, expected)
+}
+
+func TestSignMissingSecret(t *testing.T) {
+	ctx := context.New()
+	fn := newHandler()
+	Sign(Options{}).Exec("before dial", ctx, fn.fn)
+	st.Expect(t, fn.called, true)
+	st.Expect(t, ctx.Error, ErrMissingSecret)
+}
+
+type handler struct {
+	fn     context.Handler
+	called bool
+}
+
+func newHandler() *handler {
+	h := &handler{}
+	h.fn = context.NewHandler(func(c *context.Context) {
+		h.called = true
+	})
+	return h
+}
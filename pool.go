@@ -0,0 +1,191 @@
+package gentleman
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+// DefaultPoolSize stores the default maximum number of clients cached by a ClientPool.
+const DefaultPoolSize = 100
+
+// errFactoryPanic is returned to the callers waiting for a client whose factory panicked.
+var errFactoryPanic = errors.New("gentleman: client factory panicked")
+
+// ClientFactory represents the function used to configure the pooled child client
+// created for the given key, such as a tenant, host or credential identifier.
+type ClientFactory func(key string, cli *Client) error
+
+// ClientPool lazily creates and caches child clients by key, inheriting from a parent client,
+// such as one client per tenant with its own base URL and credentials.
+// The least recently used clients are evicted and closed once the pool size is exceeded:
+// their new requests fail with ErrClientClosed, while the resources of their plugins
+// are released once the requests in flight at eviction are complete.
+// Callers should therefore call Get for every request instead of holding the pooled clients.
+// ClientPool is safe for concurrent use.
+type ClientPool struct {
+	// mtx protects the pool state.
+	mtx sync.Mutex
+
+	// parent stores the client inherited by the pooled clients.
+	parent *Client
+
+	// factory stores the function configuring the pooled clients.
+	factory ClientFactory
+
+	// size stores the maximum number of pooled clients.
+	size int
+
+	// lru stores the pooled clients, most recently used first.
+	lru *list.List
+
+	// clients stores the pooled clients list elements by key.
+	clients map[string]*list.Element
+
+	// pending stores the clients being created by key.
+	pending map[string]*pooled
+}
+
+// pooled represents a pooled client.
+type pooled struct {
+	key    string
+	client *Client
+
+	// ready is closed once the client is created.
+	ready chan struct{}
+
+	// err stores the client factory error, if any.
+	err error
+}
+
+// NewClientPool creates a new client pool of the given size, which defaults to DefaultPoolSize,
+// creating the child clients of the given parent configured via the given factory, if any.
+func NewClientPool(parent *Client, size int, factory ClientFactory) *ClientPool {
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	return &ClientPool{
+		parent:  parent,
+		factory: factory,
+		size:    size,
+		lru:     list.New(),
+		clients: make(map[string]*list.Element),
+		pending: make(map[string]*pooled),
+	}
+}
+
+// Get returns the pooled client for the given key, creating it if required.
+// The factory is called once per key, outside the pool lock, while concurrent
+// callers of the same key wait for its result.
+// Factory errors are returned and the client is not pooled.
+func (p *ClientPool) Get(key string) (*Client, error) {
+	p.mtx.Lock()
+	if elem, ok := p.clients[key]; ok {
+		p.lru.MoveToFront(elem)
+		p.mtx.Unlock()
+		return elem.Value.(*pooled).client, nil
+	}
+	if entry, ok := p.pending[key]; ok {
+		p.mtx.Unlock()
+		<-entry.ready
+		return entry.client, entry.err
+	}
+	entry := &pooled{key: key, ready: make(chan struct{})}
+	p.pending[key] = entry
+	p.mtx.Unlock()
+
+	for _, old := range p.create(entry) {
+		retire(old.client)
+	}
+	return entry.client, entry.err
+}
+
+// create creates and pools the client of the given pending entry, returning the evicted clients.
+// The pending entry is released even if the factory panics.
+func (p *ClientPool) create(entry *pooled) (evicted []*pooled) {
+	cli := New().UseParent(p.parent)
+	entry.err = errFactoryPanic
+	defer func() {
+		if entry.err != nil {
+			cli.Close()
+		}
+		p.mtx.Lock()
+		delete(p.pending, entry.key)
+		if entry.err == nil {
+			entry.client = cli
+			p.clients[entry.key] = p.lru.PushFront(entry)
+			for p.lru.Len() > p.size {
+				evicted = append(evicted, p.remove(p.lru.Back()))
+			}
+		}
+		p.mtx.Unlock()
+		close(entry.ready)
+	}()
+
+	if p.factory != nil {
+		entry.err = p.factory(entry.key, cli)
+	} else {
+		entry.err = nil
+	}
+	return nil
+}
+
+// Remove removes and closes the pooled client for the given key, if any.
+func (p *ClientPool) Remove(key string) {
+	p.mtx.Lock()
+	elem, ok := p.clients[key]
+	if ok {
+		p.remove(elem)
+	}
+	p.mtx.Unlock()
+
+	if ok {
+		elem.Value.(*pooled).client.Close()
+	}
+}
+
+// Len returns the number of pooled clients.
+func (p *ClientPool) Len() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.lru.Len()
+}
+
+// Close removes and closes every pooled client, returning the first close error, if any.
+// The parent client is not closed.
+func (p *ClientPool) Close() error {
+	var removed []*pooled
+	p.mtx.Lock()
+	for p.lru.Len() > 0 {
+		removed = append(removed, p.remove(p.lru.Back()))
+	}
+	p.mtx.Unlock()
+
+	var err error
+	for _, entry := range removed {
+		if closeErr := entry.client.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// remove removes the pooled client of the given element. The caller must hold the pool mutex.
+func (p *ClientPool) remove(elem *list.Element) *pooled {
+	entry := p.lru.Remove(elem).(*pooled)
+	delete(p.clients, entry.key)
+	return entry
+}
+
+// retire closes the given evicted client, rejecting its new requests,
+// and releases the resources of its plugins once the requests in flight
+// at eviction are complete.
+func retire(cli *Client) {
+	release := func() { closePlugins(cli.Middleware.GetStack()) }
+	if cl := getCloser(cli.Context); cl != nil {
+		cl.close()
+		cl.drain(release)
+		return
+	}
+	release()
+}
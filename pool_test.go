@@ -0,0 +1,162 @@
+package gentleman
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+func TestClientPool(t *testing.T) {
+	var tenant, client string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, client = r.Header.Get("X-Tenant"), r.Header.Get("X-Client")
+	}))
+	defer ts.Close()
+
+	parent := New().URL(ts.URL).SetHeader("X-Client", "gentleman")
+	var created, closed []string
+	fail := errors.New("unknown tenant")
+	pool := NewClientPool(parent, 2, func(key string, cli *Client) error {
+		if key == "unknown" {
+			return fail
+		}
+		created = append(created, key)
+		cli.SetHeader("X-Tenant", key)
+		cli.Use(plugin.WithCloser(plugin.New(), func() error {
+			closed = append(closed, key)
+			return nil
+		}))
+		return nil
+	})
+
+	acme, err := pool.Get("acme")
+	st.Expect(t, err, nil)
+	res, err := acme.Get().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, tenant, "acme")
+	st.Expect(t, client, "gentleman")
+
+	// Clients are cached by key
+	cached, err := pool.Get("acme")
+	st.Expect(t, err, nil)
+	st.Expect(t, cached, acme)
+
+	_, err = pool.Get("unknown")
+	st.Expect(t, err, fail)
+	st.Expect(t, pool.Len(), 1)
+
+	// The least recently used client is evicted and closed
+	globex, _ := pool.Get("globex")
+	pool.Get("acme")
+	pool.Get("initech")
+	st.Expect(t, pool.Len(), 2)
+	st.Expect(t, created, []string{"acme", "globex", "initech"})
+	st.Expect(t, closed, []string{"globex"})
+
+	_, err = globex.Get().Send()
+	st.Expect(t, err, ErrClientClosed)
+	_, err = acme.Get().Send()
+	st.Expect(t, err, nil)
+
+	pool.Remove("initech")
+	st.Expect(t, closed, []string{"globex", "initech"})
+	st.Expect(t, pool.Close(), nil)
+	st.Expect(t, closed, []string{"globex", "initech", "acme"})
+	st.Expect(t, pool.Len(), 0)
+
+	// The parent client is not closed
+	_, err = parent.Get().Send()
+	st.Expect(t, err, nil)
+	_, err = acme.Get().Send()
+	st.Expect(t, err, ErrClientClosed)
+}
+
+func TestClientPoolSingleFlight(t *testing.T) {
+	var created int32
+	start := make(chan struct{})
+	pool := NewClientPool(New(), 0, func(key string, cli *Client) error {
+		atomic.AddInt32(&created, 1)
+		if key == "acme" {
+			<-start
+		}
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	clients := make([]*Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = pool.Get("acme")
+		}(i)
+	}
+
+	// Other keys are not blocked by the pending factory call
+	_, err := pool.Get("globex")
+	st.Expect(t, err, nil)
+
+	close(start)
+	wg.Wait()
+	st.Expect(t, atomic.LoadInt32(&created), int32(2))
+	for _, cli := range clients {
+		st.Expect(t, cli == clients[0], true)
+	}
+}
+
+func TestClientPoolEvictInFlight(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	var closed int32
+	pool := NewClientPool(New().URL(ts.URL), 1, func(key string, cli *Client) error {
+		cli.Use(plugin.WithCloser(plugin.New(), func() error {
+			atomic.AddInt32(&closed, 1)
+			return nil
+		}))
+		return nil
+	})
+
+	acme, _ := pool.Get("acme")
+	res, err := acme.Get().Send()
+	st.Expect(t, err, nil)
+
+	// The evicted client plugins are released once the in-flight request is complete
+	pool.Get("globex")
+	st.Expect(t, atomic.LoadInt32(&closed), int32(0))
+	st.Expect(t, res.String(), "hello")
+	st.Expect(t, atomic.LoadInt32(&closed), int32(1))
+
+	// The evicted client is closed
+	_, err = acme.Get().Send()
+	st.Expect(t, err, ErrClientClosed)
+}
+
+func TestClientPoolFactoryPanic(t *testing.T) {
+	pool := NewClientPool(New(), 0, func(key string, cli *Client) error {
+		if key == "acme" {
+			panic("boom")
+		}
+		return nil
+	})
+
+	func() {
+		defer func() { st.Expect(t, recover(), "boom") }()
+		pool.Get("acme")
+	}()
+
+	// The pending entry is released
+	st.Expect(t, pool.Len(), 0)
+	st.Expect(t, len(pool.pending), 0)
+	_, err := pool.Get("globex")
+	st.Expect(t, err, nil)
+}
@@ -0,0 +1,104 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// ProblemMediaType defines the RFC 7807 problem details JSON media type.
+const ProblemMediaType = "application/problem+json"
+
+// ProblemDetails represents an RFC 7807 problem details error response.
+// Non-2xx responses served as application/problem+json are decoded into it
+// and stored in HTTPError.Model, so it can be retrieved via errors.As.
+type ProblemDetails struct {
+	// Type stores the URI reference identifying the problem type.
+	Type string `json:"type,omitempty"`
+
+	// Title stores the short human-readable summary of the problem type.
+	Title string `json:"title,omitempty"`
+
+	// Status stores the HTTP status code generated by the server.
+	Status int `json:"status,omitempty"`
+
+	// Detail stores the human-readable explanation of this problem occurrence.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance stores the URI reference identifying this problem occurrence.
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions stores the additional problem members.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// problemMembers stores the problem details standard members.
+var problemMembers = []string{"type", "title", "status", "detail", "instance"}
+
+// Error returns the error message.
+func (p *ProblemDetails) Error() string {
+	msg := "gentleman: problem"
+	if p.Title != "" {
+		msg += ": " + p.Title
+	} else if p.Type != "" {
+		msg += ": " + p.Type
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return msg
+}
+
+// UnmarshalJSON decodes the problem details, preserving the extension members.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type problem ProblemDetails
+	if err := json.Unmarshal(data, (*problem)(p)); err != nil {
+		return err
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, name := range problemMembers {
+		delete(members, name)
+	}
+
+	p.Extensions = nil
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+// MarshalJSON encodes the problem details, including the extension members.
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+len(problemMembers))
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+
+	type problem ProblemDetails
+	data, err := json.Marshal((*problem)(p))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// decodeProblem decodes the given body as problem details if served
+// as application/problem+json, returning nil otherwise.
+func decodeProblem(header http.Header, body []byte) *ProblemDetails {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != ProblemMediaType {
+		return nil
+	}
+	problem := &ProblemDetails{}
+	if json.Unmarshal(body, problem) != nil {
+		return nil
+	}
+	return problem
+}
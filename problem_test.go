@@ -0,0 +1,41 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestFailOnErrorProblemDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(403)
+		w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.",` +
+			`"status":403,"detail":"Your current balance is 30, but that costs 50.","balance":30}`))
+	}))
+	defer ts.Close()
+
+	_, err := New().URL(ts.URL).FailOnError().Request().Send()
+	var problem *ProblemDetails
+	st.Expect(t, errors.As(err, &problem), true)
+	st.Expect(t, problem.Type, "https://example.com/probs/out-of-credit")
+	st.Expect(t, problem.Status, 403)
+	st.Expect(t, problem.Detail, "Your current balance is 30, but that costs 50.")
+	st.Expect(t, problem.Extensions, map[string]interface{}{"balance": float64(30)})
+	st.Expect(t, problem.Error(), "gentleman: problem: You do not have enough credit.: Your current balance is 30, but that costs 50.")
+
+	data, _ := json.Marshal(problem)
+	decoded := &ProblemDetails{}
+	st.Expect(t, json.Unmarshal(data, decoded), nil)
+	st.Expect(t, decoded, problem)
+
+	// Registered error types take precedence
+	_, err = New().URL(ts.URL).ErrorType(&apiError{}).Request().Send()
+	var apiErr *apiError
+	st.Expect(t, errors.As(err, &apiErr), true)
+	st.Expect(t, errors.As(err, &problem), false)
+}
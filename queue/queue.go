@@ -0,0 +1,563 @@
+// Package queue implements a disk-backed outbound delivery queue on top of gentleman,
+// such as for webhook deliveries, where every queued request is persisted to disk
+// and retried with backoff until delivered, surviving process restarts.
+//
+// Deliveries are sent via the given gentleman Client, therefore
+// all the client middleware and plugins are honored for every attempt.
+package queue
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2"
+	"gopkg.in/h2non/gentleman.v2/plugins/retry"
+)
+
+// DefaultWorkers defines the default number of concurrent deliveries.
+const DefaultWorkers = 1
+
+// DefaultMaxAttempts defines the default maximum number of attempts per delivery.
+const DefaultMaxAttempts = 10
+
+// DefaultMinDelay defines the default delay before the first retry.
+var DefaultMinDelay = time.Second
+
+// DefaultMaxDelay defines the default maximum delay between retries.
+var DefaultMaxDelay = time.Hour
+
+// failedDir defines the directory, relative to the queue directory, storing the failed deliveries.
+const failedDir = "failed"
+
+// fileExt defines the delivery files extension.
+const fileExt = ".json"
+
+var (
+	// ErrMissingDir is returned when no queue directory is configured.
+	ErrMissingDir = errors.New("queue: missing queue directory")
+
+	// ErrMissingURL is returned when enqueuing a delivery with no URL.
+	ErrMissingURL = errors.New("queue: missing delivery URL")
+
+	// ErrInvalidID is returned when enqueuing a delivery whose identifier contains path separators.
+	ErrInvalidID = errors.New("queue: invalid delivery identifier")
+
+	// ErrClosed is returned when enqueuing a delivery in a closed queue.
+	ErrClosed = errors.New("queue: queue closed")
+)
+
+// State represents a delivery state.
+type State int
+
+const (
+	// Queued is reported once a delivery is persisted in the queue.
+	Queued State = iota
+
+	// Retrying is reported when a failed delivery attempt is going to be retried.
+	Retrying
+
+	// Delivered is reported once a delivery succeeded and was removed from the queue.
+	Delivered
+
+	// Failed is reported once a delivery failed permanently, either due to
+	// a non retryable response or after the maximum number of attempts,
+	// and was moved to the failed deliveries directory.
+	Failed
+)
+
+// stateNames stores the delivery state names.
+var stateNames = map[State]string{
+	Queued:    "Queued",
+	Retrying:  "Retrying",
+	Delivered: "Delivered",
+	Failed:    "Failed",
+}
+
+// String returns the delivery state name.
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Delivery represents a persisted outbound request.
+type Delivery struct {
+	// ID stores the delivery unique identifier. Generated on enqueue, if empty.
+	ID string `json:"id"`
+
+	// Method stores the request method. Defaults to POST.
+	Method string `json:"method"`
+
+	// URL stores the request URL.
+	URL string `json:"url"`
+
+	// Header stores the request headers, if any.
+	Header http.Header `json:"header,omitempty"`
+
+	// Body stores the request body, if any.
+	Body []byte `json:"body,omitempty"`
+
+	// Attempts stores the number of performed delivery attempts.
+	Attempts int `json:"attempts"`
+
+	// Created stores when the delivery was enqueued.
+	Created time.Time `json:"created"`
+
+	// Next stores when the next delivery attempt is due.
+	Next time.Time `json:"next"`
+
+	// LastStatus stores the response status of the last attempt, if any.
+	LastStatus int `json:"lastStatus,omitempty"`
+
+	// LastError stores the error message of the last attempt, if any.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Report represents a delivery state change.
+type Report struct {
+	// State stores the new delivery state.
+	State State
+
+	// Delivery stores a copy of the delivery.
+	Delivery Delivery
+
+	// StatusCode stores the response status of the last attempt, if any.
+	StatusCode int
+
+	// Error stores the error of the last attempt, if any.
+	Error error
+
+	// Delay stores the delay before the next attempt, used by Retrying reports.
+	Delay time.Duration
+}
+
+// Options represents the queue options.
+type Options struct {
+	// Dir defines the directory used to persist the queued deliveries.
+	Dir string
+
+	// Workers defines the maximum number of concurrent deliveries. Defaults to DefaultWorkers.
+	Workers int
+
+	// MaxAttempts defines the maximum number of attempts per delivery,
+	// including the first one. Defaults to DefaultMaxAttempts.
+	MaxAttempts int
+
+	// Backoff defines the function used to calculate the delay before the given
+	// retry attempt, starting from 1. Defaults to an exponential backoff
+	// from DefaultMinDelay up to DefaultMaxDelay.
+	Backoff func(attempt int) time.Duration
+
+	// Retry defines the function deciding if a failed attempt must be retried.
+	// Defaults to DefaultRetry.
+	Retry func(res *gentleman.Response, err error) bool
+
+	// OnStateChange defines the function called with every delivery state change, if any.
+	// It is called synchronously from the delivery workers, so it should not block.
+	OnStateChange func(Report)
+
+	// Client defines the client used to send the deliveries,
+	// such as a client with webhook signing plugins. Defaults to a new client.
+	Client *gentleman.Client
+}
+
+// DefaultRetry retries the attempts failed due to network errors
+// or replied with a 408, 425, 429 or 5xx response status.
+func DefaultRetry(res *gentleman.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return res.StatusCode >= 500
+}
+
+// Queue represents a disk-backed outbound delivery queue,
+// delivering the queued requests in the background. Queue is safe for concurrent use.
+type Queue struct {
+	// opts stores the queue options.
+	opts Options
+
+	// cli stores the deliveries client.
+	cli *gentleman.Client
+
+	// mtx protects the queue state.
+	mtx sync.Mutex
+
+	// pending stores the queued deliveries by identifier.
+	pending map[string]*Delivery
+
+	// inflight stores the identifiers of the deliveries being sent.
+	inflight map[string]bool
+
+	// sem limits the number of concurrent deliveries.
+	sem chan struct{}
+
+	// wake is used to notify the dispatcher of new or completed deliveries.
+	wake chan struct{}
+
+	// done is closed once the queue is closed.
+	done chan struct{}
+
+	// closed stores if the queue was closed.
+	closed bool
+
+	// wg tracks the dispatcher and the in-flight deliveries.
+	wg sync.WaitGroup
+}
+
+// Open opens the queue persisted in the configured directory, creating it if required,
+// and starts delivering the previously queued deliveries, if any, in the background.
+func Open(opts Options) (*Queue, error) {
+	if opts.Dir == "" {
+		return nil, ErrMissingDir
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = retry.Exponential(DefaultMinDelay, DefaultMaxDelay)
+	}
+	if opts.Retry == nil {
+		opts.Retry = DefaultRetry
+	}
+	if err := os.MkdirAll(filepath.Join(opts.Dir, failedDir), 0700); err != nil {
+		return nil, err
+	}
+
+	cli := opts.Client
+	if cli == nil {
+		cli = gentleman.New()
+	}
+
+	q := &Queue{
+		opts:     opts,
+		cli:      gentleman.New().UseParent(cli),
+		pending:  make(map[string]*Delivery),
+		inflight: make(map[string]bool),
+		sem:      make(chan struct{}, opts.Workers),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	q.wg.Add(1)
+	go q.run()
+	return q, nil
+}
+
+// Enqueue persists the given delivery in the queue, to be delivered in the background,
+// returning the delivery identifier.
+func (q *Queue) Enqueue(d Delivery) (string, error) {
+	if d.URL == "" {
+		return "", ErrMissingURL
+	}
+	if d.Method == "" {
+		d.Method = "POST"
+	}
+	if d.ID == "" {
+		d.ID = newID()
+	}
+	if strings.ContainsAny(d.ID, `/\`) {
+		return "", ErrInvalidID
+	}
+	if d.Created.IsZero() {
+		d.Created = time.Now()
+	}
+	if d.Next.IsZero() {
+		d.Next = d.Created
+	}
+
+	q.mtx.Lock()
+	if q.closed {
+		q.mtx.Unlock()
+		return "", ErrClosed
+	}
+	if err := writeFile(q.path(d.ID), &d); err != nil {
+		q.mtx.Unlock()
+		return "", err
+	}
+	q.pending[d.ID] = &d
+	q.mtx.Unlock()
+
+	q.report(Report{State: Queued, Delivery: d})
+	q.notify()
+	return d.ID, nil
+}
+
+// EnqueueJSON enqueues a POST delivery of the given data, serialized as JSON, to the given URL.
+func (q *Queue) EnqueueJSON(url string, data interface{}) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	return q.Enqueue(Delivery{URL: url, Header: header, Body: body})
+}
+
+// Pending returns a copy of the queued deliveries, sorted by due time.
+func (q *Queue) Pending() []Delivery {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	deliveries := make([]Delivery, 0, len(q.pending))
+	for _, d := range q.sorted() {
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries
+}
+
+// Len returns the number of queued deliveries.
+func (q *Queue) Len() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return len(q.pending)
+}
+
+// Close stops delivering the queued deliveries, waiting for the in-flight ones to complete.
+// The queued deliveries are kept on disk, to be delivered once the queue is opened again.
+func (q *Queue) Close() error {
+	q.mtx.Lock()
+	if q.closed {
+		q.mtx.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	q.mtx.Unlock()
+
+	q.wg.Wait()
+	return nil
+}
+
+// run dispatches the due deliveries until the queue is closed.
+func (q *Queue) run() {
+	defer q.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		wait := q.dispatch()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var due <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			due = timer.C
+		}
+
+		select {
+		case <-q.done:
+			return
+		case <-q.wake:
+		case <-due:
+		}
+	}
+}
+
+// dispatch starts the due deliveries, as long as workers are available,
+// returning the delay until the next delivery is due, or -1 if none.
+func (q *Queue) dispatch() time.Duration {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.closed {
+		return -1
+	}
+
+	now := time.Now()
+	for _, d := range q.sorted() {
+		if q.inflight[d.ID] {
+			continue
+		}
+		if wait := d.Next.Sub(now); wait > 0 {
+			return wait
+		}
+		select {
+		case q.sem <- struct{}{}:
+		default:
+			// Workers are busy: completed deliveries notify the dispatcher
+			return -1
+		}
+		q.inflight[d.ID] = true
+		q.wg.Add(1)
+		go q.deliver(d)
+	}
+	return -1
+}
+
+// deliver performs a delivery attempt, updating the delivery state.
+func (q *Queue) deliver(d *Delivery) {
+	defer q.wg.Done()
+	defer q.notify()
+	defer func() { <-q.sem }()
+
+	req := q.cli.Request().Method(d.Method).URL(d.URL).Body(bytes.NewReader(d.Body))
+	for name, values := range d.Header {
+		req.SetHeaderValues(name, values...)
+	}
+	res, err := req.Send()
+	if res != nil {
+		res.Close()
+	}
+
+	q.mtx.Lock()
+	delete(q.inflight, d.ID)
+	d.Attempts++
+	d.LastStatus, d.LastError = 0, ""
+	if res != nil {
+		d.LastStatus = res.StatusCode
+	}
+	if err != nil {
+		d.LastError = err.Error()
+	}
+
+	report := Report{StatusCode: d.LastStatus, Error: err}
+	var persistErr error
+	switch {
+	case err == nil && res.StatusCode >= 200 && res.StatusCode < 300:
+		report.State = Delivered
+		delete(q.pending, d.ID)
+		persistErr = os.Remove(q.path(d.ID))
+	case d.Attempts < q.opts.MaxAttempts && q.opts.Retry(res, err):
+		report.State = Retrying
+		report.Delay = q.opts.Backoff(d.Attempts)
+		if res != nil {
+			if after, ok := retry.ParseRetryAfter(res.Header.Get("Retry-After")); ok {
+				report.Delay = after
+			}
+		}
+		d.Next = time.Now().Add(report.Delay)
+		persistErr = writeFile(q.path(d.ID), d)
+	default:
+		report.State = Failed
+		delete(q.pending, d.ID)
+		if persistErr = writeFile(q.failedPath(d.ID), d); persistErr == nil {
+			persistErr = os.Remove(q.path(d.ID))
+		}
+	}
+	if report.Error == nil && persistErr != nil {
+		report.Error = persistErr
+	}
+	report.Delivery = *d
+	q.mtx.Unlock()
+
+	q.report(report)
+}
+
+// load loads the queued deliveries from the queue directory.
+func (q *Queue) load() error {
+	paths, err := filepath.Glob(filepath.Join(q.opts.Dir, "*"+fileExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		d := &Delivery{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return err
+		}
+		q.pending[d.ID] = d
+	}
+	return nil
+}
+
+// sorted returns the queued deliveries sorted by due time.
+// The caller must hold the queue mutex.
+func (q *Queue) sorted() []*Delivery {
+	deliveries := make([]*Delivery, 0, len(q.pending))
+	for _, d := range q.pending {
+		deliveries = append(deliveries, d)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].Next.Equal(deliveries[j].Next) {
+			return deliveries[i].Created.Before(deliveries[j].Created)
+		}
+		return deliveries[i].Next.Before(deliveries[j].Next)
+	})
+	return deliveries
+}
+
+// report calls the state change function, if any, with the given report.
+func (q *Queue) report(report Report) {
+	if q.opts.OnStateChange != nil {
+		q.opts.OnStateChange(report)
+	}
+}
+
+// notify notifies the dispatcher, without blocking.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// path returns the file path of the given queued delivery.
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.opts.Dir, id+fileExt)
+}
+
+// failedPath returns the file path of the given failed delivery.
+func (q *Queue) failedPath(id string) string {
+	return filepath.Join(q.opts.Dir, failedDir, id+fileExt)
+}
+
+// writeFile writes the given delivery into the given file path.
+func writeFile(path string, d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	// Write atomically to prevent corrupted files on crashes
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".delivery-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// newID returns a new random delivery identifier.
+func newID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
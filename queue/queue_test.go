@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+// tempDir creates a temporary queue directory.
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "queue")
+	st.Expect(t, err, nil)
+	return dir
+}
+
+// waitReport waits for the next report with the given state.
+func waitReport(t *testing.T, reports chan Report, state State) Report {
+	for {
+		select {
+		case report := <-reports:
+			if report.State == state {
+				return report
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s report", state)
+		}
+	}
+}
+
+func TestQueueDelivery(t *testing.T) {
+	var calls int32
+	var body, tenant string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		body, tenant = string(data), r.Header.Get("X-Tenant")
+	}))
+	defer ts.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	reports := make(chan Report, 10)
+	q, err := Open(Options{
+		Dir:           dir,
+		Backoff:       func(int) time.Duration { return time.Millisecond },
+		OnStateChange: func(report Report) { reports <- report },
+	})
+	st.Expect(t, err, nil)
+	defer q.Close()
+
+	id, err := q.Enqueue(Delivery{URL: ts.URL, Header: http.Header{"X-Tenant": {"acme"}}, Body: []byte("hello")})
+	st.Expect(t, err, nil)
+	st.Expect(t, waitReport(t, reports, Queued).Delivery.ID, id)
+
+	report := waitReport(t, reports, Retrying)
+	st.Expect(t, report.StatusCode, 503)
+	st.Expect(t, report.Delay, time.Millisecond)
+	st.Expect(t, report.Delivery.Attempts, 1)
+
+	report = waitReport(t, reports, Delivered)
+	st.Expect(t, report.Delivery.Attempts, 2)
+	st.Expect(t, report.StatusCode, 200)
+	st.Expect(t, body, "hello")
+	st.Expect(t, tenant, "acme")
+	st.Expect(t, q.Len(), 0)
+
+	_, err = os.Stat(filepath.Join(dir, id+fileExt))
+	st.Expect(t, os.IsNotExist(err), true)
+}
+
+func TestQueueRestart(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer ts.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	q, err := Open(Options{Dir: dir})
+	st.Expect(t, err, nil)
+	// Deliveries due in the future are not sent before the queue is closed
+	id, err := q.Enqueue(Delivery{URL: ts.URL, Body: []byte("created"), Next: time.Now().Add(100 * time.Millisecond)})
+	st.Expect(t, err, nil)
+	q.Close()
+
+	// The closed queue keeps the undelivered deliveries on disk
+	st.Expect(t, body, "")
+	_, err = q.Enqueue(Delivery{URL: ts.URL})
+	st.Expect(t, err, ErrClosed)
+	_, err = os.Stat(filepath.Join(dir, id+fileExt))
+	st.Expect(t, err, nil)
+
+	reports := make(chan Report, 10)
+	q, err = Open(Options{Dir: dir, OnStateChange: func(report Report) { reports <- report }})
+	st.Expect(t, err, nil)
+	defer q.Close()
+	st.Expect(t, len(q.Pending()), 1)
+
+	report := waitReport(t, reports, Delivered)
+	st.Expect(t, report.Delivery.ID, id)
+	st.Expect(t, body, "created")
+}
+
+func TestQueueFailed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	reports := make(chan Report, 10)
+	q, err := Open(Options{Dir: dir, MaxAttempts: 2, OnStateChange: func(report Report) { reports <- report }})
+	st.Expect(t, err, nil)
+	defer q.Close()
+
+	// Non retryable responses fail permanently
+	id, err := q.EnqueueJSON(ts.URL+"/invalid", map[string]string{"event": "created"})
+	st.Expect(t, err, nil)
+	report := waitReport(t, reports, Failed)
+	st.Expect(t, report.Delivery.ID, id)
+	st.Expect(t, report.Delivery.Method, "POST")
+	st.Expect(t, report.Delivery.Header.Get("Content-Type"), "application/json")
+	st.Expect(t, string(report.Delivery.Body), `{"event":"created"}`)
+	st.Expect(t, report.Delivery.Attempts, 1)
+	st.Expect(t, report.StatusCode, 400)
+	_, err = os.Stat(filepath.Join(dir, failedDir, id+fileExt))
+	st.Expect(t, err, nil)
+
+	// Retryable responses fail once the attempts are exhausted,
+	// honoring the Retry-After header
+	id, _ = q.Enqueue(Delivery{URL: ts.URL})
+	st.Expect(t, waitReport(t, reports, Retrying).Delay, time.Duration(0))
+	report = waitReport(t, reports, Failed)
+	st.Expect(t, report.Delivery.ID, id)
+	st.Expect(t, report.Delivery.Attempts, 2)
+	st.Expect(t, q.Len(), 0)
+}
+
+func TestQueueErrors(t *testing.T) {
+	_, err := Open(Options{})
+	st.Expect(t, err, ErrMissingDir)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	q, err := Open(Options{Dir: dir})
+	st.Expect(t, err, nil)
+	defer q.Close()
+
+	_, err = q.Enqueue(Delivery{})
+	st.Expect(t, err, ErrMissingURL)
+	_, err = q.Enqueue(Delivery{ID: "../escape", URL: "http://localhost"})
+	st.Expect(t, err, ErrInvalidID)
+	st.Expect(t, Failed.String(), "Failed")
+}
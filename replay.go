@@ -0,0 +1,122 @@
+package gentleman
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+// ReplayableKey is the context store key used to enable the buffering of the
+// final request body, so sent requests can be snapshotted. See Client.Replayable().
+const ReplayableKey = "$replayable"
+
+// emptyBody stores the type of the default empty request body.
+var emptyBody = reflect.TypeOf(utils.NopCloser())
+
+// RequestSnapshot represents a portable representation of a request as it was sent,
+// once the middleware ran, which can be serialized as JSON, stored and sent again later
+// by another process or client, such as for dead-letter queues and manual replays.
+//
+// Snapshots store the request headers and body as is, including credentials,
+// so they must be stored accordingly.
+type RequestSnapshot struct {
+	// Method stores the request method.
+	Method string `json:"method"`
+
+	// URL stores the request URL.
+	URL string `json:"url"`
+
+	// Host stores the Host header, if it differs from the URL host.
+	Host string `json:"host,omitempty"`
+
+	// Header stores the request headers.
+	Header http.Header `json:"header,omitempty"`
+
+	// Body stores the request body, if any.
+	Body []byte `json:"body,omitempty"`
+
+	// Time stores when the snapshot was taken.
+	Time time.Time `json:"time"`
+
+	// Error stores the request error message, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// NewRequestSnapshot creates a new snapshot of the given request.
+// The request body, if any, must be replayable via the GetBody field.
+func NewRequestSnapshot(req *http.Request) (*RequestSnapshot, error) {
+	snapshot := &RequestSnapshot{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+		Time:   time.Now(),
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		snapshot.Host = req.Host
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		if snapshot.Body, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+	} else if req.Body != nil && req.Body != http.NoBody && reflect.TypeOf(req.Body) != emptyBody {
+		return nil, ErrNotReplayable
+	}
+	return snapshot, nil
+}
+
+// ParseRequestSnapshot parses the given JSON serialized request snapshot.
+func ParseRequestSnapshot(data []byte) (*RequestSnapshot, error) {
+	snapshot := &RequestSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Snapshot creates a new snapshot of the request sent, including the request error, if any.
+// Requests with a body must be sent via a client or request with Replayable() enabled.
+func (r *Response) Snapshot() (*RequestSnapshot, error) {
+	snapshot, err := NewRequestSnapshot(r.RawRequest)
+	if err != nil {
+		return nil, err
+	}
+	if r.Error != nil {
+		snapshot.Error = r.Error.Error()
+	}
+	return snapshot, nil
+}
+
+// Replay creates a new request of the current client from the given snapshot,
+// defining its method, URL, headers and body. The client middleware runs again
+// when the request is sent, so plugins such as authentication can refresh their headers.
+func (c *Client) Replay(snapshot *RequestSnapshot) *Request {
+	req := c.Request().Method(snapshot.Method).URL(snapshot.URL)
+	raw := req.Context.Request
+	for name, values := range snapshot.Header {
+		raw.Header[name] = append([]string(nil), values...)
+	}
+	if snapshot.Host != "" {
+		raw.Host = snapshot.Host
+	}
+	if snapshot.Body != nil {
+		body := snapshot.Body
+		raw.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		raw.Body, _ = raw.GetBody()
+		raw.ContentLength = int64(len(body))
+	}
+	return req
+}
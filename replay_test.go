@@ -0,0 +1,69 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestReplay(t *testing.T) {
+	var calls int32
+	var received, token, trace string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		received, token, trace = string(data), r.Header.Get("Authorization"), r.Header.Get("X-Trace")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).Replayable().FailOnError()
+	cli.SetHeader("Authorization", "Bearer first")
+	res, err := cli.Request().Method("POST").Path("/orders").SetHeader("X-Trace", "abc").BodyString(`{"id":1}`).Send()
+	st.Reject(t, err, nil)
+
+	snapshot, err := res.Snapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, snapshot.Method, "POST")
+	st.Expect(t, snapshot.URL, ts.URL+"/orders")
+	st.Expect(t, string(snapshot.Body), `{"id":1}`)
+	st.Expect(t, snapshot.Header.Get("X-Trace"), "abc")
+	st.Reject(t, snapshot.Error, "")
+
+	// The snapshot is portable
+	data, err := json.Marshal(snapshot)
+	st.Expect(t, err, nil)
+	snapshot, err = ParseRequestSnapshot(data)
+	st.Expect(t, err, nil)
+
+	// The replaying client middleware runs again
+	other := New().SetHeader("Authorization", "Bearer second")
+	res, err = other.Replay(snapshot).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, received, `{"id":1}`)
+	st.Expect(t, trace, "abc")
+	st.Expect(t, token, "Bearer second")
+}
+
+func TestSnapshotNotReplayable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	res, err := NewRequest().URL(ts.URL).Method("POST").BodyString("data").Send()
+	st.Expect(t, err, nil)
+	_, err = res.Snapshot()
+	st.Expect(t, err, ErrNotReplayable)
+
+	res, err = NewRequest().URL(ts.URL).Send()
+	st.Expect(t, err, nil)
+	snapshot, err := res.Snapshot()
+	st.Expect(t, err, nil)
+	st.Expect(t, snapshot.Body == nil, true)
+}
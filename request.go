@@ -1,12 +1,18 @@
 package gentleman
 
 import (
-	"errors"
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"encoding/xml"
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
 
+	"golang.org/x/text/language"
 	"gopkg.in/h2non/gentleman.v2/context"
 	"gopkg.in/h2non/gentleman.v2/middleware"
 	"gopkg.in/h2non/gentleman.v2/mux"
@@ -16,13 +22,19 @@ import (
 	"gopkg.in/h2non/gentleman.v2/plugins/cookies"
 	"gopkg.in/h2non/gentleman.v2/plugins/headers"
 	"gopkg.in/h2non/gentleman.v2/plugins/multipart"
+	"gopkg.in/h2non/gentleman.v2/plugins/negotiate"
 	"gopkg.in/h2non/gentleman.v2/plugins/query"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
 	"gopkg.in/h2non/gentleman.v2/plugins/url"
 )
 
 const (
 	// UserAgent represents the static user agent name and version.
 	UserAgent = "gentleman/" + Version
+
+	// MethodQuery represents the HTTP QUERY method, a safe and idempotent
+	// alternative to GET carrying the query in the request body.
+	MethodQuery = "QUERY"
 )
 
 var (
@@ -57,6 +69,9 @@ type Request struct {
 	// Stores if the request was already dispatched
 	dispatched bool
 
+	// Stores the errors recorded by the builder methods
+	errs []error
+
 	// Optional reference to the gentleman.Client instance
 	Client *Client
 
@@ -78,6 +93,71 @@ func NewRequest() *Request {
 	}
 }
 
+// FromHTTPRequest creates a new Request importing the method, URL, headers, body and context
+// of the given *http.Request, such as an inbound server request forwarded by a proxy.
+// Hop-by-hop headers are not imported. Inbound server requests lacking the URL scheme and host
+// are completed via the request Host and TLS state. Use SetClient() in order to send the
+// request via a client middleware stack, which is able to override the imported fields,
+// such as via BaseURL() to forward it to an upstream server.
+func FromHTTPRequest(in *http.Request) *Request {
+	req := NewRequest()
+	req.Context.SetCancelContext(in.Context())
+
+	raw := req.Context.Request
+	raw.Method = in.Method
+	u := *in.URL
+	if u.Host == "" {
+		u.Host = in.Host
+		u.Scheme = "http"
+		if in.TLS != nil {
+			u.Scheme = "https"
+		}
+	} else if in.Host != "" && in.Host != u.Host {
+		raw.Host = in.Host
+	}
+	raw.URL = &u
+
+	for name, values := range in.Header {
+		raw.Header[name] = append([]string(nil), values...)
+	}
+	removeHopHeaders(raw.Header)
+
+	if in.Body != nil && in.Body != http.NoBody {
+		raw.Body = in.Body
+		raw.GetBody = in.GetBody
+		raw.ContentLength = in.ContentLength
+	}
+	return req
+}
+
+// hopHeaders stores the hop-by-hop headers, which are only meaningful for a single connection.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders removes the hop-by-hop headers from the given header,
+// including the ones listed by the Connection header.
+func removeHopHeaders(header http.Header) {
+	for _, value := range header["Connection"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
 // SetClient Attach a client to the current Request
 // This is mostly done internally.
 func (r *Request) SetClient(cli *Client) *Request {
@@ -94,23 +174,30 @@ func (r *Request) Mux() *mux.Mux {
 	return mx
 }
 
-// Method defines the HTTP verb to be used.
+// Method defines the HTTP verb to be used, such as GET, QUERY or any custom
+// method like the WebDAV PROPFIND. Invalid method names fail with ErrInvalidMethod.
 func (r *Request) Method(method string) *Request {
-	r.Middleware.UseRequest(func(ctx *context.Context, h context.Handler) {
-		ctx.Request.Method = method
-		h.Next(ctx)
-	})
+	if !validToken(method) {
+		r.AddError(ErrInvalidMethod)
+	}
+	r.Middleware.UseRequest(methodHandler(method))
 	return r
 }
 
 // URL parses and defines the URL to be used in the outgoing request.
 func (r *Request) URL(uri string) *Request {
+	if _, err := url.Parse(uri); err != nil {
+		r.AddError(err)
+	}
 	r.Use(url.URL(uri))
 	return r
 }
 
 // BaseURL parses the given URL and uses the URL schema and host in the outgoing request.
 func (r *Request) BaseURL(uri string) *Request {
+	if _, err := url.Parse(uri); err != nil {
+		r.AddError(err)
+	}
 	r.Use(url.BaseURL(uri))
 	return r
 }
@@ -179,12 +266,29 @@ func (r *Request) SetHeaders(fields map[string]string) *Request {
 	return r
 }
 
+// SetHeaderValues sets a multi-valued header field by name, sent as multiple field lines.
+// If another header exists with the same key, it will be overwritten.
+func (r *Request) SetHeaderValues(name string, values ...string) *Request {
+	r.Use(headers.SetValues(name, values...))
+	return r
+}
+
 // DelHeader deletes a header field by its name
 func (r *Request) DelHeader(name string) *Request {
 	r.Use(headers.Del(name))
 	return r
 }
 
+// PreserveHeaderCase sends the headers defined via SetHeader(), AddHeader() and SetHeaders()
+// with the exact given name casing, instead of the canonical one.
+// The header fields keep their canonical name, so http.Header.Get() still finds them,
+// and the exact names are written on the wire. Only HTTP/1.x requests preserve the casing.
+func (r *Request) PreserveHeaderCase() *Request {
+	headers.EnablePreserveCase(r.Context)
+	r.Use(transport.HeaderCase())
+	return r
+}
+
 // AddCookie sets a new cookie field bsaed on the given http.Cookie struct
 // without overwriting any existent cookie.
 func (r *Request) AddCookie(cookie *http.Cookie) *Request {
@@ -201,10 +305,18 @@ func (r *Request) AddCookies(data []*http.Cookie) *Request {
 
 // CookieJar creates a cookie jar to store HTTP cookies when they are sent down.
 func (r *Request) CookieJar() *Request {
-	r.Use(cookies.Jar())
+	jar, _ := cookies.NewStore(cookies.StoreOptions{})
+	r.Context.Set(cookies.JarKey, jar)
+	r.Use(cookies.UseJar(jar))
 	return r
 }
 
+// Jar returns the cookie jar created via CookieJar(), if any, which can be used
+// to list, get, set and delete cookies. The jar is inherited from the client.
+func (r *Request) Jar() *cookies.Store {
+	return cookies.GetJar(r.Context)
+}
+
 // Type defines the Content-Type header field based on the given type name alias or value.
 // You can use the following content type aliases: json, xml, form, html, text and urlencoded.
 func (r *Request) Type(name string) *Request {
@@ -212,6 +324,21 @@ func (r *Request) Type(name string) *Request {
 	return r
 }
 
+// Accepts defines the Accept header field based on the given media ranges and q-values,
+// such as Accepts("application/json", "application/xml;q=0.8"). MIME type aliases,
+// like json or xml, are supported. Invalid media ranges fail the request.
+func (r *Request) Accepts(types ...string) *Request {
+	r.Use(negotiate.Accept(types...))
+	return r
+}
+
+// AcceptLanguage defines the Accept-Language header field based on the given
+// language preferences, sorted by preference, generating decreasing q-values.
+func (r *Request) AcceptLanguage(tags ...language.Tag) *Request {
+	r.Use(negotiate.AcceptLanguage(tags...))
+	return r
+}
+
 // Body defines the request body based on a io.Reader stream.
 func (r *Request) Body(reader io.Reader) *Request {
 	r.Use(body.Reader(reader))
@@ -228,14 +355,34 @@ func (r *Request) BodyString(data string) *Request {
 
 // JSON serializes and defines as request body based on the given input.
 // The proper Content-Type header will be transparently added for you.
+// The input is serialized once, recording the serialization error, if any.
 func (r *Request) JSON(data interface{}) *Request {
+	switch data.(type) {
+	case string, []byte:
+	default:
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(data); err != nil {
+			return r.AddError(err)
+		}
+		data = buf.Bytes()
+	}
 	r.Use(body.JSON(data))
 	return r
 }
 
 // XML serializes and defines the request body based on the given input.
 // The proper Content-Type header will be transparently added for you.
+// The input is serialized once, recording the serialization error, if any.
 func (r *Request) XML(data interface{}) *Request {
+	switch data.(type) {
+	case string, []byte:
+	default:
+		buf := &bytes.Buffer{}
+		if err := xml.NewEncoder(buf).Encode(data); err != nil {
+			return r.AddError(err)
+		}
+		data = buf.Bytes()
+	}
 	r.Use(body.XML(data))
 	return r
 }
@@ -261,16 +408,55 @@ func (r *Request) Files(files []multipart.FormFile) *Request {
 	return r
 }
 
+// TeeBody streams the raw response body to the given writer, such as a file,
+// hash or logger, as it is read by the caller, without buffering it twice.
+// Write errors are reported to the body reader.
+func (r *Request) TeeBody(w io.Writer) *Request {
+	r.UseResponse(func(ctx *context.Context, h context.Handler) {
+		if body := ctx.Response.Body; body != nil {
+			ctx.Response.Body = &teeBody{Reader: io.TeeReader(body, w), Closer: body}
+		}
+		h.Next(ctx)
+	})
+	return r
+}
+
+// teeBody implements a response body copied to a writer as it is read.
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
 // Send is an alias to Do(), which executes the current request
 // and returns the response.
 func (r *Request) Send() (*Response, error) {
 	return r.Do()
 }
 
+// AddError records the given error on the request, such as an invalid input
+// passed to a builder method. Requests with recorded errors fail with the first
+// one when sent, before running any middleware. Nil errors are ignored.
+func (r *Request) AddError(err error) *Request {
+	if err != nil {
+		r.errs = append(r.errs, err)
+	}
+	return r
+}
+
+// Errors returns the errors recorded by the request builder methods, if any.
+func (r *Request) Errors() []error {
+	return append([]error(nil), r.errs...)
+}
+
 // Do performs the HTTP request and returns the HTTP response.
+// Returns the first error recorded by the builder methods, if any. See AddError().
 func (r *Request) Do() (*Response, error) {
 	if r.dispatched {
-		return nil, errors.New("gentleman: Request was already dispatched")
+		return nil, ErrAlreadyDispatched
+	}
+	if len(r.errs) > 0 {
+		r.dispatched = true
+		return nil, r.errs[0]
 	}
 
 	r.dispatched = true
@@ -279,12 +465,166 @@ func (r *Request) Do() (*Response, error) {
 	return buildResponse(ctx)
 }
 
+// Build runs the request, before dial and validate middleware over a copy of the request, returning the
+// fully-formed *http.Request, including the final URL, headers and body, without sending it.
+// This is useful to sign, inspect or queue the request, or to send it via a different transport.
+// The given context, if any, is used as the context of the built request.
+// The request itself is not dispatched, so it can still be sent later: stream bodies defined
+// via Body() are therefore read into memory.
+func (r *Request) Build(ctx gocontext.Context) (*http.Request, error) {
+	return r.build(ctx, nil)
+}
+
+// build builds the final request, calling the given function, if any,
+// with the time spent running the middleware of every phase.
+func (r *Request) build(ctx gocontext.Context, observe func(phase string, elapsed time.Duration)) (*http.Request, error) {
+	if r.dispatched {
+		return nil, ErrAlreadyDispatched
+	}
+	if len(r.errs) > 0 {
+		return nil, r.errs[0]
+	}
+
+	// Use a copy of the request, owning its own URL and headers
+	req := r.Clone()
+	raw := req.Context.Request
+	u := *raw.URL
+	raw.URL = &u
+	raw.Header = raw.Header.Clone()
+	if ctx != nil {
+		req.Context.SetCancelContext(ctx)
+	}
+	// Buffer the stream bodies, so the request can still be sent
+	req.Context.Set(body.BufferKey, true)
+
+	d := NewDispatcher(req)
+	for _, phase := range []string{"request", "before dial", "validate"} {
+		start := time.Now()
+		req.Context, _ = d.run(phase, req.Context)
+		if observe != nil {
+			observe(phase, time.Since(start))
+		}
+		if req.Context.Error != nil {
+			return nil, req.Context.Error
+		}
+		if req.Context.Stopped || req.Context.Intercepted() {
+			break
+		}
+	}
+	if err := validationError(req.Context); err != nil {
+		return nil, err
+	}
+
+	return req.Context.Request, nil
+}
+
+// OnInformational registers a function called on every interim 1xx response
+// received before the final response, such as 103 Early Hints preload links.
+func (r *Request) OnInformational(fn InformationalHandler) *Request {
+	r.Use(informational(fn))
+	return r
+}
+
+// UserAgent adds the given product name, version and comments to the User-Agent header,
+// such as "app/1.0 (linux)", composed with the client tokens and the library token.
+func (r *Request) UserAgent(product, version string, comments ...string) *Request {
+	r.Use(userAgent(product, version, comments...))
+	return r
+}
+
+// Recover enables the recovery of panics raised by plugins, which are
+// reported as *middleware.PanicError via the error phase instead of crashing the program.
+func (r *Request) Recover() *Request {
+	middleware.EnableRecover(r.Context)
+	return r
+}
+
+// Replayable enables the buffering of the final request body, so the sent request
+// can be snapshotted via Response.Snapshot().
+func (r *Request) Replayable() *Request {
+	r.Context.Set(ReplayableKey, true)
+	return r
+}
+
+// OnBeforeSend registers a function called with the outgoing request before it is sent,
+// once the request phase defined it. This is a simple alternative to before dial plugins.
+func (r *Request) OnBeforeSend(fn BeforeSendHook) *Request {
+	r.Use(beforeSend(fn))
+	return r
+}
+
+// OnAfterResponse registers a function called with the received response.
+// This is a simple alternative to response plugins.
+func (r *Request) OnAfterResponse(fn AfterResponseHook) *Request {
+	r.Use(afterResponse(fn))
+	return r
+}
+
+// Validate registers the given validators, inspecting the final request before it is sent.
+// Failed validators veto the request with a *ValidationError reported via the error phase.
+func (r *Request) Validate(validators ...Validator) *Request {
+	r.Use(validate(validators...))
+	return r
+}
+
+// FailOnError enables the conversion of non-2xx responses into *HTTPError,
+// which is reported via the error phase and returned by Send().
+func (r *Request) FailOnError() *Request {
+	r.Context.Set(FailOnErrorKey, true)
+	return r
+}
+
+// ExpectStatus fails the request with an *HTTPError if the response status
+// is not one of the given status codes. It takes precedence over FailOnError().
+func (r *Request) ExpectStatus(codes ...int) *Request {
+	r.Context.Set(ExpectStatusKey, codes)
+	return r
+}
+
+// ErrorType registers the type used to decode non-2xx JSON response bodies,
+// such as &APIError{}. Non-2xx responses are reported as *HTTPError storing
+// the decoded value in Model, which can be retrieved via errors.As if it implements error.
+// Bodies larger than MaxErrorBodySize are not decoded.
+func (r *Request) ErrorType(model interface{}) *Request {
+	r.Context.Set(ErrorTypeKey, errorType(model))
+	return r
+}
+
 // Use uses a new plugin in the middleware stack.
 func (r *Request) Use(p plugin.Plugin) *Request {
 	r.Middleware.Use(p)
 	return r
 }
 
+// UseWhen uses a new plugin which is only executed if the given predicate matches.
+func (r *Request) UseWhen(predicate plugin.Predicate, p plugin.Plugin) *Request {
+	r.Middleware.Use(plugin.When(predicate, p))
+	return r
+}
+
+// Unuse removes the plugins registered with the given name,
+// if supported by the request middleware. See middleware.Replacer.
+// Plugins inherited from the Client are only removed for the current Request.
+func (r *Request) Unuse(name string) *Request {
+	if m, ok := r.Middleware.(middleware.Replacer); ok {
+		m.Unuse(name)
+	}
+	return r
+}
+
+// Replace replaces the plugins registered with the given name by the given plugin,
+// or registers it if there is no plugin with the given name or the request middleware
+// does not support replacing plugins. See middleware.Replacer.
+// Plugins inherited from the Client are only replaced for the current Request.
+func (r *Request) Replace(name string, p plugin.Plugin) *Request {
+	if m, ok := r.Middleware.(middleware.Replacer); ok {
+		m.Replace(name, p)
+		return r
+	}
+	r.Middleware.Use(plugin.WithName(name, p))
+	return r
+}
+
 // UseRequest uses a request middleware handler.
 func (r *Request) UseRequest(fn context.HandlerFunc) *Request {
 	r.Middleware.UseRequest(fn)
@@ -315,9 +655,36 @@ func (r *Request) Clone() *Request {
 	req.Client = r.Client
 	req.Context = r.Context.Clone()
 	req.Middleware = r.Middleware.Clone()
+	req.errs = append([]error(nil), r.errs...)
 	return req
 }
 
+// methodHandler creates the request middleware handler defining the given HTTP method.
+func methodHandler(method string) context.HandlerFunc {
+	return func(ctx *context.Context, h context.Handler) {
+		if !validToken(method) {
+			h.Error(ctx, ErrInvalidMethod)
+			return
+		}
+		ctx.Request.Method = method
+		h.Next(ctx)
+	}
+}
+
+// validToken returns true if the given value is a valid RFC 7230 token.
+func validToken(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r > unicode.MaxASCII || !strings.ContainsRune("!#$%&'*+-.^_`|~", r) &&
+			!('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
 // NewDefaultTransport returns a new http.Transport with default values
 // based on the given net.Dialer.
 func NewDefaultTransport(dialer *net.Dialer) *http.Transport {
@@ -2,6 +2,7 @@ package gentleman
 
 import (
 	"bytes"
+	gocontext "context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -18,6 +19,7 @@ import (
 	"github.com/nbio/st"
 	"gopkg.in/h2non/gentleman.v2/context"
 	"gopkg.in/h2non/gentleman.v2/plugins/multipart"
+	"gopkg.in/h2non/gentleman.v2/plugins/query"
 	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
@@ -298,6 +300,25 @@ func TestRequestMethod(t *testing.T) {
 	st.Expect(t, req.Context.Request.Method, "POST")
 }
 
+func TestRequestCustomMethod(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Query().BodyString("foo").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "QUERY foo")
+
+	res, err = New().URL(ts.URL).Request().Method("PROPFIND").BodyString("<propfind/>").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "PROPFIND <propfind/>")
+
+	_, err = New().URL(ts.URL).Request().Method("FOO BAR").Send()
+	st.Expect(t, err, ErrInvalidMethod)
+}
+
 func TestRequestURL(t *testing.T) {
 	url := "http://foo.com"
 	req := NewRequest()
@@ -361,6 +382,7 @@ func TestRequestSetQuery(t *testing.T) {
 	req := NewRequest()
 	req.SetQuery("foo", "bar")
 	req.Middleware.Run("request", req.Context)
+	query.Flush(req.Context)
 	st.Expect(t, req.Context.Request.URL.RawQuery, "foo=bar")
 }
 
@@ -369,6 +391,7 @@ func TestRequestAddQuery(t *testing.T) {
 	req.AddQuery("foo", "bar")
 	req.AddQuery("foo", "bar")
 	req.Middleware.Run("request", req.Context)
+	query.Flush(req.Context)
 	st.Expect(t, req.Context.Request.URL.RawQuery, "foo=bar&foo=bar")
 }
 
@@ -376,6 +399,7 @@ func TestRequestSetQueryParams(t *testing.T) {
 	req := NewRequest()
 	req.SetQueryParams(map[string]string{"foo": "bar"})
 	req.Middleware.Run("request", req.Context)
+	query.Flush(req.Context)
 	st.Expect(t, req.Context.Request.URL.RawQuery, "foo=bar")
 }
 
@@ -411,6 +435,14 @@ func TestRequestSetHeaders(t *testing.T) {
 	st.Expect(t, req.Context.Request.Header.Get("baz"), "foo")
 }
 
+func TestRequestSetHeaderValues(t *testing.T) {
+	req := NewRequest()
+	req.AddHeader("foo", "qux")
+	req.SetHeaderValues("foo", "bar", "baz")
+	req.Middleware.Run("request", req.Context)
+	st.Expect(t, req.Context.Request.Header["Foo"], []string{"bar", "baz"})
+}
+
 func TestRequestAddCookie(t *testing.T) {
 	req := NewRequest()
 	cookie := &http.Cookie{Name: "foo", Value: "bar"}
@@ -533,6 +565,19 @@ func TestRequestFiles(t *testing.T) {
 	st.Expect(t, strings.Contains(string(body), "content2"), true)
 }
 
+func TestRequestTeeBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	res, err := NewRequest().URL(ts.URL).TeeBody(buf).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "hello world")
+	st.Expect(t, buf.String(), "hello world")
+}
+
 func TestRequestClone(t *testing.T) {
 	req1 := NewRequest()
 	req1.UseRequest(func(c *context.Context, h context.Handler) {})
@@ -543,6 +588,30 @@ func TestRequestClone(t *testing.T) {
 	st.Expect(t, len(req2.Middleware.GetStack()), 1)
 }
 
+func TestRequestCloneRelease(t *testing.T) {
+	var auth, other string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, other = r.Header.Get("Authorization"), r.Header.Get("X-Other")
+	}))
+	defer ts.Close()
+
+	req := NewRequest().URL(ts.URL)
+	req.Context.Request.Header.Set("Authorization", "Bearer token")
+	clone := req.Clone()
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	res.Release()
+
+	_, err = NewRequest().URL(ts.URL).SetHeader("X-Other", "foo").Send()
+	st.Expect(t, err, nil)
+
+	// The clone headers are not shared with the released request
+	_, err = clone.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, auth, "Bearer token")
+	st.Expect(t, other, "")
+}
+
 func BenchmarkSimpleRequestGet(b *testing.B) {
 	ts := createEchoServer()
 	defer ts.Close()
@@ -572,6 +641,19 @@ func BenchmarkSimpleRequestPostLargeString(b *testing.B) {
 	}
 }
 
+func BenchmarkSimpleRequestGetRelease(b *testing.B) {
+	ts := createEchoServer()
+	defer ts.Close()
+
+	for n := 0; n < b.N; n++ {
+		res, err := NewRequest().URL(ts.URL).Send()
+		if err == nil {
+			res.Bytes()
+			res.Release()
+		}
+	}
+}
+
 func BenchmarkRequestPlugins(b *testing.B) {
 	ts := createEchoServer()
 	defer ts.Close()
@@ -610,3 +692,151 @@ func randomString(n int) string {
 	}
 	return string(b)
 }
+
+func TestRequestBuild(t *testing.T) {
+	var dialed bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dialed = true
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).SetHeader("Authorization", "Bearer token")
+	cli.UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		ctx.Request.Header.Set("X-Signature", "signed")
+		h.Next(ctx)
+	})
+
+	type key struct{}
+	parent := gocontext.WithValue(gocontext.Background(), key{}, "value")
+	req := cli.Request().Method("POST").Path("/orders").BodyString(`{"id":1}`)
+	raw, err := req.Build(parent)
+	st.Expect(t, err, nil)
+	st.Expect(t, dialed, false)
+	st.Expect(t, raw.Method, "POST")
+	st.Expect(t, raw.URL.String(), ts.URL+"/orders")
+	st.Expect(t, raw.Header.Get("Authorization"), "Bearer token")
+	st.Expect(t, raw.Header.Get("X-Signature"), "signed")
+	st.Expect(t, raw.Context().Value(key{}), "value")
+	body, _ := ioutil.ReadAll(raw.Body)
+	st.Expect(t, string(body), `{"id":1}`)
+
+	// The request was not mutated, so it can still be sent
+	st.Expect(t, req.Context.Request.Header.Get("X-Signature"), "")
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, dialed, true)
+
+	_, err = req.Build(nil)
+	st.Expect(t, err, ErrAlreadyDispatched)
+
+	// Middleware errors are returned
+	fail := errors.New("invalid request")
+	req = cli.Request().UseRequest(func(ctx *context.Context, h context.Handler) {
+		h.Error(ctx, fail)
+	})
+	_, err = req.Build(nil)
+	st.Expect(t, err, fail)
+}
+
+func TestFromHTTPRequest(t *testing.T) {
+	var method, path, query, forwarded, connection, body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, path, query, body = r.Method, r.URL.Path, r.URL.RawQuery, string(data)
+		forwarded, connection = r.Header.Get("X-Forwarded"), r.Header.Get("X-Hop")
+		w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+
+	cli := New().BaseURL(upstream.URL)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := FromHTTPRequest(r)
+		st.Expect(t, req.Context.Request.URL.Host, r.Host)
+		st.Expect(t, req.Context.Request.URL.Scheme, "http")
+
+		res, err := req.SetClient(cli).Send()
+		st.Expect(t, err, nil)
+		w.Write(res.Bytes())
+	}))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("PUT", proxy.URL+"/orders?id=1", strings.NewReader("data"))
+	req.Header.Set("X-Forwarded", "yes")
+	req.Header.Set("X-Hop", "foo")
+	req.Header.Set("Connection", "X-Hop")
+	res, err := http.DefaultClient.Do(req)
+	st.Expect(t, err, nil)
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	st.Expect(t, string(data), "upstream")
+	st.Expect(t, method, "PUT")
+	st.Expect(t, path, "/orders")
+	st.Expect(t, query, "id=1")
+	st.Expect(t, body, "data")
+	st.Expect(t, forwarded, "yes")
+	st.Expect(t, connection, "")
+}
+
+func TestRequestBuildSend(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+	}))
+	defer ts.Close()
+
+	// Stream bodies are buffered, so they can still be sent
+	req := NewRequest().URL(ts.URL).Method("POST").Body(strings.NewReader("hello"))
+	raw, err := req.Build(nil)
+	st.Expect(t, err, nil)
+	body, _ := ioutil.ReadAll(raw.Body)
+	st.Expect(t, string(body), "hello")
+
+	res, err := req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, received, "hello")
+}
+
+func TestRequestBuilderErrors(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	var phases int
+	req := NewRequest().URL("http://[::1").Method("BAD METHOD").JSON(map[string]interface{}{"fn": func() {}})
+	req.UseRequest(func(ctx *context.Context, h context.Handler) {
+		phases++
+		h.Next(ctx)
+	})
+	st.Expect(t, len(req.Errors()), 3)
+	_, ok := req.Errors()[0].(*url.Error)
+	st.Expect(t, ok, true)
+	st.Expect(t, req.Errors()[1], ErrInvalidMethod)
+
+	// The first error is returned before running any middleware
+	clone := req.Clone()
+	res, err := req.Send()
+	st.Expect(t, err, req.Errors()[0])
+	st.Expect(t, res, (*Response)(nil))
+	st.Expect(t, phases, 0)
+	_, err = clone.Build(nil)
+	st.Expect(t, err, req.Errors()[0])
+
+	fail := errors.New("invalid input")
+	res, err = NewRequest().URL(ts.URL).AddError(nil).AddError(fail).Send()
+	st.Expect(t, err, fail)
+	st.Expect(t, calls, 0)
+
+	// Valid inputs record no errors
+	req = NewRequest().URL(ts.URL).Method("POST").JSON(map[string]int{"id": 1})
+	st.Expect(t, len(req.Errors()), 0)
+	res, err = req.Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, calls, 1)
+}
@@ -4,18 +4,30 @@ package gentleman
 // Apache License Version 2.0
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
+	"strings"
 
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/language"
 	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+	"gopkg.in/h2non/gentleman.v2/plugins/negotiate"
 	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
+// sniffLen defines the amount of body bytes used to detect the response charset.
+const sniffLen = 1024
+
 // Response provides a more convenient and higher level Response struct.
 // Implements an io.ReadCloser interface.
 type Response struct {
@@ -51,10 +63,14 @@ type Response struct {
 
 	// Internal buffer store
 	buffer *bytes.Buffer
+
+	// pool stores the buffer pool used to allocate the internal buffer
+	pool *utils.BufferPool
 }
 
 func buildResponse(ctx *context.Context) (*Response, error) {
 	resp := ctx.Response
+	pool := utils.DefaultBufferPool
 	statusRange := int(resp.StatusCode / 100)
 
 	res := &Response{
@@ -69,7 +85,8 @@ func buildResponse(ctx *context.Context) (*Response, error) {
 		StatusCode:  resp.StatusCode,
 		Header:      resp.Header,
 		Cookies:     resp.Cookies(),
-		buffer:      bytes.NewBuffer([]byte{}),
+		buffer:      pool.Get(),
+		pool:        pool,
 	}
 
 	return res, res.Error
@@ -119,12 +136,13 @@ func (r *Response) SaveToFile(fileName string) error {
 
 // JSON is a method that will populate a struct that is provided `userStruct`
 // with the JSON returned within the response body.
+// Non UTF-8 bodies are transcoded to UTF-8 if the charset is declared.
 func (r *Response) JSON(userStruct interface{}) error {
 	if r.Error != nil {
 		return r.Error
 	}
 
-	jsonDecoder := json.NewDecoder(r.getInternalReader())
+	jsonDecoder := json.NewDecoder(r.textReader())
 	defer r.Close()
 
 	err := jsonDecoder.Decode(&userStruct)
@@ -155,7 +173,32 @@ func (r *Response) XML(userStruct interface{}, charsetReader utils.XMLCharDecode
 	return nil
 }
 
+// HTML parses the response body as an HTML document and returns the root node.
+// The body is buffered and transcoded to UTF-8 based on the declared or sniffed charset.
+// The returned node can be used to create goquery documents via goquery.NewDocumentFromNode().
+func (r *Response) HTML() (*html.Node, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	r.populateResponseByteBuffer()
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	body := r.buffer.Bytes()
+	if enc, _ := r.encoding(body); enc != nil {
+		return html.Parse(enc.NewDecoder().Reader(bytes.NewReader(body)))
+	}
+	return html.Parse(bytes.NewReader(body))
+}
+
 // Bytes returns the response as a byte array.
+// Bytes are returned as received, without charset transcoding.
+// The returned slice is backed by the internal buffer, therefore the body
+// is neither read again nor copied on subsequent calls. The slice must not be
+// modified and is only valid until ClearInternalBuffer() or Release() is called.
+// Prefer Bytes() over String() for large bodies, since String() copies the body.
 func (r *Response) Bytes() []byte {
 	if r.Error != nil {
 		return nil
@@ -171,13 +214,97 @@ func (r *Response) Bytes() []byte {
 }
 
 // String returns the response as a string.
+// Non UTF-8 bodies are transcoded to UTF-8 based on the charset declared
+// in the Content-Type header, byte order mark or HTML meta tags.
 func (r *Response) String() string {
 	if r.Error != nil {
 		return ""
 	}
 
 	r.populateResponseByteBuffer()
-	return r.buffer.String()
+	enc, _ := r.encoding(r.buffer.Bytes())
+	if enc == nil {
+		return r.buffer.String()
+	}
+
+	body, err := enc.NewDecoder().Bytes(r.buffer.Bytes())
+	if err != nil {
+		return r.buffer.String()
+	}
+	return string(body)
+}
+
+// MediaType returns the lower-case media type of the response Content-Type header,
+// such as "application/json", which identifies the representation negotiated
+// via Accept. Returns an empty string if the header is missing or invalid.
+func (r *Response) MediaType() string {
+	if r.Header == nil {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// HeaderValues returns all the values of the given response header, splitting
+// the comma-separated lists of every field line, such as the multiple links of a Link header.
+// See headers.Values() for details.
+func (r *Response) HeaderValues(name string) []string {
+	return headers.Values(r.Header, name)
+}
+
+// ContentLanguage returns the language tags of the response Content-Language header,
+// ignoring the invalid ones.
+func (r *Response) ContentLanguage() []language.Tag {
+	if r.Header == nil {
+		return nil
+	}
+	return negotiate.ParseContentLanguage(r.Header.Get("Content-Language"))
+}
+
+// Charset returns the name of the response body character encoding
+// used to transcode the body to UTF-8 by String() and JSON().
+func (r *Response) Charset() string {
+	if r.Error != nil {
+		return ""
+	}
+
+	r.populateResponseByteBuffer()
+	_, name := r.encoding(r.buffer.Bytes())
+	return name
+}
+
+// Replay buffers the response body and rewinds it, allowing the raw body
+// to be read again via Read(). Buffered accessors, such as Bytes() or JSON(),
+// can be called multiple times without calling Replay.
+func (r *Response) Replay() error {
+	if r.Error != nil {
+		return r.Error
+	}
+
+	r.populateResponseByteBuffer()
+	if r.Error != nil {
+		return r.Error
+	}
+
+	r.RawResponse.Body = ioutil.NopCloser(bytes.NewReader(r.buffer.Bytes()))
+	return nil
+}
+
+// CopyCookies copies the cookies parsed from the response Set-Cookie headers,
+// available in the Cookies field, to the given client. Cookies are stored in the
+// client cookie jar, if created via CookieJar(), or sent in every client request otherwise.
+func (r *Response) CopyCookies(cli *Client) {
+	if len(r.Cookies) == 0 {
+		return
+	}
+	if jar := cli.Jar(); jar != nil && r.RawRequest != nil && r.RawRequest.URL != nil {
+		jar.SetCookies(r.RawRequest.URL, r.Cookies)
+		return
+	}
+	cli.AddCookies(r.Cookies)
 }
 
 // ClearInternalBuffer is a function that will clear the internal buffer that we
@@ -190,6 +317,19 @@ func (r *Response) ClearInternalBuffer() {
 	r.buffer.Reset()
 }
 
+// Release returns the response internal buffer and context to the internal pools
+// for reuse, reducing the allocations per request in high throughput scenarios.
+// Calling Release is optional. The response, its context and the data returned
+// by Bytes() must not be used after calling Release.
+func (r *Response) Release() {
+	if r.buffer == nil {
+		return
+	}
+	r.pool.Put(r.buffer)
+	r.buffer = nil
+	r.Context.Release()
+}
+
 // createResponseBytesBuffer is a utility method that will populate
 // the internal byte reader – this is largely used for .String() and .Bytes()
 func (r *Response) populateResponseByteBuffer() {
@@ -205,8 +345,10 @@ func (r *Response) populateResponseByteBuffer() {
 	}
 
 	// Did the server tell us how big the response is going to be?
+	// Reserve room for the final read, preventing the buffer from being grown
+	// and copied once the whole body has been buffered.
 	if r.RawResponse.ContentLength > 0 {
-		r.buffer.Grow(int(r.RawResponse.ContentLength))
+		r.buffer.Grow(int(r.RawResponse.ContentLength) + bytes.MinRead)
 	}
 
 	_, err := io.Copy(r.buffer, r)
@@ -218,14 +360,110 @@ func (r *Response) populateResponseByteBuffer() {
 
 // getInternalReader because we implement io.ReadCloser and
 // optionally hold a large buffer of the response (created by
-// the user's request).
+// the user's request). The internal buffer is not consumed.
 func (r *Response) getInternalReader() io.Reader {
 	if r.buffer.Len() != 0 {
-		return r.buffer
+		return bytes.NewReader(r.buffer.Bytes())
 	}
 	return r
 }
 
+// textReader returns a reader of the response body transcoded to UTF-8, if required.
+// The internal buffer is read in place, if populated.
+func (r *Response) textReader() io.Reader {
+	if r.buffer.Len() == 0 {
+		return r.decodeReader(r)
+	}
+	body := bytes.NewReader(r.buffer.Bytes())
+	if enc, _ := r.encoding(r.buffer.Bytes()); enc != nil {
+		return enc.NewDecoder().Reader(body)
+	}
+	return body
+}
+
+// decodeReader returns a reader which transcodes the given
+// response body reader to UTF-8, if required.
+func (r *Response) decodeReader(reader io.Reader) io.Reader {
+	buf := bufio.NewReaderSize(reader, sniffLen)
+	peek, _ := buf.Peek(sniffLen)
+	enc, _ := r.encoding(peek)
+	if enc == nil {
+		return buf
+	}
+	return enc.NewDecoder().Reader(buf)
+}
+
+// encoding determines the response body character encoding based on the
+// Content-Type charset, the byte order mark or, for text bodies, the HTML meta tags.
+// Returns a nil encoding if the body must not be transcoded, which includes
+// bodies with no declared charset, since guessed encodings are unreliable.
+func (r *Response) encoding(content []byte) (encoding.Encoding, string) {
+	contentType := r.Header.Get("Content-Type")
+	enc, name, certain := charset.DetermineEncoding(content, contentType)
+	if !certain {
+		enc, name = nil, ""
+		if isTextContent(contentType) {
+			enc, name = metaEncoding(content)
+		}
+	}
+	if enc == nil || name == "utf-8" {
+		return nil, "utf-8"
+	}
+	return enc, name
+}
+
+// metaEncoding returns the character encoding declared by the HTML meta tags
+// within the first sniffLen bytes of the given content, if any.
+func metaEncoding(content []byte) (encoding.Encoding, string) {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return nil, ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag, hasAttr := tokenizer.TagName()
+			if string(tag) != "meta" {
+				continue
+			}
+
+			var label, httpEquiv, value string
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				switch string(key) {
+				case "charset":
+					label = string(val)
+				case "http-equiv":
+					httpEquiv = strings.ToLower(string(val))
+				case "content":
+					value = string(val)
+				}
+			}
+			if label == "" && httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(value); err == nil {
+					label = params["charset"]
+				}
+			}
+			if label == "" {
+				continue
+			}
+			if enc, name := charset.Lookup(label); enc != nil {
+				return enc, name
+			}
+		}
+	}
+}
+
+// isTextContent returns true if the given content type represents a text document.
+func isTextContent(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "html")
+}
+
 // isChunkedResponse iterates over the response's transfer encodings
 // and returns either true whether 'chunked' is found, or false, otherwise.
 func isChunkedResponse(res *http.Response) bool {
@@ -3,10 +3,16 @@ package gentleman
 import (
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/nbio/st"
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
+	"gopkg.in/h2non/gentleman.v2/plugins/negotiate"
 	"gopkg.in/h2non/gentleman.v2/utils"
 )
 
@@ -197,6 +203,115 @@ func TestResponseBytes(t *testing.T) {
 	st.Expect(t, string(body), "foo bar")
 }
 
+func TestResponseBytesNoCopy(t *testing.T) {
+	ctx := NewContext()
+	utils.WriteBodyString(ctx.Response, "foo bar")
+	res, _ := buildResponse(ctx)
+
+	body := res.Bytes()
+	st.Expect(t, string(body), "foo bar")
+	st.Expect(t, &res.Bytes()[0] == &body[0], true)
+	st.Expect(t, &res.buffer.Bytes()[0] == &body[0], true)
+}
+
+func TestResponseStringCharset(t *testing.T) {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=ISO-8859-1")
+	utils.WriteBodyString(ctx.Response, "caf\xe9")
+	res, err := buildResponse(ctx)
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "café")
+	st.Expect(t, res.Bytes(), []byte("caf\xe9"))
+	st.Expect(t, res.Charset(), "windows-1252")
+}
+
+func TestResponseStringCharsetSniff(t *testing.T) {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "text/html")
+	utils.WriteBodyString(ctx.Response, "<html><head><meta charset=\"iso-8859-1\"></head><body>caf\xe9</body></html>")
+	res, _ := buildResponse(ctx)
+	st.Expect(t, res.String(), `<html><head><meta charset="iso-8859-1"></head><body>café</body></html>`)
+
+	// Binary bodies are never transcoded
+	ctx = NewContext()
+	ctx.Response.Header.Set("Content-Type", "application/octet-stream")
+	utils.WriteBodyString(ctx.Response, "caf\xe9")
+	res, _ = buildResponse(ctx)
+	st.Expect(t, res.String(), "caf\xe9")
+	st.Expect(t, res.Charset(), "utf-8")
+
+	// Legacy http-equiv meta tags are honored too
+	ctx = NewContext()
+	ctx.Response.Header.Set("Content-Type", "text/html")
+	utils.WriteBodyString(ctx.Response, "<meta http-equiv=\"Content-Type\" content=\"text/html; charset=iso-8859-1\">caf\xe9")
+	res, _ = buildResponse(ctx)
+	st.Expect(t, res.Charset(), "windows-1252")
+	st.Expect(t, strings.HasSuffix(res.String(), "café"), true)
+}
+
+func TestResponseStringCharsetUndeclared(t *testing.T) {
+	// Bodies with no declared charset are not transcoded based on an ASCII prefix
+	body := "<html><body>" + strings.Repeat("a", 1100) + "café ünïcode</body></html>"
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "text/html")
+	utils.WriteBodyString(ctx.Response, body)
+	res, _ := buildResponse(ctx)
+	st.Expect(t, res.Charset(), "utf-8")
+	st.Expect(t, res.String(), body)
+
+	doc, err := res.HTML()
+	st.Expect(t, err, nil)
+	st.Expect(t, strings.HasSuffix(findNode(doc, "body").FirstChild.Data, "café ünïcode"), true)
+}
+
+func TestResponseJSONCharset(t *testing.T) {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "application/json; charset=iso-8859-1")
+	utils.WriteBodyString(ctx.Response, "{\"foo\":\"caf\xe9\"}")
+	res, _ := buildResponse(ctx)
+
+	body := map[string]string{}
+	st.Expect(t, res.JSON(&body), nil)
+	st.Expect(t, body["foo"], "café")
+}
+
+func findNode(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findNode(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestResponseHTML(t *testing.T) {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", "text/html; charset=iso-8859-1")
+	utils.WriteBodyString(ctx.Response, "<html><head><title>Caf\xe9</title></head><body><a href=\"/foo\">foo</a></body></html>")
+	res, _ := buildResponse(ctx)
+
+	doc, err := res.HTML()
+	st.Expect(t, err, nil)
+	st.Expect(t, doc.Type, html.DocumentNode)
+	st.Expect(t, findNode(doc, "title").FirstChild.Data, "Café")
+	st.Expect(t, findNode(doc, "a").Attr[0].Val, "/foo")
+
+	// Body remains buffered
+	st.Expect(t, res.String(), "<html><head><title>Café</title></head><body><a href=\"/foo\">foo</a></body></html>")
+}
+
+func TestResponseHTMLError(t *testing.T) {
+	ctx := NewContext()
+	ctx.Error = errors.New("foo error")
+	res, _ := buildResponse(ctx)
+	doc, err := res.HTML()
+	st.Expect(t, err, ctx.Error)
+	st.Expect(t, doc == nil, true)
+}
+
 func TestResponseBytesError(t *testing.T) {
 	ctx := NewContext()
 	ctx.Error = errors.New("foo error")
@@ -222,6 +337,49 @@ func TestResponseReaderBuffer(t *testing.T) {
 	st.Expect(t, string(res.Bytes()), "")
 }
 
+func TestResponseReaderBufferReuse(t *testing.T) {
+	ctx := NewContext()
+	utils.WriteBodyString(ctx.Response, `{"foo":"bar"}`)
+	res, err := buildResponse(ctx)
+	st.Expect(t, err, nil)
+	st.Expect(t, string(res.Bytes()), `{"foo":"bar"}`)
+
+	// Buffered accessors do not consume the internal buffer
+	data := struct {
+		Foo string `json:"foo"`
+	}{}
+	st.Expect(t, res.JSON(&data), nil)
+	st.Expect(t, data.Foo, "bar")
+	values, err := res.JSONPath("$.foo")
+	st.Expect(t, err, nil)
+	st.Expect(t, values, []interface{}{"bar"})
+
+	st.Expect(t, res.SaveToFile("body.tmp"), nil)
+	defer os.Remove("body.tmp")
+	body, _ := ioutil.ReadFile("body.tmp")
+	st.Expect(t, string(body), `{"foo":"bar"}`)
+	st.Expect(t, string(res.Bytes()), `{"foo":"bar"}`)
+}
+
+func TestResponseReplay(t *testing.T) {
+	ctx := NewContext()
+	utils.WriteBodyString(ctx.Response, "foo bar")
+	res, _ := buildResponse(ctx)
+
+	for i := 0; i < 2; i++ {
+		st.Expect(t, res.Replay(), nil)
+		body, err := ioutil.ReadAll(res)
+		st.Expect(t, err, nil)
+		st.Expect(t, string(body), "foo bar")
+	}
+	st.Expect(t, res.String(), "foo bar")
+
+	ctx = NewContext()
+	ctx.Error = errors.New("foo error")
+	res, _ = buildResponse(ctx)
+	st.Reject(t, res.Replay(), nil)
+}
+
 func TestResponseReaderEmtpyBuffer(t *testing.T) {
 	ctx := NewContext()
 	res, err := buildResponse(ctx)
@@ -247,3 +405,121 @@ func TestResponseReaderBufferError(t *testing.T) {
 	res.ClearInternalBuffer()
 	st.Expect(t, res.buffer.Len(), 0)
 }
+
+func TestResponseRelease(t *testing.T) {
+	ctx := NewContext()
+	utils.WriteBodyString(ctx.Response, "foo bar")
+	ctx.Response.Header.Set("foo", "bar")
+	res, err := buildResponse(ctx)
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "foo bar")
+
+	res.Release()
+	st.Expect(t, res.buffer == nil, true)
+	st.Expect(t, ctx.Request == nil, true)
+
+	// Released twice is a noop
+	res.Release()
+}
+
+func BenchmarkResponseBytes(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		ctx := NewContext()
+		utils.WriteBodyString(ctx.Response, "foo bar")
+		res, _ := buildResponse(ctx)
+		res.Bytes()
+		res.Release()
+	}
+}
+
+func TestResponseCopyCookies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+			return
+		}
+		cookie, _ := r.Cookie("session")
+		if cookie != nil {
+			w.Write([]byte(cookie.Value))
+		}
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Request().Path("/login").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, len(res.Cookies), 1)
+	st.Expect(t, res.Cookies[0].Name, "session")
+
+	// Clients without cookie jar send the cookies in every request
+	cli := New().URL(ts.URL)
+	res.CopyCookies(cli)
+	res, err = cli.Request().Path("/profile").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "secret")
+
+	// Clients with cookie jar store the cookies
+	res, _ = New().URL(ts.URL).Request().Path("/login").Send()
+	cli = New().URL(ts.URL).CookieJar()
+	res.CopyCookies(cli)
+	st.Expect(t, len(cli.Jar().All()), 1)
+	res, err = cli.Request().Path("/profile").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "secret")
+}
+
+func TestResponseNegotiatedMediaType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/xml, application/json;q=0.5" {
+			w.Header().Set("Content-Type", "Application/XML; charset=utf-8")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Accepts("xml", "json;q=0.5").Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.MediaType(), "application/xml")
+
+	res, err = New().URL(ts.URL).Request().Accepts("json").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.MediaType(), "text/plain")
+
+	_, err = New().URL(ts.URL).Request().Accepts("json;q=5").Send()
+	st.Expect(t, err, negotiate.ErrInvalidQuality)
+}
+
+func TestResponseContentLanguage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Language") == "de-DE, en;q=0.9" {
+			w.Header().Set("Content-Language", "de-DE")
+		}
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).AcceptLanguage(language.MustParse("de-DE"), language.English).Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.ContentLanguage(), []language.Tag{language.MustParse("de-DE")})
+
+	res, err = New().URL(ts.URL).Request().AcceptLanguage(language.English).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, len(res.ContentLanguage()), 0)
+}
+
+func TestResponseHeaderValues(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=9>; rel="last"`)
+		w.Header().Add("Link", `<https://api.example.com/items?a=1,2>; title="a, b"; rel="help"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+	}))
+	defer ts.Close()
+
+	res, err := New().URL(ts.URL).Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.HeaderValues("link"), []string{
+		`<https://api.example.com/items?page=2>; rel="next"`,
+		`<https://api.example.com/items?page=9>; rel="last"`,
+		`<https://api.example.com/items?a=1,2>; title="a, b"; rel="help"`,
+	})
+	st.Expect(t, res.HeaderValues("Last-Modified"), []string{"Wed, 21 Oct 2015 07:28:00 GMT"})
+	st.Expect(t, res.HeaderValues("X-Missing"), []string(nil))
+}
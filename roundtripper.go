@@ -0,0 +1,53 @@
+package gentleman
+
+import (
+	"net/http"
+)
+
+// RoundTripper returns a http.RoundTripper which sends the given requests
+// through the client middleware stack, so the client plugins such as retries,
+// authentication or metrics can be used by any code accepting a *http.Client.
+//
+// The method, URL, host, headers, body and context of the given request
+// are used as is, while the client plugins are still able to override them.
+// Note that the client follows the redirects by itself, unless configured otherwise.
+func (c *Client) RoundTripper() http.RoundTripper {
+	return &roundTripper{client: c}
+}
+
+// roundTripper implements a http.RoundTripper dispatching the requests
+// via a gentleman client.
+type roundTripper struct {
+	client *Client
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.client.Request().Method(req.Method).URL(req.URL.String())
+	r.Context.SetCancelContext(req.Context())
+
+	raw := r.Context.Request
+	for name, values := range req.Header {
+		raw.Header[name] = append([]string(nil), values...)
+	}
+	if req.Host != "" {
+		raw.Host = req.Host
+	}
+	if req.Body != nil {
+		raw.Body = req.Body
+		raw.GetBody = req.GetBody
+		raw.ContentLength = req.ContentLength
+	}
+	raw.Close = req.Close
+	raw.Trailer = req.Trailer
+	raw.TransferEncoding = req.TransferEncoding
+
+	res, err := r.Do()
+	if err != nil {
+		if res != nil && res.RawResponse != nil && res.RawResponse.Body != nil {
+			res.RawResponse.Body.Close()
+		}
+		return nil, err
+	}
+	return res.RawResponse, nil
+}
@@ -0,0 +1,58 @@
+package gentleman
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestClientRoundTripper(t *testing.T) {
+	var received, token, trace string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		received, token, trace = string(data), r.Header.Get("Authorization"), r.Header.Get("X-Trace")
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var phases []string
+	cli := New().SetHeader("Authorization", "Bearer token")
+	cli.UseResponse(func(ctx *context.Context, h context.Handler) {
+		phases = append(phases, "response")
+		h.Next(ctx)
+	})
+
+	client := &http.Client{Transport: cli.RoundTripper()}
+	req, _ := http.NewRequest("POST", ts.URL+"/orders", strings.NewReader(`{"id":1}`))
+	req.Header.Set("X-Trace", "abc")
+	res, err := client.Do(req)
+	st.Expect(t, err, nil)
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	st.Expect(t, string(body), "ok")
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, res.Header.Get("X-Path"), "/orders")
+	st.Expect(t, received, `{"id":1}`)
+	st.Expect(t, token, "Bearer token")
+	st.Expect(t, trace, "abc")
+	st.Expect(t, phases, []string{"response"})
+}
+
+func TestClientRoundTripperError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: New().FailOnError().RoundTripper()}
+	res, err := client.Get(ts.URL)
+	st.Reject(t, err, nil)
+	st.Expect(t, res, (*http.Response)(nil))
+}
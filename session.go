@@ -0,0 +1,122 @@
+package gentleman
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+	"gopkg.in/h2non/gentleman.v2/plugins/cookies"
+)
+
+// SessionPluginName defines the name of the plugin registered via Client.UseSession().
+const SessionPluginName = "session"
+
+// Session represents a client session state, storing the cookies, default headers
+// and authorization credentials sent in every request, which can be exported
+// and imported as a serializable blob in order to persist and resume long-running sessions.
+// Session is safe for concurrent use.
+type Session struct {
+	// mtx protects the session headers.
+	mtx sync.RWMutex
+
+	// header stores the headers sent in every request.
+	header http.Header
+
+	// jar stores the session cookies.
+	jar *cookies.Store
+}
+
+// sessionState represents the serializable session state.
+type sessionState struct {
+	Header  http.Header      `json:"header,omitempty"`
+	Cookies []*cookies.Entry `json:"cookies,omitempty"`
+}
+
+// NewSession creates a new empty session.
+func NewSession() *Session {
+	jar, _ := cookies.NewStore(cookies.StoreOptions{})
+	return &Session{header: make(http.Header), jar: jar}
+}
+
+// SetHeader sets a header sent in every request of the session.
+func (s *Session) SetHeader(name, value string) *Session {
+	s.mtx.Lock()
+	s.header.Set(name, value)
+	s.mtx.Unlock()
+	return s
+}
+
+// DelHeader removes a header sent in every request of the session.
+func (s *Session) DelHeader(name string) *Session {
+	s.mtx.Lock()
+	s.header.Del(name)
+	s.mtx.Unlock()
+	return s
+}
+
+// Header returns a copy of the headers sent in every request of the session.
+func (s *Session) Header() http.Header {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return cloneHeader(s.header)
+}
+
+// SetToken defines the authorization bearer token sent in every request of the session.
+func (s *Session) SetToken(token string) *Session {
+	return s.SetHeader("Authorization", "Bearer "+token)
+}
+
+// Jar returns the session cookie jar.
+func (s *Session) Jar() *cookies.Store {
+	return s.jar
+}
+
+// Export serializes the session state, including the non expired cookies, as JSON.
+func (s *Session) Export() ([]byte, error) {
+	return json.Marshal(sessionState{Header: s.Header(), Cookies: s.jar.All()})
+}
+
+// Import restores the session state serialized via Export(), replacing
+// the current headers and merging the cookies.
+func (s *Session) Import(data []byte) error {
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	if state.Header == nil {
+		state.Header = make(http.Header)
+	}
+	s.mtx.Lock()
+	s.header = state.Header
+	s.mtx.Unlock()
+
+	s.jar.Add(state.Cookies...)
+	return nil
+}
+
+// plugin creates the plugin applying the session state to the outgoing requests.
+func (s *Session) plugin() plugin.Plugin {
+	return plugin.WithName(SessionPluginName, plugin.NewRequestPlugin(func(ctx *context.Context, h context.Handler) {
+		s.mtx.RLock()
+		for name, values := range s.header {
+			ctx.Request.Header[name] = append([]string(nil), values...)
+		}
+		s.mtx.RUnlock()
+
+		ctx.Client.Jar = s.jar
+		ctx.Set(cookies.JarKey, s.jar)
+		h.Next(ctx)
+	}))
+}
+
+// cloneHeader returns a deep copy of the given header.
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for name, values := range header {
+		clone[name] = append([]string(nil), values...)
+	}
+	return clone
+}
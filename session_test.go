@@ -0,0 +1,62 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", MaxAge: 3600})
+			return
+		}
+		cookie, _ := r.Cookie("session")
+		if cookie == nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("X-Client") + " " + cookie.Value))
+	}))
+	defer ts.Close()
+
+	session := NewSession().SetToken("token").SetHeader("X-Client", "foo")
+	cli := New().URL(ts.URL).UseSession(session)
+	_, err := cli.Request().Path("/login").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, cli.Jar(), session.Jar())
+
+	data, err := session.Export()
+	st.Expect(t, err, nil)
+
+	// Resume the session in a new client
+	resumed := NewSession()
+	st.Expect(t, resumed.Import(data), nil)
+	st.Expect(t, resumed.Header().Get("Authorization"), "Bearer token")
+	st.Expect(t, len(resumed.Jar().All()), 1)
+
+	res, err := New().URL(ts.URL).UseSession(resumed).Request().Path("/profile").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.String(), "Bearer token foo secret")
+
+	// Sessions can be replaced
+	cli = New().URL(ts.URL).UseSession(resumed).UseSession(NewSession())
+	res, err = cli.Request().Path("/profile").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 401)
+
+	st.Reject(t, resumed.Import([]byte("invalid")), nil)
+}
+
+func TestSessionHeader(t *testing.T) {
+	session := NewSession().SetHeader("foo", "bar")
+	header := session.Header()
+	header.Set("foo", "baz")
+	st.Expect(t, session.Header().Get("foo"), "bar")
+
+	session.DelHeader("foo")
+	st.Expect(t, len(session.Header()), 0)
+}
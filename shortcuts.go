@@ -0,0 +1,50 @@
+package gentleman
+
+import (
+	"io"
+)
+
+// DefaultClient is the client used by the package-level shortcut functions,
+// such as Get() or PostJSON(), which can be configured as any other client.
+// Shortcut functions are designed for scripts and small tools: create
+// dedicated clients via New() otherwise.
+var DefaultClient = New()
+
+// Get performs a GET request to the given URL via DefaultClient.
+func Get(uri string) (*Response, error) {
+	return DefaultClient.Get().URL(uri).Send()
+}
+
+// Head performs a HEAD request to the given URL via DefaultClient.
+func Head(uri string) (*Response, error) {
+	return DefaultClient.Head().URL(uri).Send()
+}
+
+// Delete performs a DELETE request to the given URL via DefaultClient.
+func Delete(uri string) (*Response, error) {
+	return DefaultClient.Delete().URL(uri).Send()
+}
+
+// Post performs a POST request to the given URL via DefaultClient,
+// sending the given body with the given content type.
+func Post(uri, contentType string, body io.Reader) (*Response, error) {
+	return DefaultClient.Post().URL(uri).SetHeader("Content-Type", contentType).Body(body).Send()
+}
+
+// PostJSON performs a POST request to the given URL via DefaultClient,
+// sending the given data serialized as JSON.
+func PostJSON(uri string, data interface{}) (*Response, error) {
+	return DefaultClient.Post().URL(uri).JSON(data).Send()
+}
+
+// PutJSON performs a PUT request to the given URL via DefaultClient,
+// sending the given data serialized as JSON.
+func PutJSON(uri string, data interface{}) (*Response, error) {
+	return DefaultClient.Put().URL(uri).JSON(data).Send()
+}
+
+// PatchJSON performs a PATCH request to the given URL via DefaultClient,
+// sending the given data serialized as JSON.
+func PatchJSON(uri string, data interface{}) (*Response, error) {
+	return DefaultClient.Patch().URL(uri).JSON(data).Send()
+}
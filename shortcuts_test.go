@@ -0,0 +1,48 @@
+package gentleman
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nbio/st"
+)
+
+func TestShortcuts(t *testing.T) {
+	var method, contentType, body, token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		method, contentType, body, token = r.Method, r.Header.Get("Content-Type"), string(data), r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	defer func(cli *Client) { DefaultClient = cli }(DefaultClient)
+	DefaultClient = New().SetHeader("Authorization", "Bearer token")
+
+	cases := []struct {
+		send        func() (*Response, error)
+		method      string
+		contentType string
+		body        string
+	}{
+		{func() (*Response, error) { return Get(ts.URL) }, "GET", "", ""},
+		{func() (*Response, error) { return Head(ts.URL) }, "HEAD", "", ""},
+		{func() (*Response, error) { return Delete(ts.URL) }, "DELETE", "", ""},
+		{func() (*Response, error) { return Post(ts.URL, "text/plain", strings.NewReader("foo")) }, "POST", "text/plain", "foo"},
+		{func() (*Response, error) { return PostJSON(ts.URL, map[string]int{"id": 1}) }, "POST", "application/json", `{"id":1}`},
+		{func() (*Response, error) { return PutJSON(ts.URL, map[string]int{"id": 1}) }, "PUT", "application/json", `{"id":1}`},
+		{func() (*Response, error) { return PatchJSON(ts.URL, map[string]int{"id": 1}) }, "PATCH", "application/json", `{"id":1}`},
+	}
+
+	for _, test := range cases {
+		res, err := test.send()
+		st.Expect(t, err, nil)
+		st.Expect(t, res.StatusCode, 200)
+		st.Expect(t, method, test.method)
+		st.Expect(t, contentType, test.contentType)
+		st.Expect(t, strings.TrimSpace(body), test.body)
+		st.Expect(t, token, "Bearer token")
+	}
+}
@@ -0,0 +1,154 @@
+package gentleman
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+// timingKey is the context store key used to store the request timing tracker.
+const timingKey = "$timing"
+
+// Timing represents the timing metadata of a request, based on the last dial attempt.
+// Phases not performed, such as DNS resolution on reused connections, are zero.
+type Timing struct {
+	// Queued stores the time spent waiting for a connection, excluding the DNS, connect and TLS phases.
+	Queued time.Duration
+
+	// DNS stores the time spent resolving the server host.
+	DNS time.Duration
+
+	// Connect stores the time spent establishing the TCP connection.
+	Connect time.Duration
+
+	// TLS stores the time spent performing the TLS handshake.
+	TLS time.Duration
+
+	// TTFB stores the time to first byte, since the connection was requested
+	// until the first response byte was received.
+	TTFB time.Duration
+
+	// Download stores the time spent reading the response body,
+	// once fully read or closed.
+	Download time.Duration
+
+	// Total stores the time elapsed since the request was dispatched until the response
+	// body was fully read or closed, or until the response headers were received
+	// if the body was not consumed yet.
+	Total time.Duration
+
+	// Reused stores if the connection was reused.
+	Reused bool
+}
+
+// timing tracks the timing of a request. timing is safe for concurrent use.
+type timing struct {
+	mtx   sync.Mutex
+	start time.Time
+	marks marks
+}
+
+// marks stores the timing marks of a dial attempt.
+type marks struct {
+	getConn, gotConn         time.Time
+	dnsStart, dnsDone        time.Time
+	connectStart, connectEnd time.Time
+	tlsStart, tlsDone        time.Time
+	firstByte, responded     time.Time
+	done                     time.Time
+	reused                   bool
+}
+
+// getTiming returns the timing tracker stored in the given context, if any.
+func getTiming(ctx *context.Context) *timing {
+	t, _ := ctx.Get(timingKey).(*timing)
+	return t
+}
+
+// trace creates the client trace tracking the timing of a dial attempt.
+func (t *timing) trace() *httptrace.ClientTrace {
+	record := func(field *time.Time) {
+		t.mtx.Lock()
+		*field = time.Now()
+		t.mtx.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// Reset the marks of the previous attempt, if any
+			t.mtx.Lock()
+			t.marks = marks{getConn: time.Now()}
+			t.mtx.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mtx.Lock()
+			t.marks.gotConn, t.marks.reused = time.Now(), info.Reused
+			t.mtx.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&t.marks.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&t.marks.dnsDone) },
+		ConnectStart:         func(string, string) { record(&t.marks.connectStart) },
+		ConnectDone:          func(string, string, error) { record(&t.marks.connectEnd) },
+		TLSHandshakeStart:    func() { record(&t.marks.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.marks.tlsDone) },
+		GotFirstResponseByte: func() { record(&t.marks.firstByte) },
+	}
+}
+
+// responseReceived records when the response headers were received.
+func (t *timing) responseReceived() {
+	t.mtx.Lock()
+	t.marks.responded = time.Now()
+	t.mtx.Unlock()
+}
+
+// bodyDone records when the response body was fully read or closed.
+func (t *timing) bodyDone() {
+	t.mtx.Lock()
+	if t.marks.done.IsZero() {
+		t.marks.done = time.Now()
+	}
+	t.mtx.Unlock()
+}
+
+// snapshot returns the tracked timing metadata.
+func (t *timing) snapshot() Timing {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	since := func(start, end time.Time) time.Duration {
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			return 0
+		}
+		return end.Sub(start)
+	}
+
+	m := t.marks
+	timing := Timing{
+		DNS:     since(m.dnsStart, m.dnsDone),
+		Connect: since(m.connectStart, m.connectEnd),
+		TLS:     since(m.tlsStart, m.tlsDone),
+		TTFB:    since(m.getConn, m.firstByte),
+		Reused:  m.reused,
+	}
+	if queued := since(m.getConn, m.gotConn) - timing.DNS - timing.Connect - timing.TLS; queued > 0 {
+		timing.Queued = queued
+	}
+	if !m.done.IsZero() {
+		timing.Download = since(m.firstByte, m.done)
+		timing.Total = since(t.start, m.done)
+	} else {
+		timing.Total = since(t.start, m.responded)
+	}
+	return timing
+}
+
+// Timing returns the timing metadata of the request.
+func (r *Response) Timing() Timing {
+	if t := getTiming(r.Context); t != nil {
+		return t.snapshot()
+	}
+	return Timing{}
+}
@@ -0,0 +1,47 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/plugins/transport"
+)
+
+func TestResponseTiming(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cli := New().URL(ts.URL).Use(transport.Set(ts.Client().Transport))
+	res, err := cli.Get().Send()
+	st.Expect(t, err, nil)
+
+	timing := res.Timing()
+	st.Expect(t, timing.Reused, false)
+	st.Expect(t, timing.Connect > 0, true)
+	st.Expect(t, timing.TLS > 0, true)
+	st.Expect(t, timing.TTFB >= 10*time.Millisecond, true)
+	st.Expect(t, timing.TTFB >= timing.Connect+timing.TLS, true)
+	st.Expect(t, timing.Download, time.Duration(0))
+	st.Expect(t, timing.Total >= timing.TTFB, true)
+
+	st.Expect(t, res.String(), "ok")
+	timing = res.Timing()
+	st.Expect(t, timing.Download > 0, true)
+	st.Expect(t, timing.Total >= timing.TTFB+timing.Download, true)
+
+	// Reused connections skip the connection phases
+	res, err = cli.Get().Send()
+	st.Expect(t, err, nil)
+	res.Close()
+	timing = res.Timing()
+	st.Expect(t, timing.Reused, true)
+	st.Expect(t, timing.Connect, time.Duration(0))
+	st.Expect(t, timing.TLS, time.Duration(0))
+	st.Expect(t, timing.TTFB > 0, true)
+}
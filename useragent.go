@@ -0,0 +1,67 @@
+package gentleman
+
+import (
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// ProductToken returns the RFC 7231 User-Agent product token composed by the given
+// product name, optional version and comments, such as "app/1.0 (linux; amd64)".
+// Fails with ErrInvalidUserAgent if the product name or version are not valid HTTP tokens.
+func ProductToken(product, version string, comments ...string) (string, error) {
+	if !validToken(product) || version != "" && !validToken(version) {
+		return "", ErrInvalidUserAgent
+	}
+
+	token := product
+	if version != "" {
+		token += "/" + version
+	}
+
+	var parts []string
+	for _, comment := range comments {
+		if comment = strings.TrimSpace(comment); comment != "" {
+			parts = append(parts, escapeComment(comment))
+		}
+	}
+	if len(parts) > 0 {
+		token += " (" + strings.Join(parts, "; ") + ")"
+	}
+	return token, nil
+}
+
+// userAgent creates the plugin adding the given product token to the outgoing
+// request User-Agent header, preserving the tokens defined by parent clients.
+// The library token is always kept as the less significant product.
+func userAgent(product, version string, comments ...string) plugin.Plugin {
+	return plugin.NewRequestPlugin(func(ctx *context.Context, h context.Handler) {
+		token, err := ProductToken(product, version, comments...)
+		if err != nil {
+			h.Error(ctx, err)
+			return
+		}
+		ctx.Request.Header.Set("User-Agent", composeUserAgent(ctx.Request.Header.Get("User-Agent"), token))
+		h.Next(ctx)
+	})
+}
+
+// composeUserAgent adds the product token to the given User-Agent header value,
+// before the trailing library token, if present.
+func composeUserAgent(header, token string) string {
+	switch {
+	case header == "":
+		return token
+	case header == UserAgent:
+		return token + " " + UserAgent
+	case strings.HasSuffix(header, " "+UserAgent):
+		return strings.TrimSuffix(header, UserAgent) + token + " " + UserAgent
+	}
+	return header + " " + token
+}
+
+// escapeComment escapes the parentheses and backslashes of a User-Agent comment.
+func escapeComment(comment string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(comment)
+}
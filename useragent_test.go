@@ -0,0 +1,43 @@
+package gentleman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/plugins/headers"
+)
+
+func TestClientUserAgent(t *testing.T) {
+	var agent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	parent := New().URL(ts.URL).UserAgent("app", "1.0")
+	child := New().UseParent(parent).UserAgent("module", "2.1", "linux", "amd64")
+
+	_, err := parent.Request().Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, agent, "app/1.0 "+UserAgent)
+
+	_, err = child.Request().UserAgent("task", "", "retry (2)").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, agent, `app/1.0 module/2.1 (linux; amd64) task (retry \(2\)) `+UserAgent)
+
+	_, err = parent.Request().Use(headers.Set("User-Agent", "custom")).UserAgent("task", "3").Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, agent, "custom task/3")
+}
+
+func TestUserAgentInvalidProduct(t *testing.T) {
+	_, err := ProductToken("my app", "1.0")
+	st.Expect(t, err, ErrInvalidUserAgent)
+	_, err = ProductToken("app", "1.0/beta")
+	st.Expect(t, err, ErrInvalidUserAgent)
+
+	_, err = New().URL("http://localhost").UserAgent("", "1.0").Request().Send()
+	st.Expect(t, err, ErrInvalidUserAgent)
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// DefaultMaxBufferSize defines the default maximum capacity
+// of the buffers retained by a BufferPool.
+const DefaultMaxBufferSize = 1 << 20
+
+// DefaultBufferPool stores the buffer pool used by gentleman to buffer
+// request and response bodies. It can be replaced to tune the retained buffers size.
+var DefaultBufferPool = NewBufferPool(DefaultMaxBufferSize)
+
+// BufferPool represents a pool of reusable byte buffers used to buffer
+// request and response bodies. Buffers which grow beyond the pool maximum size
+// are released to the garbage collector instead of being retained,
+// preventing large payloads from permanently inflating the heap.
+type BufferPool struct {
+	// maxSize stores the maximum capacity of the retained buffers.
+	maxSize int
+
+	// pool stores the retained buffers.
+	pool sync.Pool
+}
+
+// NewBufferPool creates a new buffer pool retaining buffers up to the given capacity.
+// A zero or negative max size disables buffer retention.
+func NewBufferPool(maxSize int) *BufferPool {
+	return &BufferPool{maxSize: maxSize}
+}
+
+// MaxSize returns the maximum capacity of the retained buffers.
+func (p *BufferPool) MaxSize() int {
+	return p.maxSize
+}
+
+// Get returns an empty buffer from the pool.
+func (p *BufferPool) Get() *bytes.Buffer {
+	if buf, ok := p.pool.Get().(*bytes.Buffer); ok {
+		return buf
+	}
+	return new(bytes.Buffer)
+}
+
+// Put resets and returns the given buffer to the pool.
+// The buffer must not be used after calling Put.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > p.maxSize {
+		return
+	}
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+// ReadCloser returns a reader of the given buffer which returns it to the pool
+// once closed, suitable for request bodies. Read and Close can be called concurrently.
+func (p *BufferPool) ReadCloser(buf *bytes.Buffer) io.ReadCloser {
+	return &bufferReader{pool: p, buf: buf}
+}
+
+// bufferReader implements an io.ReadCloser of a pooled buffer.
+type bufferReader struct {
+	mtx  sync.Mutex
+	pool *BufferPool
+	buf  *bytes.Buffer
+}
+
+// Read reads from the buffer, returning io.EOF once closed.
+func (r *bufferReader) Read(b []byte) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.buf == nil {
+		return 0, io.EOF
+	}
+	return r.buf.Read(b)
+}
+
+// Close returns the buffer to the pool.
+func (r *bufferReader) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.pool.Put(r.buf)
+	r.buf = nil
+	return nil
+}
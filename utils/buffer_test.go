@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := NewBufferPool(16)
+	if pool.MaxSize() != 16 {
+		t.Fatalf("Invalid max size: %d", pool.MaxSize())
+	}
+
+	buf := pool.Get()
+	if buf.Len() != 0 {
+		t.Fatal("Buffer must be empty")
+	}
+	buf.WriteString("foo")
+	pool.Put(buf)
+
+	// Buffers above the max size are not retained
+	large := bytes.NewBuffer(make([]byte, 0, 32))
+	pool.Put(large)
+	for i := 0; i < 10; i++ {
+		if pool.Get() == large {
+			t.Fatal("Large buffers must not be retained")
+		}
+	}
+	pool.Put(nil)
+}
+
+func TestBufferPoolReadCloser(t *testing.T) {
+	pool := NewBufferPool(DefaultMaxBufferSize)
+	buf := pool.Get()
+	buf.WriteString("foo bar")
+
+	rc := pool.ReadCloser(buf)
+	body, err := ioutil.ReadAll(rc)
+	if err != nil || string(body) != "foo bar" {
+		t.Fatalf("Invalid body: %s", body)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closed readers do not access the released buffer
+	if n, err := rc.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Fatalf("Closed reader must return EOF: %s", err)
+	}
+}
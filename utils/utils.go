@@ -27,6 +27,49 @@ func WriteBodyString(res *http.Response, body string) {
 	res.ContentLength = int64(len(body))
 }
 
+// ReplayBody reads the whole response body and replaces it by a replayable copy,
+// so the body can be inspected by a plugin and read again by subsequent plugins
+// or the response consumer. Returns the body bytes, which must not be modified.
+func ReplayBody(res *http.Response) ([]byte, error) {
+	if res.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// ReplayRequestBody reads the whole request body, if any, defining GetBody,
+// so the body can be inspected by a plugin and sent again on retries and redirects.
+// If GetBody is already defined, the body is read from a GetBody copy instead.
+// Returns the body bytes, which must not be modified, or nil if the request has no body.
+func ReplayRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(body)
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(data))
+	return data, nil
+}
+
 // StringReader creates an io.ReadCloser interface from a string.
 func StringReader(body string) io.ReadCloser {
 	b := bytes.NewReader([]byte(body))
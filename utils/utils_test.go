@@ -3,6 +3,7 @@ package utils
 import (
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +35,46 @@ func TestWriteBodyString(t *testing.T) {
 		t.Fatal("Invalid body data")
 	}
 }
+
+func TestReplayBody(t *testing.T) {
+	res := &http.Response{}
+	WriteBodyString(res, "hello world")
+
+	body, err := ReplayBody(res)
+	if err != nil || string(body) != "hello world" {
+		t.Fatalf("Invalid body data: %s", body)
+	}
+
+	contents, _ := ioutil.ReadAll(res.Body)
+	if string(contents) != "hello world" {
+		t.Fatal("Body must be replayed")
+	}
+
+	if body, _ := ReplayBody(&http.Response{}); body != nil {
+		t.Fatal("Empty body must be nil")
+	}
+}
+
+func TestReplayRequestBody(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://foo", ioutil.NopCloser(strings.NewReader("hello world")))
+	req.ContentLength = -1
+
+	body, err := ReplayRequestBody(req)
+	if err != nil || string(body) != "hello world" {
+		t.Fatalf("Invalid body data: %s", body)
+	}
+	if req.ContentLength != 11 || req.GetBody == nil {
+		t.Fatal("Body must be replayable")
+	}
+
+	// The body is read again from GetBody
+	body, _ = ReplayRequestBody(req)
+	contents, _ := ioutil.ReadAll(req.Body)
+	if string(body) != "hello world" || string(contents) != "hello world" {
+		t.Fatal("Body must be replayed")
+	}
+
+	if body, _ := ReplayRequestBody(&http.Request{Body: http.NoBody}); body != nil {
+		t.Fatal("Empty body must be nil")
+	}
+}
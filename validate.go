@@ -0,0 +1,107 @@
+package gentleman
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"gopkg.in/h2non/gentleman.v2/context"
+	"gopkg.in/h2non/gentleman.v2/plugin"
+)
+
+// validationKey is the context store key used to collect the errors
+// reported by the request validators.
+const validationKey = "$validationErrors"
+
+// Validator represents a function inspecting the final outgoing request before it is sent.
+// A non-nil error vetoes the request. See Client.Validate() and Request.Validate().
+type Validator func(req *http.Request) error
+
+// ValidationError is returned when the outgoing request is vetoed by one or more validators.
+type ValidationError struct {
+	// Errors stores the errors reported by the failed validators, in registration order.
+	Errors []error
+}
+
+// Error returns the error message, listing the validation errors.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "gentleman: invalid request: " + strings.Join(msgs, "; ")
+}
+
+// validate creates the validate phase plugin collecting the errors of the given validators.
+func validate(validators ...Validator) plugin.Plugin {
+	return plugin.NewPhasePlugin("validate", func(ctx *context.Context, h context.Handler) {
+		errs, _ := ctx.Get(validationKey).([]error)
+		for _, validator := range validators {
+			if err := validator(ctx.Request); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			ctx.Set(validationKey, errs)
+		}
+		h.Next(ctx)
+	})
+}
+
+// validationError returns the validation error collected in the given context, if any.
+func validationError(ctx *context.Context) error {
+	errs, _ := ctx.Get(validationKey).([]error)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// RequireHeader returns a Validator requiring the given headers to be present with a non-empty value.
+func RequireHeader(names ...string) Validator {
+	return func(req *http.Request) error {
+		var missing []string
+		for _, name := range names {
+			if req.Header.Get(name) == "" {
+				missing = append(missing, http.CanonicalHeaderKey(name))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing required header: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}
+}
+
+// RequireHost returns a Validator requiring the request URL to define the server host.
+func RequireHost() Validator {
+	return func(req *http.Request) error {
+		if req.URL == nil || req.URL.Host == "" {
+			return errors.New("missing URL host")
+		}
+		return nil
+	}
+}
+
+// RequireBody returns a Validator requiring a request body to be defined for the
+// requests using the given methods, which default to POST, PUT and PATCH.
+func RequireBody(methods ...string) Validator {
+	if len(methods) == 0 {
+		methods = []string{"POST", "PUT", "PATCH"}
+	}
+	return func(req *http.Request) error {
+		for _, method := range methods {
+			if strings.EqualFold(req.Method, method) && emptyRequestBody(req) {
+				return fmt.Errorf("missing request body for %s request", req.Method)
+			}
+		}
+		return nil
+	}
+}
+
+// emptyRequestBody returns true if the given request body is not defined.
+func emptyRequestBody(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || reflect.TypeOf(req.Body) == emptyBody
+}
@@ -0,0 +1,71 @@
+package gentleman
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/context"
+)
+
+func TestValidate(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	var reported error
+	cli := New().URL(ts.URL).Validate(RequireHost(), RequireHeader("X-Request-Id", "X-Tenant"), RequireBody())
+	cli.UseError(func(ctx *context.Context, h context.Handler) {
+		reported = ctx.Error
+		h.Next(ctx)
+	})
+
+	_, err := cli.Post().SetHeader("X-Tenant", "acme").Send()
+	st.Expect(t, err, reported)
+	verr, ok := err.(*ValidationError)
+	st.Expect(t, ok, true)
+	st.Expect(t, len(verr.Errors), 2)
+	st.Expect(t, err.Error(), "gentleman: invalid request: missing required header: X-Request-Id; missing request body for POST request")
+	st.Expect(t, calls, 0)
+
+	// Validators inspect the final request
+	res, err := cli.Post().BodyString("data").UseHandler("before dial", func(ctx *context.Context, h context.Handler) {
+		ctx.Request.Header.Set("X-Request-Id", "123")
+		ctx.Request.Header.Set("X-Tenant", "acme")
+		h.Next(ctx)
+	}).Send()
+	st.Expect(t, err, nil)
+	st.Expect(t, res.StatusCode, 200)
+	st.Expect(t, calls, 1)
+
+	// Request validators and custom validators
+	fail := errors.New("forbidden path")
+	_, err = cli.Get().SetHeader("X-Request-Id", "123").SetHeader("X-Tenant", "acme").Path("/admin").Validate(func(req *http.Request) error {
+		if req.URL.Path == "/admin" {
+			return fail
+		}
+		return nil
+	}).Send()
+	st.Expect(t, err.(*ValidationError).Errors, []error{fail})
+
+	// Build also validates the request
+	_, err = cli.Delete().Build(nil)
+	_, ok = err.(*ValidationError)
+	st.Expect(t, ok, true)
+	st.Expect(t, calls, 1)
+}
+
+func TestValidators(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "/path", nil)
+	st.Reject(t, RequireHost()(req), nil)
+	st.Reject(t, RequireBody()(req), nil)
+	st.Expect(t, RequireBody("POST")(req), nil)
+	st.Reject(t, RequireHeader("Authorization")(req), nil)
+
+	req.Header.Set("Authorization", "Bearer token")
+	st.Expect(t, RequireHeader("authorization")(req), nil)
+}
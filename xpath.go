@@ -0,0 +1,399 @@
+package gentleman
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// xpathTestExpr matches the supported XPath node tests.
+var xpathTestExpr = regexp.MustCompile(`^(?:\.\.?|text\(\)|node\(\)|@?(?:\*|[\pL_][\pL\pN_.\-]*(?::[\pL_][\pL\pN_.\-]*)?))$`)
+
+// xpathNode represents a document node used to evaluate XPath expressions.
+type xpathNode struct {
+	// name stores the element or attribute local name.
+	name string
+
+	// text stores the text or attribute value.
+	text string
+
+	// attr stores if the node represents an attribute.
+	attr bool
+
+	// element stores if the node represents an element.
+	element bool
+
+	// attrs stores the element attributes.
+	attrs []*xpathNode
+
+	// children stores the element and text child nodes.
+	children []*xpathNode
+
+	// parent stores the parent node, or nil for the document node.
+	parent *xpathNode
+}
+
+// xpathStep represents a compiled XPath location step.
+type xpathStep struct {
+	// descendant stores if the step uses the descendant axis (//).
+	descendant bool
+
+	// test stores the node test: a name, "*", "@name", "@*", "text()", "node()", "." or "..".
+	test string
+
+	// predicates stores the step predicates.
+	predicates []string
+}
+
+// XPath evaluates the given XPath expression against the XML or HTML response body
+// and returns the string value of the matched nodes, such as `//item[@type='book']/title`.
+// HTML bodies are detected by the response Content-Type.
+// Supported syntax: absolute and relative paths, the child (/) and descendant (//)
+// axes, name tests (name, prefix:name or *), attributes (@name or @*), text(), "." and "..",
+// and predicates by position ([1] or [last()]), attribute ([@id] or [@id='foo'])
+// or child value ([name] or [name='foo']).
+func (r *Response) XPath(expr string) ([]string, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	steps, err := compileXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.populateResponseByteBuffer()
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	var doc *xpathNode
+	if strings.Contains(strings.ToLower(r.Header.Get("Content-Type")), "html") {
+		node, err := r.HTML()
+		if err != nil {
+			return nil, err
+		}
+		doc = htmlToXPath(node)
+	} else if doc, err = parseXPathXML(bytes.NewReader(r.buffer.Bytes())); err != nil {
+		return nil, err
+	}
+
+	nodes := []*xpathNode{doc}
+	for _, step := range steps {
+		nodes = step.eval(nodes)
+	}
+
+	values := make([]string, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.value()
+	}
+	return values, nil
+}
+
+// compileXPath parses the given XPath expression.
+func compileXPath(expr string) ([]xpathStep, error) {
+	invalid := &ExpressionError{Syntax: "XPath", Expr: expr}
+
+	path := strings.TrimSpace(expr)
+	if path == "" {
+		return nil, invalid
+	}
+	path = strings.TrimPrefix(path, "/")
+
+	var steps []xpathStep
+	descendant := strings.HasPrefix(expr, "//")
+	if descendant {
+		path = path[1:]
+	}
+
+	for _, part := range splitXPath(path) {
+		if part == "" {
+			if descendant {
+				return nil, invalid
+			}
+			descendant = true
+			continue
+		}
+
+		step := xpathStep{descendant: descendant}
+		descendant = false
+
+		if i := strings.Index(part, "["); i >= 0 {
+			step.test = part[:i]
+			for rest := part[i:]; rest != ""; {
+				end := indexBracket(rest, 0)
+				if rest[0] != '[' || end < 0 {
+					return nil, invalid
+				}
+				predicate := strings.TrimSpace(rest[1:end])
+				if !validXPathPredicate(predicate) {
+					return nil, invalid
+				}
+				step.predicates = append(step.predicates, predicate)
+				rest = rest[end+1:]
+			}
+		} else {
+			step.test = part
+		}
+
+		if !xpathTestExpr.MatchString(step.test) {
+			return nil, invalid
+		}
+		steps = append(steps, step)
+	}
+
+	if descendant {
+		return nil, invalid
+	}
+	return steps, nil
+}
+
+// validXPathPredicate returns true if the given predicate is supported:
+// a position, last(), or a node test optionally compared with a string literal.
+func validXPathPredicate(predicate string) bool {
+	if predicate == "last()" {
+		return true
+	}
+	if _, err := strconv.Atoi(predicate); err == nil {
+		return true
+	}
+
+	name := predicate
+	if i := strings.Index(predicate, "="); i > 0 {
+		name = strings.TrimSpace(predicate[:i])
+		value := strings.TrimSpace(predicate[i+1:])
+		if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] ||
+			strings.IndexByte(value[1:len(value)-1], value[0]) >= 0 {
+			return false
+		}
+	}
+	return xpathTestExpr.MatchString(name)
+}
+
+// splitXPath splits the given path by slashes outside predicates.
+func splitXPath(path string) []string {
+	var parts []string
+	var quote byte
+	depth, last := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			parts = append(parts, path[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, path[last:])
+}
+
+// eval applies the step to the given context nodes and returns the matched nodes.
+func (s xpathStep) eval(nodes []*xpathNode) []*xpathNode {
+	var matches []*xpathNode
+	seen := make(map[*xpathNode]bool)
+
+	for _, node := range nodes {
+		contexts := []*xpathNode{node}
+		if s.descendant {
+			contexts = node.descendants(nil)
+		}
+
+		for _, ctx := range contexts {
+			for _, match := range s.filter(s.candidates(ctx)) {
+				if !seen[match] {
+					seen[match] = true
+					matches = append(matches, match)
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// candidates returns the nodes of the given context node matching the step node test.
+func (s xpathStep) candidates(ctx *xpathNode) []*xpathNode {
+	var nodes []*xpathNode
+	switch {
+	case s.test == ".":
+		return []*xpathNode{ctx}
+	case s.test == "..":
+		if ctx.parent != nil {
+			nodes = append(nodes, ctx.parent)
+		}
+	case strings.HasPrefix(s.test, "@"):
+		for _, attr := range ctx.attrs {
+			if s.test == "@*" || localName(s.test[1:]) == attr.name {
+				nodes = append(nodes, attr)
+			}
+		}
+	default:
+		for _, child := range ctx.children {
+			if matchXPathTest(s.test, child) {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes
+}
+
+// filter applies the step predicates to the given nodes.
+func (s xpathStep) filter(nodes []*xpathNode) []*xpathNode {
+	for _, predicate := range s.predicates {
+		var filtered []*xpathNode
+		for i, node := range nodes {
+			if matchXPathPredicate(predicate, node, i+1, len(nodes)) {
+				filtered = append(filtered, node)
+			}
+		}
+		nodes = filtered
+	}
+	return nodes
+}
+
+// matchXPathTest returns true if the given node matches the node test.
+func matchXPathTest(test string, node *xpathNode) bool {
+	switch test {
+	case "node()":
+		return true
+	case "text()":
+		return !node.element
+	case "*":
+		return node.element
+	}
+	return node.element && node.name == localName(test)
+}
+
+// matchXPathPredicate returns true if the given node at the given position matches the predicate.
+func matchXPathPredicate(predicate string, node *xpathNode, position, size int) bool {
+	if predicate == "last()" {
+		return position == size
+	}
+	if n, err := strconv.Atoi(predicate); err == nil {
+		return position == n
+	}
+
+	name, value, compare := predicate, "", false
+	if i := strings.Index(predicate, "="); i > 0 {
+		name, value, compare = strings.TrimSpace(predicate[:i]), strings.TrimSpace(predicate[i+1:]), true
+		if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+			return false
+		}
+		value = value[1 : len(value)-1]
+	}
+
+	for _, match := range (xpathStep{test: name}).candidates(node) {
+		if !compare || match.value() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// localName returns the given qualified name without namespace prefix.
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// value returns the XPath string value of the node.
+func (n *xpathNode) value() string {
+	if !n.element {
+		return n.text
+	}
+	var buf strings.Builder
+	for _, node := range n.descendants(nil) {
+		if !node.element {
+			buf.WriteString(node.text)
+		}
+	}
+	return buf.String()
+}
+
+// descendants appends the node and all its descendants, in document order.
+func (n *xpathNode) descendants(nodes []*xpathNode) []*xpathNode {
+	nodes = append(nodes, n)
+	for _, child := range n.children {
+		nodes = child.descendants(nodes)
+	}
+	return nodes
+}
+
+// parseXPathXML parses the given XML document.
+func parseXPathXML(r io.Reader) (*xpathNode, error) {
+	doc := &xpathNode{element: true}
+	stack := []*xpathNode{doc}
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xpathNode{name: t.Name.Local, element: true, parent: parent}
+			for _, attr := range t.Attr {
+				node.attrs = append(node.attrs, &xpathNode{name: attr.Name.Local, text: attr.Value, attr: true, parent: node})
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				parent.children = append(parent.children, &xpathNode{text: string(t), parent: parent})
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// htmlToXPath converts the given HTML node tree.
+func htmlToXPath(n *html.Node) *xpathNode {
+	node := &xpathNode{name: n.Data, element: true}
+	if n.Type == html.DocumentNode {
+		node.name = ""
+	}
+	for _, attr := range n.Attr {
+		node.attrs = append(node.attrs, &xpathNode{name: attr.Key, text: attr.Val, attr: true, parent: node})
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			child := htmlToXPath(c)
+			child.parent = node
+			node.children = append(node.children, child)
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				node.children = append(node.children, &xpathNode{text: c.Data, parent: node})
+			}
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,94 @@
+package gentleman
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nbio/st"
+	"gopkg.in/h2non/gentleman.v2/utils"
+)
+
+const xpathXML = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <catalog>
+      <item id="1" type="book"><title>Foo</title><price>10</price></item>
+      <item id="2" type="music"><title>Bar</title><price>20</price></item>
+      <item id="3" type="book"><title>Baz</title><price>30</price></item>
+    </catalog>
+  </soap:Body>
+</soap:Envelope>`
+
+func xpathResponse(contentType, body string) *Response {
+	ctx := NewContext()
+	ctx.Response.Header.Set("Content-Type", contentType)
+	utils.WriteBodyString(ctx.Response, body)
+	res, _ := buildResponse(ctx)
+	return res
+}
+
+func TestResponseXPath(t *testing.T) {
+	cases := []struct {
+		expr   string
+		values []string
+	}{
+		{"//item/title", []string{"Foo", "Bar", "Baz"}},
+		{"/soap:Envelope/soap:Body/catalog/item[1]/title", []string{"Foo"}},
+		{"//item[last()]/@id", []string{"3"}},
+		{"//item[@type='book']/title/text()", []string{"Foo", "Baz"}},
+		{"//item[price='20']/@type", []string{"music"}},
+		{"//catalog/*[@id]/@id", []string{"1", "2", "3"}},
+		{"//item[@type='book'][2]/title", []string{"Baz"}},
+		{"//item[2]", []string{"Bar20"}},
+		{"//title[.='Bar']/../@id", []string{"2"}},
+		{"//item/@type/..", []string{"Foo10", "Bar20", "Baz30"}},
+		{"//price/../../item[@type='music']/title", []string{"Bar"}},
+		{"/..", []string{}},
+		{"//missing", []string{}},
+	}
+
+	res := xpathResponse("application/xml", xpathXML)
+	for _, test := range cases {
+		values, err := res.XPath(test.expr)
+		st.Expect(t, err, nil)
+		st.Expect(t, values, test.values)
+	}
+}
+
+func TestResponseXPathHTML(t *testing.T) {
+	res := xpathResponse("text/html", `<html><head><title>Foo</title></head><body><ul><li><a href="/foo">foo</a></li><li><a href="/bar">bar</a></li></ul></body></html>`)
+
+	values, err := res.XPath("//a/@href")
+	st.Expect(t, err, nil)
+	st.Expect(t, values, []string{"/foo", "/bar"})
+
+	values, err = res.XPath("//title")
+	st.Expect(t, err, nil)
+	st.Expect(t, values, []string{"Foo"})
+
+	values, err = res.XPath("//a[.='bar']/../../li[1]/a/@href")
+	st.Expect(t, err, nil)
+	st.Expect(t, values, []string{"/foo"})
+}
+
+func TestResponseXPathInvalid(t *testing.T) {
+	res := xpathResponse("application/xml", xpathXML)
+	for _, expr := range []string{"", "//", "/foo//", "//item[1"} {
+		_, err := res.XPath(expr)
+		st.Reject(t, err, nil)
+	}
+
+	// Unsupported functions and operators are reported as expression errors
+	for _, expr := range []string{
+		"//item[position()=1]", "//item[@type!='book']", "//item[@type='book' or @id]",
+		"//item[contains(title, 'Foo')]", "//item[price>10]", "count(//item)", "//item/title()",
+	} {
+		_, err := res.XPath(expr)
+		var exprErr *ExpressionError
+		st.Expect(t, errors.As(err, &exprErr), true)
+	}
+
+	res = xpathResponse("application/xml", "<foo><bar></foo>")
+	_, err := res.XPath("//bar")
+	st.Reject(t, err, nil)
+}